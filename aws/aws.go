@@ -2,12 +2,13 @@
 package aws
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"path/filepath"
+	"strings"
 
 	"github.com/fox-tech/creds-fetcher/client"
 	"github.com/fox-tech/creds-fetcher/fsmanager"
@@ -22,14 +23,17 @@ var (
 	CredentialsDirectory = ".aws"
 	CredentialsFileName  = "credentials"
 
-	ErrBadRequest        = errors.New("invalid request to STS")
-	ErrBadResponse       = errors.New("could not read response from STS")
-	ErrFailedMarshal     = errors.New("encoding credentials failed")
-	ErrFailedUnmarshal   = errors.New("decoding credentials failed")
-	ErrFileHandlerFailed = errors.New("error handling file")
-	ErrMissingProfile    = errors.New("profile required to create provider")
-	ErrNotAuthorized     = errors.New("authentication failed")
-	ErrUnknown           = errors.New("unexpected error ocurred")
+	ErrBadRequest         = errors.New("invalid request to STS")
+	ErrBadResponse        = errors.New("could not read response from STS")
+	ErrClusterNotFound    = errors.New("cluster not found")
+	ErrCredentialsExpired = errors.New("credentials for profile expired, login again")
+	ErrFailedMarshal      = errors.New("encoding credentials failed")
+	ErrFailedUnmarshal    = errors.New("decoding credentials failed")
+	ErrFileHandlerFailed  = errors.New("error handling file")
+	ErrMissingProfile     = errors.New("profile required to create provider")
+	ErrNoCredentials      = errors.New("no credentials found for profile, login first")
+	ErrNotAuthorized      = errors.New("authentication failed")
+	ErrUnknown            = errors.New("unexpected error ocurred")
 
 	ioReadAll    = io.ReadAll
 	iniMarshal   = ini.Marshal
@@ -41,11 +45,42 @@ type Profile struct {
 	Name         string
 	RoleARN      string
 	PrincipalARN string
+
+	// WebIdentity indicates credentials are requested with an OIDC token
+	// instead of a SAML assertion, so no principal is needed
+	WebIdentity bool
+
+	// Direct indicates the credentials of the role are obtained without STS,
+	// e.g. from IAM Identity Center, and saved with SaveCredentials, so only
+	// the name is needed. AccountID is the account of the role when there's
+	// no RoleARN
+	Direct    bool
+	AccountID string
+
+	// Stored indicates only the saved credentials of the profile are used,
+	// e.g. to show or remove them, whatever its type, so only the name is
+	// needed
+	Stored bool
 }
 
-// IsEmpty verifies whether all fields of the profile are empty.
+// IsEmpty verifies whether the fields required for the profile are empty.
 func (p Profile) IsEmpty() bool {
-	return p.Name == "" || p.RoleARN == "" || p.PrincipalARN == ""
+	if p.Direct || p.Stored {
+		return p.Name == ""
+	}
+
+	return p.Name == "" || p.RoleARN == "" || (p.PrincipalARN == "" && !p.WebIdentity)
+}
+
+// AccountID returns the account ID of the given ARN, or an empty string if it
+// is not a valid ARN.
+func AccountID(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" {
+		return ""
+	}
+
+	return parts[4]
 }
 
 // Provider exposes the methods to interact with AWS
@@ -53,14 +88,17 @@ type Provider struct {
 	fs fileSystemManager
 	httpClient
 
+	// eksURL and ecrURL override the regional EKS and ECR endpoints when set
+	eksURL string
+	ecrURL string
+	// stsURL overrides STSURL when set
+	stsURL string
+
 	Profile Profile
 }
 
 // httpClient defines the methods that the provider needs an http client to have
-type httpClient interface {
-	Get(r_url string, params map[string]string, body io.Reader) (*http.Response, error)
-	Post(r_url string, params map[string]string, headers map[string]string, body interface{}) (*http.Response, error)
-}
+type httpClient = client.HTTPClient
 
 // fileSystemManager defines the methods that the provider needs a file system
 // manager to have
@@ -90,16 +128,16 @@ func New(prf Profile, opts ...Option) (Provider, error) {
 }
 
 // GenerateCredentials requests AWS CLI credentials using a SAML assertion
-// and saves them to a file
-func (aws Provider) GenerateCredentials(saml string) error {
+// and saves them to a file, unless ctx is done before they are saved
+func (aws Provider) GenerateCredentials(ctx context.Context, saml string) error {
 	// Exchange SAML for AWS Credentials
-	cred, err := aws.getSTSCredentialsFromSAML(saml)
+	cred, err := aws.getSTSCredentialsFromSAML(ctx, saml)
 	if err != nil {
 		return err
 	}
 
 	// Write credentials to file
-	err = aws.updateCredentialsFile(cred)
+	err = aws.updateCredentialsFile(ctx, cred)
 	if err != nil {
 		return err
 	}
@@ -107,8 +145,57 @@ func (aws Provider) GenerateCredentials(saml string) error {
 	return nil
 }
 
-// updateCredentialsFile reads exising credentials, adds or replaces the new credentials and saves them to file
-func (p Provider) updateCredentialsFile(newCred credentials) error {
+// Credentials represents the AWS credentials returned by STS, for callers that
+// use them directly instead of through the credentials file.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      string
+}
+
+// AssumeRoleWithWebIdentity requests AWS credentials using an OIDC token and
+// returns them without saving them.
+func (p Provider) AssumeRoleWithWebIdentity(ctx context.Context, token string) (Credentials, error) {
+	cred, err := p.getSTSCredentialsFromWebIdentity(ctx, token)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	return Credentials{
+		AccessKeyID:     cred.AccessKeyId,
+		SecretAccessKey: cred.SecretAccessKey,
+		SessionToken:    cred.SessionToken,
+		Expiration:      cred.Expiration,
+	}, nil
+}
+
+// GenerateCredentialsWithWebIdentity requests AWS CLI credentials using an
+// OIDC token and saves them to a file, unless ctx is done before they are saved
+func (p Provider) GenerateCredentialsWithWebIdentity(ctx context.Context, token string) error {
+	cred, err := p.getSTSCredentialsFromWebIdentity(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	return p.updateCredentialsFile(ctx, cred)
+}
+
+// SaveCredentials saves credentials obtained without STS, e.g. from a
+// credential provider plugin, to the credentials file, unless ctx is done
+// before they are saved.
+func (p Provider) SaveCredentials(ctx context.Context, cred Credentials) error {
+	return p.updateCredentialsFile(ctx, credentials{
+		AccessKeyId:     cred.AccessKeyID,
+		SecretAccessKey: cred.SecretAccessKey,
+		SessionToken:    cred.SessionToken,
+		Expiration:      cred.Expiration,
+	})
+}
+
+// updateCredentialsFile reads exising credentials, adds or replaces the new credentials and saves them to file.
+// Nothing is written if ctx is done.
+func (p Provider) updateCredentialsFile(ctx context.Context, newCred credentials) error {
 	log.Print("updating credentials file...")
 
 	data, err := p.fs.ReadFile(CredentialsDirectory, CredentialsFileName)
@@ -128,6 +215,10 @@ func (p Provider) updateCredentialsFile(newCred credentials) error {
 		return fmt.Errorf("%w: %v", ErrFailedMarshal, err)
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	credentialsFilepath := filepath.Join(CredentialsDirectory, CredentialsFileName)
 	if err = p.fs.WriteFile(credentialsFilepath, writeData); err != nil {
 		return fmt.Errorf("%w: %v", ErrFileHandlerFailed, err)
@@ -136,3 +227,47 @@ func (p Provider) updateCredentialsFile(newCred credentials) error {
 	log.Print("credentials saved to file")
 	return nil
 }
+
+// readCredentials returns the credentials stored in the credentials file for
+// the provider profile. Returns ErrNoCredentials if there are none.
+func (p Provider) readCredentials() (credentials, error) {
+	data, err := p.fs.ReadFile(CredentialsDirectory, CredentialsFileName)
+	if err != nil {
+		return credentials{}, fmt.Errorf("%w: %v", ErrFileHandlerFailed, err)
+	}
+
+	creds := map[string]credentials{}
+	if err = iniUnmarshal(data, creds); err != nil {
+		return credentials{}, fmt.Errorf("%w: %v", ErrFailedUnmarshal, err)
+	}
+
+	cred, ok := creds[p.Profile.Name]
+	if !ok || cred.AccessKeyId == "" || cred.SecretAccessKey == "" {
+		return credentials{}, fmt.Errorf("%w: %s", ErrNoCredentials, p.Profile.Name)
+	}
+
+	return cred, nil
+}
+
+// RemoveCredentials deletes the credentials of the provider profile from the
+// credentials file and reports whether there were any. The rest of the file
+// is left as it is, including profiles not written by the provider.
+func (p Provider) RemoveCredentials() (bool, error) {
+	data, err := p.fs.ReadFile(CredentialsDirectory, CredentialsFileName)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrFileHandlerFailed, err)
+	}
+
+	data, found := ini.RemoveSection(data, p.Profile.Name)
+	if !found {
+		return false, nil
+	}
+
+	credentialsFilepath := filepath.Join(CredentialsDirectory, CredentialsFileName)
+	if err := p.fs.WriteFile(credentialsFilepath, data); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrFileHandlerFailed, err)
+	}
+
+	log.Printf("credentials of profile %s removed from file", p.Profile.Name)
+	return true, nil
+}
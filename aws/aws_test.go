@@ -2,11 +2,15 @@ package aws
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"errors"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"path"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/fox-tech/creds-fetcher/client"
@@ -88,7 +92,7 @@ func TestNew(t *testing.T) {
 			args: args{
 				p: prf,
 				opts: []Option{
-					setHTTPClient(mckClient),
+					SetHTTPClient(mckClient),
 				},
 			},
 			expect: expect{
@@ -196,6 +200,7 @@ func TestGetSTSCredentialsFromSAML(t *testing.T) {
 					AccessKeyId:     "AWSACCESSKEYID",
 					SecretAccessKey: "Super/Secret/AccessKey",
 					SessionToken:    "reallylongandsecretsessiontoken",
+					Expiration:      "2022-06-07T22:54:14Z",
 				},
 				err: nil,
 			},
@@ -310,11 +315,11 @@ func TestGetSTSCredentialsFromSAML(t *testing.T) {
 			defer resetOpts()
 
 			p, _ := New(tt.opts.p,
-				setHTTPClient(tt.opts.mckClient),
+				SetHTTPClient(tt.opts.mckClient),
 				setFileManager(tt.opts.mckFs),
 			)
 
-			cred, err := p.getSTSCredentialsFromSAML(tt.arg)
+			cred, err := p.getSTSCredentialsFromSAML(context.Background(), tt.arg)
 
 			if !errors.Is(err, tt.expect.err) {
 				t.Errorf("getSTSCredentialsFromSAML() expected error: %s, got: %s", tt.expect.err, err)
@@ -322,7 +327,8 @@ func TestGetSTSCredentialsFromSAML(t *testing.T) {
 
 			if cred.AccessKeyId != tt.expect.cred.AccessKeyId ||
 				cred.SecretAccessKey != tt.expect.cred.SecretAccessKey ||
-				cred.SessionToken != tt.expect.cred.SessionToken {
+				cred.SessionToken != tt.expect.cred.SessionToken ||
+				cred.Expiration != tt.expect.cred.Expiration {
 				t.Errorf("getSTSCredentialsFromSAML() expected AccessKeyId: %v, got: %v", tt.expect.cred, cred)
 			}
 		})
@@ -350,8 +356,9 @@ func TestUpdateCredentialsFile(t *testing.T) {
 	credentialsFilepath := path.Join(CredentialsDirectory, CredentialsFileName)
 
 	tests := []struct {
-		name string
-		arg  credentials
+		name      string
+		arg       credentials
+		cancelled bool
 		expect
 		opts
 	}{
@@ -446,6 +453,23 @@ func TestUpdateCredentialsFile(t *testing.T) {
 				err:  ErrFileHandlerFailed,
 			},
 		},
+		{
+			name:      "context cancelled: file is not written",
+			arg:       cred,
+			cancelled: true,
+			opts: opts{
+				p: prf,
+				mckFs: fsmanager.MockFileSystem{
+					Files: map[string][]byte{
+						credentialsFilepath: []byte(credentialsFileContent),
+					},
+				},
+			},
+			expect: expect{
+				data: []byte(credentialsFileContent),
+				err:  context.Canceled,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -454,11 +478,17 @@ func TestUpdateCredentialsFile(t *testing.T) {
 			defer resetOpts()
 
 			p, _ := New(tt.opts.p,
-				setHTTPClient(tt.opts.mckClient),
+				SetHTTPClient(tt.opts.mckClient),
 				setFileManager(tt.opts.mckFs),
 			)
 
-			err := p.updateCredentialsFile(tt.arg)
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancelled {
+				cancel()
+			}
+			defer cancel()
+
+			err := p.updateCredentialsFile(ctx, tt.arg)
 
 			if !errors.Is(err, tt.expect.err) {
 				t.Errorf("updateCredentialsFile() expected error: %s, got: %s", tt.expect.err, err)
@@ -472,6 +502,84 @@ func TestUpdateCredentialsFile(t *testing.T) {
 	}
 }
 
+func TestRemoveCredentials(t *testing.T) {
+	prf := Profile{
+		Name:         "test-profile",
+		RoleARN:      "arn:aws:iam::ROLEARN",
+		PrincipalARN: "arn:aws:iam::ProviderARN",
+	}
+
+	credentialsFilepath := path.Join(CredentialsDirectory, CredentialsFileName)
+	other := "[other]\naws_access_key_id=OTHER\n# kept as written\naws_secret_access_key = secret\n\n"
+
+	tests := []struct {
+		name        string
+		fs          fsmanager.MockFileSystem
+		expectFound bool
+		expectData  []byte
+		expectErr   error
+	}{
+		{
+			name: "stored credentials: the profile is removed, others are untouched",
+			fs: fsmanager.MockFileSystem{
+				Files: map[string][]byte{
+					credentialsFilepath: []byte(other + credentialsFileContent),
+				},
+			},
+			expectFound: true,
+			expectData:  []byte(other),
+		},
+		{
+			name: "no credentials: file is unchanged",
+			fs: fsmanager.MockFileSystem{
+				Files: map[string][]byte{
+					credentialsFilepath: []byte(other),
+				},
+			},
+			expectData: []byte(other),
+		},
+		{
+			name: "error reading file: error is returned",
+			fs: fsmanager.MockFileSystem{
+				ReadErr: errors.New("broken pipe"),
+			},
+			expectData: []byte{},
+			expectErr:  ErrFileHandlerFailed,
+		},
+		{
+			name: "error writing file: error is returned",
+			fs: fsmanager.MockFileSystem{
+				Files: map[string][]byte{
+					credentialsFilepath: []byte(credentialsFileContent),
+				},
+				WriteErr: errors.New("permission denied"),
+			},
+			expectData: []byte(credentialsFileContent),
+			expectErr:  ErrFileHandlerFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := New(prf, setFileManager(tt.fs))
+
+			found, err := p.RemoveCredentials()
+			if !errors.Is(err, tt.expectErr) {
+				t.Errorf("RemoveCredentials() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if found != tt.expectFound {
+				t.Errorf("RemoveCredentials() expected found: %t, got: %t", tt.expectFound, found)
+			}
+
+			savedData, _ := tt.fs.ReadFile(CredentialsDirectory, CredentialsFileName)
+			if !bytes.Equal(savedData, tt.expectData) {
+				t.Errorf("RemoveCredentials() expected file data: %q, got: %q", tt.expectData, savedData)
+			}
+		})
+	}
+}
+
 func TestGenerateCredentials(t *testing.T) {
 	prf := Profile{
 		Name:         "test-profile",
@@ -502,7 +610,7 @@ func TestGenerateCredentials(t *testing.T) {
 			},
 			expect: expect{
 				err:  nil,
-				data: []byte(newCredentialsFileContent),
+				data: []byte(generatedCredentialsFileContent),
 			},
 		},
 		{
@@ -544,11 +652,11 @@ func TestGenerateCredentials(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p, _ := New(tt.opts.p,
-				setHTTPClient(tt.opts.mckClient),
+				SetHTTPClient(tt.opts.mckClient),
 				setFileManager(tt.opts.mckFs),
 			)
 
-			err := p.GenerateCredentials(tt.arg)
+			err := p.GenerateCredentials(context.Background(), tt.arg)
 
 			if !errors.Is(err, tt.expect.err) {
 				t.Errorf("GenerateCredentials() expected error: %s, got: %s", tt.expect.err, err)
@@ -562,6 +670,198 @@ func TestGenerateCredentials(t *testing.T) {
 	}
 }
 
+func TestGenerateCredentialsWithWebIdentity(t *testing.T) {
+	prf := Profile{
+		Name:        "test-profile",
+		RoleARN:     "arn:aws:iam::ROLEARN",
+		WebIdentity: true,
+	}
+
+	type expect struct {
+		data []byte
+		err  error
+	}
+	tests := []struct {
+		name string
+		expect
+		opts
+	}{
+		{
+			name: "sucess: credentials are generated",
+			opts: opts{
+				p: prf,
+				mckClient: client.MockHttpClient{
+					PostStatusCode: http.StatusOK,
+					PostStatus:     "OK",
+					PostBodyData:   []byte(SuccessWebIdentityResponse),
+				},
+				mckFs: fsmanager.NewMock(),
+			},
+			expect: expect{
+				err:  nil,
+				data: []byte(generatedCredentialsFileContent),
+			},
+		},
+		{
+			name: "error: invalid token",
+			opts: opts{
+				p: prf,
+				mckClient: client.MockHttpClient{
+					PostStatusCode: http.StatusBadRequest,
+					PostStatus:     "Bad Request",
+					PostBodyData:   []byte(errSTSResponse),
+				},
+				mckFs: fsmanager.NewMock(),
+			},
+			expect: expect{
+				err:  ErrBadRequest,
+				data: []byte{},
+			},
+		},
+		{
+			name: "error: response cannot be unmarshalled",
+			opts: opts{
+				p: prf,
+				mckClient: client.MockHttpClient{
+					PostStatusCode: http.StatusOK,
+					PostStatus:     "OK",
+				},
+				mckFs: fsmanager.NewMock(),
+			},
+			expect: expect{
+				err:  ErrBadResponse,
+				data: []byte{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := New(tt.opts.p,
+				SetHTTPClient(tt.opts.mckClient),
+				setFileManager(tt.opts.mckFs),
+			)
+
+			err := p.GenerateCredentialsWithWebIdentity(context.Background(), "header.eyJzdWIiOiIwMHUxIn0.signature")
+
+			if !errors.Is(err, tt.expect.err) {
+				t.Errorf("GenerateCredentialsWithWebIdentity() expected error: %s, got: %s", tt.expect.err, err)
+			}
+
+			savedData, _ := tt.opts.mckFs.ReadFile(CredentialsDirectory, CredentialsFileName)
+			if !bytes.Equal(savedData, tt.expect.data) {
+				t.Errorf("GenerateCredentialsWithWebIdentity() expected file data: %s, got: %s", tt.expect.data, savedData)
+			}
+		})
+	}
+}
+
+func TestSaveCredentials(t *testing.T) {
+	fs := fsmanager.NewMock()
+
+	p, _ := New(Profile{Name: "test-profile", RoleARN: "arn:aws:iam::ROLEARN", WebIdentity: true}, setFileManager(fs))
+
+	err := p.SaveCredentials(context.Background(), Credentials{
+		AccessKeyID:     "AWSACCESSKEYID",
+		SecretAccessKey: "Super/Secret/AccessKey",
+		SessionToken:    "sessiontoken",
+		Expiration:      "2022-06-07T22:54:14Z",
+	})
+	if err != nil {
+		t.Fatalf("SaveCredentials() unexpected error: %v", err)
+	}
+
+	expect := "[test-profile]\naws_access_key_id = AWSACCESSKEYID\naws_secret_access_key = Super/Secret/AccessKey\naws_session_token = sessiontoken\nx_security_token_expires = 2022-06-07T22:54:14Z\n\n"
+	savedData, _ := fs.ReadFile(CredentialsDirectory, CredentialsFileName)
+	if string(savedData) != expect {
+		t.Errorf("SaveCredentials() expected file data: %q, got: %q", expect, savedData)
+	}
+}
+
+func TestAssumeRoleWithWebIdentity(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("Action") != "AssumeRoleWithWebIdentity" || r.FormValue("RoleSessionName") != "00u1" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(errSTSResponse))
+			return
+		}
+
+		w.Write([]byte(SuccessWebIdentityResponse))
+	}))
+	defer s.Close()
+
+	p, err := New(Profile{Name: "ci", RoleARN: "arn:aws:iam::ROLEARN", WebIdentity: true}, SetSTSURL(s.URL))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	cred, err := p.AssumeRoleWithWebIdentity(context.Background(), "header.eyJzdWIiOiIwMHUxIn0.signature")
+	if err != nil {
+		t.Fatalf("AssumeRoleWithWebIdentity() unexpected error: %v", err)
+	}
+
+	expect := Credentials{
+		AccessKeyID:     "AWSACCESSKEYID",
+		SecretAccessKey: "Super/Secret/AccessKey",
+		SessionToken:    "reallylongandsecretsessiontoken",
+		Expiration:      "2022-06-07T22:54:14Z",
+	}
+	if cred != expect {
+		t.Errorf("AssumeRoleWithWebIdentity() expected: %v, got: %v", expect, cred)
+	}
+}
+
+func TestWebIdentitySessionName(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims string
+		expect string
+	}{
+		{
+			name:   "email claim: email is used",
+			claims: `{"sub":"00u1","email":"jane.doe@example.com"}`,
+			expect: "jane.doe@example.com",
+		},
+		{
+			name:   "no email claim: sub is used",
+			claims: `{"sub":"00u1"}`,
+			expect: "00u1",
+		},
+		{
+			name:   "invalid characters: they are replaced",
+			claims: `{"sub":"auth0|jane doe"}`,
+			expect: "auth0-jane-doe",
+		},
+		{
+			name:   "long subject: it is truncated",
+			claims: `{"sub":"` + strings.Repeat("a", 70) + `"}`,
+			expect: strings.Repeat("a", 64),
+		},
+		{
+			name:   "no claims: default name is used",
+			claims: `{}`,
+			expect: defaultSessionName,
+		},
+		{
+			name:   "invalid token: default name is used",
+			expect: defaultSessionName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := "notatoken"
+			if tt.claims != "" {
+				token = "header." + base64.RawURLEncoding.EncodeToString([]byte(tt.claims)) + ".signature"
+			}
+
+			if got := webIdentitySessionName(token); got != tt.expect {
+				t.Errorf("webIdentitySessionName() expected: %s, got: %s", tt.expect, got)
+			}
+		})
+	}
+}
+
 func TestIsEmpty(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -601,6 +901,36 @@ func TestIsEmpty(t *testing.T) {
 			},
 			expect: true,
 		},
+		{
+			name: "web identity Profile without principalARN",
+			p: Profile{
+				Name:        "test-profile",
+				RoleARN:     "role-arn",
+				WebIdentity: true,
+			},
+			expect: false,
+		},
+		{
+			name: "direct Profile without ARNs",
+			p: Profile{
+				Name:   "test-profile",
+				Direct: true,
+			},
+			expect: false,
+		},
+		{
+			name: "stored Profile without ARNs",
+			p: Profile{
+				Name:   "test-profile",
+				Stored: true,
+			},
+			expect: false,
+		},
+		{
+			name:   "stored Profile without name",
+			p:      Profile{Stored: true},
+			expect: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -612,3 +942,35 @@ func TestIsEmpty(t *testing.T) {
 		})
 	}
 }
+
+func TestAccountID(t *testing.T) {
+	tests := []struct {
+		name   string
+		arn    string
+		expect string
+	}{
+		{
+			name:   "role arn",
+			arn:    "arn:aws:iam::123456789012:role/okta-oie-ReadOnly",
+			expect: "123456789012",
+		},
+		{
+			name:   "provider arn",
+			arn:    "arn:aws:iam::123456789012:saml-provider/okta",
+			expect: "123456789012",
+		},
+		{
+			name:   "invalid arn",
+			arn:    "arn:aws:iam::ROLEARN",
+			expect: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AccountID(tt.arn); got != tt.expect {
+				t.Errorf("AccountID() expected: %v, got %v", tt.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package aws
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// codeCommitDateFormat is the timestamp format signed for CodeCommit, the
+	// password carries it with a trailing Z.
+	codeCommitDateFormat = "20060102T150405"
+	// credentialsExpirationMargin is how long before their expiration stored
+	// credentials are considered expired, so they are not used for requests
+	// that could outlive them.
+	credentialsExpirationMargin = time.Minute
+)
+
+var codeCommitHost = regexp.MustCompile(`^git-codecommit(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+
+// GitCredentials represents the username and password git uses to access a
+// CodeCommit repository over HTTPS.
+type GitCredentials struct {
+	Username string
+	Password string
+}
+
+// GenerateCodeCommitCredentials returns the git credentials to access the
+// CodeCommit repository in the given host and path, e.g.
+// git-codecommit.us-east-1.amazonaws.com and /v1/repos/my-repo. The password
+// is a signature generated locally from the stored credentials, the same way
+// git-remote-codecommit does. Returns ErrCredentialsExpired if the stored
+// credentials expired.
+func (p Provider) GenerateCodeCommitCredentials(host, path string) (GitCredentials, error) {
+	region := CodeCommitRegion(host)
+	if region == "" {
+		return GitCredentials{}, fmt.Errorf("%w: not a CodeCommit host: %s", ErrBadRequest, host)
+	}
+
+	cred, err := p.readCredentials()
+	if err != nil {
+		return GitCredentials{}, err
+	}
+
+	if cred.expired(credentialsExpirationMargin) {
+		return GitCredentials{}, fmt.Errorf("%w: %s", ErrCredentialsExpired, p.Profile.Name)
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	cr := "GIT\n" + path + "\n\nhost:" + host + "\n\nhost\n"
+	timestamp := timeNow().UTC().Format(codeCommitDateFormat)
+	signature := signer{credentials: cred, region: region, service: "codecommit"}.signature(timestamp, cr)
+
+	username := cred.AccessKeyId
+	if cred.SessionToken != "" {
+		username += "%" + cred.SessionToken
+	}
+
+	return GitCredentials{
+		Username: username,
+		Password: timestamp + "Z" + signature,
+	}, nil
+}
+
+// CodeCommitRegion returns the region of a CodeCommit git host name, e.g.
+// us-east-1 for git-codecommit.us-east-1.amazonaws.com. Returns an empty
+// string if the host is not a CodeCommit host.
+func CodeCommitRegion(host string) string {
+	m := codeCommitHost.FindStringSubmatch(strings.ToLower(host))
+	if m == nil {
+		return ""
+	}
+
+	return m[1]
+}
@@ -0,0 +1,123 @@
+package aws
+
+import (
+	"errors"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/fsmanager"
+)
+
+func TestGenerateCodeCommitCredentials(t *testing.T) {
+	prevNow := timeNow
+	defer func() { timeNow = prevNow }()
+
+	prf := Profile{
+		Name:         "test-profile",
+		RoleARN:      "arn:aws:iam::ROLEARN",
+		PrincipalARN: "arn:aws:iam::ProviderARN",
+	}
+
+	credentialsFilepath := path.Join(CredentialsDirectory, CredentialsFileName)
+	withCredentials := fsmanager.MockFileSystem{
+		Files: map[string][]byte{
+			credentialsFilepath: []byte(generatedCredentialsFileContent),
+		},
+	}
+
+	tests := []struct {
+		name string
+		host string
+		now  time.Time
+		fs   fsmanager.MockFileSystem
+		err  error
+	}{
+		{
+			name: "valid credentials: git credentials are generated",
+			host: "git-codecommit.us-west-2.amazonaws.com",
+			fs:   withCredentials,
+		},
+		{
+			name: "credentials without expiration: git credentials are generated",
+			host: "git-codecommit.us-west-2.amazonaws.com",
+			fs: fsmanager.MockFileSystem{
+				Files: map[string][]byte{
+					credentialsFilepath: []byte(newCredentialsFileContent),
+				},
+			},
+		},
+		{
+			name: "credentials about to expire: error is returned",
+			host: "git-codecommit.us-west-2.amazonaws.com",
+			now:  time.Date(2022, 6, 7, 22, 53, 30, 0, time.UTC),
+			fs:   withCredentials,
+			err:  ErrCredentialsExpired,
+		},
+		{
+			name: "not a CodeCommit host: error is returned",
+			host: "github.com",
+			fs:   withCredentials,
+			err:  ErrBadRequest,
+		},
+		{
+			name: "no stored credentials: error is returned",
+			host: "git-codecommit.us-west-2.amazonaws.com",
+			fs:   fsmanager.NewMock(),
+			err:  ErrNoCredentials,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := tt.now
+			if now.IsZero() {
+				now = time.Date(2022, 6, 7, 10, 0, 0, 0, time.UTC)
+			}
+			timeNow = func() time.Time { return now }
+
+			p, _ := New(prf, setFileManager(tt.fs))
+
+			creds, err := p.GenerateCodeCommitCredentials(tt.host, "/v1/repos/my-repo")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("GenerateCodeCommitCredentials() expected error: %v, got: %v", tt.err, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			expectUser := "AWSACCESSKEYID%reallylongandsecretsessiontoken"
+			if creds.Username != expectUser {
+				t.Errorf("GenerateCodeCommitCredentials() expected username: %s, got: %s", expectUser, creds.Username)
+			}
+
+			cr := "GIT\n/v1/repos/my-repo\n\nhost:git-codecommit.us-west-2.amazonaws.com\n\nhost\n"
+			sig := signer{
+				credentials: credentials{SecretAccessKey: "Super/Secret/AccessKey"},
+				region:      "us-west-2",
+				service:     "codecommit",
+			}.signature("20220607T100000", cr)
+
+			if expect := "20220607T100000Z" + sig; creds.Password != expect {
+				t.Errorf("GenerateCodeCommitCredentials() expected password: %s, got: %s", expect, creds.Password)
+			}
+		})
+	}
+}
+
+func TestCodeCommitRegion(t *testing.T) {
+	tests := map[string]string{
+		"git-codecommit.us-east-1.amazonaws.com":          "us-east-1",
+		"git-codecommit-fips.us-gov-west-1.amazonaws.com": "us-gov-west-1",
+		"git-codecommit.cn-north-1.amazonaws.com.cn":      "cn-north-1",
+		"codecommit.us-east-1.amazonaws.com":              "",
+		"github.com":                                      "",
+	}
+
+	for host, expect := range tests {
+		if got := CodeCommitRegion(host); got != expect {
+			t.Errorf("CodeCommitRegion(%s) expected: %q, got: %q", host, expect, got)
+		}
+	}
+}
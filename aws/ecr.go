@@ -0,0 +1,133 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ecrTarget      = "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken"
+	ecrContentType = "application/x-amz-json-1.1"
+)
+
+var (
+	// ECRURL represents the AWS ECR API URL, it must be formatted with the
+	// region of the registry.
+	ECRURL = "https://api.ecr.%s.amazonaws.com"
+)
+
+// ECRAuthorization represents the decoded credentials to log in to an ECR
+// registry.
+type ECRAuthorization struct {
+	Username      string
+	Password      string
+	ProxyEndpoint string
+	ExpiresAt     time.Time
+}
+
+// getAuthorizationTokenResponse represents the response of the ECR
+// GetAuthorizationToken action.
+type getAuthorizationTokenResponse struct {
+	AuthorizationData []struct {
+		AuthorizationToken string  `json:"authorizationToken"`
+		ExpiresAt          float64 `json:"expiresAt"`
+		ProxyEndpoint      string  `json:"proxyEndpoint"`
+	} `json:"authorizationData"`
+}
+
+// ecrError represents the error message returned from ECR when the request
+// failed.
+type ecrError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+// GetECRAuthorization requests an ECR authorization token for the given region
+// using the credentials stored for the provider profile and returns the
+// decoded username and password.
+func (p Provider) GetECRAuthorization(region string) (ECRAuthorization, error) {
+	log.Print("getting ECR authorization token...")
+
+	cred, err := p.readCredentials()
+	if err != nil {
+		return ECRAuthorization{}, err
+	}
+
+	endpoint := p.ecrURL
+	if endpoint == "" {
+		endpoint = fmt.Sprintf(ECRURL, region)
+	}
+
+	body := []byte("{}")
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return ECRAuthorization{}, fmt.Errorf("%w: %v", ErrBadRequest, err)
+	}
+	req.Header.Set("Content-Type", ecrContentType)
+	req.Header.Set("X-Amz-Target", ecrTarget)
+
+	signer{credentials: cred, region: region, service: "ecr"}.sign(req, body)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return ECRAuthorization{}, fmt.Errorf("%w: %v", ErrBadRequest, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioReadAll(resp.Body)
+	if err != nil {
+		return ECRAuthorization{}, fmt.Errorf("%w: %v", ErrBadResponse, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		errResponse := ecrError{}
+
+		// ignoring unmarshall error to continue in case response does not have body
+		json.Unmarshal(respBody, &errResponse)
+
+		switch resp.StatusCode {
+		case http.StatusBadRequest:
+			return ECRAuthorization{}, fmt.Errorf("%w: status: %s, code: %s message: %s", ErrBadRequest, resp.Status, errResponse.Type, errResponse.Message)
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return ECRAuthorization{}, fmt.Errorf("%w: status: %s, code: %s message: %s", ErrNotAuthorized, resp.Status, errResponse.Type, errResponse.Message)
+		default:
+			return ECRAuthorization{}, fmt.Errorf("%w: status: %s, code: %s message: %s", ErrUnknown, resp.Status, errResponse.Type, errResponse.Message)
+		}
+	}
+
+	ecrResp := getAuthorizationTokenResponse{}
+	if err := json.Unmarshal(respBody, &ecrResp); err != nil {
+		return ECRAuthorization{}, fmt.Errorf("%w: could not unmarshall response: %v", ErrBadResponse, err)
+	}
+
+	if len(ecrResp.AuthorizationData) == 0 {
+		return ECRAuthorization{}, fmt.Errorf("%w: no authorization data", ErrBadResponse)
+	}
+	data := ecrResp.AuthorizationData[0]
+
+	decoded, err := base64.StdEncoding.DecodeString(data.AuthorizationToken)
+	if err != nil {
+		return ECRAuthorization{}, fmt.Errorf("%w: invalid authorization token: %v", ErrBadResponse, err)
+	}
+
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return ECRAuthorization{}, fmt.Errorf("%w: authorization token is not user:password", ErrBadResponse)
+	}
+
+	log.Print("ECR authorization token retrieved")
+
+	sec := int64(data.ExpiresAt)
+	return ECRAuthorization{
+		Username:      parts[0],
+		Password:      parts[1],
+		ProxyEndpoint: data.ProxyEndpoint,
+		ExpiresAt:     time.Unix(sec, int64((data.ExpiresAt-float64(sec))*float64(time.Second))).UTC(),
+	}, nil
+}
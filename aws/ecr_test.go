@@ -0,0 +1,117 @@
+package aws
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/client"
+	"github.com/fox-tech/creds-fetcher/fsmanager"
+)
+
+func TestGetECRAuthorization(t *testing.T) {
+	type expect struct {
+		auth ECRAuthorization
+		err  error
+	}
+
+	prf := Profile{
+		Name:         "test-profile",
+		RoleARN:      "arn:aws:iam::ROLEARN",
+		PrincipalARN: "arn:aws:iam::ProviderARN",
+	}
+
+	withCredentials := fsmanager.MockFileSystem{
+		Files: map[string][]byte{
+			path.Join(CredentialsDirectory, CredentialsFileName): []byte(newCredentialsFileContent),
+		},
+	}
+
+	tests := []struct {
+		name string
+		opts
+		expect
+	}{
+		{
+			name: "authorized: decoded credentials are returned",
+			opts: opts{
+				p:     prf,
+				mckFs: withCredentials,
+				mckClient: client.MockHttpClient{
+					PostStatusCode: http.StatusOK,
+					PostStatus:     "OK",
+					PostBodyData:   []byte(SuccessECRResponse),
+				},
+			},
+			expect: expect{
+				auth: ECRAuthorization{
+					Username:      "AWS",
+					Password:      "ecrpassword",
+					ProxyEndpoint: "https://123456789012.dkr.ecr.us-east-1.amazonaws.com",
+					ExpiresAt:     time.Unix(1654684454, int64(500*time.Millisecond)).UTC(),
+				},
+			},
+		},
+		{
+			name: "not authorized: error is returned",
+			opts: opts{
+				p:     prf,
+				mckFs: withCredentials,
+				mckClient: client.MockHttpClient{
+					PostStatusCode: http.StatusBadRequest,
+					PostStatus:     "Bad Request",
+					PostBodyData:   []byte(errECRResponse),
+				},
+			},
+			expect: expect{
+				err: ErrBadRequest,
+			},
+		},
+		{
+			name: "empty authorization data: error is returned",
+			opts: opts{
+				p:     prf,
+				mckFs: withCredentials,
+				mckClient: client.MockHttpClient{
+					PostStatusCode: http.StatusOK,
+					PostStatus:     "OK",
+					PostBodyData:   []byte(`{"authorizationData":[]}`),
+				},
+			},
+			expect: expect{
+				err: ErrBadResponse,
+			},
+		},
+		{
+			name: "no stored credentials: error is returned",
+			opts: opts{
+				p:     prf,
+				mckFs: fsmanager.NewMock(),
+			},
+			expect: expect{
+				err: ErrNoCredentials,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := New(tt.opts.p,
+				SetHTTPClient(tt.opts.mckClient),
+				setFileManager(tt.opts.mckFs),
+			)
+
+			auth, err := p.GetECRAuthorization("us-east-1")
+
+			if !errors.Is(err, tt.expect.err) {
+				t.Errorf("GetECRAuthorization() expected error: %s, got: %s", tt.expect.err, err)
+			}
+
+			if auth != tt.expect.auth {
+				t.Errorf("GetECRAuthorization() expected: %v, got: %v", tt.expect.auth, auth)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package aws
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// eksTokenPrefix is the prefix the EKS authenticator expects in bearer
+	// tokens.
+	eksTokenPrefix = "k8s-aws-v1."
+	// eksClusterHeader is the header binding a token to a cluster.
+	eksClusterHeader = "x-k8s-aws-id"
+	// eksTokenExpiration is how long a presigned token is valid for the EKS
+	// authenticator. Tokens are reported as expiring a minute earlier.
+	eksTokenExpiration = 15 * time.Minute
+)
+
+var (
+	// EKSURL represents the AWS EKS API URL, it must be formatted with the
+	// region of the cluster.
+	EKSURL = "https://eks.%s.amazonaws.com"
+)
+
+// Cluster represents the values of an EKS cluster needed to connect to it.
+type Cluster struct {
+	Name                 string `json:"name"`
+	ARN                  string `json:"arn"`
+	Endpoint             string `json:"endpoint"`
+	Status               string `json:"status"`
+	CertificateAuthority struct {
+		Data string `json:"data"`
+	} `json:"certificateAuthority"`
+}
+
+// describeClusterResponse represents the response of the EKS DescribeCluster
+// action.
+type describeClusterResponse struct {
+	Cluster Cluster `json:"cluster"`
+}
+
+// eksError represents the error message returned from EKS when the request
+// failed.
+type eksError struct {
+	Message string `json:"message"`
+}
+
+// EKSToken represents a bearer token accepted by EKS clusters.
+type EKSToken struct {
+	Token      string
+	Expiration time.Time
+}
+
+// DescribeCluster returns the cluster with the given name in the given region
+// using the credentials stored for the provider profile.
+func (p Provider) DescribeCluster(name, region string) (Cluster, error) {
+	log.Printf("describing EKS cluster %s...", name)
+
+	cred, err := p.readCredentials()
+	if err != nil {
+		return Cluster{}, err
+	}
+
+	endpoint := p.eksURL
+	if endpoint == "" {
+		endpoint = fmt.Sprintf(EKSURL, region)
+	}
+
+	uri := fmt.Sprintf("%s/clusters/%s", strings.TrimSuffix(endpoint, "/"), url.PathEscape(name))
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return Cluster{}, fmt.Errorf("%w: %v", ErrBadRequest, err)
+	}
+
+	signer{credentials: cred, region: region, service: "eks"}.sign(req, nil)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return Cluster{}, fmt.Errorf("%w: %v", ErrBadRequest, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioReadAll(resp.Body)
+	if err != nil {
+		return Cluster{}, fmt.Errorf("%w: %v", ErrBadResponse, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		errResponse := eksError{}
+
+		// ignoring unmarshall error to continue in case response does not have body
+		json.Unmarshal(respBody, &errResponse)
+
+		switch resp.StatusCode {
+		case http.StatusBadRequest:
+			return Cluster{}, fmt.Errorf("%w: status: %s, message: %s", ErrBadRequest, resp.Status, errResponse.Message)
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return Cluster{}, fmt.Errorf("%w: status: %s, message: %s", ErrNotAuthorized, resp.Status, errResponse.Message)
+		case http.StatusNotFound:
+			return Cluster{}, fmt.Errorf("%w: %s: %s", ErrClusterNotFound, name, errResponse.Message)
+		default:
+			return Cluster{}, fmt.Errorf("%w: status: %s, message: %s", ErrUnknown, resp.Status, errResponse.Message)
+		}
+	}
+
+	eksResp := describeClusterResponse{}
+	if err := json.Unmarshal(respBody, &eksResp); err != nil {
+		return Cluster{}, fmt.Errorf("%w: could not unmarshall response: %v", ErrBadResponse, err)
+	}
+
+	log.Print("EKS cluster described")
+
+	return eksResp.Cluster, nil
+}
+
+// GenerateEKSToken returns a token to authenticate against the given EKS
+// cluster. The token is a presigned STS GetCallerIdentity request bound to the
+// cluster name, so it can be generated locally from the stored credentials.
+func (p Provider) GenerateEKSToken(cluster string) (EKSToken, error) {
+	cred, err := p.readCredentials()
+	if err != nil {
+		return EKSToken{}, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, STSURL, nil)
+	if err != nil {
+		return EKSToken{}, fmt.Errorf("%w: %v", ErrBadRequest, err)
+	}
+	req.URL.RawQuery = url.Values{
+		"Action":  []string{"GetCallerIdentity"},
+		"Version": []string{"2011-06-15"},
+	}.Encode()
+	req.Header.Set(eksClusterHeader, cluster)
+
+	signer{credentials: cred, region: "us-east-1", service: "sts"}.presign(req, 60*time.Second)
+
+	return EKSToken{
+		Token:      eksTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(req.URL.String())),
+		Expiration: timeNow().UTC().Add(eksTokenExpiration - time.Minute),
+	}, nil
+}
@@ -0,0 +1,181 @@
+package aws
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/client"
+	"github.com/fox-tech/creds-fetcher/fsmanager"
+)
+
+func TestDescribeCluster(t *testing.T) {
+	type expect struct {
+		cluster Cluster
+		err     error
+	}
+
+	prf := Profile{
+		Name:         "test-profile",
+		RoleARN:      "arn:aws:iam::ROLEARN",
+		PrincipalARN: "arn:aws:iam::ProviderARN",
+	}
+
+	credentialsFilepath := path.Join(CredentialsDirectory, CredentialsFileName)
+	withCredentials := fsmanager.MockFileSystem{
+		Files: map[string][]byte{
+			credentialsFilepath: []byte(newCredentialsFileContent),
+		},
+	}
+
+	tests := []struct {
+		name string
+		opts
+		expect
+	}{
+		{
+			name: "cluster exists: cluster is returned",
+			opts: opts{
+				p:     prf,
+				mckFs: withCredentials,
+				mckClient: client.MockHttpClient{
+					GetStatusCode: http.StatusOK,
+					GetStatus:     "OK",
+					GetBodyData:   []byte(SuccessDescribeClusterResponse),
+				},
+			},
+			expect: expect{
+				cluster: Cluster{
+					Name:     "dev",
+					ARN:      "arn:aws:eks:us-east-1:123456789012:cluster/dev",
+					Endpoint: "https://ABCDEF.gr7.us-east-1.eks.amazonaws.com",
+					Status:   "ACTIVE",
+				},
+			},
+		},
+		{
+			name: "cluster does not exist: error is returned",
+			opts: opts{
+				p:     prf,
+				mckFs: withCredentials,
+				mckClient: client.MockHttpClient{
+					GetStatusCode: http.StatusNotFound,
+					GetStatus:     "Not Found",
+					GetBodyData:   []byte(errEKSResponse),
+				},
+			},
+			expect: expect{
+				err: ErrClusterNotFound,
+			},
+		},
+		{
+			name: "not authorized: error is returned",
+			opts: opts{
+				p:     prf,
+				mckFs: withCredentials,
+				mckClient: client.MockHttpClient{
+					GetStatusCode: http.StatusForbidden,
+					GetStatus:     "Forbidden",
+				},
+			},
+			expect: expect{
+				err: ErrNotAuthorized,
+			},
+		},
+		{
+			name: "request fails: error is returned",
+			opts: opts{
+				p:     prf,
+				mckFs: withCredentials,
+				mckClient: client.MockHttpClient{
+					GetErr: errors.New("connection refused"),
+				},
+			},
+			expect: expect{
+				err: ErrBadRequest,
+			},
+		},
+		{
+			name: "no stored credentials: error is returned",
+			opts: opts{
+				p:     prf,
+				mckFs: fsmanager.NewMock(),
+			},
+			expect: expect{
+				err: ErrNoCredentials,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := New(tt.opts.p,
+				SetHTTPClient(tt.opts.mckClient),
+				setFileManager(tt.opts.mckFs),
+			)
+
+			c, err := p.DescribeCluster("dev", "us-east-1")
+
+			if !errors.Is(err, tt.expect.err) {
+				t.Errorf("DescribeCluster() expected error: %s, got: %s", tt.expect.err, err)
+			}
+
+			if c.Name != tt.expect.cluster.Name || c.ARN != tt.expect.cluster.ARN || c.Endpoint != tt.expect.cluster.Endpoint {
+				t.Errorf("DescribeCluster() expected cluster: %v, got: %v", tt.expect.cluster, c)
+			}
+		})
+	}
+}
+
+func TestGenerateEKSToken(t *testing.T) {
+	prevNow := timeNow
+	now := time.Date(2022, 6, 7, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = prevNow }()
+
+	prf := Profile{
+		Name:         "test-profile",
+		RoleARN:      "arn:aws:iam::ROLEARN",
+		PrincipalARN: "arn:aws:iam::ProviderARN",
+	}
+
+	fs := fsmanager.MockFileSystem{
+		Files: map[string][]byte{
+			path.Join(CredentialsDirectory, CredentialsFileName): []byte(newCredentialsFileContent),
+		},
+	}
+
+	p, _ := New(prf, setFileManager(fs))
+	token, err := p.GenerateEKSToken("dev")
+	if err != nil {
+		t.Fatalf("GenerateEKSToken() unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(token.Token, eksTokenPrefix) {
+		t.Fatalf("GenerateEKSToken() expected prefix %s, got: %s", eksTokenPrefix, token.Token)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token.Token, eksTokenPrefix))
+	if err != nil {
+		t.Fatalf("GenerateEKSToken() expected base64 url, got error: %v", err)
+	}
+
+	u, err := url.Parse(string(raw))
+	if err != nil {
+		t.Fatalf("GenerateEKSToken() expected presigned url, got error: %v", err)
+	}
+
+	q := u.Query()
+	if q.Get("Action") != "GetCallerIdentity" || q.Get("X-Amz-SignedHeaders") != "host;x-k8s-aws-id" || q.Get("X-Amz-Security-Token") != "reallylongandsecretsessiontoken" {
+		t.Errorf("GenerateEKSToken() unexpected presigned query: %v", q)
+	}
+
+	if !token.Expiration.Equal(now.Add(14 * time.Minute)) {
+		t.Errorf("GenerateEKSToken() expected expiration: %v, got: %v", now.Add(14*time.Minute), token.Expiration)
+	}
+}
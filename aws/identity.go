@@ -0,0 +1,90 @@
+package aws
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Identity represents the AWS identity of the credentials of a profile.
+type Identity struct {
+	Account string
+	// ARN is the assumed role ARN of the credentials, empty when they were
+	// stored before it was kept
+	ARN         string
+	SessionName string
+	// Expiration is zero when the credentials have no expiration
+	Expiration time.Time
+}
+
+// getCallerIdentityResponse represents part of the STS response to a
+// GetCallerIdentity request when it was successful
+type getCallerIdentityResponse struct {
+	Result struct {
+		Arn     string `xml:"Arn"`
+		Account string `xml:"Account"`
+	} `xml:"GetCallerIdentityResult"`
+}
+
+// StoredIdentity returns the identity of the credentials stored for the
+// provider profile as they were saved at login, without any request.
+// Returns ErrNoCredentials if there are none.
+func (p Provider) StoredIdentity() (Identity, error) {
+	cred, err := p.readCredentials()
+	if err != nil {
+		return Identity{}, err
+	}
+
+	id := cred.identity(cred.AssumedRoleARN)
+	if id.Account == "" {
+		id.Account = AccountID(p.Profile.RoleARN)
+	}
+	if id.Account == "" {
+		id.Account = p.Profile.AccountID
+	}
+
+	return id, nil
+}
+
+// GetCallerIdentity requests STS the identity of the credentials stored for
+// the provider profile, which fails if they are not valid anymore. Returns
+// ErrNoCredentials if there are none.
+func (p Provider) GetCallerIdentity(ctx context.Context) (Identity, error) {
+	cred, err := p.readCredentials()
+	if err != nil {
+		return Identity{}, err
+	}
+
+	body := map[string]string{
+		"Version": "2011-06-15",
+		"Action":  "GetCallerIdentity",
+	}
+
+	stsResp := getCallerIdentityResponse{}
+	if err := p.stsRequest(ctx, body, &cred, &stsResp); err != nil {
+		return Identity{}, err
+	}
+
+	id := cred.identity(stsResp.Result.Arn)
+	id.Account = stsResp.Result.Account
+	return id, nil
+}
+
+// identity returns the identity of the credentials with the given assumed
+// role ARN, arn:aws:sts::ACCOUNT:assumed-role/ROLE/SESSION.
+func (c credentials) identity(arn string) Identity {
+	id := Identity{
+		Account: AccountID(arn),
+		ARN:     arn,
+	}
+
+	if parts := strings.Split(arn, "/"); len(parts) == 3 && strings.HasSuffix(parts[0], ":assumed-role") {
+		id.SessionName = parts[2]
+	}
+
+	if exp, err := time.Parse(time.RFC3339, c.Expiration); err == nil {
+		id.Expiration = exp
+	}
+
+	return id
+}
@@ -0,0 +1,152 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/fsmanager"
+)
+
+const successGetCallerIdentityResponse = `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult>
+    <Arn>arn:aws:sts::4543372610:assumed-role/okta-oie-ReadOnly/mail@mail.com</Arn>
+    <UserId>AROARORTY3BBGGVCOV4OP:mail@mail.com</UserId>
+    <Account>4543372610</Account>
+  </GetCallerIdentityResult>
+</GetCallerIdentityResponse>`
+
+func TestStoredIdentity(t *testing.T) {
+	prf := Profile{
+		Name:         "test-profile",
+		RoleARN:      "arn:aws:iam::123456789012:role/dev",
+		PrincipalARN: "arn:aws:iam::ProviderARN",
+	}
+
+	credentialsFilepath := path.Join(CredentialsDirectory, CredentialsFileName)
+
+	tests := []struct {
+		name      string
+		data      string
+		accountID string
+		expect    Identity
+		expectErr error
+	}{
+		{
+			name: "assumed role stored: identity of the session",
+			data: generatedCredentialsFileContent,
+			expect: Identity{
+				Account:     "4543372610",
+				ARN:         "arn:aws:sts::4543372610:assumed-role/okta-oie-ReadOnly/mail@mail.com",
+				SessionName: "mail@mail.com",
+				Expiration:  time.Date(2022, 6, 7, 22, 54, 14, 0, time.UTC),
+			},
+		},
+		{
+			name:   "no assumed role stored: account of the profile role",
+			data:   newCredentialsFileContent,
+			expect: Identity{Account: "123456789012"},
+		},
+		{
+			name:      "direct profile without role: account of the profile",
+			data:      newCredentialsFileContent,
+			accountID: "210987654321",
+			expect:    Identity{Account: "210987654321"},
+		},
+		{
+			name:      "no credentials: error is returned",
+			expectErr: ErrNoCredentials,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := fsmanager.NewMock()
+			fs.Files[credentialsFilepath] = []byte(tt.data)
+
+			profile := prf
+			if tt.accountID != "" {
+				profile = Profile{Name: prf.Name, Direct: true, AccountID: tt.accountID}
+			}
+
+			p, _ := New(profile, setFileManager(fs))
+
+			id, err := p.StoredIdentity()
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("StoredIdentity() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if id != tt.expect {
+				t.Errorf("StoredIdentity() expected: %+v, got: %+v", tt.expect, id)
+			}
+		})
+	}
+}
+
+func TestGetCallerIdentity(t *testing.T) {
+	var authorization string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+
+		if r.FormValue("Action") != "GetCallerIdentity" || r.Header.Get("X-Amz-Security-Token") != "reallylongandsecretsessiontoken" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Write([]byte(successGetCallerIdentityResponse))
+	}))
+	defer srv.Close()
+
+	prf := Profile{
+		Name:         "test-profile",
+		RoleARN:      "arn:aws:iam::123456789012:role/dev",
+		PrincipalARN: "arn:aws:iam::ProviderARN",
+	}
+
+	fs := fsmanager.NewMock()
+	fs.Files[path.Join(CredentialsDirectory, CredentialsFileName)] = []byte(newCredentialsFileContent)
+
+	p, _ := New(prf, setFileManager(fs), SetSTSURL(srv.URL))
+
+	id, err := p.GetCallerIdentity(context.Background())
+	if err != nil {
+		t.Fatalf("GetCallerIdentity() unexpected error: %v", err)
+	}
+
+	expect := Identity{
+		Account:     "4543372610",
+		ARN:         "arn:aws:sts::4543372610:assumed-role/okta-oie-ReadOnly/mail@mail.com",
+		SessionName: "mail@mail.com",
+	}
+	if id != expect {
+		t.Errorf("GetCallerIdentity() expected: %+v, got: %+v", expect, id)
+	}
+
+	if !strings.Contains(authorization, "Credential=AWSACCESSKEYID/") || !strings.Contains(authorization, "/us-east-1/sts/aws4_request") {
+		t.Errorf("GetCallerIdentity() expected request signed for STS, got authorization: %s", authorization)
+	}
+
+	fs.Files[path.Join(CredentialsDirectory, CredentialsFileName)] = nil
+	if _, err := p.GetCallerIdentity(context.Background()); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("GetCallerIdentity() expected error: %v, got: %v", ErrNoCredentials, err)
+	}
+}
+
+func TestSTSRegion(t *testing.T) {
+	tests := map[string]string{
+		"https://sts.amazonaws.com/":           "us-east-1",
+		"https://sts.eu-west-1.amazonaws.com/": "eu-west-1",
+		"http://127.0.0.1:8080":                "us-east-1",
+	}
+
+	for endpoint, expect := range tests {
+		if got := stsRegion(endpoint); got != expect {
+			t.Errorf("stsRegion(%s) expected: %s, got: %s", endpoint, expect, got)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package aws
 
+import "github.com/fox-tech/creds-fetcher/client"
+
 // Option represents an optional configuration value passed to the
 // provider object to change the default value set during initialization.
 type Option func(*Provider)
@@ -12,10 +14,34 @@ func setFileManager(fm fileSystemManager) Option {
 	}
 }
 
-// setHTTPClient returns a function to assign the passed httpClient
-// to the provider.
-func setHTTPClient(c httpClient) Option {
+// SetHTTPClient returns a function to assign the passed client to the
+// provider, e.g. to share the client used for Okta.
+func SetHTTPClient(c client.HTTPClient) Option {
 	return func(p *Provider) {
 		p.httpClient = c
 	}
 }
+
+// SetEKSURL returns a function to override the EKS endpoint used by the
+// provider instead of the regional one.
+func SetEKSURL(u string) Option {
+	return func(p *Provider) {
+		p.eksURL = u
+	}
+}
+
+// SetSTSURL returns a function to override the STS endpoint used by the
+// provider to get credentials instead of STSURL.
+func SetSTSURL(u string) Option {
+	return func(p *Provider) {
+		p.stsURL = u
+	}
+}
+
+// SetECRURL returns a function to override the ECR endpoint used by the
+// provider instead of the regional one.
+func SetECRURL(u string) Option {
+	return func(p *Provider) {
+		p.ecrURL = u
+	}
+}
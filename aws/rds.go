@@ -0,0 +1,65 @@
+package aws
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// rdsTokenExpiration is how long an RDS authentication token is valid.
+	rdsTokenExpiration = 15 * time.Minute
+)
+
+// GenerateRDSToken returns an IAM authentication token to connect to the RDS
+// database in host and port as the given database user. The token is a
+// presigned rds-db:connect request, so it's generated locally from the stored
+// credentials. If region is empty, it's taken from the RDS host name.
+//
+// More at https://docs.aws.amazon.com/AmazonRDS/latest/UserGuide/UsingWithRDS.IAMDBAuth.Connecting.html.
+func (p Provider) GenerateRDSToken(host string, port int, user, region string) (string, error) {
+	if region == "" {
+		region = RDSRegion(host)
+	}
+
+	if host == "" || user == "" || region == "" {
+		return "", fmt.Errorf("%w: host, user and region are required", ErrBadRequest)
+	}
+
+	cred, err := p.readCredentials()
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := net.JoinHostPort(host, strconv.Itoa(port))
+	req, err := http.NewRequest(http.MethodGet, "https://"+endpoint+"/", nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrBadRequest, err)
+	}
+	req.URL.RawQuery = url.Values{
+		"Action": []string{"connect"},
+		"DBUser": []string{user},
+	}.Encode()
+
+	signer{credentials: cred, region: region, service: "rds-db"}.presign(req, rdsTokenExpiration)
+
+	return strings.TrimPrefix(req.URL.String(), "https://"), nil
+}
+
+// RDSRegion returns the region of an RDS endpoint host name, e.g. us-east-1 for
+// db.cluster-abc.us-east-1.rds.amazonaws.com. Returns an empty string if the
+// host is not an RDS endpoint.
+func RDSRegion(host string) string {
+	parts := strings.Split(host, ".")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] == "rds" {
+			return parts[i-1]
+		}
+	}
+
+	return ""
+}
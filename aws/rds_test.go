@@ -0,0 +1,99 @@
+package aws
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/fsmanager"
+)
+
+func TestGenerateRDSToken(t *testing.T) {
+	prevNow := timeNow
+	timeNow = func() time.Time { return time.Date(2022, 6, 7, 10, 0, 0, 0, time.UTC) }
+	defer func() { timeNow = prevNow }()
+
+	prf := Profile{
+		Name:         "test-profile",
+		RoleARN:      "arn:aws:iam::ROLEARN",
+		PrincipalARN: "arn:aws:iam::ProviderARN",
+	}
+
+	withCredentials := fsmanager.MockFileSystem{
+		Files: map[string][]byte{
+			path.Join(CredentialsDirectory, CredentialsFileName): []byte(newCredentialsFileContent),
+		},
+	}
+
+	type args struct {
+		host   string
+		user   string
+		region string
+	}
+
+	tests := []struct {
+		name         string
+		args         args
+		fs           fsmanager.MockFileSystem
+		expectRegion string
+		err          error
+	}{
+		{
+			name:         "region from host: token is generated",
+			args:         args{host: "db.cluster-abc.us-west-2.rds.amazonaws.com", user: "jane"},
+			fs:           withCredentials,
+			expectRegion: "us-west-2",
+		},
+		{
+			name:         "explicit region: token is generated",
+			args:         args{host: "db.example.com", user: "jane", region: "eu-west-1"},
+			fs:           withCredentials,
+			expectRegion: "eu-west-1",
+		},
+		{
+			name: "unknown region: error is returned",
+			args: args{host: "db.example.com", user: "jane"},
+			fs:   withCredentials,
+			err:  ErrBadRequest,
+		},
+		{
+			name: "no stored credentials: error is returned",
+			args: args{host: "db.cluster-abc.us-west-2.rds.amazonaws.com", user: "jane"},
+			fs:   fsmanager.NewMock(),
+			err:  ErrNoCredentials,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := New(prf, setFileManager(tt.fs))
+
+			token, err := p.GenerateRDSToken(tt.args.host, 5432, tt.args.user, tt.args.region)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("GenerateRDSToken() expected error: %v, got: %v", tt.err, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if !strings.HasPrefix(token, tt.args.host+":5432/?") {
+				t.Fatalf("GenerateRDSToken() expected token for %s:5432, got: %s", tt.args.host, token)
+			}
+
+			u, _ := url.Parse("https://" + token)
+			q := u.Query()
+			if q.Get("Action") != "connect" || q.Get("DBUser") != "jane" || q.Get("X-Amz-Expires") != "900" {
+				t.Errorf("GenerateRDSToken() unexpected query: %v", q)
+			}
+
+			expectCredential := "AWSACCESSKEYID/20220607/" + tt.expectRegion + "/rds-db/aws4_request"
+			if q.Get("X-Amz-Credential") != expectCredential {
+				t.Errorf("GenerateRDSToken() expected credential: %s, got: %s", expectCredential, q.Get("X-Amz-Credential"))
+			}
+		})
+	}
+}
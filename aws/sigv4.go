@@ -0,0 +1,192 @@
+package aws
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4DateFormat = "20060102T150405Z"
+	sigV4DayFormat  = "20060102"
+)
+
+var timeNow = time.Now
+
+// signer signs requests to AWS services with Signature Version 4 using the
+// credentials stored for a profile.
+//
+// More at https://docs.aws.amazon.com/general/latest/gr/sigv4_signing.html.
+type signer struct {
+	credentials credentials
+	region      string
+	service     string
+}
+
+// sign adds the authorization headers to the request. The body must be the
+// same payload that is sent with the request.
+func (s signer) sign(req *http.Request, body []byte) {
+	now := timeNow().UTC()
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", now.Format(sigV4DateFormat))
+	if s.credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.credentials.SessionToken)
+	}
+
+	headers, signedHeaders := canonicalHeaders(req.Header)
+	cr := strings.Join([]string{
+		req.Method,
+		canonicalPath(req.URL),
+		canonicalQuery(req.URL.Query()),
+		headers,
+		signedHeaders,
+		hashHex(body),
+	}, "\n")
+
+	signature := s.signature(now.Format(sigV4DateFormat), cr)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, s.credentials.AccessKeyId, s.scope(now.Format(sigV4DayFormat)), signedHeaders, signature))
+	req.Header.Del("Host")
+}
+
+// presign adds the authorization values to the query of the request URL, so
+// the URL can be used without any extra header for the given duration.
+func (s signer) presign(req *http.Request, expires time.Duration) {
+	now := timeNow().UTC()
+
+	req.Header.Set("Host", req.URL.Host)
+	headers, signedHeaders := canonicalHeaders(req.Header)
+
+	q := req.URL.Query()
+	q.Set("X-Amz-Algorithm", sigV4Algorithm)
+	q.Set("X-Amz-Credential", s.credentials.AccessKeyId+"/"+s.scope(now.Format(sigV4DayFormat)))
+	q.Set("X-Amz-Date", now.Format(sigV4DateFormat))
+	q.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	q.Set("X-Amz-SignedHeaders", signedHeaders)
+	if s.credentials.SessionToken != "" {
+		q.Set("X-Amz-Security-Token", s.credentials.SessionToken)
+	}
+
+	cr := strings.Join([]string{
+		req.Method,
+		canonicalPath(req.URL),
+		canonicalQuery(q),
+		headers,
+		signedHeaders,
+		hashHex(nil),
+	}, "\n")
+
+	q.Set("X-Amz-Signature", s.signature(now.Format(sigV4DateFormat), cr))
+	req.URL.RawQuery = canonicalQuery(q)
+	req.Header.Del("Host")
+}
+
+// scope returns the credential scope for the given day.
+func (s signer) scope(day string) string {
+	return strings.Join([]string{day, s.region, s.service, "aws4_request"}, "/")
+}
+
+// signature returns the hex encoded signature of the canonical request. The
+// timestamp is used as is in the string to sign and its first eight
+// characters must be the day of the request.
+func (s signer) signature(timestamp string, canonicalRequest string) string {
+	day := timestamp[:len(sigV4DayFormat)]
+	sts := strings.Join([]string{
+		sigV4Algorithm,
+		timestamp,
+		s.scope(day),
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.credentials.SecretAccessKey), day)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, s.service)
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, sts))
+}
+
+// canonicalHeaders returns the canonical headers block and the list of signed
+// headers. Only the host, content-type and x-amz-* headers are signed.
+func canonicalHeaders(h http.Header) (string, string) {
+	names := []string{}
+	values := map[string]string{}
+	for k, v := range h {
+		lk := strings.ToLower(k)
+		if lk != "host" && lk != "content-type" && !strings.HasPrefix(lk, "x-amz-") && !strings.HasPrefix(lk, "x-k8s-") {
+			continue
+		}
+
+		vals := make([]string, len(v))
+		for i := range v {
+			vals[i] = strings.Join(strings.Fields(v[i]), " ")
+		}
+
+		names = append(names, lk)
+		values[lk] = strings.Join(vals, ",")
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, n := range names {
+		b.WriteString(n + ":" + values[n] + "\n")
+	}
+
+	return b.String(), strings.Join(names, ";")
+}
+
+// canonicalPath returns the URI encoded path, or / if it's empty.
+func canonicalPath(u *url.URL) string {
+	p := u.EscapedPath()
+	if p == "" {
+		return "/"
+	}
+
+	return p
+}
+
+// canonicalQuery returns the query values sorted by key and encoded as
+// required by Signature Version 4.
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := []string{}
+	for _, k := range keys {
+		vals := append([]string{}, q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+// uriEncode encodes every byte except the unreserved characters of RFC 3986.
+func uriEncode(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func hashHex(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,101 @@
+package aws
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// test values from the AWS Signature Version 4 test suite and documentation
+var testSigV4Credentials = credentials{
+	AccessKeyId:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+func TestSign(t *testing.T) {
+	prevNow := timeNow
+	timeNow = func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+	defer func() { timeNow = prevNow }()
+
+	tests := []struct {
+		name    string
+		url     string
+		service string
+		headers map[string]string
+		expect  string
+	}{
+		{
+			name:    "get-vanilla",
+			url:     "https://example.amazonaws.com/",
+			service: "service",
+			expect:  "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:    "iam list users",
+			url:     "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08",
+			service: "iam",
+			headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=utf-8"},
+			expect:  "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			signer{credentials: testSigV4Credentials, region: "us-east-1", service: tt.service}.sign(req, nil)
+
+			if got := req.Header.Get("Authorization"); got != tt.expect {
+				t.Errorf("sign() expected authorization: %s, got: %s", tt.expect, got)
+			}
+
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("sign() expected date: 20150830T123600Z, got: %s", got)
+			}
+		})
+	}
+}
+
+func TestPresign(t *testing.T) {
+	prevNow := timeNow
+	timeNow = func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+	defer func() { timeNow = prevNow }()
+
+	cred := testSigV4Credentials
+	cred.SessionToken = "session/token"
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/?Action=connect", nil)
+	signer{credentials: cred, region: "us-east-1", service: "service"}.presign(req, 15*time.Minute)
+
+	q := req.URL.Query()
+	expect := map[string]string{
+		"Action":               "connect",
+		"X-Amz-Algorithm":      "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":     "AKIDEXAMPLE/20150830/us-east-1/service/aws4_request",
+		"X-Amz-Date":           "20150830T123600Z",
+		"X-Amz-Expires":        "900",
+		"X-Amz-SignedHeaders":  "host",
+		"X-Amz-Security-Token": "session/token",
+	}
+	for k, v := range expect {
+		if q.Get(k) != v {
+			t.Errorf("presign() expected %s: %s, got: %s", k, v, q.Get(k))
+		}
+	}
+
+	if len(q.Get("X-Amz-Signature")) != 64 {
+		t.Errorf("presign() expected hex signature, got: %s", q.Get("X-Amz-Signature"))
+	}
+
+	if strings.Contains(req.URL.RawQuery, "+") {
+		t.Errorf("presign() expected spaces and slashes to be percent encoded, got: %s", req.URL.RawQuery)
+	}
+
+	if req.Header.Get("Host") != "" {
+		t.Errorf("presign() expected no Host header left in the request")
+	}
+}
@@ -1,22 +1,61 @@
 package aws
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/client"
+)
+
+const (
+	// defaultSessionName is the role session name used when the web identity
+	// token has no usable subject.
+	defaultSessionName   = "creds-fetcher"
+	maxSessionNameLength = 64
+
+	// defaultSTSRegion is the region of the global STS endpoint
+	defaultSTSRegion = "us-east-1"
 )
 
+var invalidSessionNameChars = regexp.MustCompile(`[^\w+=,.@-]`)
+
 // assumeRoleWithSAMLResponse represents part of the STS response to an
 // AssumeRoleWithSAML request when it was successful
 type assumeRoleWithSAMLResponse struct {
 	AssumeRoleResult assumeRoleResult `xml:"AssumeRoleWithSAMLResult"`
 }
 
-// assumeRoleResult contains the credentials returned from a successful STS
-// AssumeRoleWithSAML request
+// assumeRoleWithWebIdentityResponse represents part of the STS response to an
+// AssumeRoleWithWebIdentity request when it was successful
+type assumeRoleWithWebIdentityResponse struct {
+	AssumeRoleResult assumeRoleResult `xml:"AssumeRoleWithWebIdentityResult"`
+}
+
+// assumeRoleResult contains the credentials and assumed role returned from a
+// successful STS AssumeRoleWithSAML or AssumeRoleWithWebIdentity request
 type assumeRoleResult struct {
-	Credentials credentials `xml:"Credentials"`
+	Credentials     credentials `xml:"Credentials"`
+	AssumedRoleUser struct {
+		Arn string `xml:"Arn"`
+	} `xml:"AssumedRoleUser"`
+}
+
+// credentials returns the credentials of the result with the ARN of the
+// assumed role.
+func (r assumeRoleResult) credentials() credentials {
+	cred := r.Credentials
+	cred.AssumedRoleARN = r.AssumedRoleUser.Arn
+	return cred
 }
 
 // credentials represents the login values returned by STS
@@ -24,6 +63,24 @@ type credentials struct {
 	AccessKeyId     string `xml:"AccessKeyId" ini:"aws_access_key_id"`
 	SecretAccessKey string `xml:"SecretAccessKey" ini:"aws_secret_access_key"`
 	SessionToken    string `xml:"SessionToken" ini:"aws_session_token"`
+	Expiration      string `xml:"Expiration" ini:"x_security_token_expires,omitempty"`
+	// AssumedRoleARN is kept to show the identity of the credentials offline
+	AssumedRoleARN string `xml:"-" ini:"x_assumed_role_arn,omitempty"`
+}
+
+// expired reports whether the credentials expired or expire within the given
+// margin. Credentials without expiration never expire.
+func (c credentials) expired(margin time.Duration) bool {
+	if c.Expiration == "" {
+		return false
+	}
+
+	exp, err := time.Parse(time.RFC3339, c.Expiration)
+	if err != nil {
+		return false
+	}
+
+	return !timeNow().Add(margin).Before(exp)
 }
 
 // assumeRoleWithSAMLError represents part of the STS response to an
@@ -41,7 +98,7 @@ type stsError struct {
 
 // getSTSCredentialsFromSAML uses provided saml string to requests AWS CLI
 // credentials using STS.
-func (p Provider) getSTSCredentialsFromSAML(saml string) (credentials, error) {
+func (p Provider) getSTSCredentialsFromSAML(ctx context.Context, saml string) (credentials, error) {
 	log.Print("getting STS credentials...")
 
 	body := map[string]string{
@@ -52,19 +109,76 @@ func (p Provider) getSTSCredentialsFromSAML(saml string) (credentials, error) {
 		"SAMLAssertion": saml,
 	}
 
-	headers := map[string]string{
-		"Content-Type": "application/x-www-form-urlencoded",
+	stsResp := assumeRoleWithSAMLResponse{}
+	if err := p.stsRequest(ctx, body, nil, &stsResp); err != nil {
+		return credentials{}, err
+	}
+
+	log.Print("STS credentials retrieved")
+
+	return stsResp.AssumeRoleResult.credentials(), nil
+}
+
+// getSTSCredentialsFromWebIdentity uses provided OIDC token to request AWS
+// CLI credentials using STS. The session is named after the token subject.
+func (p Provider) getSTSCredentialsFromWebIdentity(ctx context.Context, token string) (credentials, error) {
+	log.Print("getting STS credentials with web identity...")
+
+	body := map[string]string{
+		"Version":          "2011-06-15",
+		"Action":           "AssumeRoleWithWebIdentity",
+		"RoleArn":          p.Profile.RoleARN,
+		"RoleSessionName":  webIdentitySessionName(token),
+		"WebIdentityToken": token,
+	}
+
+	stsResp := assumeRoleWithWebIdentityResponse{}
+	if err := p.stsRequest(ctx, body, nil, &stsResp); err != nil {
+		return credentials{}, err
+	}
+
+	log.Print("STS credentials retrieved")
+
+	return stsResp.AssumeRoleResult.credentials(), nil
+}
+
+// stsRequest sends the form values in body to STS and decodes the successful
+// response into v. The request is signed with cred when it's not nil. The
+// request is cancelled when ctx is done.
+func (p Provider) stsRequest(ctx context.Context, body map[string]string, cred *credentials, v interface{}) error {
+	endpoint := p.stsURL
+	if endpoint == "" {
+		endpoint = STSURL
+	}
+
+	form := url.Values{}
+	for key, value := range body {
+		form.Set(key, value)
 	}
 
-	resp, err := p.httpClient.Post(STSURL, nil, headers, body)
+	data := []byte(form.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
 	if err != nil {
-		return credentials{}, fmt.Errorf("%w: %v", ErrBadRequest, err)
+		return fmt.Errorf("%w: %v", ErrBadRequest, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if cred != nil {
+		signer{credentials: *cred, region: stsRegion(endpoint), service: "sts"}.sign(req, data)
+	} else {
+		// assuming a role has no side effect, it can be retried after 5xx errors
+		client.SetIdempotent(req)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadRequest, err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := ioReadAll(resp.Body)
 	if err != nil {
-		return credentials{}, fmt.Errorf("%w: %v", ErrBadResponse, err)
+		return fmt.Errorf("%w: %v", ErrBadResponse, err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -75,20 +189,69 @@ func (p Provider) getSTSCredentialsFromSAML(saml string) (credentials, error) {
 
 		switch resp.StatusCode {
 		case http.StatusBadRequest:
-			return credentials{}, fmt.Errorf("%w: status: %scode: %s message: %s", ErrBadRequest, resp.Status, errResponse.Error.Code, errResponse.Error.Message)
+			return fmt.Errorf("%w: status: %scode: %s message: %s", ErrBadRequest, resp.Status, errResponse.Error.Code, errResponse.Error.Message)
 		case http.StatusForbidden:
-			return credentials{}, fmt.Errorf("%w: status: %s, code: %s message: %s", ErrNotAuthorized, resp.Status, errResponse.Error.Code, errResponse.Error.Message)
+			return fmt.Errorf("%w: status: %s, code: %s message: %s", ErrNotAuthorized, resp.Status, errResponse.Error.Code, errResponse.Error.Message)
 		default:
-			return credentials{}, fmt.Errorf("%w: status: %s, code: %s message: %s", ErrUnknown, resp.Status, errResponse.Error.Code, errResponse.Error.Message)
+			return fmt.Errorf("%w: status: %s, code: %s message: %s", ErrUnknown, resp.Status, errResponse.Error.Code, errResponse.Error.Message)
 		}
 	}
 
-	stsResp := assumeRoleWithSAMLResponse{}
-	if err := xml.Unmarshal(respBody, &stsResp); err != nil {
-		return credentials{}, fmt.Errorf("%w: could not unmarshall response: %v", ErrBadResponse, err)
+	if err := xml.Unmarshal(respBody, v); err != nil {
+		return fmt.Errorf("%w: could not unmarshall response: %v", ErrBadResponse, err)
 	}
 
-	log.Print("STS credentials retrieved")
+	return nil
+}
+
+// webIdentitySessionName returns a role session name from the email or, if
+// missing, the sub claim of the token. The token is not verified, STS does it
+// when assuming the role.
+func webIdentitySessionName(token string) string {
+	claims := struct {
+		Email   string `json:"email"`
+		Subject string `json:"sub"`
+	}{}
+
+	parts := strings.Split(token, ".")
+	if len(parts) == 3 {
+		if payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "=")); err == nil {
+			// ignoring unmarshall error to fall back to the default name
+			json.Unmarshal(payload, &claims)
+		}
+	}
+
+	name := claims.Email
+	if name == "" {
+		name = claims.Subject
+	}
+
+	// session names only allow alphanumerics and =,.@-_ with 2 to 64 characters
+	name = invalidSessionNameChars.ReplaceAllString(name, "-")
+	if len(name) > maxSessionNameLength {
+		name = name[:maxSessionNameLength]
+	}
+
+	if len(name) < 2 {
+		return defaultSessionName
+	}
+
+	return name
+}
+
+// stsRegion returns the region of the STS endpoint to sign requests, the
+// global endpoint is in us-east-1.
+func stsRegion(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return defaultSTSRegion
+	}
+
+	// regional endpoints are sts.REGION.amazonaws.com
+	parts := strings.Split(u.Hostname(), ".")
+	if len(parts) == 4 && parts[0] == "sts" && parts[2] == "amazonaws" {
+		return parts[1]
+	}
 
-	return stsResp.AssumeRoleResult.Credentials, nil
+	return defaultSTSRegion
 }
@@ -24,6 +24,28 @@ const SuccessSTSResponse = `<AssumeRoleWithSAMLResponse xmlns="https://sts.amazo
 </AssumeRoleWithSAMLResponse>
 `
 
+const SuccessWebIdentityResponse = `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <SubjectFromWebIdentityToken>00u1</SubjectFromWebIdentityToken>
+    <Audience>0oa1</Audience>
+    <AssumedRoleUser>
+      <Arn>arn:aws:sts::4543372610:assumed-role/okta-oie-ReadOnly/mail@mail.com</Arn>
+      <AssumedRoleId>AROARORTY3BBGGVCOV4OP:mail@mail.com</AssumedRoleId>
+    </AssumedRoleUser>
+    <Credentials>
+      <AccessKeyId>AWSACCESSKEYID</AccessKeyId>
+      <SecretAccessKey>Super/Secret/AccessKey</SecretAccessKey>
+      <SessionToken>reallylongandsecretsessiontoken</SessionToken>
+      <Expiration>2022-06-07T22:54:14Z</Expiration>
+    </Credentials>
+    <Provider>https://okta.example.com</Provider>
+  </AssumeRoleWithWebIdentityResult>
+  <ResponseMetadata>
+    <RequestId>ad4156e9-bce1-11e2-82e6-6b6efEXAMPLE</RequestId>
+  </ResponseMetadata>
+</AssumeRoleWithWebIdentityResponse>
+`
+
 const errSTSResponse = `
 <ErrorResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
 <Error>
@@ -37,3 +59,32 @@ const errSTSResponse = `
 
 const credentialsFileContent = "[test-profile]\naws_access_key_id = AWSACCESSKEYID\naws_secret_access_key = Super/Secret/AccessKey\naws_session_token = oldreallylongandreallysecrettoken\n\n"
 const newCredentialsFileContent = "[test-profile]\naws_access_key_id = AWSACCESSKEYID\naws_secret_access_key = Super/Secret/AccessKey\naws_session_token = reallylongandsecretsessiontoken\n\n"
+const generatedCredentialsFileContent = "[test-profile]\naws_access_key_id = AWSACCESSKEYID\naws_secret_access_key = Super/Secret/AccessKey\naws_session_token = reallylongandsecretsessiontoken\nx_security_token_expires = 2022-06-07T22:54:14Z\nx_assumed_role_arn = arn:aws:sts::4543372610:assumed-role/okta-oie-ReadOnly/mail@mail.com\n\n"
+
+const SuccessDescribeClusterResponse = `{
+  "cluster": {
+    "name": "dev",
+    "arn": "arn:aws:eks:us-east-1:123456789012:cluster/dev",
+    "endpoint": "https://ABCDEF.gr7.us-east-1.eks.amazonaws.com",
+    "status": "ACTIVE",
+    "certificateAuthority": {
+      "data": "LS0tLS1CRUdJTiBDRVJUSUZJQ0FURS0tLS0t"
+    }
+  }
+}
+`
+
+const errEKSResponse = `{"message":"No cluster found for name: dev."}`
+
+const SuccessECRResponse = `{
+  "authorizationData": [
+    {
+      "authorizationToken": "QVdTOmVjcnBhc3N3b3Jk",
+      "expiresAt": 1654684454.5,
+      "proxyEndpoint": "https://123456789012.dkr.ecr.us-east-1.amazonaws.com"
+    }
+  ]
+}
+`
+
+const errECRResponse = `{"__type":"AccessDeniedException","message":"User is not authorized to perform: ecr:GetAuthorizationToken"}`
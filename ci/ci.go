@@ -0,0 +1,157 @@
+// Package ci implements getting workload OIDC tokens in CI environments, where
+// no interactive login is possible, and exporting credentials to later steps.
+package ci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fox-tech/creds-fetcher/client"
+)
+
+const (
+	// EnvGitHubRequestURL and EnvGitHubRequestToken are set by GitHub Actions
+	// in jobs with the id-token: write permission.
+	EnvGitHubRequestURL   = "ACTIONS_ID_TOKEN_REQUEST_URL"
+	EnvGitHubRequestToken = "ACTIONS_ID_TOKEN_REQUEST_TOKEN"
+	// EnvGitHubEnv is the file GitHub Actions reads environment variables for
+	// the next steps from.
+	EnvGitHubEnv = "GITHUB_ENV"
+	// EnvTokenFile is the file with the OIDC token, as used by the AWS SDKs.
+	EnvTokenFile = "AWS_WEB_IDENTITY_TOKEN_FILE"
+
+	// DefaultAudience is the audience requested for tokens, the one AWS
+	// expects by default for OIDC identity providers.
+	DefaultAudience = "sts.amazonaws.com"
+)
+
+var (
+	ErrNoTokenSource = errors.New("no OIDC token source found, set a token file or run in GitHub Actions with id-token: write")
+	ErrTokenRequest  = errors.New("oidc token request")
+	ErrTokenFile     = errors.New("could not read token file")
+	ErrWriteEnv      = errors.New("could not write environment file")
+
+	// defaultHTTPClient is used when no client is passed to Token or
+	// GitHubToken.
+	defaultHTTPClient client.HTTPClient = client.NewDefault()
+)
+
+// Token returns the OIDC token of the CI job. The token is read from the file
+// in path, or the EnvTokenFile file if path is empty, and otherwise requested
+// from GitHub Actions for the given audience with hc. The request is cancelled
+// when ctx is done.
+func Token(ctx context.Context, hc client.HTTPClient, path, audience string) (string, error) {
+	if path == "" {
+		path = os.Getenv(EnvTokenFile)
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrTokenFile, err)
+		}
+
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	requestURL, requestToken := os.Getenv(EnvGitHubRequestURL), os.Getenv(EnvGitHubRequestToken)
+	if requestURL == "" || requestToken == "" {
+		return "", ErrNoTokenSource
+	}
+
+	return GitHubToken(ctx, hc, requestURL, requestToken, audience)
+}
+
+// GitHubToken requests an OIDC token for the given audience to the GitHub
+// Actions token endpoint with hc, or a default client when it's nil.
+//
+// More at https://docs.github.com/en/actions/deployment/security-hardening-your-deployments/about-security-hardening-with-openid-connect.
+func GitHubToken(ctx context.Context, hc client.HTTPClient, requestURL, requestToken, audience string) (string, error) {
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrTokenRequest, err)
+	}
+
+	if audience != "" {
+		q := u.Query()
+		q.Set("audience", audience)
+		u.RawQuery = q.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrTokenRequest, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+requestToken)
+	req.Header.Set("Accept", "application/json")
+
+	if hc == nil {
+		hc = defaultHTTPClient
+	}
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrTokenRequest, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("%w: status: %s, message: %s", ErrTokenRequest, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	token := struct {
+		Value string `json:"value"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("%w: could not decode response: %v", ErrTokenRequest, err)
+	}
+
+	if token.Value == "" {
+		return "", fmt.Errorf("%w: empty token", ErrTokenRequest)
+	}
+
+	return token.Value, nil
+}
+
+// WriteGitHubEnv appends the variables to the GitHub Actions environment file
+// in path, so they are set in the next steps of the job. The values in secrets
+// are masked in the job log by writing the mask commands to out.
+func WriteGitHubEnv(path string, out io.Writer, vars map[string]string, secrets ...string) error {
+	for _, s := range secrets {
+		if _, err := fmt.Fprintf(out, "::add-mask::%s\n", s); err != nil {
+			return fmt.Errorf("%w: %v", ErrWriteEnv, err)
+		}
+	}
+
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k + "=" + vars[k] + "\n")
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWriteEnv, err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(b.String()); err != nil {
+		return fmt.Errorf("%w: %v", ErrWriteEnv, err)
+	}
+
+	return nil
+}
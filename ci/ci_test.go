@@ -0,0 +1,117 @@
+package ci
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fox-tech/creds-fetcher/client"
+)
+
+func TestToken(t *testing.T) {
+	hc := client.MockHandlerClient{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer requesttoken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.URL.Query().Get("audience") != DefaultAudience {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Write([]byte(`{"count":1,"value":"github.oidc.token"}`))
+	})}
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("file.oidc.token\n"), 0600); err != nil {
+		t.Fatalf("writing test file: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		env    map[string]string
+		expect string
+		err    error
+	}{
+		{
+			name:   "token file: token is read",
+			path:   tokenFile,
+			expect: "file.oidc.token",
+		},
+		{
+			name:   "token file from env: token is read",
+			env:    map[string]string{EnvTokenFile: tokenFile},
+			expect: "file.oidc.token",
+		},
+		{
+			name: "missing token file: error is returned",
+			path: filepath.Join(t.TempDir(), "missing"),
+			err:  ErrTokenFile,
+		},
+		{
+			name: "github actions: token is requested",
+			env: map[string]string{
+				EnvGitHubRequestURL:   "https://pipelines.actions.example.com/token?api-version=2.0",
+				EnvGitHubRequestToken: "requesttoken",
+			},
+			expect: "github.oidc.token",
+		},
+		{
+			name: "github actions with invalid request token: error is returned",
+			env: map[string]string{
+				EnvGitHubRequestURL:   "https://pipelines.actions.example.com/token",
+				EnvGitHubRequestToken: "othertoken",
+			},
+			err: ErrTokenRequest,
+		},
+		{
+			name: "no token source: error is returned",
+			err:  ErrNoTokenSource,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{EnvTokenFile, EnvGitHubRequestURL, EnvGitHubRequestToken} {
+				t.Setenv(k, tt.env[k])
+			}
+
+			token, err := Token(context.Background(), hc, tt.path, DefaultAudience)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Token() expected error: %v, got: %v", tt.err, err)
+			}
+
+			if token != tt.expect {
+				t.Errorf("Token() expected: %s, got: %s", tt.expect, token)
+			}
+		})
+	}
+}
+
+func TestWriteGitHubEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "github_env")
+	if err := os.WriteFile(path, []byte("EXISTING=1\n"), 0600); err != nil {
+		t.Fatalf("writing test file: %v", err)
+	}
+
+	out := new(bytes.Buffer)
+	vars := map[string]string{"B": "2", "A": "1"}
+	if err := WriteGitHubEnv(path, out, vars, "secret"); err != nil {
+		t.Fatalf("WriteGitHubEnv() unexpected error: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if expect := "EXISTING=1\nA=1\nB=2\n"; string(data) != expect {
+		t.Errorf("WriteGitHubEnv() expected file: %q, got: %q", expect, data)
+	}
+
+	if expect := "::add-mask::secret\n"; out.String() != expect {
+		t.Errorf("WriteGitHubEnv() expected output: %q, got: %q", expect, out.String())
+	}
+}
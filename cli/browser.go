@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// openBrowser opens the URL in the browser set in $BROWSER or the default
+// browser of the system. It's a variable so tests don't open a browser.
+var openBrowser = func(url string) error {
+	return browserCommand(url, os.Getenv("BROWSER")).Start()
+}
+
+// browserCommand returns the command opening the URL. browser follows the
+// $BROWSER convention, the first of the colon separated commands is used and
+// %s is replaced by the URL, which is appended otherwise.
+func browserCommand(url, browser string) *exec.Cmd {
+	if browser != "" {
+		args := strings.Fields(strings.Split(browser, ":")[0])
+		if len(args) > 0 {
+			replaced := false
+			for i, a := range args {
+				if strings.Contains(a, "%s") {
+					args[i] = strings.ReplaceAll(a, "%s", url)
+					replaced = true
+				}
+			}
+			if !replaced {
+				args = append(args, url)
+			}
+
+			return exec.Command(args[0], args[1:]...)
+		}
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url)
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		return exec.Command("xdg-open", url)
+	}
+}
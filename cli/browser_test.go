@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_browserCommand(t *testing.T) {
+	url := "https://example.okta.com/activate?user_code=D"
+
+	tests := []struct {
+		name    string
+		browser string
+		expect  []string
+	}{
+		{
+			name:    "URL appended to the browser command",
+			browser: "firefox --new-window",
+			expect:  []string{"firefox", "--new-window", url},
+		},
+		{
+			name:    "URL replaces %s",
+			browser: "w3m %s -no-mouse",
+			expect:  []string{"w3m", url, "-no-mouse"},
+		},
+		{
+			name:    "first of the browser list used",
+			browser: "lynx:links",
+			expect:  []string{"lynx", url},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := browserCommand(url, tt.browser)
+
+			if !reflect.DeepEqual(cmd.Args, tt.expect) {
+				t.Errorf("browserCommand() expected: %v, got: %v", tt.expect, cmd.Args)
+			}
+		})
+	}
+}
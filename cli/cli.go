@@ -3,9 +3,13 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
+
+	"github.com/fox-tech/creds-fetcher/client"
 )
 
 const (
@@ -15,6 +19,16 @@ const (
 var (
 	ErrNotFound  = errors.New("not found")
 	ErrNoCommand = errors.New("missing command name")
+
+	// stdin and stdout are where commands read their input and write their
+	// output, stderr is used for messages when stdout is read by another program
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+
+	// httpClient is shared by the requests to Okta and AWS so they reuse
+	// connections and have the same timeouts and headers
+	httpClient client.HTTPClient = client.NewDefault()
 )
 
 // CLI represents an interpreter that will execute a given command
@@ -29,14 +43,27 @@ type CLI struct {
 }
 
 // New creates a CLI instance with default values and adds the
-// supported commands: login
+// supported commands: login, ci-login, kubeconfig, eks-token, ecr-login,
+// rds-token, git-credential, logout and whoami. When the executable is named
+// as a docker credential helper, the ecr-login command is always used.
 func New() CLI {
 	c := CLI{
 		commands: CommandMap{},
 		flags:    FlagMap{},
 	}
 	c.args = os.Args
+	if len(os.Args) > 0 && strings.HasPrefix(filepath.Base(os.Args[0]), dockerHelperPrefix) {
+		c.args = append([]string{os.Args[0], ecrLoginCmd.name}, os.Args[1:]...)
+	}
 	c.AddCommand(loginCmd)
+	c.AddCommand(ciLoginCmd)
+	c.AddCommand(kubeconfigCmd)
+	c.AddCommand(eksTokenCmd)
+	c.AddCommand(ecrLoginCmd)
+	c.AddCommand(rdsTokenCmd)
+	c.AddCommand(gitCredentialCmd)
+	c.AddCommand(logoutCmd)
+	c.AddCommand(whoamiCmd)
 	return c
 }
 
@@ -5,6 +5,9 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/fox-tech/creds-fetcher/client"
+	cfg "github.com/fox-tech/creds-fetcher/configuration"
 )
 
 func Test_New(t *testing.T) {
@@ -144,3 +147,52 @@ func Test_Execute(t *testing.T) {
 		})
 	}
 }
+
+func Test_profileHTTPClient(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       cfg.Configuration
+		expectShared bool
+		expectErr    error
+	}{
+		{
+			name:         "no settings: shared client",
+			config:       cfg.Configuration{},
+			expectShared: true,
+		},
+		{
+			name:   "retry policy: profile client",
+			config: cfg.Configuration{RetryMaxAttempts: 1},
+		},
+		{
+			name:   "proxy: profile client",
+			config: cfg.Configuration{HTTPSProxy: "http://proxy.corp:3128", NoProxy: "okta.com"},
+		},
+		{
+			name:      "missing CA bundle: error",
+			config:    cfg.Configuration{CABundle: "missing.pem"},
+			expectErr: client.ErrInvalidCABundle,
+		},
+		{
+			name:      "missing client certificate: error",
+			config:    cfg.Configuration{ClientCertificate: "missing.pem", ClientKey: "missing-key.pem"},
+			expectErr: client.ErrInvalidClientCertificate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc, err := profileHTTPClient(&tt.config)
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("profileHTTPClient() expected error: %v, got: %v", tt.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if shared := reflect.DeepEqual(hc, httpClient); shared != tt.expectShared {
+				t.Errorf("profileHTTPClient() expected shared client: %t, got: %t", tt.expectShared, shared)
+			}
+		})
+	}
+}
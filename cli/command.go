@@ -1,20 +1,155 @@
 package cli
 
-import "github.com/fox-tech/creds-fetcher/okta"
+import (
+	"context"
+	"fmt"
+	"net/http/cookiejar"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/aws"
+	"github.com/fox-tech/creds-fetcher/client"
+	cfg "github.com/fox-tech/creds-fetcher/configuration"
+)
 
 type CommandAction func(FlagMap) error
 
 type CommandMap map[string]Command
 
 type Command struct {
-	name string
-	doc  string
-	f    CommandAction
+	name  string
+	doc   string
+	f     CommandAction
+	flags []flagDef
+}
+
+// commandContext returns a context cancelled on interrupt, so Ctrl-C stops
+// requests and polling cleanly, and after timeout when it's not zero.
+func commandContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// loadConfiguration returns the profile name and its configuration using the
+// profile and config flags. The default profile is used when no profile is
+// given.
+func loadConfiguration(flags FlagMap) (string, *cfg.Configuration, error) {
+	profName, err := findString(FlagProfile, flags)
+	if err != nil {
+		return "", nil, err
+	}
+
+	configFile, err := findString(FlagConfig, flags)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if profName == "" {
+		profName = defaultKey
+	}
+
+	config, err := cfg.New(profName, configFile)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w:  %v", ErrNoConfig, err)
+	}
+
+	return profName, config, nil
+}
+
+// profileHTTPClient returns the shared HTTP client, or a client with the
+// retry policy and network settings of the profile when its configuration
+// changes them.
+func profileHTTPClient(config *cfg.Configuration) (client.HTTPClient, error) {
+	var opts []client.Option
+
+	if config.RetryMaxAttempts != 0 || config.RetryBaseDelay != "" || config.RetryMaxDelay != "" {
+		// the delays are valid, the configuration is validated when it's loaded
+		policy := client.DefaultRetryPolicy
+		if config.RetryMaxAttempts > 0 {
+			policy.MaxAttempts = config.RetryMaxAttempts
+		}
+		if d, err := time.ParseDuration(config.RetryBaseDelay); err == nil {
+			policy.BaseDelay = d
+		}
+		if d, err := time.ParseDuration(config.RetryMaxDelay); err == nil {
+			policy.MaxDelay = d
+		}
+
+		opts = append(opts, client.SetRetryPolicy(policy))
+	}
+
+	if config.HTTPSProxy != "" || config.NoProxy != "" {
+		opts = append(opts, client.SetProxy(config.HTTPSProxy, config.NoProxy))
+	}
+
+	if config.CABundle != "" {
+		pool, err := client.LoadCABundle(config.CABundle)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, client.SetRootCAs(pool))
+	}
+
+	if config.ClientCertificate != "" {
+		cert, err := client.LoadClientCertificate(config.ClientCertificate, config.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, client.SetClientCertificate(cert))
+	}
+
+	if config.AuthFlow == cfg.AuthFlowAuthn {
+		// the session of the authn login is kept in cookies through the
+		// redirects of the app embed link, New never fails without options
+		jar, _ := cookiejar.New(nil)
+		opts = append(opts, client.SetCookieJar(jar))
+	}
+
+	if len(opts) == 0 {
+		return httpClient, nil
+	}
+
+	return client.New(opts...), nil
+}
+
+// newProvider returns an AWS provider for the given profile configuration,
+// using the HTTP client of the profile unless opts sets another one.
+func newProvider(profName string, config *cfg.Configuration, opts ...aws.Option) (aws.Provider, error) {
+	hc, err := profileHTTPClient(config)
+	if err != nil {
+		return aws.Provider{}, err
+	}
 
-	authenticator Authenticator
+	return aws.New(aws.Profile{
+		Name:         profName,
+		RoleARN:      config.AWSRoleARN,
+		PrincipalARN: config.AWSProviderARN,
+		WebIdentity:  config.IsWebIdentity(),
+		Direct:       config.IdentityProvider() == cfg.ProviderSSO,
+		AccountID:    config.SSOAccountID,
+	}, append([]aws.Option{aws.SetHTTPClient(hc)}, opts...)...)
 }
 
-type Authenticator interface {
-	PreAuthorize() (okta.Device, error)
-	Authorize(device okta.Device) error
+// storedCredentialsProvider returns an AWS provider for the stored credentials
+// of the profile, requesting AWS with hc. Unlike newProvider it accepts every
+// profile type, as only the name and role of the profile are used.
+func storedCredentialsProvider(profName string, config *cfg.Configuration, hc client.HTTPClient) (aws.Provider, error) {
+	return aws.New(aws.Profile{
+		Name:      profName,
+		RoleARN:   config.AWSRoleARN,
+		AccountID: config.SSOAccountID,
+		Stored:    true,
+	}, aws.SetHTTPClient(hc))
 }
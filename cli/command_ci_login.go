@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fox-tech/creds-fetcher/aws"
+	"github.com/fox-tech/creds-fetcher/ci"
+)
+
+var ciLoginCmd = Command{
+	name: "ci-login",
+	doc:  " get credentials for AWS profile with the OIDC token of a CI job",
+	f:    ciLogin,
+	flags: []flagDef{
+		{name: FlagTokenFile, value: "", usage: "file with the OIDC token, defaults to $AWS_WEB_IDENTITY_TOKEN_FILE or the GitHub Actions token"},
+		{name: FlagAudience, value: ci.DefaultAudience, usage: "audience of the requested GitHub Actions token"},
+		{name: FlagEndpoint, value: "", usage: "override the AWS STS endpoint"},
+	},
+}
+
+// ciLogin exchanges the OIDC token of the CI job for credentials of the
+// profile role. The credentials are exported to the next steps through
+// $GITHUB_ENV when it's set, otherwise they are saved to the credentials file.
+func ciLogin(flags FlagMap) error {
+	profName, config, err := loadConfiguration(flags)
+	if err != nil {
+		return err
+	}
+
+	tokenFile, _ := findString(FlagTokenFile, flags)
+	audience, _ := findString(FlagAudience, flags)
+	endpoint, _ := findString(FlagEndpoint, flags)
+
+	ctx, cancel := commandContext(0)
+	defer cancel()
+
+	hc, err := profileHTTPClient(config)
+	if err != nil {
+		return err
+	}
+
+	token, err := ci.Token(ctx, hc, tokenFile, audience)
+	if err != nil {
+		return err
+	}
+
+	opts := []aws.Option{aws.SetHTTPClient(hc)}
+	if endpoint != "" {
+		opts = append(opts, aws.SetSTSURL(endpoint))
+	}
+
+	// any profile can be used, the job token always replaces the Okta login
+	provider, err := aws.New(aws.Profile{
+		Name:        profName,
+		RoleARN:     config.AWSRoleARN,
+		WebIdentity: true,
+	}, opts...)
+	if err != nil {
+		return err
+	}
+
+	envFile := os.Getenv(ci.EnvGitHubEnv)
+	if envFile == "" {
+		return provider.GenerateCredentialsWithWebIdentity(ctx, token)
+	}
+
+	cred, err := provider.AssumeRoleWithWebIdentity(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	vars := map[string]string{
+		"AWS_ACCESS_KEY_ID":     cred.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY": cred.SecretAccessKey,
+		"AWS_SESSION_TOKEN":     cred.SessionToken,
+	}
+	if config.AWSRegion != "" {
+		vars["AWS_REGION"] = config.AWSRegion
+	}
+
+	if err := ci.WriteGitHubEnv(envFile, stdout, vars, cred.SecretAccessKey, cred.SessionToken); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "credentials for profile %s exported to %s\n", profName, ci.EnvGitHubEnv)
+	return nil
+}
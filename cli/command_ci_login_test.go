@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fox-tech/creds-fetcher/aws"
+	"github.com/fox-tech/creds-fetcher/ci"
+)
+
+func Test_ciLogin(t *testing.T) {
+	tests := []struct {
+		name       string
+		withToken  bool
+		githubEnv  bool
+		expectFile string
+		expectData string
+		expect     error
+	}{
+		{
+			name:       "github actions: credentials are exported to GITHUB_ENV",
+			withToken:  true,
+			githubEnv:  true,
+			expectFile: "github_env",
+			expectData: "AWS_ACCESS_KEY_ID=AWSACCESSKEYID\nAWS_SECRET_ACCESS_KEY=Super/Secret/AccessKey\nAWS_SESSION_TOKEN=reallylongandsecretsessiontoken\n",
+		},
+		{
+			name:       "other CI: credentials are saved to file",
+			withToken:  true,
+			expectFile: filepath.Join(".aws", "credentials"),
+			expectData: "[test]\naws_access_key_id = AWSACCESSKEYID\n",
+		},
+		{
+			name:   "error: no token",
+			expect: ci.ErrNoTokenSource,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := setupHome(t, false)
+			home := filepath.Dir(configPath)
+
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.FormValue("Action") != "AssumeRoleWithWebIdentity" || r.FormValue("WebIdentityToken") != "job.oidc.token" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				w.Write([]byte(aws.SuccessWebIdentityResponse))
+			}))
+			defer s.Close()
+
+			tokenFile := ""
+			if tt.withToken {
+				tokenFile = filepath.Join(home, "token")
+				if err := os.WriteFile(tokenFile, []byte("job.oidc.token"), 0600); err != nil {
+					t.Fatalf("could not create token file: %v", err)
+				}
+			}
+
+			t.Setenv(ci.EnvTokenFile, "")
+			t.Setenv(ci.EnvGitHubRequestURL, "")
+			t.Setenv(ci.EnvGitHubEnv, "")
+			if tt.githubEnv {
+				t.Setenv(ci.EnvGitHubEnv, filepath.Join(home, "github_env"))
+			}
+
+			out := new(bytes.Buffer)
+			prevStdout := stdout
+			stdout = out
+			defer func() { stdout = prevStdout }()
+
+			err := ciLogin(FlagMap{
+				FlagProfile:   {Name: FlagProfile, Value: "test"},
+				FlagConfig:    {Name: FlagConfig, Value: configPath},
+				FlagTokenFile: {Name: FlagTokenFile, Value: tokenFile},
+				FlagAudience:  {Name: FlagAudience, Value: ci.DefaultAudience},
+				FlagEndpoint:  {Name: FlagEndpoint, Value: s.URL},
+			})
+
+			if !errors.Is(err, tt.expect) {
+				t.Fatalf("ciLogin() expected error: %v, got %v", tt.expect, err)
+			}
+
+			if tt.expectFile == "" {
+				return
+			}
+
+			data, err := os.ReadFile(filepath.Join(home, tt.expectFile))
+			if err != nil {
+				t.Fatalf("ciLogin() expected file %s: %v", tt.expectFile, err)
+			}
+
+			if !strings.Contains(string(data), tt.expectData) {
+				t.Errorf("ciLogin() expected file to contain: %s, got: %s", tt.expectData, data)
+			}
+
+			if tt.githubEnv && !strings.Contains(out.String(), "::add-mask::reallylongandsecretsessiontoken") {
+				t.Errorf("ciLogin() expected session token to be masked, got: %s", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/fox-tech/creds-fetcher/aws"
+	cfg "github.com/fox-tech/creds-fetcher/configuration"
+	"github.com/fox-tech/creds-fetcher/ecr"
+)
+
+const (
+	// dockerHelperPrefix is the prefix of the executable name docker uses for
+	// credential helpers. When creds-fetcher is run with this prefix, e.g.
+	// through a symlink, it runs the ecr-login command.
+	dockerHelperPrefix = "docker-credential-"
+)
+
+var (
+	ErrNoProfileForRegistry = errors.New("no profile configured for registry")
+)
+
+var ecrLoginCmd = Command{
+	name: "ecr-login",
+	doc:  " docker credential helper for ECR registries: get, store, erase or list",
+	f:    ecrLogin,
+}
+
+// ecrLogin runs the docker credential helper action given as argument, reading
+// from stdin and writing to stdout.
+func ecrLogin(flags FlagMap) error {
+	f, err := findFlag(FlagArgs, flags)
+	if err != nil {
+		return fmt.Errorf("%w: missing action", ErrMissingFlagValue)
+	}
+	args, _ := f.Value.([]string)
+
+	profName, err := findString(FlagProfile, flags)
+	if err != nil {
+		return err
+	}
+
+	configFile, err := findString(FlagConfig, flags)
+	if err != nil {
+		return err
+	}
+
+	helper := ecr.New(ecrSource{profile: profName, configFile: configFile})
+	return helper.Execute(args[0], stdin, stdout)
+}
+
+// ecrSource gets ECR credentials using the profile configured for the account
+// and region of the registry.
+type ecrSource struct {
+	profile    string
+	configFile string
+}
+
+// Credentials returns new credentials for the registry using the stored
+// credentials of its profile.
+func (s ecrSource) Credentials(reg ecr.Registry) (ecr.Credentials, error) {
+	profName, config, err := s.profileFor(reg)
+	if err != nil {
+		return ecr.Credentials{}, err
+	}
+
+	provider, err := newProvider(profName, config)
+	if err != nil {
+		return ecr.Credentials{}, err
+	}
+
+	auth, err := provider.GetECRAuthorization(reg.Region)
+	if err != nil {
+		return ecr.Credentials{}, err
+	}
+
+	return ecr.Credentials{
+		Username:  auth.Username,
+		Secret:    auth.Password,
+		ExpiresAt: auth.ExpiresAt,
+	}, nil
+}
+
+// profileFor returns the profile to use for the registry. If a profile was
+// given it's always used, otherwise the profile with a role in the account of
+// the registry is used, preferring the one configured for its region.
+func (s ecrSource) profileFor(reg ecr.Registry) (string, *cfg.Configuration, error) {
+	if s.profile != "" {
+		config, err := cfg.New(s.profile, s.configFile)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w:  %v", ErrNoConfig, err)
+		}
+
+		return s.profile, config, nil
+	}
+
+	configs, err := cfg.All(s.configFile)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w:  %v", ErrNoConfig, err)
+	}
+
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	match := ""
+	for _, name := range names {
+		config := configs[name]
+		if aws.AccountID(config.AWSRoleARN) != reg.Account {
+			continue
+		}
+
+		if config.AWSRegion == reg.Region {
+			return name, config, nil
+		}
+
+		if match == "" && config.AWSRegion == "" {
+			match = name
+		}
+	}
+
+	if match == "" {
+		return "", nil, fmt.Errorf("%w: %s", ErrNoProfileForRegistry, reg.Host)
+	}
+
+	return match, configs[match], nil
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fox-tech/creds-fetcher/aws"
+	"github.com/fox-tech/creds-fetcher/ecr"
+)
+
+func Test_ecrLogin(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		input     string
+		expectOut string
+		expect    error
+	}{
+		{
+			name:      "get: credentials for registry of configured account",
+			args:      []string{ecr.ActionGet},
+			input:     "https://123456789012.dkr.ecr.us-east-1.amazonaws.com",
+			expectOut: `"Username":"AWS","Secret":"ecrpassword"`,
+		},
+		{
+			name:   "get: no profile for registry account",
+			args:   []string{ecr.ActionGet},
+			input:  "210987654321.dkr.ecr.us-east-1.amazonaws.com",
+			expect: ErrNoProfileForRegistry,
+		},
+		{
+			name:      "get: not an ECR registry",
+			args:      []string{ecr.ActionGet},
+			input:     "index.docker.io",
+			expectOut: "credentials not found in native keychain",
+			expect:    ecr.ErrCredentialsNotFound,
+		},
+		{
+			name:   "error: missing action",
+			expect: ErrMissingFlagValue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := setupHome(t, true)
+
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("X-Amz-Target") != "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				w.Write([]byte(aws.SuccessECRResponse))
+			}))
+			defer s.Close()
+
+			prevURL := aws.ECRURL
+			aws.ECRURL = s.URL + "/%s"
+			defer func() { aws.ECRURL = prevURL }()
+
+			out := new(bytes.Buffer)
+			prevStdin, prevStdout := stdin, stdout
+			stdin, stdout = strings.NewReader(tt.input), out
+			defer func() { stdin, stdout = prevStdin, prevStdout }()
+
+			flags := FlagMap{
+				FlagProfile: {Name: FlagProfile, Value: ""},
+				FlagConfig:  {Name: FlagConfig, Value: configPath},
+			}
+			if tt.args != nil {
+				flags[FlagArgs] = Flag{Name: FlagArgs, Value: tt.args}
+			}
+
+			err := ecrLogin(flags)
+
+			if !errors.Is(err, tt.expect) {
+				t.Fatalf("ecrLogin() expected error: %v, got %v", tt.expect, err)
+			}
+
+			if !strings.Contains(out.String(), tt.expectOut) {
+				t.Errorf("ecrLogin() expected output to contain: %s, got: %s", tt.expectOut, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/kubeconfig"
+)
+
+var eksTokenCmd = Command{
+	name: "eks-token",
+	doc:  " print an EKS cluster token as a kubectl exec credential",
+	f:    eksToken,
+	flags: []flagDef{
+		{name: FlagCluster, value: "", usage: "name of the EKS cluster"},
+	},
+}
+
+// execCredential represents the ExecCredential object kubectl expects from an
+// exec credential plugin.
+type execCredential struct {
+	Kind       string               `json:"kind"`
+	APIVersion string               `json:"apiVersion"`
+	Spec       struct{}             `json:"spec"`
+	Status     execCredentialStatus `json:"status"`
+}
+
+type execCredentialStatus struct {
+	ExpirationTimestamp string `json:"expirationTimestamp"`
+	Token               string `json:"token"`
+}
+
+// eksToken prints a token for the EKS cluster generated from the stored
+// profile credentials.
+func eksToken(flags FlagMap) error {
+	cluster, _ := findString(FlagCluster, flags)
+	if cluster == "" {
+		return fmt.Errorf("%w: -%s is required", ErrMissingFlagValue, FlagCluster)
+	}
+
+	profName, config, err := loadConfiguration(flags)
+	if err != nil {
+		return err
+	}
+
+	provider, err := newProvider(profName, config)
+	if err != nil {
+		return err
+	}
+
+	token, err := provider.GenerateEKSToken(cluster)
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(stdout).Encode(execCredential{
+		Kind:       "ExecCredential",
+		APIVersion: kubeconfig.ExecAPIVersion,
+		Status: execCredentialStatus{
+			ExpirationTimestamp: token.Expiration.Format(time.RFC3339),
+			Token:               token.Token,
+		},
+	})
+}
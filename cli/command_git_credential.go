@@ -0,0 +1,185 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/fox-tech/creds-fetcher/aws"
+	cfg "github.com/fox-tech/creds-fetcher/configuration"
+)
+
+const (
+	gitActionGet   = "get"
+	gitActionStore = "store"
+	gitActionErase = "erase"
+
+	// codeCommitReposPath is the path prefix of CodeCommit repositories, the
+	// repository name follows it.
+	codeCommitReposPath = "/v1/repos/"
+)
+
+var (
+	ErrNoProfileForRepository = errors.New("no profile configured for repository")
+	ErrUnknownGitAction       = errors.New("unknown git credential action")
+	ErrMissingGitPath         = errors.New("missing repository path, set git config credential.UseHttpPath true")
+)
+
+var gitCredentialCmd = Command{
+	name: "git-credential",
+	doc:  " git credential helper for CodeCommit repositories: get, store or erase",
+	f:    gitCredential,
+}
+
+// gitCredential runs the git credential helper action given as argument,
+// reading the request from stdin and writing the credentials to stdout. Only
+// get returns credentials, as they are generated on each request store and
+// erase do nothing.
+//
+// More at https://git-scm.com/docs/gitcredentials#_custom_helpers.
+func gitCredential(flags FlagMap) error {
+	f, err := findFlag(FlagArgs, flags)
+	if err != nil {
+		return fmt.Errorf("%w: missing action", ErrMissingFlagValue)
+	}
+	args, _ := f.Value.([]string)
+
+	req, err := readGitCredentialRequest(stdin)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case gitActionGet:
+	case gitActionStore, gitActionErase:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownGitAction, args[0])
+	}
+
+	// other hosts are left to the next helper configured in git
+	host := req["host"]
+	if req["protocol"] != "https" || aws.CodeCommitRegion(host) == "" {
+		return nil
+	}
+
+	path := "/" + strings.TrimPrefix(req["path"], "/")
+	if !strings.HasPrefix(path, codeCommitReposPath) {
+		return ErrMissingGitPath
+	}
+
+	profName, err := findString(FlagProfile, flags)
+	if err != nil {
+		return err
+	}
+
+	configFile, err := findString(FlagConfig, flags)
+	if err != nil {
+		return err
+	}
+
+	repo := strings.SplitN(strings.TrimPrefix(path, codeCommitReposPath), "/", 2)[0]
+	profName, config, err := codeCommitProfile(profName, configFile, repo, aws.CodeCommitRegion(host))
+	if err != nil {
+		return err
+	}
+
+	provider, err := newProvider(profName, config)
+	if err != nil {
+		return err
+	}
+
+	creds, err := provider.GenerateCodeCommitCredentials(host, path)
+	if errors.Is(err, aws.ErrNoCredentials) || errors.Is(err, aws.ErrCredentialsExpired) {
+		ctx, cancel := commandContext(0)
+		defer cancel()
+
+		// stdout is read by git, the login instructions go to stderr
+		if err := authenticate(ctx, config, provider, stderr, true); err != nil {
+			return err
+		}
+
+		creds, err = provider.GenerateCodeCommitCredentials(host, path)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(stdout, "username=%s\npassword=%s\n", creds.Username, creds.Password)
+	return err
+}
+
+// readGitCredentialRequest reads the key=value lines sent by git until an
+// empty line or the end of the input.
+func readGitCredentialRequest(r io.Reader) (map[string]string, error) {
+	req := map[string]string{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			break
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		req[key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading git credential request: %w", err)
+	}
+
+	return req, nil
+}
+
+// codeCommitProfile returns the profile to use for the repository. If a
+// profile was given it's always used, otherwise the profile listing the
+// repository in codecommit_repositories is used, then the profile configured
+// for the region of the repository and finally the default profile.
+func codeCommitProfile(profile, configFile, repo, region string) (string, *cfg.Configuration, error) {
+	if profile != "" {
+		config, err := cfg.New(profile, configFile)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w:  %v", ErrNoConfig, err)
+		}
+
+		return profile, config, nil
+	}
+
+	configs, err := cfg.All(configFile)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w:  %v", ErrNoConfig, err)
+	}
+
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, r := range configs[name].CodeCommitRepositories {
+			if r == repo {
+				return name, configs[name], nil
+			}
+		}
+	}
+
+	for _, name := range names {
+		if configs[name].AWSRegion == region {
+			return name, configs[name], nil
+		}
+	}
+
+	if config, ok := configs[defaultKey]; ok {
+		return defaultKey, config, nil
+	}
+
+	return "", nil, fmt.Errorf("%w: %s", ErrNoProfileForRepository, repo)
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_gitCredential(t *testing.T) {
+	config := `
+	[test]
+	aws_provider_arn = "arn:aws:iam::provider"
+	aws_role_arn  = "arn:aws:iam::123456789012:role/dev"
+	okta_client_id = "123"
+	okta_app_id = "234"
+	okta_url = "https://okta.example.com"
+	codecommit_repositories = ["infra"]
+
+	[other]
+	aws_provider_arn = "arn:aws:iam::provider"
+	aws_role_arn  = "arn:aws:iam::210987654321:role/dev"
+	okta_client_id = "123"
+	okta_app_id = "234"
+	okta_url = "https://okta.example.com"
+	aws_region = "us-west-2"
+	`
+
+	tests := []struct {
+		name      string
+		args      []string
+		input     string
+		expectOut string
+		expect    error
+	}{
+		{
+			name:      "get: credentials for repository of configured profile",
+			args:      []string{gitActionGet},
+			input:     "protocol=https\nhost=git-codecommit.eu-west-1.amazonaws.com\npath=v1/repos/infra\n\n",
+			expectOut: "username=AWSACCESSKEYID%reallylongandsecretsessiontoken\npassword=",
+		},
+		{
+			name:   "get: no profile for repository",
+			args:   []string{gitActionGet},
+			input:  "protocol=https\nhost=git-codecommit.eu-west-1.amazonaws.com\npath=v1/repos/web\n\n",
+			expect: ErrNoProfileForRepository,
+		},
+		{
+			name:   "get: missing repository path",
+			args:   []string{gitActionGet},
+			input:  "protocol=https\nhost=git-codecommit.eu-west-1.amazonaws.com\n\n",
+			expect: ErrMissingGitPath,
+		},
+		{
+			name:  "get: not a CodeCommit host",
+			args:  []string{gitActionGet},
+			input: "protocol=https\nhost=github.com\npath=fox-tech/creds-fetcher\n\n",
+		},
+		{
+			name:  "store: nothing is done",
+			args:  []string{gitActionStore},
+			input: "protocol=https\nhost=git-codecommit.eu-west-1.amazonaws.com\nusername=user\npassword=secret\n\n",
+		},
+		{
+			name:   "error: unknown action",
+			args:   []string{"list"},
+			expect: ErrUnknownGitAction,
+		},
+		{
+			name:   "error: missing action",
+			expect: ErrMissingFlagValue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := setupHome(t, true)
+			if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+				t.Fatalf("could not create config file: %v", err)
+			}
+
+			out := new(bytes.Buffer)
+			prevStdin, prevStdout := stdin, stdout
+			stdin, stdout = strings.NewReader(tt.input), out
+			defer func() { stdin, stdout = prevStdin, prevStdout }()
+
+			flags := FlagMap{
+				FlagProfile: {Name: FlagProfile, Value: ""},
+				FlagConfig:  {Name: FlagConfig, Value: configPath},
+			}
+			if tt.args != nil {
+				flags[FlagArgs] = Flag{Name: FlagArgs, Value: tt.args}
+			}
+
+			err := gitCredential(flags)
+
+			if !errors.Is(err, tt.expect) {
+				t.Fatalf("gitCredential() expected error: %v, got %v", tt.expect, err)
+			}
+
+			if tt.expectOut == "" && out.Len() > 0 {
+				t.Errorf("gitCredential() expected no output, got: %s", out.String())
+			}
+
+			if !strings.Contains(out.String(), tt.expectOut) {
+				t.Errorf("gitCredential() expected output to contain: %s, got: %s", tt.expectOut, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fox-tech/creds-fetcher/aws"
+	"github.com/fox-tech/creds-fetcher/kubeconfig"
+)
+
+const (
+	// kubeconfigCommand is the command written to the kubeconfig users to get
+	// cluster tokens.
+	kubeconfigCommand = "creds-fetcher"
+)
+
+var (
+	ErrMissingFlagValue = errors.New("missing required flag value")
+	ErrKubeconfig       = errors.New("failed to update kubeconfig")
+)
+
+var kubeconfigCmd = Command{
+	name: "kubeconfig",
+	doc:  " add an EKS cluster to the kubeconfig",
+	f:    updateKubeconfig,
+	flags: []flagDef{
+		{name: FlagCluster, value: "", usage: "name of the EKS cluster"},
+		{name: FlagRegion, value: "", usage: "AWS region of the EKS cluster"},
+		{name: FlagEndpoint, value: "", usage: "override the EKS API endpoint"},
+		{name: FlagKubeconfig, value: "", usage: "path to the kubeconfig file, defaults to $KUBECONFIG or ~/.kube/config"},
+	},
+}
+
+// updateKubeconfig describes an EKS cluster with the profile credentials and
+// merges a cluster, user and context for it in the kubeconfig. The user gets
+// its tokens by executing creds-fetcher.
+func updateKubeconfig(flags FlagMap) error {
+	cluster, _ := findString(FlagCluster, flags)
+	region, _ := findString(FlagRegion, flags)
+	endpoint, _ := findString(FlagEndpoint, flags)
+	path, _ := findString(FlagKubeconfig, flags)
+
+	if cluster == "" || region == "" {
+		return fmt.Errorf("%w: -%s and -%s are required", ErrMissingFlagValue, FlagCluster, FlagRegion)
+	}
+
+	profName, config, err := loadConfiguration(flags)
+	if err != nil {
+		return err
+	}
+
+	opts := []aws.Option{}
+	if endpoint != "" {
+		opts = append(opts, aws.SetEKSURL(endpoint))
+	}
+
+	provider, err := newProvider(profName, config, opts...)
+	if err != nil {
+		return err
+	}
+
+	c, err := provider.DescribeCluster(cluster, region)
+	if err != nil {
+		return err
+	}
+
+	if path == "" {
+		if path, err = defaultKubeconfigPath(); err != nil {
+			return fmt.Errorf("%w: %v", ErrKubeconfig, err)
+		}
+	}
+
+	kc, err := kubeconfig.Load(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrKubeconfig, err)
+	}
+
+	args := []string{eksTokenCmd.name, "-" + FlagProfile, profName, "-" + FlagCluster, c.Name}
+	if configFile, _ := findString(FlagConfig, flags); configFile != "" {
+		if abs, err := filepath.Abs(configFile); err == nil {
+			configFile = abs
+		}
+		args = append(args, "-"+FlagConfig, configFile)
+	}
+
+	kc.Merge(kubeconfig.Entry{
+		Name:                     c.ARN,
+		Server:                   c.Endpoint,
+		CertificateAuthorityData: c.CertificateAuthority.Data,
+		Command:                  kubeconfigCommand,
+		Args:                     args,
+	})
+
+	if err := kc.Save(path); err != nil {
+		return fmt.Errorf("%w: %v", ErrKubeconfig, err)
+	}
+
+	fmt.Fprintf(stdout, "Added context %s to %s\n", c.ARN, path)
+	return nil
+}
+
+// defaultKubeconfigPath returns the first path in $KUBECONFIG or
+// ~/.kube/config when it's not set.
+func defaultKubeconfigPath() (string, error) {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		return filepath.SplitList(env)[0], nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".kube", "config"), nil
+}
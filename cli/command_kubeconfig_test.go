@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fox-tech/creds-fetcher/aws"
+	"github.com/fox-tech/creds-fetcher/kubeconfig"
+)
+
+const testCredentials = "[test]\naws_access_key_id = AWSACCESSKEYID\naws_secret_access_key = Super/Secret/AccessKey\naws_session_token = reallylongandsecretsessiontoken\n\n"
+
+// setupHome creates a temporary home directory with a configuration file for
+// the test profile and, optionally, stored credentials for it. Returns the
+// path to the configuration file.
+func setupHome(t *testing.T, withCredentials bool) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	// the file system manager changes the working directory to the home
+	wd, _ := os.Getwd()
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	config := `
+	[test]
+	aws_provider_arn = "arn:aws:iam::provider"
+	aws_role_arn  = "arn:aws:iam::123456789012:role/dev"
+	okta_client_id = "123"
+	okta_app_id = "234"
+	okta_url = "https://okta.example.com"
+	`
+	configPath := filepath.Join(home, "config.toml")
+	if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatalf("could not create config file: %v", err)
+	}
+
+	if withCredentials {
+		if err := os.MkdirAll(filepath.Join(home, ".aws"), 0700); err != nil {
+			t.Fatalf("could not create credentials dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(home, ".aws", "credentials"), []byte(testCredentials), 0600); err != nil {
+			t.Fatalf("could not create credentials file: %v", err)
+		}
+	}
+
+	return configPath
+}
+
+func Test_updateKubeconfig(t *testing.T) {
+	tests := []struct {
+		name            string
+		cluster         string
+		withCredentials bool
+		expect          error
+	}{
+		{
+			name:            "success: cluster is added to kubeconfig",
+			cluster:         "dev",
+			withCredentials: true,
+		},
+		{
+			name:            "error: cluster not found",
+			cluster:         "prod",
+			withCredentials: true,
+			expect:          aws.ErrClusterNotFound,
+		},
+		{
+			name:    "error: no stored credentials",
+			cluster: "dev",
+			expect:  aws.ErrNoCredentials,
+		},
+		{
+			name:   "error: missing cluster",
+			expect: ErrMissingFlagValue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := setupHome(t, tt.withCredentials)
+			kubeconfigPath := filepath.Join(filepath.Dir(configPath), "kube", "config")
+
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+
+				if r.URL.Path != "/clusters/dev" {
+					w.WriteHeader(http.StatusNotFound)
+					w.Write([]byte(`{"message":"No cluster found"}`))
+					return
+				}
+
+				w.Write([]byte(aws.SuccessDescribeClusterResponse))
+			}))
+			defer s.Close()
+
+			out := new(bytes.Buffer)
+			prevStdout := stdout
+			stdout = out
+			defer func() { stdout = prevStdout }()
+
+			err := updateKubeconfig(FlagMap{
+				FlagProfile:    {Name: FlagProfile, Value: "test"},
+				FlagConfig:     {Name: FlagConfig, Value: configPath},
+				FlagCluster:    {Name: FlagCluster, Value: tt.cluster},
+				FlagRegion:     {Name: FlagRegion, Value: "us-east-1"},
+				FlagEndpoint:   {Name: FlagEndpoint, Value: s.URL},
+				FlagKubeconfig: {Name: FlagKubeconfig, Value: kubeconfigPath},
+			})
+
+			if !errors.Is(err, tt.expect) {
+				t.Fatalf("updateKubeconfig() expected error: %v, got %v", tt.expect, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			kc, err := kubeconfig.Load(kubeconfigPath)
+			if err != nil {
+				t.Fatalf("updateKubeconfig() wrote an invalid kubeconfig: %v", err)
+			}
+
+			arn := "arn:aws:eks:us-east-1:123456789012:cluster/dev"
+			if kc.CurrentContext != arn || len(kc.Users) != 1 {
+				t.Fatalf("updateKubeconfig() expected context %s, got: %+v", arn, kc)
+			}
+
+			exec, _ := kc.Users[0].User["exec"].(map[string]interface{})
+			if exec["command"] != kubeconfigCommand {
+				t.Errorf("updateKubeconfig() expected exec command %s, got: %v", kubeconfigCommand, exec["command"])
+			}
+		})
+	}
+}
+
+func Test_eksToken(t *testing.T) {
+	configPath := setupHome(t, true)
+
+	out := new(bytes.Buffer)
+	prevStdout := stdout
+	stdout = out
+	defer func() { stdout = prevStdout }()
+
+	err := eksToken(FlagMap{
+		FlagProfile: {Name: FlagProfile, Value: "test"},
+		FlagConfig:  {Name: FlagConfig, Value: configPath},
+		FlagCluster: {Name: FlagCluster, Value: "dev"},
+	})
+	if err != nil {
+		t.Fatalf("eksToken() unexpected error: %v", err)
+	}
+
+	var cred execCredential
+	if err := json.Unmarshal(out.Bytes(), &cred); err != nil {
+		t.Fatalf("eksToken() expected exec credential, got: %s", out.String())
+	}
+
+	if cred.Kind != "ExecCredential" || !strings.HasPrefix(cred.Status.Token, "k8s-aws-v1.") {
+		t.Errorf("eksToken() unexpected exec credential: %+v", cred)
+	}
+}
@@ -1,68 +1,234 @@
 package cli
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"time"
 
 	"github.com/fox-tech/creds-fetcher/aws"
 	cfg "github.com/fox-tech/creds-fetcher/configuration"
-	"github.com/fox-tech/creds-fetcher/okta"
+	"github.com/fox-tech/creds-fetcher/idp"
+	"github.com/fox-tech/creds-fetcher/internal/oauth"
+	"github.com/fox-tech/creds-fetcher/qrcode"
 )
 
 var (
 	ErrNoConfig             = errors.New("failed to obtain configuration")
 	ErrAuthenticationFailed = errors.New("failed to authenticate")
+	ErrCIProfile            = errors.New("profile can only be used with ci-login")
 )
 
 var loginCmd = Command{
 	name: "login",
 	doc:  " get credentials for AWS profile",
 	f:    login,
+	flags: []flagDef{
+		{name: FlagNoBrowser, value: false, usage: "print the authentication URL without opening the browser"},
+		{name: FlagTimeout, value: time.Duration(0), usage: "maximum duration of the login, e.g. 5m, no limit by default"},
+	},
 }
 
-// login uses OKTA to authorize and get credentials from AWS STS
+// login logs in to the identity provider of the profile to get credentials
+// from AWS STS
 func login(flags FlagMap) error {
-	pf, err := findFlag(FlagProfile, flags)
+	profName, config, err := loadConfiguration(flags)
 	if err != nil {
 		return err
 	}
 
-	cf, err := findFlag(FlagConfig, flags)
+	provider, err := newProvider(profName, config)
 	if err != nil {
 		return err
 	}
 
-	profName := pf.Value.(string)
-	if profName == "" {
-		profName = defaultKey
-	}
+	// the flags are not set when login is called by other commands
+	noBrowser, _ := findBool(FlagNoBrowser, flags)
+	f, _ := findFlag(FlagTimeout, flags)
+	timeout, _ := f.Value.(time.Duration)
+
+	ctx, cancel := commandContext(timeout)
+	defer cancel()
+
+	return authenticate(ctx, config, provider, stdout, !noBrowser)
+}
 
-	configFile := cf.Value.(string)
+// authenticate logs in to the identity provider of the profile and gets
+// credentials from AWS STS for the provider profile, with the SAML assertion
+// or the web identity token of the login. The instructions of the login, if
+// any, are written to out and its URL is opened in the browser when browser is
+// true. The login stops when ctx is done, without saving credentials.
+func authenticate(ctx context.Context, config *cfg.Configuration, provider aws.Provider, out io.Writer, browser bool) error {
+	if config.ProfileType == cfg.ProfileTypeCI {
+		return ErrCIProfile
+	}
 
-	config, err := cfg.New(profName, configFile)
+	hc, err := profileHTTPClient(config)
 	if err != nil {
-		return fmt.Errorf("%w:  %v", ErrNoConfig, err)
+		return fmt.Errorf("%w: %v", ErrAuthenticationFailed, err)
 	}
 
-	provider, err := aws.New(aws.Profile{
-		Name:         profName,
-		RoleARN:      config.AWSRoleARN,
-		PrincipalARN: config.AWSProviderARN,
+	idProvider, err := idp.New(config.IdentityProvider(), config, idp.Options{
+		Profile:    provider.Profile.Name,
+		HTTPClient: hc,
+		In:         stdin,
+		Out:        out,
 	})
-
-	oktaClient, _ := okta.New(config.OktaClientID, config.OktaURL, provider, okta.SetAppID(config.OktaAppID))
-
-	dev, err := oktaClient.PreAuthorize()
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrAuthenticationFailed, err)
 	}
-	fmt.Println("Open URL and follow authentication in browser")
-	fmt.Println(dev.VerificationURIComplete)
 
-	err = oktaClient.Authorize(dev)
+	cred, err := loginIdentityProvider(ctx, idProvider, out, browser)
+	if err == nil {
+		err = generateCredentials(ctx, provider, cred)
+	}
 	if err != nil {
+		// requests stopped by ctx fail with their own errors, ctx tells why
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
+
 		return fmt.Errorf("%w: %v", ErrAuthenticationFailed, err)
 	}
 
 	return nil
 }
+
+// loginIdentityProvider runs the login of the identity provider and returns
+// its credential. Logins with a user code are shown like the device
+// authorization flow: the URL is also rendered as a QR code to approve it from
+// a phone, e.g. when working over SSH, and the user code is shown with the
+// time left until it expires.
+func loginIdentityProvider(ctx context.Context, p idp.Provider, out io.Writer, browser bool) (idp.Credential, error) {
+	login, err := p.Start(ctx)
+	if err != nil {
+		return idp.Credential{}, err
+	}
+
+	stop := func() {}
+
+	switch {
+	case login.UserCode != "":
+		fmt.Fprintln(out, "Open URL and follow authentication in browser")
+		fmt.Fprintln(out, login.URL)
+
+		// URLs too long for a QR code are only printed
+		if code, err := qrcode.Encode([]byte(login.URL), qrcode.Low); err == nil {
+			fmt.Fprintln(out)
+			fmt.Fprint(out, code)
+		}
+
+		fmt.Fprintf(out, "\nConfirm the code in the browser: %s\n\n", login.UserCode)
+
+		if browser {
+			// the URL is printed in case the browser can't be opened
+			openBrowser(login.URL)
+		}
+
+		if !login.ExpiresAt.IsZero() {
+			stop = countdown(out, login.UserCode, time.Until(login.ExpiresAt))
+		}
+	case login.URL != "":
+		fmt.Fprintln(out, "Follow authentication in browser, if it didn't open, open URL")
+		fmt.Fprintln(out, login.URL)
+
+		if browser {
+			// the URL is printed in case the browser can't be opened
+			openBrowser(login.URL)
+		}
+	}
+
+	cred, err := p.Wait(ctx, login)
+	stop()
+
+	if login.UserCode != "" {
+		err = deviceLoginError(err, login.UserCode)
+	}
+
+	return cred, err
+}
+
+// deviceLoginError explains the device authorization errors of the login of
+// the user code, the other errors are returned as they are.
+func deviceLoginError(err error, userCode string) error {
+	switch {
+	case errors.Is(err, oauth.ErrAccessDenied):
+		return fmt.Errorf("login was denied in the browser: %w", err)
+	case errors.Is(err, oauth.ErrExpiredToken), errors.Is(err, oauth.ErrDeviceAuthorizationExpired):
+		return fmt.Errorf("code %s expired before the login was approved, run login again: %w", userCode, err)
+	}
+
+	return err
+}
+
+// generateCredentials gets the credentials of the provider with the web
+// identity token of the credential, or its SAML assertion. AWS credentials
+// are saved as they are.
+func generateCredentials(ctx context.Context, provider aws.Provider, cred idp.Credential) error {
+	switch {
+	case cred.AWSCredentials != nil:
+		return provider.SaveCredentials(ctx, *cred.AWSCredentials)
+	case cred.WebIdentityToken != "":
+		return provider.GenerateCredentialsWithWebIdentity(ctx, cred.WebIdentityToken)
+	case cred.SAMLAssertion != "":
+		return provider.GenerateCredentials(ctx, cred.SAMLAssertion)
+	}
+
+	return idp.ErrNoCredential
+}
+
+// countdown writes the time left until the user code expires. In terminals it
+// is updated every second on the same line until the returned function is
+// called, otherwise it's written once.
+func countdown(out io.Writer, userCode string, expiresIn time.Duration) func() {
+	expiration := time.Now().Add(expiresIn)
+	line := func() string {
+		left := time.Until(expiration).Round(time.Second)
+		if left < 0 {
+			left = 0
+		}
+		return fmt.Sprintf("Code %s expires in %d:%02d", userCode, int(left.Minutes()), int(left.Seconds())%60)
+	}
+
+	if !isTerminal(out) {
+		fmt.Fprintln(out, line())
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			fmt.Fprintf(out, "\r%s ", line())
+			select {
+			case <-ticker.C:
+			case <-done:
+				fmt.Fprintln(out)
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
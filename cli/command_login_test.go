@@ -1,15 +1,39 @@
 package cli
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/fox-tech/creds-fetcher/aws"
+	"github.com/fox-tech/creds-fetcher/client"
+	cfg "github.com/fox-tech/creds-fetcher/configuration"
+	"github.com/fox-tech/creds-fetcher/idp"
+	"github.com/fox-tech/creds-fetcher/internal/oauth"
+	"github.com/fox-tech/creds-fetcher/internal/oktatest"
+	"github.com/fox-tech/creds-fetcher/internal/samltest"
+	"github.com/fox-tech/creds-fetcher/internal/ssotest"
+	"github.com/fox-tech/creds-fetcher/saml"
 )
 
 type testServerInput struct {
@@ -17,47 +41,51 @@ type testServerInput struct {
 	response []byte
 }
 
-func newTestServer(input map[string]testServerInput) *httptest.Server {
-	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var code int
-		var response []byte
-		switch r.RequestURI {
-		case "/oauth2/v1/device/authorize":
-			code = input["authorize"].code
-			response = input["authorize"].response
-		case "/oauth2/v1/token":
-			code = input["token"].code
-			response = input["token"].response
-		case "/login/token/sso?token=accesstoken":
-			code = input["sso"].code
-			response = input["sso"].response
-		// default is for STS response since it doesn't contain any uri
+// testOktaURL and testSTSURL are the Okta organization and the STS endpoint
+// answered by the client of newTestClient.
+const (
+	testOktaURL = "https://okta.example.com"
+	testSTSURL  = "https://sts.example.com"
+)
+
+// newTestClient returns a client answering the requests to testOktaURL and
+// testSTSURL with the responses in input, by step.
+func newTestClient(input map[string]testServerInput) client.HTTPClient {
+	return client.MockHandlerClient{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		step := ""
+		switch {
+		case "https://"+r.Host == testSTSURL:
+			step = "sts"
+		case r.URL.Path == "/.well-known/openid-configuration":
+			w.Write([]byte(`{"issuer":"` + testOktaURL + `","device_authorization_endpoint":"` + testOktaURL + `/oauth2/v1/device/authorize","token_endpoint":"` + testOktaURL + `/oauth2/v1/token"}`))
+			return
+		case r.URL.Path == "/oauth2/v1/device/authorize":
+			step = "authorize"
+		case r.URL.Path == "/oauth2/v1/token":
+			step = "token"
+		case r.URL.Path == "/login/token/sso" && r.URL.Query().Get("token") == "accesstoken":
+			step = "sso"
 		default:
-			code = input["sts"].code
-			response = input["sts"].response
+			w.WriteHeader(http.StatusNotFound)
+			return
 		}
-		w.WriteHeader(code)
-		w.Write(response)
-	}))
+
+		w.WriteHeader(input[step].code)
+		w.Write(input[step].response)
+	})}
 }
 
-func createConfigFile(config string) *os.File {
-	file, err := os.Create("./test-config.toml")
-	if err != nil {
-		log.Fatal("could not create temporary file: %w", err)
-	}
-	_, err = file.Write([]byte(config))
-	if err != nil {
-		log.Fatal("could not create temporary file: %w", err)
-	}
-	if err = file.Close(); err != nil {
-		log.Fatal("could not create temporary file: %w", err)
+// createConfigFile writes the config in a temporary directory removed after
+// the test and returns its path.
+func createConfigFile(t *testing.T, config string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test-config.toml")
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatalf("could not create config file: %v", err)
 	}
-	return file
-}
 
-func removeConfigFile(f *os.File) {
-	os.Remove(f.Name())
+	return path
 }
 
 func Test_login(t *testing.T) {
@@ -150,6 +178,26 @@ func Test_login(t *testing.T) {
 			},
 			expect: ErrAuthenticationFailed,
 		},
+		{
+			name: "error: login denied by the user",
+			args: args{
+				flags: FlagMap{
+					FlagProfile: Flag{Name: "profile", Value: "test"},
+					FlagConfig:  Flag{Name: "config", Value: "test-config.toml"},
+				},
+				responses: map[string]testServerInput{
+					"authorize": {
+						code:     http.StatusOK,
+						response: []byte(`{"device_code": "9b", "user_code": "D", "verification_uri": "activate", "verification_uri_complete": "oktap.com/activate?user_code=D", "expires_in":10,"interval": 1}`),
+					},
+					"token": {
+						code:     http.StatusBadRequest,
+						response: []byte(`{"error": "access_denied", "error_description": "The resource owner or authorization server denied the request."}`),
+					},
+				},
+			},
+			expect: ErrAuthenticationFailed,
+		},
 		{
 			name: "error: okta authorize error",
 			args: args{
@@ -183,16 +231,31 @@ func Test_login(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			s := newTestServer(tt.args.responses)
-			defer s.Close()
+			prevClient := httpClient
+			httpClient = newTestClient(tt.args.responses)
+			defer func() { httpClient = prevClient }()
+
+			configPath := createConfigFile(t, fmt.Sprintf("%sokta_url = \"%s\"\n", config, testOktaURL))
+			if f, ok := tt.args.flags[FlagConfig]; ok {
+				f.Value = configPath
+				tt.args.flags[FlagConfig] = f
+			}
+
+			// the credentials and caches are written to the home
+			t.Setenv("HOME", t.TempDir())
 
-			f := createConfigFile(fmt.Sprintf("%sokta_url = \"%s\"\n", config, s.URL))
-			defer removeConfigFile(f)
+			// the file system manager changes the working directory to the home
+			wd, _ := os.Getwd()
+			defer os.Chdir(wd)
 
 			prevURL := aws.STSURL
-			aws.STSURL = s.URL
+			aws.STSURL = testSTSURL
 			defer func() { aws.STSURL = prevURL }()
 
+			prevOpen := openBrowser
+			openBrowser = func(string) error { return nil }
+			defer func() { openBrowser = prevOpen }()
+
 			err := login(tt.args.flags)
 
 			if !errors.Is(err, tt.expect) {
@@ -201,3 +264,961 @@ func Test_login(t *testing.T) {
 		})
 	}
 }
+
+func Test_loginDevice(t *testing.T) {
+	tests := []struct {
+		name      string
+		noBrowser bool
+		opened    string
+	}{
+		{
+			name:   "verification URL opened in browser",
+			opened: "oktap.com/activate?user_code=ABCD-EFGH",
+		},
+		{
+			name:      "browser not opened with no-browser",
+			noBrowser: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := setupHome(t, false)
+
+			prevClient := httpClient
+			httpClient = newTestClient(map[string]testServerInput{
+				"authorize": {
+					code:     http.StatusOK,
+					response: []byte(`{"device_code": "9b", "user_code": "ABCD-EFGH", "verification_uri": "activate", "verification_uri_complete": "oktap.com/activate?user_code=ABCD-EFGH", "expires_in":600,"interval": 1}`),
+				},
+				"token": {
+					code:     http.StatusOK,
+					response: []byte(`{"access_token": "accesstoken"}`),
+				},
+				"sso": {
+					code:     http.StatusOK,
+					response: []byte(`<div><input name="SAMLResponse" value="token"/></div>`),
+				},
+				"sts": {
+					code:     http.StatusOK,
+					response: []byte(aws.SuccessSTSResponse),
+				},
+			})
+			defer func() { httpClient = prevClient }()
+
+			config := fmt.Sprintf(`
+			[test]
+			aws_provider_arn = "arn:aws:iam::provider"
+			aws_role_arn  = "arn:aws:iam::role"
+			okta_client_id = "123"
+			okta_app_id = "234"
+			okta_url = "%s"
+			`, testOktaURL)
+			if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+				t.Fatalf("could not create config file: %v", err)
+			}
+
+			prevURL := aws.STSURL
+			aws.STSURL = testSTSURL
+			defer func() { aws.STSURL = prevURL }()
+
+			var opened string
+			prevOpen := openBrowser
+			openBrowser = func(url string) error {
+				opened = url
+				return nil
+			}
+			defer func() { openBrowser = prevOpen }()
+
+			out := new(bytes.Buffer)
+			prevStdout := stdout
+			stdout = out
+			defer func() { stdout = prevStdout }()
+
+			err := login(FlagMap{
+				FlagProfile:   {Name: FlagProfile, Value: "test"},
+				FlagConfig:    {Name: FlagConfig, Value: configPath},
+				FlagNoBrowser: {Name: FlagNoBrowser, Value: tt.noBrowser},
+			})
+			if err != nil {
+				t.Fatalf("login() unexpected error: %v", err)
+			}
+
+			if opened != tt.opened {
+				t.Errorf("login() expected browser opened with: %q, got: %q", tt.opened, opened)
+			}
+
+			for _, expect := range []string{
+				"oktap.com/activate?user_code=ABCD-EFGH\n",
+				"Confirm the code in the browser: ABCD-EFGH",
+				"Code ABCD-EFGH expires in 10:00",
+				"█",
+			} {
+				if !strings.Contains(out.String(), expect) {
+					t.Errorf("login() expected output to contain: %q, got: %s", expect, out.String())
+				}
+			}
+		})
+	}
+}
+
+func Test_loginTimeout(t *testing.T) {
+	configPath := setupHome(t, false)
+	home := filepath.Dir(configPath)
+
+	prevClient := httpClient
+	httpClient = newTestClient(map[string]testServerInput{
+		"authorize": {
+			code:     http.StatusOK,
+			response: []byte(`{"device_code": "9b", "user_code": "D", "verification_uri": "activate", "verification_uri_complete": "oktap.com/activate?user_code=D", "expires_in":600,"interval": 1}`),
+		},
+		"token": {
+			code:     http.StatusBadRequest,
+			response: []byte(`{"error": "authorization_pending"}`),
+		},
+	})
+	defer func() { httpClient = prevClient }()
+
+	config := fmt.Sprintf(`
+	[test]
+	aws_provider_arn = "arn:aws:iam::provider"
+	aws_role_arn  = "arn:aws:iam::role"
+	okta_client_id = "123"
+	okta_app_id = "234"
+	okta_url = "%s"
+	`, testOktaURL)
+	if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatalf("could not create config file: %v", err)
+	}
+
+	prevOpen := openBrowser
+	openBrowser = func(string) error { return nil }
+	defer func() { openBrowser = prevOpen }()
+
+	prevStdout := stdout
+	stdout = new(bytes.Buffer)
+	defer func() { stdout = prevStdout }()
+
+	start := time.Now()
+	err := login(FlagMap{
+		FlagProfile: {Name: FlagProfile, Value: "test"},
+		FlagConfig:  {Name: FlagConfig, Value: configPath},
+		FlagTimeout: {Name: FlagTimeout, Value: 1500 * time.Millisecond},
+	})
+
+	if !errors.Is(err, ErrAuthenticationFailed) || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Fatalf("login() expected error: %v with deadline exceeded, got %v", ErrAuthenticationFailed, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("login() expected to stop on timeout, took: %v", elapsed)
+	}
+
+	if _, err := os.Stat(filepath.Join(home, ".aws", "credentials")); !os.IsNotExist(err) {
+		t.Errorf("login() expected no credentials file, got: %v", err)
+	}
+}
+
+func Test_loginClientCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		withKey bool
+		expect  error
+	}{
+		{
+			name:    "successful login without user interaction",
+			withKey: true,
+		},
+		{
+			name:   "error: private key not found",
+			expect: ErrAuthenticationFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := setupHome(t, false)
+			home := filepath.Dir(configPath)
+
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case !strings.HasPrefix(r.UserAgent(), "creds-fetcher/"):
+					// Okta and STS are requested with the shared client
+					w.WriteHeader(http.StatusForbidden)
+				case r.URL.Path == "/login/token/sso":
+					w.Write([]byte(`<div><input name="SAMLResponse" value="token"/></div>`))
+				case r.URL.Path == "/oauth2/v1/keys":
+					w.Write([]byte(oktatest.JWKS()))
+				case r.FormValue("grant_type") == "client_credentials" && r.FormValue("client_assertion") != "":
+					fmt.Fprintf(w, `{"access_token": "serviceaccesstoken", "id_token": %q}`, oktatest.IDToken("http://"+r.Host, "123", "", time.Now()))
+				case r.FormValue("subject_token_type") == "urn:ietf:params:oauth:token-type:id_token" && r.FormValue("actor_token") == "serviceaccesstoken" && r.FormValue("client_assertion") != "":
+					w.Write([]byte(`{"access_token": "ssotoken"}`))
+				case r.FormValue("Action") == "AssumeRoleWithSAML":
+					w.Write([]byte(aws.SuccessSTSResponse))
+				default:
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"error": "invalid_request"}`))
+				}
+			}))
+			defer s.Close()
+
+			keyPath := filepath.Join(home, "service.pem")
+			if tt.withKey {
+				key, _ := rsa.GenerateKey(rand.Reader, 2048)
+				data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+				if err := os.WriteFile(keyPath, data, 0600); err != nil {
+					t.Fatalf("could not create key file: %v", err)
+				}
+			}
+
+			config := fmt.Sprintf(`
+			[service]
+			aws_provider_arn = "arn:aws:iam::provider"
+			aws_role_arn  = "arn:aws:iam::role"
+			okta_client_id = "123"
+			okta_app_id = "234"
+			okta_url = "%s"
+			auth_flow = "client_credentials"
+			okta_private_key = "%s"
+			`, s.URL, keyPath)
+			if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+				t.Fatalf("could not create config file: %v", err)
+			}
+
+			prevURL := aws.STSURL
+			aws.STSURL = s.URL
+			defer func() { aws.STSURL = prevURL }()
+
+			err := login(FlagMap{
+				FlagProfile: {Name: FlagProfile, Value: "service"},
+				FlagConfig:  {Name: FlagConfig, Value: configPath},
+			})
+
+			if !errors.Is(err, tt.expect) {
+				t.Fatalf("login() expected error: %v, got %v", tt.expect, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			data, _ := os.ReadFile(filepath.Join(home, ".aws", "credentials"))
+			if !strings.Contains(string(data), "[service]\naws_access_key_id = AWSACCESSKEYID") {
+				t.Errorf("login() expected credentials for profile service, got: %s", data)
+			}
+		})
+	}
+}
+
+func Test_loginPKCE(t *testing.T) {
+	configPath := setupHome(t, false)
+	home := filepath.Dir(configPath)
+
+	var nonce string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/login/token/sso":
+			w.Write([]byte(`<div><input name="SAMLResponse" value="token"/></div>`))
+		case r.URL.Path == "/oauth2/v1/keys":
+			w.Write([]byte(oktatest.JWKS()))
+		case r.FormValue("grant_type") == "authorization_code" && r.FormValue("code") == "authcode":
+			fmt.Fprintf(w, `{"access_token": "accesstoken", "id_token": %q}`, oktatest.IDToken("http://"+r.Host, "123", nonce, time.Now()))
+		case r.FormValue("subject_token_type") == "urn:ietf:params:oauth:token-type:id_token":
+			w.Write([]byte(`{"access_token": "ssotoken"}`))
+		case r.FormValue("Action") == "AssumeRoleWithSAML":
+			w.Write([]byte(aws.SuccessSTSResponse))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "invalid_request"}`))
+		}
+	}))
+	defer s.Close()
+
+	config := fmt.Sprintf(`
+	[test]
+	aws_provider_arn = "arn:aws:iam::provider"
+	aws_role_arn  = "arn:aws:iam::role"
+	okta_client_id = "123"
+	okta_app_id = "234"
+	okta_url = "%s"
+	auth_flow = "pkce"
+	`, s.URL)
+	if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatalf("could not create config file: %v", err)
+	}
+
+	prevURL := aws.STSURL
+	aws.STSURL = s.URL
+	defer func() { aws.STSURL = prevURL }()
+
+	// the browser is redirected to the loopback listener once the user logs in
+	prevOpen := openBrowser
+	openBrowser = func(authURL string) error {
+		u, err := url.Parse(authURL)
+		if err != nil {
+			return err
+		}
+
+		q := u.Query()
+		nonce = q.Get("nonce")
+		resp, err := http.Get(q.Get("redirect_uri") + "?code=authcode&state=" + url.QueryEscape(q.Get("state")))
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+	defer func() { openBrowser = prevOpen }()
+
+	out := new(bytes.Buffer)
+	prevStdout := stdout
+	stdout = out
+	defer func() { stdout = prevStdout }()
+
+	err := login(FlagMap{
+		FlagProfile: {Name: FlagProfile, Value: "test"},
+		FlagConfig:  {Name: FlagConfig, Value: configPath},
+	})
+	if err != nil {
+		t.Fatalf("login() unexpected error: %v", err)
+	}
+
+	if !strings.Contains(out.String(), s.URL+"/oauth2/v1/authorize?") {
+		t.Errorf("login() expected authorization URL in output, got: %s", out.String())
+	}
+
+	data, _ := os.ReadFile(filepath.Join(home, ".aws", "credentials"))
+	if !strings.Contains(string(data), "[test]\naws_access_key_id = AWSACCESSKEYID") {
+		t.Errorf("login() expected credentials for profile test, got: %s", data)
+	}
+}
+
+func Test_loginAuthn(t *testing.T) {
+	configPath := setupHome(t, false)
+	home := filepath.Dir(configPath)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if r.Header.Get("Content-Type") == "application/json" {
+			json.NewDecoder(r.Body).Decode(&body)
+		}
+
+		switch {
+		case r.URL.Path == "/api/v1/authn" && body["username"] == "jane" && body["password"] == "secret":
+			fmt.Fprintf(w, `{"status":"MFA_REQUIRED","stateToken":"state","_embedded":{"factors":[`+
+				`{"id":"f1","factorType":"question","provider":"OKTA"},`+
+				`{"id":"f2","factorType":"push","provider":"OKTA","profile":{"name":"Pixel"},"_links":{"verify":{"href":"http://%[1]s/api/v1/authn/factors/f2/verify"}}},`+
+				`{"id":"f3","factorType":"token:software:totp","provider":"OKTA","_links":{"verify":{"href":"http://%[1]s/api/v1/authn/factors/f3/verify"}}}]}}`, r.Host)
+		case r.URL.Path == "/api/v1/authn/factors/f3/verify" && body["stateToken"] == "state" && body["passCode"] == "123456":
+			w.Write([]byte(`{"status":"SUCCESS","sessionToken":"session"}`))
+		case r.URL.Path == "/home/amazon_aws/0oa1/272" && r.FormValue("sessionToken") == "session":
+			// the app page needs the session cookie set by the embed link
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "session-cookie", Path: "/"})
+			http.Redirect(w, r, "/app/amazon_aws/exk1/sso/saml", http.StatusFound)
+		case r.URL.Path == "/app/amazon_aws/exk1/sso/saml":
+			if c, err := r.Cookie("sid"); err != nil || c.Value != "session-cookie" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			w.Write([]byte(`<div><input name="SAMLResponse" value="token"/></div>`))
+		case r.FormValue("Action") == "AssumeRoleWithSAML":
+			w.Write([]byte(aws.SuccessSTSResponse))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errorCode":"E0000004","errorSummary":"Authentication failed"}`))
+		}
+	}))
+	defer s.Close()
+
+	config := fmt.Sprintf(`
+	[test]
+	aws_provider_arn = "arn:aws:iam::provider"
+	aws_role_arn  = "arn:aws:iam::role"
+	okta_client_id = "123"
+	okta_url = "%[1]s"
+	auth_flow = "authn"
+	okta_app_url = "%[1]s/home/amazon_aws/0oa1/272"
+	`, s.URL)
+	if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatalf("could not create config file: %v", err)
+	}
+
+	prevURL := aws.STSURL
+	aws.STSURL = s.URL
+	defer func() { aws.STSURL = prevURL }()
+
+	tests := []struct {
+		name      string
+		input     string
+		expectOut string
+		expect    error
+	}{
+		{
+			name:      "successful login with password and TOTP code",
+			input:     "jane\nsecret\n3\n2\n123456\n",
+			expectOut: "Username: Password: 1) Okta Verify push to Pixel\n2) Okta Verify code\nFactor [1]: Enter a number from 1 to 2\nFactor [1]: Okta Verify code: ",
+		},
+		{
+			name:      "error: wrong password",
+			input:     "jane\nwrong\n",
+			expectOut: "Username: Password: ",
+			expect:    ErrAuthenticationFailed,
+		},
+		{
+			name:      "error: no answer to the prompts",
+			expectOut: "Username: ",
+			expect:    ErrAuthenticationFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			prevStdout, prevStdin := stdout, stdin
+			stdout, stdin = out, strings.NewReader(tt.input)
+			defer func() { stdout, stdin = prevStdout, prevStdin }()
+
+			err := login(FlagMap{
+				FlagProfile: {Name: FlagProfile, Value: "test"},
+				FlagConfig:  {Name: FlagConfig, Value: configPath},
+			})
+			if !errors.Is(err, tt.expect) {
+				t.Fatalf("login() expected error: %v, got %v", tt.expect, err)
+			}
+
+			if out.String() != tt.expectOut {
+				t.Errorf("login() expected output:\n%q\ngot:\n%q", tt.expectOut, out.String())
+			}
+
+			if err != nil {
+				return
+			}
+
+			data, _ := os.ReadFile(filepath.Join(home, ".aws", "credentials"))
+			if !strings.Contains(string(data), "[test]\naws_access_key_id = AWSACCESSKEYID") {
+				t.Errorf("login() expected credentials for profile test, got: %s", data)
+			}
+		})
+	}
+}
+
+func Test_loginSAMLLoopback(t *testing.T) {
+	configPath := setupHome(t, false)
+	home := filepath.Dir(configPath)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("Action") != "AssumeRoleWithSAML" || r.FormValue("SAMLAssertion") != samltest.Response(samltest.StatusSuccess) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Write([]byte(aws.SuccessSTSResponse))
+	}))
+	defer s.Close()
+
+	prevURL := aws.STSURL
+	aws.STSURL = s.URL
+	defer func() { aws.STSURL = prevURL }()
+
+	// the ACS listens in a free port
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not find a free port: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	config := fmt.Sprintf(`
+	[entra]
+	aws_provider_arn = "arn:aws:iam::provider"
+	aws_role_arn  = "arn:aws:iam::role"
+	auth_flow = "saml_loopback"
+	saml_login_url = "https://idp.example.com/app/aws"
+	saml_acs_port = %d
+	`, port)
+	if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatalf("could not create config file: %v", err)
+	}
+
+	// the identity provider posts the SAML response once the user logs in
+	var opened string
+	prevOpen := openBrowser
+	openBrowser = func(loginURL string) error {
+		opened = loginURL
+
+		resp, err := http.PostForm(fmt.Sprintf("http://127.0.0.1:%d%s", port, saml.ACSPath), url.Values{
+			"SAMLResponse": {samltest.Response(samltest.StatusSuccess)},
+		})
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+	defer func() { openBrowser = prevOpen }()
+
+	out := new(bytes.Buffer)
+	prevStdout := stdout
+	stdout = out
+	defer func() { stdout = prevStdout }()
+
+	err = login(FlagMap{
+		FlagProfile: {Name: FlagProfile, Value: "entra"},
+		FlagConfig:  {Name: FlagConfig, Value: configPath},
+	})
+	if err != nil {
+		t.Fatalf("login() unexpected error: %v", err)
+	}
+
+	if opened != "https://idp.example.com/app/aws" {
+		t.Errorf("login() expected login URL opened, got: %s", opened)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(home, ".aws", "credentials"))
+	if !strings.Contains(string(data), "[entra]\naws_access_key_id = AWSACCESSKEYID") {
+		t.Errorf("login() expected credentials for profile entra, got: %s", data)
+	}
+}
+
+// testIdentityProvider is an identity provider with a user code that returns
+// the SAML assertion of the test response.
+type testIdentityProvider struct{}
+
+func init() {
+	idp.Register("test", func(config *cfg.Configuration, opts idp.Options) (idp.Provider, error) {
+		return testIdentityProvider{}, nil
+	})
+}
+
+func (testIdentityProvider) Start(ctx context.Context) (idp.Login, error) {
+	return idp.Login{
+		URL:       "https://idp.example.com/activate",
+		UserCode:  "ABCD-EFGH",
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	}, nil
+}
+
+func (testIdentityProvider) Wait(ctx context.Context, login idp.Login) (idp.Credential, error) {
+	return idp.Credential{SAMLAssertion: samltest.Response(samltest.StatusSuccess)}, nil
+}
+
+func Test_loginIdentityProvider(t *testing.T) {
+	tests := []struct {
+		name      string
+		provider  string
+		expectOut []string
+		expectErr error
+	}{
+		{
+			name:     "registered provider: login shown and credentials saved",
+			provider: "test",
+			expectOut: []string{
+				"https://idp.example.com/activate",
+				"Confirm the code in the browser: ABCD-EFGH",
+			},
+		},
+		{
+			name:      "unknown provider: fails",
+			provider:  "unknown",
+			expectErr: ErrAuthenticationFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := setupHome(t, false)
+			home := filepath.Dir(configPath)
+
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.FormValue("SAMLAssertion") != samltest.Response(samltest.StatusSuccess) {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				w.Write([]byte(aws.SuccessSTSResponse))
+			}))
+			defer s.Close()
+
+			prevURL := aws.STSURL
+			aws.STSURL = s.URL
+			defer func() { aws.STSURL = prevURL }()
+
+			config := fmt.Sprintf(`
+			[custom]
+			provider = %q
+			aws_provider_arn = "arn:aws:iam::provider"
+			aws_role_arn  = "arn:aws:iam::role"
+			`, tt.provider)
+			if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+				t.Fatalf("could not create config file: %v", err)
+			}
+
+			out := new(bytes.Buffer)
+			prevStdout := stdout
+			stdout = out
+			defer func() { stdout = prevStdout }()
+
+			err := login(FlagMap{
+				FlagProfile:   {Name: FlagProfile, Value: "custom"},
+				FlagConfig:    {Name: FlagConfig, Value: configPath},
+				FlagNoBrowser: {Name: FlagNoBrowser, Value: true},
+			})
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("login() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			for _, want := range tt.expectOut {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("login() expected output to contain %q, got: %s", want, out.String())
+				}
+			}
+
+			data, _ := os.ReadFile(filepath.Join(home, ".aws", "credentials"))
+			if saved := strings.Contains(string(data), "[custom]"); saved != (tt.expectErr == nil) {
+				t.Errorf("login() expected credentials saved: %t, got: %s", tt.expectErr == nil, data)
+			}
+		})
+	}
+}
+
+func Test_loginOIDC(t *testing.T) {
+	configPath := setupHome(t, false)
+	home := filepath.Dir(configPath)
+
+	assertion := samltest.Response(samltest.StatusSuccess)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/realms/aws/.well-known/openid-configuration":
+			fmt.Fprintf(w, `{"device_authorization_endpoint":"http://%[1]s/device","token_endpoint":"http://%[1]s/token"}`, r.Host)
+		case "/device":
+			fmt.Fprintf(w, `{"device_code":"devicecode","user_code":"ABCD-EFGH","verification_uri":"http://%s/activate","expires_in":5,"interval":1}`, r.Host)
+		case "/token":
+			if r.FormValue("grant_type") == "urn:ietf:params:oauth:grant-type:device_code" {
+				w.Write([]byte(`{"access_token":"accesstoken","id_token":"idtoken"}`))
+				return
+			}
+
+			if r.FormValue("subject_token") != "accesstoken" || r.FormValue("audience") != "urn:amazon:webservices" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"invalid_request"}`))
+				return
+			}
+
+			// the SAML response is issued base64url encoded
+			data, _ := base64.StdEncoding.DecodeString(assertion)
+			fmt.Fprintf(w, `{"access_token":%q,"issued_token_type":"urn:ietf:params:oauth:token-type:saml2"}`, base64.RawURLEncoding.EncodeToString(data))
+		case "/sts":
+			if r.FormValue("Action") != "AssumeRoleWithSAML" || r.FormValue("SAMLAssertion") != assertion {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			w.Write([]byte(aws.SuccessSTSResponse))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	prevURL := aws.STSURL
+	aws.STSURL = s.URL + "/sts"
+	defer func() { aws.STSURL = prevURL }()
+
+	config := fmt.Sprintf(`
+	[keycloak]
+	provider = "oidc"
+	oidc_issuer = "%s/realms/aws"
+	oidc_client_id = "creds-fetcher"
+	oidc_audience = "urn:amazon:webservices"
+	aws_provider_arn = "arn:aws:iam::provider"
+	aws_role_arn  = "arn:aws:iam::role"
+	`, s.URL)
+	if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatalf("could not create config file: %v", err)
+	}
+
+	out := new(bytes.Buffer)
+	prevStdout := stdout
+	stdout = out
+	defer func() { stdout = prevStdout }()
+
+	err := login(FlagMap{
+		FlagProfile:   {Name: FlagProfile, Value: "keycloak"},
+		FlagConfig:    {Name: FlagConfig, Value: configPath},
+		FlagNoBrowser: {Name: FlagNoBrowser, Value: true},
+	})
+	if err != nil {
+		t.Fatalf("login() unexpected error: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "Confirm the code in the browser: ABCD-EFGH") {
+		t.Errorf("login() expected user code in output, got: %s", out.String())
+	}
+
+	data, _ := os.ReadFile(filepath.Join(home, ".aws", "credentials"))
+	if !strings.Contains(string(data), "[keycloak]\naws_access_key_id = AWSACCESSKEYID") {
+		t.Errorf("login() expected credentials for profile keycloak, got: %s", data)
+	}
+}
+
+// buildReferencePlugin builds the reference plugin in plugin/testdata/reference into a
+// temporary directory and returns the path of the command.
+func buildReferencePlugin(t *testing.T) string {
+	t.Helper()
+
+	command := filepath.Join(t.TempDir(), "reference-plugin")
+	if runtime.GOOS == "windows" {
+		command += ".exe"
+	}
+
+	cmd := exec.Command("go", "build", "-o", command, ".")
+	cmd.Dir = filepath.Join("..", "plugin", "testdata", "reference")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("could not build the reference plugin: %v: %s", err, out)
+	}
+
+	return command
+}
+
+func Test_loginPlugin(t *testing.T) {
+	command := buildReferencePlugin(t)
+
+	tests := []struct {
+		name       string
+		credential string
+		expectKey  string
+		expectSTS  bool
+		expectErr  error
+	}{
+		{
+			name:      "SAML assertion: credentials from STS",
+			expectKey: "AWSACCESSKEYID",
+			expectSTS: true,
+		},
+		{
+			name:       "AWS credentials: saved without STS",
+			credential: "aws_credentials",
+			expectKey:  "REFERENCEACCESSKEYID",
+		},
+		{
+			name:       "rejected login: fails",
+			credential: "error",
+			expectErr:  ErrAuthenticationFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REFERENCE_PLUGIN_CREDENTIAL", tt.credential)
+
+			configPath := setupHome(t, false)
+			home := filepath.Dir(configPath)
+
+			stsRequested := false
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				stsRequested = true
+				if r.FormValue("Action") != "AssumeRoleWithSAML" || r.FormValue("SAMLAssertion") == "" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				w.Write([]byte(aws.SuccessSTSResponse))
+			}))
+			defer s.Close()
+
+			prevURL := aws.STSURL
+			aws.STSURL = s.URL
+			defer func() { aws.STSURL = prevURL }()
+
+			config := fmt.Sprintf(`
+			[internal]
+			provider_command = %q
+			aws_provider_arn = "arn:aws:iam::provider"
+			aws_role_arn  = "arn:aws:iam::role"
+			`, command)
+			if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+				t.Fatalf("could not create config file: %v", err)
+			}
+
+			out := new(bytes.Buffer)
+			prevStdout := stdout
+			stdout = out
+			defer func() { stdout = prevStdout }()
+
+			errOut := new(bytes.Buffer)
+			prevStderr := stderr
+			stderr = errOut
+			defer func() { stderr = prevStderr }()
+
+			err := login(FlagMap{
+				FlagProfile:   {Name: FlagProfile, Value: "internal"},
+				FlagConfig:    {Name: FlagConfig, Value: configPath},
+				FlagNoBrowser: {Name: FlagNoBrowser, Value: true},
+			})
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("login() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if stsRequested != tt.expectSTS {
+				t.Errorf("login() expected STS requested: %t, got: %t", tt.expectSTS, stsRequested)
+			}
+
+			if !strings.Contains(out.String(), "Confirm the code in the browser: REF-1234") {
+				t.Errorf("login() expected user code of the plugin in output, got: %s", out.String())
+			}
+
+			if !strings.Contains(errOut.String(), "reference plugin: logging in profile internal") {
+				t.Errorf("login() expected standard error of the plugin, got: %s", errOut.String())
+			}
+
+			data, _ := os.ReadFile(filepath.Join(home, ".aws", "credentials"))
+			if tt.expectKey != "" && !strings.Contains(string(data), "[internal]\naws_access_key_id = "+tt.expectKey) {
+				t.Errorf("login() expected credentials %s for profile internal, got: %s", tt.expectKey, data)
+			}
+		})
+	}
+}
+
+func Test_loginSSO(t *testing.T) {
+	tests := []struct {
+		name           string
+		cachedToken    bool
+		deny           bool
+		expectRegister int
+		expectErr      error
+	}{
+		{
+			name:           "no cached token: device authorization",
+			expectRegister: 1,
+		},
+		{
+			name:        "cached token: no login",
+			cachedToken: true,
+		},
+		{
+			name:           "denied login: fails",
+			deny:           true,
+			expectRegister: 1,
+			expectErr:      ErrAuthenticationFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := setupHome(t, false)
+			home := filepath.Dir(configPath)
+
+			standIn := &ssotest.Server{AccountID: "123456789012", RoleName: "Developer", Deny: tt.deny}
+			s := httptest.NewServer(standIn)
+			defer s.Close()
+
+			config := fmt.Sprintf(`
+			[identity-center]
+			sso_start_url = "https://example.awsapps.com/start"
+			sso_region = "eu-west-1"
+			sso_account_id = "123456789012"
+			sso_role_name = "Developer"
+			sso_oidc_endpoint = %q
+			sso_portal_endpoint = %q
+			`, s.URL, s.URL)
+			if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+				t.Fatalf("could not create config file: %v", err)
+			}
+
+			if tt.cachedToken {
+				// the token of a login of the AWS CLI
+				sum := sha1.Sum([]byte("https://example.awsapps.com/start"))
+				cachePath := filepath.Join(home, ".aws", "sso", "cache", hex.EncodeToString(sum[:])+".json")
+				cache := fmt.Sprintf(`{"startUrl":"https://example.awsapps.com/start","region":"eu-west-1","accessToken":%q,"expiresAt":%q}`,
+					ssotest.AccessToken, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+				if err := os.MkdirAll(filepath.Dir(cachePath), 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(cachePath, []byte(cache), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			out := new(bytes.Buffer)
+			prevStdout := stdout
+			stdout = out
+			defer func() { stdout = prevStdout }()
+
+			err := login(FlagMap{
+				FlagProfile:   {Name: FlagProfile, Value: "identity-center"},
+				FlagConfig:    {Name: FlagConfig, Value: configPath},
+				FlagNoBrowser: {Name: FlagNoBrowser, Value: true},
+			})
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("login() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if n := standIn.Requests("/client/register"); n != tt.expectRegister {
+				t.Errorf("login() expected %d client registrations, got: %d", tt.expectRegister, n)
+			}
+
+			if shown := strings.Contains(out.String(), "Confirm the code in the browser: STAN-DIN1"); shown != (tt.expectRegister > 0) {
+				t.Errorf("login() expected user code shown: %t, got output: %s", tt.expectRegister > 0, out.String())
+			}
+
+			data, _ := os.ReadFile(filepath.Join(home, ".aws", "credentials"))
+			saved := strings.Contains(string(data), "[identity-center]\naws_access_key_id = "+ssotest.AccessKeyID)
+			if saved != (tt.expectErr == nil) {
+				t.Errorf("login() expected credentials saved: %t, got: %s", tt.expectErr == nil, data)
+			}
+
+			if saved && !strings.Contains(string(data), "x_security_token_expires = 2030-01-01T00:00:00Z") {
+				t.Errorf("login() expected expiration of the role credentials, got: %s", data)
+			}
+		})
+	}
+}
+
+func Test_deviceLoginError(t *testing.T) {
+	errOther := errors.New("other")
+
+	tests := []struct {
+		name      string
+		err       error
+		expect    string
+		expectErr error
+	}{
+		{
+			name:      "denied: explained",
+			err:       fmt.Errorf("%w: user denied", oauth.ErrAccessDenied),
+			expect:    "login was denied in the browser",
+			expectErr: oauth.ErrAccessDenied,
+		},
+		{
+			name:      "device code expired: explained with the code",
+			err:       oauth.ErrExpiredToken,
+			expect:    "code ABCD-EFGH expired",
+			expectErr: oauth.ErrExpiredToken,
+		},
+		{
+			name:      "polling expired: explained with the code",
+			err:       oauth.ErrDeviceAuthorizationExpired,
+			expect:    "code ABCD-EFGH expired",
+			expectErr: oauth.ErrDeviceAuthorizationExpired,
+		},
+		{
+			name:      "other error: unchanged",
+			err:       errOther,
+			expect:    "other",
+			expectErr: errOther,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := deviceLoginError(tt.err, "ABCD-EFGH")
+			if !errors.Is(err, tt.expectErr) || !strings.Contains(err.Error(), tt.expect) {
+				t.Errorf("deviceLoginError() expected %q wrapping %v, got: %v", tt.expect, tt.expectErr, err)
+			}
+		})
+	}
+
+	if err := deviceLoginError(nil, "ABCD-EFGH"); err != nil {
+		t.Errorf("deviceLoginError() expected no error, got: %v", err)
+	}
+}
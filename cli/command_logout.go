@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	cfg "github.com/fox-tech/creds-fetcher/configuration"
+)
+
+var (
+	ErrLogout       = errors.New("failed to log out")
+	ErrInvalidFlags = errors.New("invalid flags")
+)
+
+var logoutCmd = Command{
+	name: "logout",
+	doc:  " revoke the Okta tokens and remove the credentials of AWS profiles",
+	f:    logout,
+	flags: []flagDef{
+		{name: FlagAll, value: false, usage: "log out of every profile of the configuration file"},
+	},
+}
+
+// logout revokes the cached Okta tokens of the profile, or of every profile
+// with -all, and removes its credentials from the credentials file, reporting
+// what was removed. The profiles left are logged out when one fails.
+func logout(flags FlagMap) error {
+	all, _ := findBool(FlagAll, flags)
+	if !all {
+		profName, config, err := loadConfiguration(flags)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := commandContext(0)
+		defer cancel()
+
+		if err := logoutProfile(ctx, profName, config, stdout); err != nil {
+			return fmt.Errorf("%w: %v", ErrLogout, err)
+		}
+
+		return nil
+	}
+
+	if profName, _ := findString(FlagProfile, flags); profName != "" {
+		return fmt.Errorf("%w: -%s can't be used with -%s", ErrInvalidFlags, FlagProfile, FlagAll)
+	}
+
+	configFile, _ := findString(FlagConfig, flags)
+	configs, err := cfg.All(configFile)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNoConfig, err)
+	}
+
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ctx, cancel := commandContext(0)
+	defer cancel()
+
+	var failed []string
+	for _, name := range names {
+		if err := logoutProfile(ctx, name, configs[name], stdout); err != nil {
+			log.Printf("%s: %v", name, err)
+			failed = append(failed, name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: profiles %s", ErrLogout, strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// logoutProfile revokes the Okta tokens cached for the profile and removes
+// its credentials, writing what was removed to out. The credentials are
+// removed even when the tokens can't be revoked, which is then returned as
+// error.
+func logoutProfile(ctx context.Context, profName string, config *cfg.Configuration, out io.Writer) error {
+	hc, err := profileHTTPClient(config)
+	if err != nil {
+		return err
+	}
+
+	provider, err := storedCredentialsProvider(profName, config, hc)
+	if err != nil {
+		return err
+	}
+
+	var revokeErr error
+	removed := false
+
+	// CI profiles and profiles of other identity providers have no Okta login
+	if config.UsesOkta() {
+		var revoked []string
+
+		oktaClient, err := newOktaClient(config, profName, provider, hc)
+		if err == nil {
+			revoked, err = oktaClient.Logout(ctx)
+		}
+		revokeErr = err
+
+		for _, hint := range revoked {
+			fmt.Fprintf(out, "%s: revoked Okta %s\n", profName, strings.ReplaceAll(hint, "_", " "))
+			removed = true
+		}
+	}
+
+	found, err := provider.RemoveCredentials()
+	if err != nil {
+		return err
+	}
+
+	if found {
+		fmt.Fprintf(out, "%s: removed AWS credentials\n", profName)
+		removed = true
+	}
+
+	if !removed && revokeErr == nil {
+		fmt.Fprintf(out, "%s: already logged out\n", profName)
+	}
+
+	return revokeErr
+}
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fox-tech/creds-fetcher/okta"
+)
+
+func Test_logout(t *testing.T) {
+	var revoked []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauth2/v1/revoke" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.FormValue("token") == "invalid_token" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_request","error_description":"The token is not valid."}`))
+			return
+		}
+
+		revoked = append(revoked, r.FormValue("token"))
+	}))
+	defer srv.Close()
+
+	// profiles not managed by creds-fetcher are kept as they are
+	unmanaged := "[personal]\naws_access_key_id=PERSONAL\naws_secret_access_key=secret\n\n"
+	credentials := "[test]\naws_access_key_id = TEST\n\n" + unmanaged + "[other]\naws_access_key_id = OTHER\n\n"
+
+	tests := []struct {
+		name              string
+		profile           string
+		all               bool
+		accessToken       string
+		expectOut         string
+		expectRevoked     []string
+		expectCredentials string
+		expect            error
+	}{
+		{
+			name:              "profile: tokens are revoked and credentials removed",
+			profile:           "test",
+			accessToken:       "test_access",
+			expectOut:         "test: revoked Okta refresh token\ntest: revoked Okta access token\ntest: removed AWS credentials\n",
+			expectRevoked:     []string{"test_refresh", "test_access"},
+			expectCredentials: unmanaged + "[other]\naws_access_key_id = OTHER\n\n",
+		},
+		{
+			name:              "all: every profile is logged out",
+			all:               true,
+			accessToken:       "test_access",
+			expectOut:         "other: removed AWS credentials\ntest: revoked Okta refresh token\ntest: revoked Okta access token\ntest: removed AWS credentials\n",
+			expectRevoked:     []string{"test_refresh", "test_access"},
+			expectCredentials: unmanaged,
+		},
+		{
+			name:              "revocation error: credentials are removed",
+			profile:           "test",
+			accessToken:       "invalid_token",
+			expectOut:         "test: revoked Okta refresh token\ntest: removed AWS credentials\n",
+			expectRevoked:     []string{"test_refresh"},
+			expectCredentials: unmanaged + "[other]\naws_access_key_id = OTHER\n\n",
+			expect:            ErrLogout,
+		},
+		{
+			name:              "profile and all: error is returned",
+			profile:           "test",
+			all:               true,
+			accessToken:       "test_access",
+			expectCredentials: credentials,
+			expect:            ErrInvalidFlags,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked = nil
+
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+
+			// the file system manager changes the working directory to the home
+			wd, _ := os.Getwd()
+			t.Cleanup(func() { os.Chdir(wd) })
+
+			config := fmt.Sprintf(`
+			[test]
+			aws_provider_arn = "arn:aws:iam::provider"
+			aws_role_arn  = "arn:aws:iam::123456789012:role/dev"
+			okta_client_id = "123"
+			okta_app_id = "234"
+			okta_url = %[1]q
+
+			[other]
+			aws_provider_arn = "arn:aws:iam::provider"
+			aws_role_arn  = "arn:aws:iam::123456789012:role/other"
+			okta_client_id = "123"
+			okta_app_id = "234"
+			okta_url = %[1]q
+			`, srv.URL)
+			configPath := filepath.Join(home, "config.toml")
+			if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+				t.Fatalf("could not create config file: %v", err)
+			}
+
+			for _, dir := range []string{".aws", okta.CacheDirectory} {
+				if err := os.MkdirAll(filepath.Join(home, dir), 0700); err != nil {
+					t.Fatalf("could not create %s dir: %v", dir, err)
+				}
+			}
+
+			credentialsPath := filepath.Join(home, ".aws", "credentials")
+			if err := os.WriteFile(credentialsPath, []byte(credentials), 0600); err != nil {
+				t.Fatalf("could not create credentials file: %v", err)
+			}
+
+			tokens := fmt.Sprintf(`{"test":{"access_token":%q,"refresh_token":"test_refresh"}}`, tt.accessToken)
+			if err := os.WriteFile(filepath.Join(home, okta.CacheDirectory, okta.TokenCacheFileName), []byte(tokens), 0600); err != nil {
+				t.Fatalf("could not create token cache: %v", err)
+			}
+
+			out := new(bytes.Buffer)
+			prevStdout := stdout
+			stdout = out
+			defer func() { stdout = prevStdout }()
+
+			err := logout(FlagMap{
+				FlagProfile: {Name: FlagProfile, Value: tt.profile},
+				FlagConfig:  {Name: FlagConfig, Value: configPath},
+				FlagAll:     {Name: FlagAll, Value: tt.all},
+			})
+			if !errors.Is(err, tt.expect) {
+				t.Fatalf("logout() expected error: %v, got %v", tt.expect, err)
+			}
+
+			if out.String() != tt.expectOut {
+				t.Errorf("logout() expected output: %q, got: %q", tt.expectOut, out.String())
+			}
+
+			if fmt.Sprint(revoked) != fmt.Sprint(tt.expectRevoked) {
+				t.Errorf("logout() expected revoked tokens: %v, got: %v", tt.expectRevoked, revoked)
+			}
+
+			data, _ := os.ReadFile(credentialsPath)
+			if string(data) != tt.expectCredentials {
+				t.Errorf("logout() expected credentials file: %q, got: %q", tt.expectCredentials, data)
+			}
+		})
+	}
+}
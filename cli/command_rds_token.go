@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fox-tech/creds-fetcher/aws"
+	"github.com/fox-tech/creds-fetcher/fsmanager"
+)
+
+var (
+	ErrPgpass = errors.New("failed to update pgpass file")
+)
+
+var rdsTokenCmd = Command{
+	name: "rds-token",
+	doc:  " print an IAM authentication token for an RDS database",
+	f:    rdsToken,
+	flags: []flagDef{
+		{name: FlagHost, value: "", usage: "RDS endpoint host name"},
+		{name: FlagPort, value: 5432, usage: "RDS endpoint port"},
+		{name: FlagUser, value: "", usage: "database user name"},
+		{name: FlagRegion, value: "", usage: "AWS region of the database, defaults to the region in the host name or aws_region"},
+		{name: FlagPgpass, value: false, usage: "also write the token to $PGPASSFILE or ~/.pgpass"},
+	},
+}
+
+// rdsToken prints an RDS IAM authentication token generated from the stored
+// profile credentials and optionally writes it as a .pgpass entry.
+func rdsToken(flags FlagMap) error {
+	host, _ := findString(FlagHost, flags)
+	user, _ := findString(FlagUser, flags)
+	region, _ := findString(FlagRegion, flags)
+
+	port := 5432
+	if f, err := findFlag(FlagPort, flags); err == nil {
+		port, _ = f.Value.(int)
+	}
+
+	if host == "" || user == "" {
+		return fmt.Errorf("%w: -%s and -%s are required", ErrMissingFlagValue, FlagHost, FlagUser)
+	}
+
+	profName, config, err := loadConfiguration(flags)
+	if err != nil {
+		return err
+	}
+
+	if region == "" {
+		region = aws.RDSRegion(host)
+	}
+
+	if region == "" {
+		region = config.AWSRegion
+	}
+
+	provider, err := newProvider(profName, config)
+	if err != nil {
+		return err
+	}
+
+	token, err := provider.GenerateRDSToken(host, port, user, region)
+	if err != nil {
+		return err
+	}
+
+	if f, err := findFlag(FlagPgpass, flags); err == nil && f.Value == true {
+		path, err := pgpassPath()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrPgpass, err)
+		}
+
+		if err := writePgpass(path, host, strconv.Itoa(port), user, token); err != nil {
+			return fmt.Errorf("%w: %v", ErrPgpass, err)
+		}
+	}
+
+	fmt.Fprintln(stdout, token)
+	return nil
+}
+
+// pgpassPath returns $PGPASSFILE or ~/.pgpass when it's not set.
+func pgpassPath() (string, error) {
+	if p := os.Getenv("PGPASSFILE"); p != "" {
+		return p, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".pgpass"), nil
+}
+
+// writePgpass adds or replaces the entry for host, port and user in the
+// .pgpass file in path, matching any database. Other entries are kept as they
+// are.
+func writePgpass(path, host, port, user, password string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	prefix := strings.Join([]string{pgpassEscape(host), port, "*", pgpassEscape(user)}, ":") + ":"
+	entry := prefix + pgpassEscape(password)
+
+	var b bytes.Buffer
+	replaced := false
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, prefix) {
+			if replaced {
+				continue
+			}
+			line = entry
+			replaced = true
+		}
+		b.WriteString(line + "\n")
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	if !replaced {
+		b.WriteString(entry + "\n")
+	}
+
+	// libpq ignores the file unless only the owner can read it, WriteFile
+	// replaces it with a new 0600 file so an existing file with wider
+	// permissions is fixed and the other entries are never lost half-written
+	return fsmanager.NewDefault().WriteFile(path, b.Bytes())
+}
+
+// pgpassEscape escapes the characters with special meaning in .pgpass files.
+func pgpassEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `:`, `\:`).Replace(s)
+}
@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fox-tech/creds-fetcher/aws"
+)
+
+func Test_rdsToken(t *testing.T) {
+	host := "db.cluster-abc.us-east-1.rds.amazonaws.com"
+
+	tests := []struct {
+		name            string
+		user            string
+		pgpass          bool
+		withCredentials bool
+		expect          error
+	}{
+		{
+			name:            "success: token is printed",
+			user:            "jane",
+			withCredentials: true,
+		},
+		{
+			name:            "success: token is printed and written to pgpass",
+			user:            "jane",
+			pgpass:          true,
+			withCredentials: true,
+		},
+		{
+			name:   "error: no stored credentials",
+			user:   "jane",
+			expect: aws.ErrNoCredentials,
+		},
+		{
+			name:   "error: missing user",
+			expect: ErrMissingFlagValue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := setupHome(t, tt.withCredentials)
+			pgpass := filepath.Join(filepath.Dir(configPath), "pgpass")
+			t.Setenv("PGPASSFILE", pgpass)
+
+			existing := "other.example.com:5432:*:john:secret\n"
+			if err := os.WriteFile(pgpass, []byte(existing), 0600); err != nil {
+				t.Fatalf("could not create pgpass file: %v", err)
+			}
+
+			out := new(bytes.Buffer)
+			prevStdout := stdout
+			stdout = out
+			defer func() { stdout = prevStdout }()
+
+			err := rdsToken(FlagMap{
+				FlagProfile: {Name: FlagProfile, Value: "test"},
+				FlagConfig:  {Name: FlagConfig, Value: configPath},
+				FlagHost:    {Name: FlagHost, Value: host},
+				FlagPort:    {Name: FlagPort, Value: 5432},
+				FlagUser:    {Name: FlagUser, Value: tt.user},
+				FlagRegion:  {Name: FlagRegion, Value: ""},
+				FlagPgpass:  {Name: FlagPgpass, Value: tt.pgpass},
+			})
+
+			if !errors.Is(err, tt.expect) {
+				t.Fatalf("rdsToken() expected error: %v, got %v", tt.expect, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			token := strings.TrimSpace(out.String())
+			if !strings.HasPrefix(token, host+":5432/?Action=connect") {
+				t.Fatalf("rdsToken() unexpected token: %s", token)
+			}
+
+			data, _ := os.ReadFile(pgpass)
+			if !strings.HasPrefix(string(data), existing) {
+				t.Errorf("rdsToken() expected existing pgpass entries to be kept, got: %s", data)
+			}
+
+			written := strings.Contains(string(data), host+`:5432:*:jane:`+pgpassEscape(token))
+			if written != tt.pgpass {
+				t.Errorf("rdsToken() expected pgpass entry written: %v, got: %s", tt.pgpass, data)
+			}
+		})
+	}
+}
+
+func Test_writePgpass(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pgpass")
+	content := "db:5432:*:jane:old\nother:5432:app:john:secret\ndb:5432:*:jane:older\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("could not create pgpass file: %v", err)
+	}
+
+	if err := writePgpass(path, "db", "5432", "jane", `new:pass\word`); err != nil {
+		t.Fatalf("writePgpass() unexpected error: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	expect := "db:5432:*:jane:new\\:pass\\\\word\nother:5432:app:john:secret\n"
+	if string(data) != expect {
+		t.Errorf("writePgpass() expected: %q, got: %q", expect, data)
+	}
+}
+
+func Test_writePgpassPermissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pgpass")
+	if err := os.WriteFile(path, []byte("other:5432:app:john:secret\n"), 0644); err != nil {
+		t.Fatalf("could not create pgpass file: %v", err)
+	}
+	// the umask may have narrowed the mode of the new file
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writePgpass(path, "db", "5432", "jane", "token"); err != nil {
+		t.Fatalf("writePgpass() unexpected error: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("writePgpass() expected mode 0600, got: %v", info.Mode().Perm())
+	}
+
+	data, _ := os.ReadFile(path)
+	expect := "other:5432:app:john:secret\ndb:5432:*:jane:token\n"
+	if string(data) != expect {
+		t.Errorf("writePgpass() expected: %q, got: %q", expect, data)
+	}
+}
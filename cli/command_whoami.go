@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/aws"
+	"github.com/fox-tech/creds-fetcher/okta"
+)
+
+var whoamiCmd = Command{
+	name: "whoami",
+	doc:  " show the Okta user and the AWS identity of a profile",
+	f:    whoami,
+	flags: []flagDef{
+		{name: FlagVerify, value: false, usage: "check the identities with Okta and AWS instead of using the cached data"},
+	},
+}
+
+// whoami writes the Okta user of the last login of the profile and the AWS
+// identity of its stored credentials. They are read from the token cache and
+// the credentials file, with -verify they are requested to Okta and STS,
+// which fails when the login is not valid anymore.
+func whoami(flags FlagMap) error {
+	profName, config, err := loadConfiguration(flags)
+	if err != nil {
+		return err
+	}
+
+	verify, _ := findBool(FlagVerify, flags)
+
+	hc, err := profileHTTPClient(config)
+	if err != nil {
+		return err
+	}
+
+	provider, err := storedCredentialsProvider(profName, config, hc)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext(0)
+	defer cancel()
+
+	// CI profiles and profiles of other identity providers have no Okta user
+	if config.UsesOkta() {
+		oktaClient, err := newOktaClient(config, profName, provider, hc)
+		if err != nil {
+			return err
+		}
+
+		if err := writeOktaIdentity(ctx, stdout, oktaClient, verify); err != nil {
+			return err
+		}
+	}
+
+	var id aws.Identity
+	if verify {
+		id, err = provider.GetCallerIdentity(ctx)
+	} else {
+		id, err = provider.StoredIdentity()
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, "AWS")
+	writeField(stdout, "account", id.Account)
+	writeField(stdout, "role", id.ARN)
+	writeField(stdout, "session", id.SessionName)
+	if !id.Expiration.IsZero() {
+		writeField(stdout, "expires", expiration(id.Expiration))
+	}
+
+	return nil
+}
+
+// writeOktaIdentity writes the claims of the user of the last login, from the
+// cached ID token or, with verify, the userinfo endpoint.
+func writeOktaIdentity(ctx context.Context, out io.Writer, oktaClient okta.Client, verify bool) error {
+	var (
+		claims    okta.Claims
+		expiresAt time.Time
+		err       error
+	)
+	if verify {
+		claims, err = oktaClient.UserInfo(ctx)
+	} else {
+		claims, expiresAt, err = oktaClient.CachedClaims()
+	}
+
+	switch {
+	case errors.Is(err, okta.ErrNoCachedToken):
+		fmt.Fprintln(out, "Okta\n  not logged in")
+		return nil
+	case err != nil:
+		return err
+	}
+
+	fmt.Fprintln(out, "Okta")
+
+	name := claims.Name
+	if name == "" {
+		name = claims.PreferredUsername
+	}
+
+	writeField(out, "name", name)
+	writeField(out, "email", claims.Email)
+	writeField(out, "groups", strings.Join(claims.Groups, ", "))
+	writeField(out, "subject", claims.Subject)
+	if !expiresAt.IsZero() {
+		writeField(out, "expires", expiration(expiresAt))
+	}
+
+	return nil
+}
+
+// writeField writes the name and value aligned with the other fields, values
+// that are unknown are left out.
+func writeField(out io.Writer, name, value string) {
+	if value == "" {
+		return
+	}
+
+	fmt.Fprintf(out, "  %-8s %s\n", name+":", value)
+}
+
+// expiration returns the time t in UTC, marked when it's already past.
+func expiration(t time.Time) string {
+	s := t.UTC().Format(time.RFC3339)
+	if !time.Now().Before(t) {
+		s += " (expired)"
+	}
+
+	return s
+}
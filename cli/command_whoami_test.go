@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/aws"
+	"github.com/fox-tech/creds-fetcher/internal/oktatest"
+	"github.com/fox-tech/creds-fetcher/okta"
+)
+
+func Test_whoami(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2/v1/userinfo":
+			if r.Header.Get("Authorization") != "Bearer test_access" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			w.Write([]byte(`{"sub":"00u1","name":"Jane Doe","email":"mail@mail.com","groups":["Everyone","Developers"]}`))
+		case "/":
+			if r.FormValue("Action") != "GetCallerIdentity" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			w.Write([]byte(`<GetCallerIdentityResponse><GetCallerIdentityResult><Arn>arn:aws:sts::123456789012:assumed-role/dev/jane@mail.com</Arn><Account>123456789012</Account></GetCallerIdentityResult></GetCallerIdentityResponse>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	prevURL := aws.STSURL
+	aws.STSURL = srv.URL
+	defer func() { aws.STSURL = prevURL }()
+
+	credentials := "[test]\naws_access_key_id = AWSACCESSKEYID\naws_secret_access_key = Super/Secret/AccessKey\naws_session_token = reallylongandsecretsessiontoken\nx_security_token_expires = 2099-01-01T00:00:00Z\nx_assumed_role_arn = arn:aws:sts::123456789012:assumed-role/dev/mail@mail.com\n\n"
+	tokens := fmt.Sprintf(`{"test":{"access_token":"test_access","id_token":%q,"expires_at":"2000-01-01T00:00:00Z"}}`, oktatest.IDToken(srv.URL, "123", "", time.Now()))
+
+	tests := []struct {
+		name        string
+		verify      bool
+		credentials string
+		tokens      string
+		expectOut   string
+		expect      error
+	}{
+		{
+			name:        "cached identities",
+			credentials: credentials,
+			tokens:      tokens,
+			expectOut: "Okta\n  email:   mail@mail.com\n  subject: 00u1\n  expires: 2000-01-01T00:00:00Z (expired)\n" +
+				"AWS\n  account: 123456789012\n  role:    arn:aws:sts::123456789012:assumed-role/dev/mail@mail.com\n  session: mail@mail.com\n  expires: 2099-01-01T00:00:00Z\n",
+		},
+		{
+			name:        "verified identities",
+			verify:      true,
+			credentials: credentials,
+			tokens:      tokens,
+			expectOut: "Okta\n  name:    Jane Doe\n  email:   mail@mail.com\n  groups:  Everyone, Developers\n  subject: 00u1\n" +
+				"AWS\n  account: 123456789012\n  role:    arn:aws:sts::123456789012:assumed-role/dev/jane@mail.com\n  session: jane@mail.com\n  expires: 2099-01-01T00:00:00Z\n",
+		},
+		{
+			name:        "not logged in to Okta: AWS identity",
+			credentials: credentials,
+			tokens:      `{}`,
+			expectOut: "Okta\n  not logged in\n" +
+				"AWS\n  account: 123456789012\n  role:    arn:aws:sts::123456789012:assumed-role/dev/mail@mail.com\n  session: mail@mail.com\n  expires: 2099-01-01T00:00:00Z\n",
+		},
+		{
+			name:      "error: no stored credentials",
+			tokens:    `{}`,
+			expectOut: "Okta\n  not logged in\n",
+			expect:    aws.ErrNoCredentials,
+		},
+		{
+			name:        "error: Okta login not valid anymore",
+			verify:      true,
+			credentials: credentials,
+			tokens:      `{"test":{"access_token":"expired_access"}}`,
+			expect:      okta.ErrUserinfoRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+
+			// the file system manager changes the working directory to the home
+			wd, _ := os.Getwd()
+			t.Cleanup(func() { os.Chdir(wd) })
+
+			config := fmt.Sprintf(`
+			[test]
+			aws_provider_arn = "arn:aws:iam::provider"
+			aws_role_arn  = "arn:aws:iam::123456789012:role/dev"
+			okta_client_id = "123"
+			okta_app_id = "234"
+			okta_url = %q
+			`, srv.URL)
+			configPath := filepath.Join(home, "config.toml")
+			if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+				t.Fatalf("could not create config file: %v", err)
+			}
+
+			files := map[string]string{
+				filepath.Join(".aws", "credentials"):                        tt.credentials,
+				filepath.Join(okta.CacheDirectory, okta.TokenCacheFileName): tt.tokens,
+			}
+			for name, data := range files {
+				if err := os.MkdirAll(filepath.Join(home, filepath.Dir(name)), 0700); err != nil {
+					t.Fatalf("could not create %s dir: %v", name, err)
+				}
+				if err := os.WriteFile(filepath.Join(home, name), []byte(data), 0600); err != nil {
+					t.Fatalf("could not create %s: %v", name, err)
+				}
+			}
+
+			out := new(bytes.Buffer)
+			prevStdout := stdout
+			stdout = out
+			defer func() { stdout = prevStdout }()
+
+			err := whoami(FlagMap{
+				FlagProfile: {Name: FlagProfile, Value: "test"},
+				FlagConfig:  {Name: FlagConfig, Value: configPath},
+				FlagVerify:  {Name: FlagVerify, Value: tt.verify},
+			})
+			if !errors.Is(err, tt.expect) {
+				t.Fatalf("whoami() expected error: %v, got %v", tt.expect, err)
+			}
+
+			if out.String() != tt.expectOut {
+				t.Errorf("whoami() expected output:\n%s\ngot:\n%s", tt.expectOut, out.String())
+			}
+		})
+	}
+}
@@ -3,11 +3,31 @@ package cli
 import (
 	"flag"
 	"fmt"
+	"reflect"
+	"time"
 )
 
 const (
-	FlagProfile = "profile"
-	FlagConfig  = "config"
+	FlagProfile    = "profile"
+	FlagConfig     = "config"
+	FlagCluster    = "cluster"
+	FlagRegion     = "region"
+	FlagEndpoint   = "endpoint"
+	FlagKubeconfig = "kubeconfig"
+	FlagHost       = "host"
+	FlagPort       = "port"
+	FlagUser       = "user"
+	FlagPgpass     = "pgpass"
+	FlagTokenFile  = "token-file"
+	FlagAudience   = "audience"
+	FlagNoBrowser  = "no-browser"
+	FlagTimeout    = "timeout"
+	FlagAll        = "all"
+	FlagVerify     = "verify"
+
+	// FlagArgs holds the positional arguments left after the flags, it's
+	// only set when there are any.
+	FlagArgs = "args"
 )
 
 type Flag struct {
@@ -17,6 +37,14 @@ type Flag struct {
 
 type FlagMap map[string]Flag
 
+// flagDef defines a flag supported only by a specific command. The type of the
+// value sets the type of the flag and is used as its default value.
+type flagDef struct {
+	name  string
+	value interface{}
+	usage string
+}
+
 // ParseFlags creates a FlagSet, defines all flags currently supported for the
 // cli and parses them from the provided args. Then it creates a FlagMap and
 // assigns it to the calling cli flags
@@ -25,6 +53,21 @@ func (c *CLI) ParseFlags(name string, args []string) {
 	fs := flag.NewFlagSet(name, flag.ContinueOnError)
 	profileFlag := fs.String(FlagProfile, "", "profile to use in command")
 	configFlag := fs.String(FlagConfig, "", "path to config file")
+
+	// command specific flags
+	cmdFlags := map[string]interface{}{}
+	for _, fd := range c.commands[name].flags {
+		switch v := fd.value.(type) {
+		case string:
+			cmdFlags[fd.name] = fs.String(fd.name, v, fd.usage)
+		case bool:
+			cmdFlags[fd.name] = fs.Bool(fd.name, v, fd.usage)
+		case int:
+			cmdFlags[fd.name] = fs.Int(fd.name, v, fd.usage)
+		case time.Duration:
+			cmdFlags[fd.name] = fs.Duration(fd.name, v, fd.usage)
+		}
+	}
 	fs.Parse(args)
 
 	c.flags = FlagMap{
@@ -37,6 +80,20 @@ func (c *CLI) ParseFlags(name string, args []string) {
 			Value: *configFlag,
 		},
 	}
+
+	for n, v := range cmdFlags {
+		c.flags[n] = Flag{
+			Name:  n,
+			Value: reflect.ValueOf(v).Elem().Interface(),
+		}
+	}
+
+	if fs.NArg() > 0 {
+		c.flags[FlagArgs] = Flag{
+			Name:  FlagArgs,
+			Value: fs.Args(),
+		}
+	}
 }
 
 // findFlag searches for a specific flag inside a FlagMap. Returns an error
@@ -47,3 +104,27 @@ func findFlag(name string, flags FlagMap) (Flag, error) {
 	}
 	return Flag{}, fmt.Errorf("flag %w: %s", ErrNotFound, name)
 }
+
+// findString searches for a specific flag inside a FlagMap and returns its
+// value as a string. Returns an error if the flag was not found
+func findString(name string, flags FlagMap) (string, error) {
+	f, err := findFlag(name, flags)
+	if err != nil {
+		return "", err
+	}
+
+	s, _ := f.Value.(string)
+	return s, nil
+}
+
+// findBool searches for a specific flag inside a FlagMap and returns its value
+// as a bool. Returns an error if the flag was not found
+func findBool(name string, flags FlagMap) (bool, error) {
+	f, err := findFlag(name, flags)
+	if err != nil {
+		return false, err
+	}
+
+	b, _ := f.Value.(bool)
+	return b, nil
+}
@@ -61,6 +61,33 @@ func Test_ParseFlags(t *testing.T) {
 				FlagConfig:  {Name: FlagConfig, Value: ".aws/config"},
 			},
 		},
+		{
+			name: "parse command specific flags",
+			args: args{
+				name: "kubeconfig",
+				args: []string{"-profile", "dev1", "-cluster", "dev", "-region=us-east-1"},
+			},
+			expect: FlagMap{
+				FlagProfile:    {Name: FlagProfile, Value: "dev1"},
+				FlagConfig:     {Name: FlagConfig, Value: ""},
+				FlagCluster:    {Name: FlagCluster, Value: "dev"},
+				FlagRegion:     {Name: FlagRegion, Value: "us-east-1"},
+				FlagEndpoint:   {Name: FlagEndpoint, Value: ""},
+				FlagKubeconfig: {Name: FlagKubeconfig, Value: ""},
+			},
+		},
+		{
+			name: "parse positional arguments after flags",
+			args: args{
+				name: "test",
+				args: []string{"-profile", "dev1", "get", "extra"},
+			},
+			expect: FlagMap{
+				FlagProfile: {Name: FlagProfile, Value: "dev1"},
+				FlagConfig:  {Name: FlagConfig, Value: ""},
+				FlagArgs:    {Name: FlagArgs, Value: []string{"get", "extra"}},
+			},
+		},
 	}
 
 	for _, tt := range tests {
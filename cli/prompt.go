@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fox-tech/creds-fetcher/okta"
+	"golang.org/x/term"
+)
+
+var ErrNoInput = errors.New("no input to answer the prompt")
+
+// terminalPrompter asks the user for the values of the Okta authn login,
+// reading the answers from in and writing the prompts to out.
+type terminalPrompter struct {
+	username string
+
+	file io.Reader
+	in   *bufio.Reader
+	out  io.Writer
+}
+
+// newTerminalPrompter returns a prompter reading from in and writing to out.
+// The username is only asked when it's empty.
+func newTerminalPrompter(username string, in io.Reader, out io.Writer) *terminalPrompter {
+	return &terminalPrompter{
+		username: username,
+		file:     in,
+		in:       bufio.NewReader(in),
+		out:      out,
+	}
+}
+
+// Credentials asks for the username, unless it's configured, and the password,
+// which is not echoed when the input is a terminal.
+func (p *terminalPrompter) Credentials() (string, string, error) {
+	username := p.username
+	if username == "" {
+		var err error
+		if username, err = p.ask("Username: "); err != nil {
+			return "", "", err
+		}
+	}
+
+	fmt.Fprint(p.out, "Password: ")
+
+	if f, ok := p.file.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		password, err := term.ReadPassword(int(f.Fd()))
+		// the newline typed by the user is not echoed either
+		fmt.Fprintln(p.out)
+		return username, string(password), err
+	}
+
+	password, err := p.readLine()
+	return username, password, err
+}
+
+// ChooseFactor lists the factors and asks for the number of one of them until
+// a valid one is entered, the first one is used when the answer is empty.
+// There's no question when there's only one factor.
+func (p *terminalPrompter) ChooseFactor(factors []okta.Factor) (okta.Factor, error) {
+	if len(factors) == 1 {
+		return factors[0], nil
+	}
+
+	for i, f := range factors {
+		fmt.Fprintf(p.out, "%d) %s\n", i+1, f)
+	}
+
+	for {
+		answer, err := p.ask("Factor [1]: ")
+		if err != nil {
+			return okta.Factor{}, err
+		}
+		if answer == "" {
+			return factors[0], nil
+		}
+
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(factors) {
+			return factors[n-1], nil
+		}
+
+		fmt.Fprintf(p.out, "Enter a number from 1 to %d\n", len(factors))
+	}
+}
+
+// PassCode asks for the code of the factor.
+func (p *terminalPrompter) PassCode(factor okta.Factor) (string, error) {
+	return p.ask(factor.String() + ": ")
+}
+
+// Notify writes the message in its own line.
+func (p *terminalPrompter) Notify(msg string) {
+	fmt.Fprintln(p.out, msg)
+}
+
+// ask writes the prompt and returns the answer without surrounding spaces.
+func (p *terminalPrompter) ask(prompt string) (string, error) {
+	fmt.Fprint(p.out, prompt)
+
+	answer, err := p.readLine()
+	return strings.TrimSpace(answer), err
+}
+
+// readLine returns the next line of the input without the line break. The
+// last line can end without one.
+func (p *terminalPrompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return "", ErrNoInput
+	}
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
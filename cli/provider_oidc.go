@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	cfg "github.com/fox-tech/creds-fetcher/configuration"
+	"github.com/fox-tech/creds-fetcher/idp"
+	"github.com/fox-tech/creds-fetcher/oidc"
+)
+
+func init() {
+	idp.Register(cfg.ProviderOIDC, newOIDCProvider)
+}
+
+// oidcProvider logs in to an OpenID Connect authorization server with the
+// device authorization flow, exchanging the tokens of the login for a SAML
+// assertion or using its ID token for web identity profiles.
+type oidcProvider struct {
+	client      oidc.Client
+	webIdentity bool
+}
+
+// newOIDCProvider returns the OIDC provider for the profile configuration.
+func newOIDCProvider(config *cfg.Configuration, opts idp.Options) (idp.Provider, error) {
+	oidcOpts := []oidc.Option{
+		oidc.SetHTTPClient(opts.HTTPClient),
+		oidc.SetEndpoints(config.OIDCDeviceAuthorizationEndpoint, config.OIDCTokenEndpoint),
+		oidc.SetAudience(config.OIDCAudience),
+	}
+	if config.OIDCIssuer != "" {
+		oidcOpts = append(oidcOpts, oidc.SetIssuer(config.OIDCIssuer))
+	}
+	if config.OIDCScopes != "" {
+		oidcOpts = append(oidcOpts, oidc.SetScopes(config.OIDCScopes))
+	}
+
+	c, err := oidc.New(config.OIDCClientID, oidcOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return oidcProvider{client: c, webIdentity: config.IsWebIdentity()}, nil
+}
+
+// Start starts the device authorization and returns its verification URL,
+// the one with the user code when the server returns it.
+func (p oidcProvider) Start(ctx context.Context) (idp.Login, error) {
+	dev, err := p.client.DeviceAuthorization(ctx)
+	if err != nil {
+		return idp.Login{}, err
+	}
+
+	uri := dev.VerificationURIComplete
+	if uri == "" {
+		uri = dev.VerificationURI
+	}
+
+	return idp.Login{
+		URL:       uri,
+		UserCode:  dev.UserCode,
+		ExpiresAt: time.Now().Add(time.Duration(dev.ExpiresIn) * time.Second),
+		State:     dev,
+	}, nil
+}
+
+// Wait waits for the user to approve the login and returns its web identity
+// token or the SAML assertion it's exchanged for.
+func (p oidcProvider) Wait(ctx context.Context, login idp.Login) (idp.Credential, error) {
+	dev, ok := login.State.(oidc.Device)
+	if !ok {
+		return idp.Credential{}, idp.ErrLoginNotStarted
+	}
+
+	token, err := p.client.PollToken(ctx, dev)
+	if err != nil {
+		return idp.Credential{}, err
+	}
+
+	if p.webIdentity {
+		idToken, err := p.client.WebIdentityToken(ctx, token)
+		if err != nil {
+			return idp.Credential{}, err
+		}
+
+		return idp.Credential{WebIdentityToken: idToken}, nil
+	}
+
+	assertion, err := p.client.ExchangeSAML(ctx, token)
+	if err != nil {
+		return idp.Credential{}, err
+	}
+
+	return idp.Credential{SAMLAssertion: assertion}, nil
+}
@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/client"
+	cfg "github.com/fox-tech/creds-fetcher/configuration"
+	"github.com/fox-tech/creds-fetcher/idp"
+	"github.com/fox-tech/creds-fetcher/okta"
+)
+
+func init() {
+	idp.Register(cfg.ProviderOkta, newOktaProvider)
+}
+
+// oktaProvider logs in to Okta with the auth flow of the profile. The Okta
+// client hands the SAML assertion or ID token of the login to a recorder,
+// which keeps it for Wait instead of getting AWS credentials.
+type oktaProvider struct {
+	client   okta.Client
+	flow     string
+	port     int
+	prompter okta.AuthnPrompter
+
+	credential *idp.Credential
+}
+
+// newOktaProvider returns the Okta provider for the profile configuration.
+// Profiles with the authn flow are asked their password and MFA factor with
+// the input and output of opts.
+func newOktaProvider(config *cfg.Configuration, opts idp.Options) (idp.Provider, error) {
+	p := oktaProvider{
+		flow:       config.AuthFlow,
+		port:       config.OktaRedirectPort,
+		credential: new(idp.Credential),
+	}
+
+	if config.AuthFlow == cfg.AuthFlowAuthn {
+		p.prompter = newTerminalPrompter(config.OktaUsername, opts.In, opts.Out)
+	}
+
+	var err error
+	p.client, err = newOktaClient(config, opts.Profile, credentialRecorder{p.credential}, opts.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Start starts the device authorization flow, or the authorization code flow
+// with PKCE, which return the URL to approve the login. The client credentials
+// and authn flows have nothing to open and run in Wait.
+func (p oktaProvider) Start(ctx context.Context) (idp.Login, error) {
+	switch p.flow {
+	case cfg.AuthFlowClientCredentials, cfg.AuthFlowAuthn:
+		return idp.Login{}, nil
+	case cfg.AuthFlowPKCE:
+		auth, err := p.client.StartPKCE(ctx, p.port)
+		if err != nil {
+			return idp.Login{}, err
+		}
+
+		return idp.Login{URL: auth.URL, State: auth}, nil
+	}
+
+	dev, err := p.client.PreAuthorize(ctx)
+	if err != nil {
+		return idp.Login{}, err
+	}
+
+	return idp.Login{
+		URL:       dev.VerificationURIComplete,
+		UserCode:  dev.UserCode,
+		ExpiresAt: time.Now().Add(time.Duration(dev.ExpiresIn) * time.Second),
+		State:     dev,
+	}, nil
+}
+
+// Wait completes the login of the auth flow and returns the SAML assertion, or
+// the ID token for web identity profiles.
+func (p oktaProvider) Wait(ctx context.Context, login idp.Login) (idp.Credential, error) {
+	var err error
+
+	switch p.flow {
+	case cfg.AuthFlowClientCredentials:
+		err = p.client.AuthorizeClientCredentials(ctx)
+	case cfg.AuthFlowAuthn:
+		err = p.client.AuthorizeAuthn(ctx, p.prompter)
+	case cfg.AuthFlowPKCE:
+		auth, ok := login.State.(okta.PKCEAuthorization)
+		if !ok {
+			return idp.Credential{}, idp.ErrLoginNotStarted
+		}
+
+		err = p.client.AuthorizePKCE(ctx, auth)
+	default:
+		dev, ok := login.State.(okta.Device)
+		if !ok {
+			return idp.Credential{}, idp.ErrLoginNotStarted
+		}
+
+		err = p.client.Authorize(ctx, dev)
+	}
+	if err != nil {
+		return idp.Credential{}, err
+	}
+
+	return *p.credential, nil
+}
+
+// credentialRecorder is the okta.Provider of the Okta provider, it records
+// the credential of the login in c.
+type credentialRecorder struct {
+	c *idp.Credential
+}
+
+func (r credentialRecorder) GenerateCredentials(ctx context.Context, assertion string) error {
+	r.c.SAMLAssertion = assertion
+	return nil
+}
+
+func (r credentialRecorder) GenerateCredentialsWithWebIdentity(ctx context.Context, token string) error {
+	r.c.WebIdentityToken = token
+	return nil
+}
+
+// newOktaClient returns an Okta client for the profile configuration that
+// generates the credentials of the provider, requesting Okta with hc. The
+// tokens of its logins are cached under the profile name.
+func newOktaClient(config *cfg.Configuration, profName string, provider okta.Provider, hc client.HTTPClient) (okta.Client, error) {
+	opts := []okta.Option{
+		okta.SetAppID(config.OktaAppID),
+		okta.SetHTTPClient(hc),
+		okta.SetDiscoveryCache(),
+		okta.SetTokenCache(profName),
+	}
+	if config.OktaAuthServerID != "" {
+		opts = append(opts, okta.SetAuthServerID(config.OktaAuthServerID))
+	}
+	if config.OktaACRValues != "" {
+		opts = append(opts, okta.SetACRValues(config.OktaACRValues))
+	}
+	// the max age is valid, the configuration is validated when it's loaded
+	if d, err := time.ParseDuration(config.OktaMaxAge); err == nil {
+		opts = append(opts, okta.SetMaxAge(d))
+	}
+	if config.OktaAppURL != "" {
+		opts = append(opts, okta.SetAppURL(config.OktaAppURL))
+	}
+	if config.IsWebIdentity() {
+		opts = append(opts, okta.SetWebIdentity())
+	}
+
+	if config.AuthFlow == cfg.AuthFlowClientCredentials {
+		key, err := okta.LoadPrivateKey(config.OktaPrivateKey)
+		if err != nil {
+			return okta.Client{}, err
+		}
+
+		opts = append(opts, okta.SetPrivateKeyJWT(key, config.OktaKeyID))
+	}
+
+	return okta.New(config.OktaClientID, config.OktaURL, provider, opts...)
+}
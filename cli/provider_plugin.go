@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/aws"
+	cfg "github.com/fox-tech/creds-fetcher/configuration"
+	"github.com/fox-tech/creds-fetcher/idp"
+	"github.com/fox-tech/creds-fetcher/plugin"
+)
+
+func init() {
+	idp.Register(cfg.ProviderPlugin, newPluginProvider)
+}
+
+// pluginProvider logs in with the credential provider plugin of the profile.
+// The plugin can return AWS credentials instead of the credential of the
+// profile type.
+type pluginProvider struct {
+	command  string
+	request  plugin.LoginRequest
+	accepted []string
+	stderr   io.Writer
+}
+
+// newPluginProvider returns the plugin provider for the profile configuration.
+// The standard error of the plugin is written to stderr, also when the login
+// instructions are written to stdout.
+func newPluginProvider(config *cfg.Configuration, opts idp.Options) (idp.Provider, error) {
+	profileType := config.ProfileType
+	if profileType == "" {
+		profileType = cfg.ProfileTypeSAML
+	}
+
+	accepted := []string{plugin.CredentialSAML, plugin.CredentialAWSCredentials}
+	if config.IsWebIdentity() {
+		accepted = []string{plugin.CredentialWebIdentity, plugin.CredentialAWSCredentials}
+	}
+
+	return pluginProvider{
+		command: config.ProviderCommand,
+		request: plugin.LoginRequest{
+			Profile:        opts.Profile,
+			ProfileType:    profileType,
+			AWSRoleARN:     config.AWSRoleARN,
+			AWSProviderARN: config.AWSProviderARN,
+			AWSRegion:      config.AWSRegion,
+		},
+		accepted: accepted,
+		stderr:   stderr,
+	}, nil
+}
+
+// pluginLogin is the state of a plugin login, the running plugin and its
+// credential when it was sent without a URL to show.
+type pluginLogin struct {
+	process    *plugin.Process
+	credential *plugin.Message
+}
+
+// Start runs the plugin and returns the URL it asks to show, if any.
+func (p pluginProvider) Start(ctx context.Context) (idp.Login, error) {
+	process, err := plugin.Start(ctx, p.command, p.request, p.accepted, p.stderr)
+	if err != nil {
+		return idp.Login{}, err
+	}
+
+	msg, err := process.Next()
+	if err != nil {
+		process.Close()
+		return idp.Login{}, err
+	}
+
+	if msg.Type == plugin.TypeCredential {
+		return idp.Login{State: pluginLogin{process: process, credential: &msg}}, nil
+	}
+
+	login := idp.Login{
+		URL:      msg.URL,
+		UserCode: msg.UserCode,
+		State:    pluginLogin{process: process},
+	}
+	if msg.ExpiresIn > 0 {
+		login.ExpiresAt = time.Now().Add(time.Duration(msg.ExpiresIn) * time.Second)
+	}
+
+	return login, nil
+}
+
+// Wait waits for the credential of the plugin, which is stopped afterwards.
+func (p pluginProvider) Wait(ctx context.Context, login idp.Login) (idp.Credential, error) {
+	state, ok := login.State.(pluginLogin)
+	if !ok {
+		return idp.Credential{}, idp.ErrLoginNotStarted
+	}
+	defer state.process.Close()
+
+	msg := state.credential
+	if msg == nil {
+		next, err := state.process.Next()
+		if err != nil {
+			return idp.Credential{}, err
+		}
+
+		// the URL is already shown
+		if next.Type != plugin.TypeCredential {
+			return idp.Credential{}, fmt.Errorf("%w: unexpected %q message after the URL", plugin.ErrProtocol, next.Type)
+		}
+
+		msg = &next
+	}
+
+	cred := idp.Credential{
+		SAMLAssertion:    msg.SAMLAssertion,
+		WebIdentityToken: msg.WebIdentityToken,
+	}
+	if c := msg.AWSCredentials; c != nil {
+		cred.AWSCredentials = &aws.Credentials{
+			AccessKeyID:     c.AccessKeyID,
+			SecretAccessKey: c.SecretAccessKey,
+			SessionToken:    c.SessionToken,
+			Expiration:      c.Expiration,
+		}
+	}
+
+	return cred, nil
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+
+	cfg "github.com/fox-tech/creds-fetcher/configuration"
+	"github.com/fox-tech/creds-fetcher/idp"
+	"github.com/fox-tech/creds-fetcher/saml"
+)
+
+func init() {
+	idp.Register(cfg.ProviderSAML, newSAMLProvider)
+}
+
+// samlProvider logs in to a SAML identity provider in the browser, which posts
+// the SAML response of the login to a loopback ACS.
+type samlProvider struct {
+	loginURL string
+	ssoURL   string
+	port     int
+}
+
+// newSAMLProvider returns the SAML provider for the profile configuration.
+func newSAMLProvider(config *cfg.Configuration, opts idp.Options) (idp.Provider, error) {
+	port := config.SAMLACSPort
+	if port == 0 {
+		port = saml.DefaultACSPort
+	}
+
+	return samlProvider{
+		loginURL: config.SAMLLoginURL,
+		ssoURL:   config.SAMLSSOURL,
+		port:     port,
+	}, nil
+}
+
+// Start starts the loopback ACS and returns the URL of the login, SP-initiated
+// when the profile has an SSO URL.
+func (p samlProvider) Start(ctx context.Context) (idp.Login, error) {
+	var (
+		login saml.Login
+		err   error
+	)
+	if p.ssoURL != "" {
+		login, err = saml.StartSPInitiated(p.ssoURL, p.port)
+	} else {
+		login, err = saml.Start(p.loginURL, p.port)
+	}
+	if err != nil {
+		return idp.Login{}, err
+	}
+
+	return idp.Login{URL: login.URL, State: login}, nil
+}
+
+// Wait returns the SAML assertion posted to the loopback ACS.
+func (p samlProvider) Wait(ctx context.Context, login idp.Login) (idp.Credential, error) {
+	l, ok := login.State.(saml.Login)
+	if !ok {
+		return idp.Credential{}, idp.ErrLoginNotStarted
+	}
+
+	assertion, err := l.Wait(ctx)
+	if err != nil {
+		return idp.Credential{}, err
+	}
+
+	return idp.Credential{SAMLAssertion: assertion}, nil
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/aws"
+	cfg "github.com/fox-tech/creds-fetcher/configuration"
+	"github.com/fox-tech/creds-fetcher/idp"
+	"github.com/fox-tech/creds-fetcher/sso"
+)
+
+func init() {
+	idp.Register(cfg.ProviderSSO, newSSOProvider)
+}
+
+// ssoProvider gets the credentials of the role of the profile from AWS IAM
+// Identity Center. Users log in with the device authorization flow, unless
+// the cached token of a previous login, also one of the AWS CLI, is valid.
+type ssoProvider struct {
+	client    sso.Client
+	accountID string
+	roleName  string
+}
+
+// newSSOProvider returns the IAM Identity Center provider for the profile
+// configuration.
+func newSSOProvider(config *cfg.Configuration, opts idp.Options) (idp.Provider, error) {
+	c, err := sso.New(config.SSOStartURL, config.SSORegion,
+		sso.SetEndpoints(config.SSOOIDCEndpoint, config.SSOPortalEndpoint),
+		sso.SetHTTPClient(opts.HTTPClient),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ssoProvider{
+		client:    c,
+		accountID: config.SSOAccountID,
+		roleName:  config.SSORoleName,
+	}, nil
+}
+
+// ssoLogin is the state of a login, the device authorization to wait for or
+// the credentials got with the cached token.
+type ssoLogin struct {
+	device      sso.Device
+	credentials *sso.RoleCredentials
+}
+
+// Start gets the credentials with the cached token, with no user interaction,
+// or starts the device authorization when there's none or it's rejected, and
+// returns its verification URL.
+func (p ssoProvider) Start(ctx context.Context) (idp.Login, error) {
+	if token, ok := p.client.CachedToken(); ok {
+		cred, err := p.client.GetRoleCredentials(ctx, token, p.accountID, p.roleName)
+		if err == nil {
+			return idp.Login{State: ssoLogin{credentials: &cred}}, nil
+		}
+
+		// tokens are rejected before they expire after logging out of the
+		// portal, the user logs in again
+		if !errors.Is(err, sso.ErrUnauthorized) {
+			return idp.Login{}, err
+		}
+	}
+
+	dev, err := p.client.StartDeviceAuthorization(ctx)
+	if err != nil {
+		return idp.Login{}, err
+	}
+
+	uri := dev.VerificationURIComplete
+	if uri == "" {
+		uri = dev.VerificationURI
+	}
+
+	return idp.Login{
+		URL:       uri,
+		UserCode:  dev.UserCode,
+		ExpiresAt: time.Now().Add(time.Duration(dev.ExpiresIn) * time.Second),
+		State:     ssoLogin{device: dev},
+	}, nil
+}
+
+// Wait waits for the user to approve the login and returns the credentials of
+// the role, which are saved without requesting STS.
+func (p ssoProvider) Wait(ctx context.Context, login idp.Login) (idp.Credential, error) {
+	state, ok := login.State.(ssoLogin)
+	if !ok {
+		return idp.Credential{}, idp.ErrLoginNotStarted
+	}
+
+	cred := state.credentials
+	if cred == nil {
+		token, err := p.client.PollToken(ctx, state.device)
+		if err != nil {
+			return idp.Credential{}, err
+		}
+
+		c, err := p.client.GetRoleCredentials(ctx, token, p.accountID, p.roleName)
+		if err != nil {
+			return idp.Credential{}, err
+		}
+
+		cred = &c
+	}
+
+	return idp.Credential{
+		AWSCredentials: &aws.Credentials{
+			AccessKeyID:     cred.AccessKeyID,
+			SecretAccessKey: cred.SecretAccessKey,
+			SessionToken:    cred.SessionToken,
+			Expiration:      cred.ExpiresAt().Format(time.RFC3339),
+		},
+	}, nil
+}
@@ -7,23 +7,147 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
-type defaultClient struct {
-	cl *http.Client
-}
+// defaultTimeout is the time limit of a request, including reading the body.
+const defaultTimeout = 30 * time.Second
 
 var (
 	ErrInvalidBody = errors.New("invalid body for request")
+
+	// Version is sent in the User-Agent header, it's set when building with
+	// -ldflags "-X github.com/fox-tech/creds-fetcher/client.Version=v1.0.0".
+	Version = "dev"
 )
 
-func NewDefault() defaultClient {
-	return defaultClient{
-		cl: &http.Client{},
+// HTTPClient defines the methods of the clients of this package, so the same
+// client can be shared by the packages making requests.
+type HTTPClient interface {
+	Get(r_url string, params map[string]string, body io.Reader) (*http.Response, error)
+	Post(r_url string, params map[string]string, headers map[string]string, body interface{}) (*http.Response, error)
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type defaultClient struct {
+	cl      *http.Client
+	headers http.Header
+	retry   RetryPolicy
+}
+
+// Option represents an optional configuration value passed to New to change
+// the default value set during initialization.
+type Option func(*defaultClient)
+
+// SetTimeout returns a function to change the time limit of the requests, zero
+// means no limit.
+func SetTimeout(d time.Duration) Option {
+	return func(c *defaultClient) {
+		c.cl.Timeout = d
 	}
 }
 
+// SetHeader returns a function to add a header sent in all the requests that
+// don't set it already.
+func SetHeader(name, value string) Option {
+	return func(c *defaultClient) {
+		c.headers.Set(name, value)
+	}
+}
+
+// SetCookieJar returns a function to keep the cookies set by the servers in
+// jar and send them in the next requests, including the redirects of a
+// request. Cookies are not kept by default.
+func SetCookieJar(jar http.CookieJar) Option {
+	return func(c *defaultClient) {
+		c.cl.Jar = jar
+	}
+}
+
+// New returns a client with the given options. By default requests time out
+// after 30 seconds, are retried with DefaultRetryPolicy, use the proxy set in
+// the environment and send creds-fetcher/Version as User-Agent. Connections
+// are reused by all copies of the client.
+func New(opts ...Option) defaultClient {
+	c := defaultClient{
+		cl: &http.Client{
+			Timeout:   defaultTimeout,
+			Transport: http.DefaultTransport.(*http.Transport).Clone(),
+		},
+		headers: http.Header{
+			"User-Agent": []string{"creds-fetcher/" + Version},
+		},
+		retry: DefaultRetryPolicy,
+	}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return c
+}
+
+// NewDefault returns a client with the default options of New.
+func NewDefault() defaultClient {
+	return New()
+}
+
 func (c defaultClient) Get(getUrl string, params map[string]string, body io.Reader) (*http.Response, error) {
+	req, err := newGetRequest(getUrl, params, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Do(req)
+}
+
+func (c defaultClient) Post(postUrl string, params map[string]string, headers map[string]string, body interface{}) (*http.Response, error) {
+	req, err := newPostRequest(postUrl, params, headers, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Do(req)
+}
+
+// Do sends an already built request. It is used by callers that need full
+// control over the request, e.g. to sign it before it is sent. The request is
+// retried following the retry policy of the client.
+func (c defaultClient) Do(req *http.Request) (*http.Response, error) {
+	for name, values := range c.headers {
+		if req.Header.Get(name) == "" {
+			req.Header[name] = values
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.cl.Do(req)
+
+		delay, ok := c.retry.retryDelay(req, resp, err, attempt)
+		if !ok {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+
+		req = req.Clone(req.Context())
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// newGetRequest returns a GET request to getUrl with the params as query.
+func newGetRequest(getUrl string, params map[string]string, body io.Reader) (*http.Request, error) {
 	req, err := http.NewRequest(http.MethodGet, getUrl, body)
 	if err != nil {
 		return nil, err
@@ -35,10 +159,13 @@ func (c defaultClient) Get(getUrl string, params map[string]string, body io.Read
 	}
 	req.URL.RawQuery = q.Encode()
 
-	return c.cl.Do(req)
+	return req, nil
 }
 
-func (c defaultClient) Post(postUrl string, params map[string]string, headers map[string]string, body interface{}) (*http.Response, error) {
+// newPostRequest returns a POST request to postUrl with the params as query.
+// The body is sent URL-encoded if it's a map[string]string or as is if it's
+// an io.Reader.
+func newPostRequest(postUrl string, params map[string]string, headers map[string]string, body interface{}) (*http.Request, error) {
 	var reqBody io.Reader
 	switch body.(type) {
 	//x-www-form-urlencoded values
@@ -70,5 +197,5 @@ func (c defaultClient) Post(postUrl string, params map[string]string, headers ma
 		req.Header.Add(k, v)
 	}
 
-	return c.cl.Do(req)
+	return req, nil
 }
@@ -0,0 +1,171 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+
+		io.WriteString(w, r.Header.Get("User-Agent")+"|"+r.Header.Get("X-Team"))
+	}))
+	defer s.Close()
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		path    string
+		headers map[string]string
+		expect  string
+		timeout bool
+	}{
+		{
+			name:   "default: creds-fetcher user agent is sent",
+			path:   "/",
+			expect: "creds-fetcher/" + Version + "|",
+		},
+		{
+			name:   "custom header: header is added",
+			opts:   []Option{SetHeader("X-Team", "platform")},
+			path:   "/",
+			expect: "creds-fetcher/" + Version + "|platform",
+		},
+		{
+			name:    "request header: header is not replaced",
+			path:    "/",
+			headers: map[string]string{"User-Agent": "other/1.0"},
+			expect:  "other/1.0|",
+		},
+		{
+			name:    "timeout: slow request fails",
+			opts:    []Option{SetTimeout(50 * time.Millisecond), SetRetryPolicy(RetryPolicy{MaxAttempts: 1})},
+			path:    "/slow",
+			timeout: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(tt.opts...)
+
+			req, _ := http.NewRequest(http.MethodGet, s.URL+tt.path, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			resp, err := c.Do(req)
+			if tt.timeout {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatalf("Do() expected timeout error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Do() unexpected error: %v", err)
+			}
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.expect {
+				t.Errorf("Do() expected headers: %s, got: %s", tt.expect, body)
+			}
+		})
+	}
+}
+
+func TestMockHandlerClient(t *testing.T) {
+	c := MockHandlerClient{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("Action") != "AssumeRole" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		io.WriteString(w, r.URL.Path)
+	})}
+
+	resp, err := c.Post("https://sts.amazonaws.com/path", nil, map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, map[string]string{"Action": "AssumeRole"})
+	if err != nil {
+		t.Fatalf("Post() unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "/path" {
+		t.Errorf("Post() expected handler response, got: %d %s", resp.StatusCode, body)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://sts.amazonaws.com/", nil)
+	if _, err := c.Do(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() expected error: %v, got: %v", context.Canceled, err)
+	}
+}
+
+func TestSetCookieJar(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "session"})
+			http.Redirect(w, r, "/app", http.StatusFound)
+			return
+		}
+
+		if c, err := r.Cookie("sid"); err == nil {
+			io.WriteString(w, c.Value)
+		}
+	}))
+	defer s.Close()
+
+	tests := []struct {
+		name   string
+		opts   []Option
+		expect string
+	}{
+		{
+			name: "default: cookies are not kept",
+		},
+		{
+			name:   "cookie jar: cookie is sent in the redirect",
+			opts:   []Option{SetCookieJar(newTestJar(t))},
+			expect: "session",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, s.URL+"/login", nil)
+
+			resp, err := New(tt.opts...).Do(req)
+			if err != nil {
+				t.Fatalf("Do() unexpected error: %v", err)
+			}
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.expect {
+				t.Errorf("Do() expected cookie: %q, got: %q", tt.expect, body)
+			}
+		})
+	}
+}
+
+func newTestJar(t *testing.T) http.CookieJar {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("could not create cookie jar: %v", err)
+	}
+
+	return jar
+}
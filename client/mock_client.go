@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 )
 
 type MockHttpClient struct {
@@ -39,3 +40,50 @@ func (m MockHttpClient) Post(postUrl string, params map[string]string, headers m
 		},
 	}, m.PostErr
 }
+
+// Do returns the Get or Post mocked response depending on the method of the
+// passed request.
+func (m MockHttpClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodGet {
+		return m.Get(req.URL.String(), nil, nil)
+	}
+
+	return m.Post(req.URL.String(), nil, nil, nil)
+}
+
+// MockHandlerClient sends the requests to Handler instead of the network, so
+// tests can answer them by path or form values without starting a server.
+type MockHandlerClient struct {
+	Handler http.Handler
+}
+
+func (m MockHandlerClient) Get(getUrl string, params map[string]string, body io.Reader) (*http.Response, error) {
+	req, err := newGetRequest(getUrl, params, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Do(req)
+}
+
+func (m MockHandlerClient) Post(postUrl string, params map[string]string, headers map[string]string, body interface{}) (*http.Response, error) {
+	req, err := newPostRequest(postUrl, params, headers, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Do(req)
+}
+
+// Do returns the response written by Handler for the request, or the error of
+// the request context if it's done.
+func (m MockHandlerClient) Do(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler.ServeHTTP(rec, req)
+
+	return rec.Result(), nil
+}
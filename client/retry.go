@@ -0,0 +1,192 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxThrottlingBody is how much of an error response is read to find an STS
+// throttling code.
+const maxThrottlingBody = 64 << 10
+
+var (
+	// DefaultRetryPolicy is the policy of the clients returned by New.
+	DefaultRetryPolicy = RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    20 * time.Second,
+	}
+
+	// throttlingCodes are the error codes of STS responses rejected by rate
+	// limits.
+	throttlingCodes = [][]byte{
+		[]byte("<Code>Throttling</Code>"),
+		[]byte("<Code>RequestLimitExceeded</Code>"),
+	}
+)
+
+// RetryPolicy defines how failed requests are retried. Rate limited requests,
+// with status 429 or an STS Throttling or RequestLimitExceeded code, are
+// rejected before being processed and are always retried. Network errors and
+// 5xx responses are only retried for idempotent requests, see SetIdempotent.
+//
+// The delay is the one asked by the server in Retry-After or Okta's
+// X-Rate-Limit-Reset headers, and otherwise BaseDelay doubled on each retry
+// with jitter. Requests aren't retried when the delay exceeds MaxDelay.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first one, 1
+	// disables retries
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// SetRetryPolicy returns a function to change the retry policy of the client.
+func SetRetryPolicy(p RetryPolicy) Option {
+	return func(c *defaultClient) {
+		c.retry = p
+	}
+}
+
+// SetIdempotent marks a request with a method that is not idempotent, e.g. a
+// POST, as safe to retry after network errors and 5xx responses. As done by
+// net/http, a nil Idempotency-Key header is set, which isn't sent. Signed
+// requests must not be marked, the header would be signed.
+func SetIdempotent(req *http.Request) {
+	req.Header["Idempotency-Key"] = nil
+}
+
+// isIdempotent reports whether the request can be sent more than once.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	if _, ok := req.Header["Idempotency-Key"]; ok {
+		return true
+	}
+
+	_, ok := req.Header["X-Idempotency-Key"]
+	return ok
+}
+
+// retryDelay returns how long to wait before sending again a request that
+// got resp or err in the given attempt, or false if it must not be retried.
+func (p RetryPolicy) retryDelay(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts || req.Context().Err() != nil {
+		return 0, false
+	}
+
+	// the body can only be sent again if it can be recreated
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return 0, false
+	}
+
+	if err != nil {
+		return p.backoff(attempt), isIdempotent(req)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests, isThrottling(resp):
+	case resp.StatusCode >= http.StatusInternalServerError && isIdempotent(req):
+	default:
+		return 0, false
+	}
+
+	if d, ok := serverDelay(resp); ok {
+		return d, d <= p.MaxDelay
+	}
+
+	return p.backoff(attempt), true
+}
+
+// backoff returns the exponential delay of the attempt, between half and the
+// full delay to spread retries of concurrent clients.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	if d <= 0 {
+		return 0
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// serverDelay returns the delay asked by the server in the Retry-After header,
+// in seconds or as a date, or the time left until the X-Rate-Limit-Reset epoch
+// of Okta rate limits.
+//
+// More at https://developer.okta.com/docs/reference/rl-best-practices/.
+func serverDelay(resp *http.Response) (time.Duration, bool) {
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+
+		if t, err := http.ParseTime(v); err == nil {
+			return nonNegative(time.Until(t)), true
+		}
+	}
+
+	if v := resp.Header.Get("X-Rate-Limit-Reset"); v != "" && resp.StatusCode == http.StatusTooManyRequests {
+		if epoch, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return nonNegative(time.Until(time.Unix(epoch, 0))), true
+		}
+	}
+
+	return 0, false
+}
+
+// isThrottling reports whether resp is an STS error response for a rate
+// limit. The read part of the body is put back for the caller.
+func isThrottling(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusBadRequest || resp.Body == nil {
+		return false
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxThrottlingBody))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+
+	for _, code := range throttlingCodes {
+		if bytes.Contains(body, code) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sleep waits for d or until ctx is done, returning its error.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
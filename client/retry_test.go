@@ -0,0 +1,252 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoRetry(t *testing.T) {
+	fastPolicy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+	tests := []struct {
+		name       string
+		policy     RetryPolicy
+		method     string
+		idempotent bool
+		// failures is the number of requests answered with status and headers
+		// before a 200
+		failures       int
+		status         int
+		headers        map[string]string
+		body           string
+		expectStatus   int
+		expectAttempts int32
+	}{
+		{
+			name:           "success: request is sent once",
+			policy:         fastPolicy,
+			method:         http.MethodGet,
+			expectStatus:   http.StatusOK,
+			expectAttempts: 1,
+		},
+		{
+			name:           "5xx GET: request is retried",
+			policy:         fastPolicy,
+			method:         http.MethodGet,
+			failures:       2,
+			status:         http.StatusServiceUnavailable,
+			expectStatus:   http.StatusOK,
+			expectAttempts: 3,
+		},
+		{
+			name:           "5xx GET: last error is returned after max attempts",
+			policy:         fastPolicy,
+			method:         http.MethodGet,
+			failures:       3,
+			status:         http.StatusBadGateway,
+			expectStatus:   http.StatusBadGateway,
+			expectAttempts: 3,
+		},
+		{
+			name:           "5xx POST: request is not retried",
+			policy:         fastPolicy,
+			method:         http.MethodPost,
+			failures:       1,
+			status:         http.StatusInternalServerError,
+			expectStatus:   http.StatusInternalServerError,
+			expectAttempts: 1,
+		},
+		{
+			name:           "5xx idempotent POST: request is retried",
+			policy:         fastPolicy,
+			method:         http.MethodPost,
+			idempotent:     true,
+			failures:       1,
+			status:         http.StatusInternalServerError,
+			expectStatus:   http.StatusOK,
+			expectAttempts: 2,
+		},
+		{
+			name:           "429 POST: rate limited request is retried",
+			policy:         fastPolicy,
+			method:         http.MethodPost,
+			failures:       1,
+			status:         http.StatusTooManyRequests,
+			headers:        map[string]string{"Retry-After": "0"},
+			expectStatus:   http.StatusOK,
+			expectAttempts: 2,
+		},
+		{
+			name:           "429 Okta rate limit: request is retried after the reset",
+			policy:         fastPolicy,
+			method:         http.MethodPost,
+			failures:       1,
+			status:         http.StatusTooManyRequests,
+			headers:        map[string]string{"X-Rate-Limit-Reset": strconv.FormatInt(time.Now().Unix(), 10)},
+			expectStatus:   http.StatusOK,
+			expectAttempts: 2,
+		},
+		{
+			name:           "429: request is not retried when the delay exceeds the max",
+			policy:         fastPolicy,
+			method:         http.MethodGet,
+			failures:       1,
+			status:         http.StatusTooManyRequests,
+			headers:        map[string]string{"Retry-After": "60"},
+			expectStatus:   http.StatusTooManyRequests,
+			expectAttempts: 1,
+		},
+		{
+			name:           "STS throttling: request is retried",
+			policy:         fastPolicy,
+			method:         http.MethodPost,
+			failures:       1,
+			status:         http.StatusBadRequest,
+			body:           "<ErrorResponse><Error><Code>Throttling</Code><Message>Rate exceeded</Message></Error></ErrorResponse>",
+			expectStatus:   http.StatusOK,
+			expectAttempts: 2,
+		},
+		{
+			name:           "STS request limit: request is retried",
+			policy:         fastPolicy,
+			method:         http.MethodPost,
+			failures:       1,
+			status:         http.StatusBadRequest,
+			body:           "<ErrorResponse><Error><Code>RequestLimitExceeded</Code></Error></ErrorResponse>",
+			expectStatus:   http.StatusOK,
+			expectAttempts: 2,
+		},
+		{
+			name:           "400: request is not retried",
+			policy:         fastPolicy,
+			method:         http.MethodPost,
+			failures:       1,
+			status:         http.StatusBadRequest,
+			body:           "<ErrorResponse><Error><Code>ValidationError</Code></Error></ErrorResponse>",
+			expectStatus:   http.StatusBadRequest,
+			expectAttempts: 1,
+		},
+		{
+			name:           "retries disabled: request is sent once",
+			policy:         RetryPolicy{MaxAttempts: 1},
+			method:         http.MethodGet,
+			failures:       1,
+			status:         http.StatusServiceUnavailable,
+			expectStatus:   http.StatusServiceUnavailable,
+			expectAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&attempts, 1)
+
+				// the body must be sent again in every attempt
+				if body, _ := io.ReadAll(r.Body); r.Method == http.MethodPost && string(body) != "a=b" {
+					t.Errorf("Do() expected body: a=b, got: %s", body)
+				}
+
+				if int(n) <= tt.failures {
+					for k, v := range tt.headers {
+						w.Header().Set(k, v)
+					}
+					w.WriteHeader(tt.status)
+					io.WriteString(w, tt.body)
+					return
+				}
+
+				io.WriteString(w, "ok")
+			}))
+			defer s.Close()
+
+			c := New(SetRetryPolicy(tt.policy))
+
+			req, _ := http.NewRequest(tt.method, s.URL, nil)
+			if tt.method == http.MethodPost {
+				req, _ = http.NewRequest(tt.method, s.URL, strings.NewReader("a=b"))
+			}
+			if tt.idempotent {
+				SetIdempotent(req)
+			}
+
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatalf("Do() unexpected error: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.expectStatus {
+				t.Errorf("Do() expected status: %d, got: %d", tt.expectStatus, resp.StatusCode)
+			}
+
+			if attempts != tt.expectAttempts {
+				t.Errorf("Do() expected attempts: %d, got: %d", tt.expectAttempts, attempts)
+			}
+
+			// the body read to find the throttling code is still returned
+			if body, _ := io.ReadAll(resp.Body); resp.StatusCode != http.StatusOK && string(body) != tt.body {
+				t.Errorf("Do() expected body: %s, got: %s", tt.body, body)
+			}
+		})
+	}
+}
+
+func TestDoRetryCancel(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	c := New(SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Minute}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+
+	start := time.Now()
+	_, err := c.Do(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do() expected error: %v, got: %v", context.DeadlineExceeded, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Do() expected to stop waiting when the context is done, took: %v", elapsed)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := []struct {
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{attempt: 1, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{attempt: 2, min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+		{attempt: 3, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{attempt: 5, min: 500 * time.Millisecond, max: time.Second},
+		{attempt: 70, min: 500 * time.Millisecond, max: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.attempt), func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				if d := p.backoff(tt.attempt); d < tt.min || d > tt.max {
+					t.Fatalf("backoff() expected delay between %v and %v, got: %v", tt.min, tt.max, d)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+var (
+	ErrInvalidCABundle          = errors.New("invalid CA bundle")
+	ErrInvalidClientCertificate = errors.New("invalid client certificate")
+)
+
+// SetProxy returns a function to change the proxy of the HTTPS requests and
+// the hosts that don't use it, with the format of the HTTPS_PROXY and NO_PROXY
+// environment variables. Empty values keep the ones set in the environment.
+func SetProxy(httpsProxy, noProxy string) Option {
+	return func(c *defaultClient) {
+		cfg := httpproxy.FromEnvironment()
+		if httpsProxy != "" {
+			cfg.HTTPSProxy = httpsProxy
+		}
+		if noProxy != "" {
+			cfg.NoProxy = noProxy
+		}
+
+		proxy := cfg.ProxyFunc()
+		c.transport().Proxy = func(r *http.Request) (*url.URL, error) {
+			return proxy(r.URL)
+		}
+	}
+}
+
+// SetRootCAs returns a function to change the certificate authorities used to
+// verify the servers, see LoadCABundle.
+func SetRootCAs(pool *x509.CertPool) Option {
+	return func(c *defaultClient) {
+		c.tlsConfig().RootCAs = pool
+	}
+}
+
+// SetClientCertificate returns a function to send cert to the servers asking
+// for a client certificate, see LoadClientCertificate.
+func SetClientCertificate(cert tls.Certificate) Option {
+	return func(c *defaultClient) {
+		c.tlsConfig().Certificates = []tls.Certificate{cert}
+	}
+}
+
+// LoadCABundle returns the system certificate pool with the certificates of
+// the PEM file in path appended, e.g. the CA of a TLS-intercepting proxy.
+func LoadCABundle(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCABundle, err)
+	}
+
+	// the system pool is not available on some platforms, the bundle is
+	// used alone then
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%w: no PEM certificate found in %s", ErrInvalidCABundle, path)
+	}
+
+	return pool, nil
+}
+
+// LoadClientCertificate returns the certificate and private key of the PEM
+// files certFile and keyFile.
+func LoadClientCertificate(certFile, keyFile string) (tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("%w: %v", ErrInvalidClientCertificate, err)
+	}
+
+	return cert, nil
+}
+
+// transport returns the transport of the client, created by New.
+func (c *defaultClient) transport() *http.Transport {
+	return c.cl.Transport.(*http.Transport)
+}
+
+// tlsConfig returns the TLS configuration of the transport, creating it when
+// it's not set yet.
+func (c *defaultClient) tlsConfig() *tls.Config {
+	t := c.transport()
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+
+	return t.TLSClientConfig
+}
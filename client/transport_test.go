@@ -0,0 +1,241 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writePEM writes the PEM block of type typ with der to a file in dir and
+// returns its path.
+func writePEM(t *testing.T, dir, name, typ string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+		t.Fatalf("could not write %s: %v", name, err)
+	}
+
+	return path
+}
+
+// writeClientCertificate writes a self-signed certificate and its private key
+// to dir and returns their paths.
+func writeClientCertificate(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "creds-fetcher"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("could not create certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("could not marshal key: %v", err)
+	}
+
+	return writePEM(t, dir, "client.pem", "CERTIFICATE", der), writePEM(t, dir, "client-key.pem", "EC PRIVATE KEY", keyDER)
+}
+
+func TestLoadCABundle(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer s.Close()
+
+	dir := t.TempDir()
+	bundle := writePEM(t, dir, "ca.pem", "CERTIFICATE", s.Certificate().Raw)
+	empty := filepath.Join(dir, "empty.pem")
+	os.WriteFile(empty, []byte("not a certificate"), 0600)
+
+	tests := []struct {
+		name      string
+		path      string
+		expectErr error
+	}{
+		{
+			name: "bundle: server certificate is trusted",
+			path: bundle,
+		},
+		{
+			name:      "missing file: error",
+			path:      filepath.Join(dir, "missing.pem"),
+			expectErr: ErrInvalidCABundle,
+		},
+		{
+			name:      "no certificate: error",
+			path:      empty,
+			expectErr: ErrInvalidCABundle,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, err := LoadCABundle(tt.path)
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("LoadCABundle() expected error: %v, got: %v", tt.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			resp, err := New(SetRootCAs(pool)).Get(s.URL, nil, nil)
+			if err != nil {
+				t.Fatalf("Get() unexpected error: %v", err)
+			}
+			resp.Body.Close()
+		})
+	}
+
+	// without the bundle the test server is not trusted
+	if _, err := New(SetRetryPolicy(RetryPolicy{MaxAttempts: 1})).Get(s.URL, nil, nil); err == nil {
+		t.Errorf("Get() expected certificate error")
+	}
+}
+
+func TestLoadClientCertificate(t *testing.T) {
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	s.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	s.StartTLS()
+	defer s.Close()
+
+	dir := t.TempDir()
+	certFile, keyFile := writeClientCertificate(t, dir)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(s.Certificate())
+
+	tests := []struct {
+		name      string
+		certFile  string
+		keyFile   string
+		expectErr error
+	}{
+		{
+			name:     "key pair: certificate is sent",
+			certFile: certFile,
+			keyFile:  keyFile,
+		},
+		{
+			name:      "missing key: error",
+			certFile:  certFile,
+			keyFile:   filepath.Join(dir, "missing.pem"),
+			expectErr: ErrInvalidClientCertificate,
+		},
+		{
+			name:      "key as certificate: error",
+			certFile:  keyFile,
+			keyFile:   keyFile,
+			expectErr: ErrInvalidClientCertificate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, err := LoadClientCertificate(tt.certFile, tt.keyFile)
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("LoadClientCertificate() expected error: %v, got: %v", tt.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			resp, err := New(SetRootCAs(pool), SetClientCertificate(cert)).Get(s.URL, nil, nil)
+			if err != nil {
+				t.Fatalf("Get() unexpected error: %v", err)
+			}
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != "creds-fetcher" {
+				t.Errorf("Get() expected client certificate: creds-fetcher, got: %s", body)
+			}
+		})
+	}
+}
+
+func TestSetProxy(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "http://env-proxy:8080")
+	t.Setenv("NO_PROXY", "")
+
+	tests := []struct {
+		name        string
+		httpsProxy  string
+		noProxy     string
+		url         string
+		expectProxy string
+	}{
+		{
+			name:        "no values: environment proxy is used",
+			url:         "https://example.okta.com/oauth2/v1/token",
+			expectProxy: "http://env-proxy:8080",
+		},
+		{
+			name:        "https proxy: environment proxy is replaced",
+			httpsProxy:  "http://proxy.corp:3128",
+			url:         "https://sts.amazonaws.com/",
+			expectProxy: "http://proxy.corp:3128",
+		},
+		{
+			name:       "no proxy: host is requested directly",
+			httpsProxy: "http://proxy.corp:3128",
+			noProxy:    "okta.com,.internal",
+			url:        "https://example.okta.com/oauth2/v1/token",
+		},
+		{
+			name:        "no proxy: other hosts use the proxy",
+			httpsProxy:  "http://proxy.corp:3128",
+			noProxy:     "okta.com,.internal",
+			url:         "https://sts.amazonaws.com/",
+			expectProxy: "http://proxy.corp:3128",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(SetProxy(tt.httpsProxy, tt.noProxy))
+
+			req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+			proxy, err := c.transport().Proxy(req)
+			if err != nil {
+				t.Fatalf("Proxy() unexpected error: %v", err)
+			}
+
+			got := ""
+			if proxy != nil {
+				got = proxy.String()
+			}
+			if got != tt.expectProxy {
+				t.Errorf("Proxy() expected: %q, got: %q", tt.expectProxy, got)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package configuration
 import (
 	"errors"
 	"fmt"
+	"net/url"
+	"time"
 
 	"github.com/fox-tech/creds-fetcher/env"
 )
@@ -16,9 +18,75 @@ var (
 	ErrInvalidOktaClientID          = errors.New("invalid okta_client_id, cannot be empty")
 	ErrInvalidOktaAppID             = errors.New("invalid okta_app_id, cannot be empty")
 	ErrInvalidOktaURL               = errors.New("invalid okta_url, cannot be empty")
+	ErrInvalidProfileType           = errors.New("invalid profile_type, must be saml, web_identity or ci")
+	ErrInvalidAuthFlow              = errors.New("invalid auth_flow, must be device, client_credentials, pkce, authn or saml_loopback")
+	ErrInvalidOktaPrivateKey        = errors.New("invalid okta_private_key, cannot be empty for client_credentials")
+	ErrInvalidOktaAppURL            = errors.New("invalid okta_app_url, cannot be empty for authn")
+	ErrInvalidAuthnProfileType      = errors.New("invalid auth_flow, authn can only be used by saml profiles")
+	ErrInvalidOktaMaxAge            = errors.New("invalid okta_max_age, must be a duration such as 15m or 0s")
+	ErrInvalidAuthPolicyAuthFlow    = errors.New("invalid okta_acr_values or okta_max_age, can only be used by device and pkce")
+	ErrInvalidSAMLURL               = errors.New("invalid saml_login_url and saml_sso_url, one of them must be set for saml_loopback")
+	ErrInvalidSAMLLoopbackProfile   = errors.New("invalid profile_type, saml_loopback can only be used by saml profiles")
+	ErrInvalidOIDCClientID          = errors.New("invalid oidc_client_id, cannot be empty")
+	ErrInvalidOIDCEndpoints         = errors.New("invalid oidc_issuer, cannot be empty without oidc_device_authorization_endpoint and oidc_token_endpoint")
+	ErrInvalidOIDCAudience          = errors.New("invalid oidc_audience, cannot be empty for saml profiles")
+	ErrInvalidProviderCommand       = errors.New("invalid provider_command, cannot be empty for plugin providers")
+	ErrInvalidSSOStartURL           = errors.New("invalid sso_start_url and sso_region, both must be set for sso")
+	ErrInvalidSSORole               = errors.New("invalid sso_account_id and sso_role_name, both must be set for sso")
+	ErrInvalidSSOProfileType        = errors.New("invalid profile_type, cannot be set for sso")
+	ErrInvalidRetryMaxAttempts      = errors.New("invalid retry_max_attempts, cannot be negative")
+	ErrInvalidRetryDelay            = errors.New("invalid retry_base_delay or retry_max_delay, must be a duration such as 500ms or 20s")
+	ErrInvalidHTTPSProxy            = errors.New("invalid https_proxy, must be a URL such as http://proxy:3128")
+	ErrInvalidClientCertificate     = errors.New("invalid client_certificate and client_key, both must be set")
 	ErrNilReader                    = errors.New("invalid reader, cannot be nil")
 )
 
+const (
+	// ProfileTypeSAML profiles get credentials with a SAML assertion of the Okta
+	// app. It's the default when no profile_type is set.
+	ProfileTypeSAML = "saml"
+	// ProfileTypeWebIdentity profiles get credentials with the Okta ID token
+	// through an OIDC identity provider in AWS, no Okta app is needed.
+	ProfileTypeWebIdentity = "web_identity"
+	// ProfileTypeCI profiles get credentials with the OIDC token of a CI job
+	// through an OIDC identity provider in AWS, no Okta values are needed.
+	ProfileTypeCI = "ci"
+
+	// ProviderOkta profiles log in to Okta with their AuthFlow. It's the
+	// default when no provider is set.
+	ProviderOkta = "okta"
+	// ProviderSAML profiles log in to any SAML identity provider with
+	// AuthFlowSAMLLoopback, the default for profiles with that flow.
+	ProviderSAML = "saml"
+	// ProviderOIDC profiles log in to any OpenID Connect authorization server
+	// with the device authorization flow.
+	ProviderOIDC = "oidc"
+	// ProviderPlugin profiles log in with the credential provider plugin in
+	// ProviderCommand, the default for profiles with a provider_command.
+	ProviderPlugin = "plugin"
+	// ProviderSSO profiles get the credentials of a role in an account from
+	// AWS IAM Identity Center, the default for profiles with an sso_start_url.
+	ProviderSSO = "sso"
+
+	// AuthFlowDevice profiles log in to Okta with the device authorization
+	// flow. It's the default when no auth_flow is set.
+	AuthFlowDevice = "device"
+	// AuthFlowClientCredentials profiles log in to Okta as a service app with
+	// the client credentials flow and a private key, with no user interaction.
+	AuthFlowClientCredentials = "client_credentials"
+	// AuthFlowPKCE profiles log in to Okta with the authorization code flow
+	// with PKCE, receiving the code in a loopback redirect from the browser.
+	AuthFlowPKCE = "pkce"
+	// AuthFlowAuthn profiles log in to Okta with a username, password and MFA
+	// factor prompted in the terminal, with no browser, and get the SAML
+	// assertion from the embed link of the app.
+	AuthFlowAuthn = "authn"
+	// AuthFlowSAMLLoopback profiles log in to any SAML identity provider in
+	// the browser, which posts the SAML response to a listener in 127.0.0.1.
+	// No Okta values are needed.
+	AuthFlowSAMLLoopback = "saml_loopback"
+)
+
 var (
 	sources = []string{
 		"stdin",
@@ -69,12 +137,129 @@ func New(profile, overrideLocation string) (cfg *Configuration, err error) {
 	return
 }
 
+// All will return every valid profile Configuration from the configuration file, using the
+// same locations as New. Profiles which fail validation are left out. Environment variable
+// overrides are not applied, as they are not specific to a profile.
+func All(overrideLocation string) (cfgs map[string]*Configuration, err error) {
+	var all map[string]*Configuration
+	if all, err = getConfigurations(overrideLocation); err != nil {
+		return
+	}
+
+	cfgs = make(map[string]*Configuration, len(all))
+	for name, cfg := range all {
+		if cfg == nil || cfg.Validate() != nil {
+			continue
+		}
+
+		cfgs[name] = cfg
+	}
+
+	return
+}
+
 type Configuration struct {
 	AWSProviderARN string `toml:"aws_provider_arn" json:"aws_provider_arn" env:"AWS_PROVIDER_ARN"`
 	AWSRoleARN     string `toml:"aws_role_arn" json:"aws_role_arn" env:"AWS_ROLE_ARN"`
 	OktaClientID   string `toml:"okta_client_id" json:"okta_client_id" env:"OKTA_CLIENT_ID"`
 	OktaAppID      string `toml:"okta_app_id" json:"okta_app_id" env:"OKTA_APP_ID"`
 	OktaURL        string `toml:"okta_url" json:"okta_url" env:"OKTA_URL"`
+	AWSRegion      string `toml:"aws_region" json:"aws_region" env:"AWS_REGION"`
+
+	// Provider is the name of the identity provider the profile logs in with,
+	// ProviderOkta, ProviderSAML, ProviderOIDC, ProviderPlugin, ProviderSSO or
+	// one registered in the idp package
+	Provider string `toml:"provider" json:"provider"`
+	// ProviderCommand is the path of the credential provider plugin of
+	// ProviderPlugin
+	ProviderCommand string `toml:"provider_command" json:"provider_command"`
+
+	// OktaAuthServerID is the ID of the custom authorization server used to
+	// log in, the org authorization server is used when it's not set
+	OktaAuthServerID string `toml:"okta_auth_server_id" json:"okta_auth_server_id"`
+
+	// ProfileType is how credentials are requested for the profile, one of
+	// ProfileTypeSAML, ProfileTypeWebIdentity or ProfileTypeCI
+	ProfileType string `toml:"profile_type" json:"profile_type"`
+
+	// AuthFlow is how the profile logs in to Okta, one of AuthFlowDevice,
+	// AuthFlowClientCredentials, AuthFlowPKCE, AuthFlowAuthn or
+	// AuthFlowSAMLLoopback
+	AuthFlow string `toml:"auth_flow" json:"auth_flow"`
+	// OktaPrivateKey is the path to the PEM private key of the service app and
+	// OktaKeyID its optional key ID, used by AuthFlowClientCredentials
+	OktaPrivateKey string `toml:"okta_private_key" json:"okta_private_key"`
+	OktaKeyID      string `toml:"okta_key_id" json:"okta_key_id"`
+	// OktaRedirectPort is the port of the loopback redirect URI used by
+	// AuthFlowPKCE, a random port is used when it's not set
+	OktaRedirectPort int `toml:"okta_redirect_port" json:"okta_redirect_port"`
+	// OktaAppURL is the embed link of the AWS app in Okta, used by
+	// AuthFlowAuthn, and OktaUsername the username it logs in with, which is
+	// prompted when it's not set
+	OktaAppURL   string `toml:"okta_app_url" json:"okta_app_url"`
+	OktaUsername string `toml:"okta_username" json:"okta_username"`
+	// OktaACRValues are the space-separated ACR values the login must satisfy,
+	// e.g. phr for phishing-resistant factors, and OktaMaxAge the longest time
+	// since the user authenticated, as a duration such as 15m, where 0s asks
+	// for a new login every time
+	OktaACRValues string `toml:"okta_acr_values" json:"okta_acr_values"`
+	OktaMaxAge    string `toml:"okta_max_age" json:"okta_max_age"`
+
+	// SAMLLoginURL is the IdP-initiated login URL of the AWS app of the
+	// identity provider and SAMLSSOURL its SSO URL for SP-initiated logins,
+	// used by AuthFlowSAMLLoopback with one of them set. SAMLACSPort is the
+	// port of the loopback ACS URL, saml.DefaultACSPort when it's not set
+	SAMLLoginURL string `toml:"saml_login_url" json:"saml_login_url"`
+	SAMLSSOURL   string `toml:"saml_sso_url" json:"saml_sso_url"`
+	SAMLACSPort  int    `toml:"saml_acs_port" json:"saml_acs_port"`
+
+	// OIDCIssuer is the URL of the authorization server of ProviderOIDC, its
+	// OpenID configuration has the endpoints that are not set. OIDCScopes are
+	// the space-separated scopes of the login, oidc.DefaultScopes when they're
+	// not set, and OIDCAudience the audience the tokens are exchanged for: the
+	// SAML client of AWS, or the client trusted by AWS for web identity
+	// profiles, which use the ID token of the login when it's not set
+	OIDCIssuer                      string `toml:"oidc_issuer" json:"oidc_issuer"`
+	OIDCDeviceAuthorizationEndpoint string `toml:"oidc_device_authorization_endpoint" json:"oidc_device_authorization_endpoint"`
+	OIDCTokenEndpoint               string `toml:"oidc_token_endpoint" json:"oidc_token_endpoint"`
+	OIDCClientID                    string `toml:"oidc_client_id" json:"oidc_client_id"`
+	OIDCScopes                      string `toml:"oidc_scopes" json:"oidc_scopes"`
+	OIDCAudience                    string `toml:"oidc_audience" json:"oidc_audience"`
+
+	// SSOStartURL is the AWS access portal URL of the IAM Identity Center of
+	// ProviderSSO, in SSORegion, and SSOAccountID and SSORoleName the account
+	// and role the credentials are for. SSOOIDCEndpoint and SSOPortalEndpoint
+	// replace the endpoints of the region, e.g. for a local stand-in
+	SSOStartURL       string `toml:"sso_start_url" json:"sso_start_url"`
+	SSORegion         string `toml:"sso_region" json:"sso_region"`
+	SSOAccountID      string `toml:"sso_account_id" json:"sso_account_id"`
+	SSORoleName       string `toml:"sso_role_name" json:"sso_role_name"`
+	SSOOIDCEndpoint   string `toml:"sso_oidc_endpoint" json:"sso_oidc_endpoint"`
+	SSOPortalEndpoint string `toml:"sso_portal_endpoint" json:"sso_portal_endpoint"`
+
+	// CodeCommitRepositories lists the CodeCommit repositories the profile is
+	// used for by the git credential helper
+	CodeCommitRepositories []string `toml:"codecommit_repositories" json:"codecommit_repositories"`
+
+	// RetryMaxAttempts is the number of attempts of the requests to Okta and
+	// AWS, 1 disables retries. RetryBaseDelay is the first delay between
+	// attempts, doubled on each retry, and RetryMaxDelay the longest one, as
+	// durations such as 500ms or 20s. The defaults are used when they're not set
+	RetryMaxAttempts int    `toml:"retry_max_attempts" json:"retry_max_attempts"`
+	RetryBaseDelay   string `toml:"retry_base_delay" json:"retry_base_delay"`
+	RetryMaxDelay    string `toml:"retry_max_delay" json:"retry_max_delay"`
+
+	// HTTPSProxy and NoProxy replace the HTTPS_PROXY and NO_PROXY environment
+	// variables for the requests to Okta and AWS
+	HTTPSProxy string `toml:"https_proxy" json:"https_proxy"`
+	NoProxy    string `toml:"no_proxy" json:"no_proxy"`
+	// CABundle is the path to PEM certificates trusted besides the system
+	// ones, e.g. the CA of a TLS-intercepting proxy
+	CABundle string `toml:"ca_bundle" json:"ca_bundle"`
+	// ClientCertificate and ClientKey are the paths to the PEM certificate
+	// and private key sent to servers asking for mutual TLS
+	ClientCertificate string `toml:"client_certificate" json:"client_certificate"`
+	ClientKey         string `toml:"client_key" json:"client_key"`
 }
 
 func (c *Configuration) OverrideWith(in *Configuration) {
@@ -97,10 +282,103 @@ func (c *Configuration) OverrideWith(in *Configuration) {
 	if len(in.OktaURL) > 0 {
 		c.OktaURL = in.OktaURL
 	}
+
+	// AWS_REGION is set by many shells and CI environments for other tools,
+	// it's only the default of the profiles without a region
+	if len(in.AWSRegion) > 0 && len(c.AWSRegion) == 0 {
+		c.AWSRegion = in.AWSRegion
+	}
+}
+
+// IsWebIdentity reports whether the profile gets credentials with a web
+// identity token instead of a SAML assertion.
+func (c *Configuration) IsWebIdentity() bool {
+	return c.ProfileType == ProfileTypeWebIdentity || c.ProfileType == ProfileTypeCI
+}
+
+// IdentityProvider returns the name of the identity provider the profile logs
+// in with, ProviderOkta when none is set, ProviderPlugin for profiles with a
+// ProviderCommand, ProviderSSO for profiles with an SSOStartURL or
+// ProviderSAML for profiles with AuthFlowSAMLLoopback.
+func (c *Configuration) IdentityProvider() string {
+	switch {
+	case len(c.Provider) > 0:
+		return c.Provider
+	case len(c.ProviderCommand) > 0:
+		return ProviderPlugin
+	case len(c.SSOStartURL) > 0:
+		return ProviderSSO
+	case c.AuthFlow == AuthFlowSAMLLoopback:
+		return ProviderSAML
+	}
+
+	return ProviderOkta
+}
+
+// UsesOkta reports whether the profile logs in to Okta, other profiles have no
+// Okta tokens or user.
+func (c *Configuration) UsesOkta() bool {
+	return c.ProfileType != ProfileTypeCI && c.IdentityProvider() == ProviderOkta
+}
+
+// hasAuthPolicy reports whether the profile requires ACR values or a max age
+// of the Okta login.
+func (c *Configuration) hasAuthPolicy() bool {
+	return len(c.OktaACRValues) > 0 || len(c.OktaMaxAge) > 0
 }
 
 func (c *Configuration) Validate() (err error) {
-	if len(c.AWSProviderARN) == 0 {
+	if c.RetryMaxAttempts < 0 {
+		return ErrInvalidRetryMaxAttempts
+	}
+
+	for _, d := range []string{c.RetryBaseDelay, c.RetryMaxDelay} {
+		if len(d) == 0 {
+			continue
+		}
+
+		if v, err := time.ParseDuration(d); err != nil || v < 0 {
+			return ErrInvalidRetryDelay
+		}
+	}
+
+	if len(c.OktaMaxAge) > 0 {
+		if v, err := time.ParseDuration(c.OktaMaxAge); err != nil || v < 0 {
+			return ErrInvalidOktaMaxAge
+		}
+	}
+
+	if len(c.HTTPSProxy) > 0 {
+		if u, err := url.Parse(c.HTTPSProxy); err != nil || u.Scheme == "" || u.Host == "" {
+			return ErrInvalidHTTPSProxy
+		}
+	}
+
+	if (len(c.ClientCertificate) == 0) != (len(c.ClientKey) == 0) {
+		return ErrInvalidClientCertificate
+	}
+
+	// IAM Identity Center profiles get the credentials of their role from it,
+	// no role is assumed with STS
+	if c.IdentityProvider() == ProviderSSO {
+		return c.validateSSO()
+	}
+
+	switch c.ProfileType {
+	case "", ProfileTypeSAML, ProfileTypeWebIdentity:
+	case ProfileTypeCI:
+		// CI profiles only assume the role with the token of the job
+		if len(c.AWSRoleARN) == 0 {
+			return ErrInvalidAWSRoleARN
+		}
+
+		return
+	default:
+		return ErrInvalidProfileType
+	}
+
+	// web identity profiles assume the role without a SAML provider or app
+	if len(c.AWSProviderARN) == 0 && !c.IsWebIdentity() {
 		return ErrInvalidAWSProviderARN
 	}
 
@@ -108,11 +386,58 @@ func (c *Configuration) Validate() (err error) {
 		return ErrInvalidAWSRoleARN
 	}
 
+	switch c.IdentityProvider() {
+	case ProviderOkta:
+		return c.validateOkta()
+	case ProviderSAML:
+		return c.validateSAML()
+	case ProviderOIDC:
+		return c.validateOIDC()
+	case ProviderPlugin:
+		return c.validatePlugin()
+	}
+
+	// the providers registered by other packages check their own values
+	return
+}
+
+// validateOkta checks the values of the profiles logging in to Okta.
+func (c *Configuration) validateOkta() error {
+	switch c.AuthFlow {
+	case "", AuthFlowDevice, AuthFlowPKCE:
+	case AuthFlowClientCredentials:
+		if len(c.OktaPrivateKey) == 0 {
+			return ErrInvalidOktaPrivateKey
+		}
+
+		// service apps have no user to authenticate again
+		if c.hasAuthPolicy() {
+			return ErrInvalidAuthPolicyAuthFlow
+		}
+	case AuthFlowAuthn:
+		// authn gets a SAML assertion from the app, there's no ID token
+		if c.IsWebIdentity() {
+			return ErrInvalidAuthnProfileType
+		}
+
+		if len(c.OktaAppURL) == 0 {
+			return ErrInvalidOktaAppURL
+		}
+
+		// there's no ID token to check the policy
+		if c.hasAuthPolicy() {
+			return ErrInvalidAuthPolicyAuthFlow
+		}
+	default:
+		return ErrInvalidAuthFlow
+	}
+
 	if len(c.OktaClientID) == 0 {
 		return ErrInvalidOktaClientID
 	}
 
-	if len(c.OktaAppID) == 0 {
+	// authn profiles request the app with its embed link instead of its ID
+	if len(c.OktaAppID) == 0 && !c.IsWebIdentity() && c.AuthFlow != AuthFlowAuthn {
 		return ErrInvalidOktaAppID
 	}
 
@@ -120,5 +445,99 @@ func (c *Configuration) Validate() (err error) {
 		return ErrInvalidOktaURL
 	}
 
-	return
+	return nil
+}
+
+// validateSAML checks the values of the profiles logging in to a SAML
+// identity provider with a loopback ACS, no Okta values are needed.
+func (c *Configuration) validateSAML() error {
+	if len(c.AuthFlow) > 0 && c.AuthFlow != AuthFlowSAMLLoopback {
+		return ErrInvalidAuthFlow
+	}
+
+	// the SAML response has no ID token to assume a web identity role with
+	if c.IsWebIdentity() {
+		return ErrInvalidSAMLLoopbackProfile
+	}
+
+	if (len(c.SAMLLoginURL) == 0) == (len(c.SAMLSSOURL) == 0) {
+		return ErrInvalidSAMLURL
+	}
+
+	if c.hasAuthPolicy() {
+		return ErrInvalidAuthPolicyAuthFlow
+	}
+
+	return nil
+}
+
+// validateOIDC checks the values of the profiles logging in to an OpenID
+// Connect authorization server, no Okta values are needed.
+func (c *Configuration) validateOIDC() error {
+	if len(c.AuthFlow) > 0 && c.AuthFlow != AuthFlowDevice {
+		return ErrInvalidAuthFlow
+	}
+
+	if len(c.OIDCClientID) == 0 {
+		return ErrInvalidOIDCClientID
+	}
+
+	if len(c.OIDCIssuer) == 0 && (len(c.OIDCDeviceAuthorizationEndpoint) == 0 || len(c.OIDCTokenEndpoint) == 0) {
+		return ErrInvalidOIDCEndpoints
+	}
+
+	// the SAML assertion is exchanged for the SAML client of AWS
+	if len(c.OIDCAudience) == 0 && !c.IsWebIdentity() {
+		return ErrInvalidOIDCAudience
+	}
+
+	if c.hasAuthPolicy() {
+		return ErrInvalidAuthPolicyAuthFlow
+	}
+
+	return nil
+}
+
+// validatePlugin checks the values of the profiles logging in with a
+// credential provider plugin, which logs in on its own.
+func (c *Configuration) validatePlugin() error {
+	if len(c.AuthFlow) > 0 {
+		return ErrInvalidAuthFlow
+	}
+
+	if len(c.ProviderCommand) == 0 {
+		return ErrInvalidProviderCommand
+	}
+
+	if c.hasAuthPolicy() {
+		return ErrInvalidAuthPolicyAuthFlow
+	}
+
+	return nil
+}
+
+// validateSSO checks the values of the profiles getting credentials from IAM
+// Identity Center, which have no profile type, role ARN or provider ARN.
+func (c *Configuration) validateSSO() error {
+	if len(c.ProfileType) > 0 {
+		return ErrInvalidSSOProfileType
+	}
+
+	if len(c.AuthFlow) > 0 {
+		return ErrInvalidAuthFlow
+	}
+
+	if len(c.SSOStartURL) == 0 || len(c.SSORegion) == 0 {
+		return ErrInvalidSSOStartURL
+	}
+
+	if len(c.SSOAccountID) == 0 || len(c.SSORoleName) == 0 {
+		return ErrInvalidSSORole
+	}
+
+	if c.hasAuthPolicy() {
+		return ErrInvalidAuthPolicyAuthFlow
+	}
+
+	return nil
 }
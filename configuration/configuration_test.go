@@ -182,6 +182,55 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestAll(t *testing.T) {
+	config := `
+[my_profile]
+aws_provider_arn = "1"
+aws_role_arn = "2"
+okta_client_id = "3"
+okta_app_id = "4"
+okta_url = "5"
+aws_region = "us-east-1"
+codecommit_repositories = ["infra", "web"]
+
+[invalid_profile]
+aws_role_arn = "2"
+`
+
+	f, err := createTestFile("./Test_All.override.toml", config)
+	if err != nil {
+		t.Fatalf("All() error preparing test: %v", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	cfgs, err := All(f.Name())
+	if err != nil {
+		t.Fatalf("All() unexpected error: %v", err)
+	}
+
+	want := map[string]*Configuration{
+		"my_profile": {
+			AWSProviderARN: "1",
+			AWSRoleARN:     "2",
+			OktaClientID:   "3",
+			OktaAppID:      "4",
+			OktaURL:        "5",
+			AWSRegion:      "us-east-1",
+
+			CodeCommitRepositories: []string{"infra", "web"},
+		},
+	}
+
+	if !reflect.DeepEqual(cfgs, want) {
+		t.Errorf("All() = %v, want %v", cfgs, want)
+	}
+
+	if _, err := All("./Test_All.missing.toml"); err == nil {
+		t.Errorf("All() expected error for missing file")
+	}
+}
+
 func TestConfiguration_OverrideWith(t *testing.T) {
 	type fields struct {
 		AWSProviderARN string
@@ -189,6 +238,7 @@ func TestConfiguration_OverrideWith(t *testing.T) {
 		OktaClientID   string
 		OktaAppID      string
 		OktaURL        string
+		AWSRegion      string
 	}
 
 	type args struct {
@@ -289,6 +339,47 @@ func TestConfiguration_OverrideWith(t *testing.T) {
 				OktaURL:        "5new",
 			},
 		},
+		{
+			name:   "AWS Region",
+			fields: baseFields,
+			args: args{
+				in: &Configuration{
+					AWSRegion: "us-west-2",
+				},
+			},
+			wantCfg: &Configuration{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				OktaClientID:   "3",
+				OktaAppID:      "4",
+				OktaURL:        "5",
+				AWSRegion:      "us-west-2",
+			},
+		},
+		{
+			name: "AWS Region of the profile kept",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				OktaClientID:   "3",
+				OktaAppID:      "4",
+				OktaURL:        "5",
+				AWSRegion:      "eu-west-1",
+			},
+			args: args{
+				in: &Configuration{
+					AWSRegion: "us-west-2",
+				},
+			},
+			wantCfg: &Configuration{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				OktaClientID:   "3",
+				OktaAppID:      "4",
+				OktaURL:        "5",
+				AWSRegion:      "eu-west-1",
+			},
+		},
 		{
 			name:   "All AWS",
 			fields: baseFields,
@@ -334,6 +425,7 @@ func TestConfiguration_OverrideWith(t *testing.T) {
 				OktaClientID:   tt.fields.OktaClientID,
 				OktaAppID:      tt.fields.OktaAppID,
 				OktaURL:        tt.fields.OktaURL,
+				AWSRegion:      tt.fields.AWSRegion,
 			}
 			c.OverrideWith(tt.args.in)
 
@@ -352,6 +444,36 @@ func TestConfiguration_Validate(t *testing.T) {
 		OktaClientID   string
 		OktaAppID      string
 		OktaURL        string
+		ProfileType    string
+		AuthFlow       string
+		OktaPrivateKey string
+		OktaAppURL     string
+		OktaACRValues  string
+		OktaMaxAge     string
+		SAMLLoginURL   string
+		SAMLSSOURL     string
+		Provider       string
+		OIDCIssuer     string
+		OIDCClientID   string
+		OIDCAudience   string
+
+		OIDCDeviceAuthorizationEndpoint string
+		OIDCTokenEndpoint               string
+
+		ProviderCommand string
+
+		SSOStartURL  string
+		SSORegion    string
+		SSOAccountID string
+		SSORoleName  string
+
+		RetryMaxAttempts int
+		RetryBaseDelay   string
+		RetryMaxDelay    string
+
+		HTTPSProxy        string
+		ClientCertificate string
+		ClientKey         string
 	}
 
 	tests := []struct {
@@ -424,6 +546,439 @@ func TestConfiguration_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "success (web identity without AWSProviderARN and OktaAppID)",
+			fields: fields{
+				AWSRoleARN:   "2",
+				OktaClientID: "3",
+				OktaURL:      "5",
+				ProfileType:  ProfileTypeWebIdentity,
+			},
+		},
+		{
+			name: "success (ci with AWSRoleARN only)",
+			fields: fields{
+				AWSRoleARN:  "2",
+				ProfileType: ProfileTypeCI,
+			},
+		},
+		{
+			name: "failure (ci without AWSRoleARN)",
+			fields: fields{
+				OktaClientID: "3",
+				OktaURL:      "5",
+				ProfileType:  ProfileTypeCI,
+			},
+			wantErr: true,
+		},
+		{
+			name: "success (client_credentials with OktaPrivateKey)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				OktaClientID:   "3",
+				OktaAppID:      "4",
+				OktaURL:        "5",
+				AuthFlow:       AuthFlowClientCredentials,
+				OktaPrivateKey: "key.pem",
+			},
+		},
+		{
+			name: "failure (client_credentials without OktaPrivateKey)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				OktaClientID:   "3",
+				OktaAppID:      "4",
+				OktaURL:        "5",
+				AuthFlow:       AuthFlowClientCredentials,
+			},
+			wantErr: true,
+		},
+		{
+			name: "success (authn with OktaAppURL and no OktaAppID)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				OktaClientID:   "3",
+				OktaURL:        "5",
+				AuthFlow:       AuthFlowAuthn,
+				OktaAppURL:     "https://okta/home/amazon_aws/0oa/272",
+			},
+		},
+		{
+			name: "failure (authn without OktaAppURL)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				OktaClientID:   "3",
+				OktaAppID:      "4",
+				OktaURL:        "5",
+				AuthFlow:       AuthFlowAuthn,
+			},
+			wantErr: true,
+		},
+		{
+			name: "failure (authn with web_identity ProfileType)",
+			fields: fields{
+				AWSRoleARN:   "2",
+				OktaClientID: "3",
+				OktaURL:      "5",
+				ProfileType:  ProfileTypeWebIdentity,
+				AuthFlow:     AuthFlowAuthn,
+				OktaAppURL:   "https://okta/home/amazon_aws/0oa/272",
+			},
+			wantErr: true,
+		},
+		{
+			name: "success (OktaACRValues and zero OktaMaxAge)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				OktaClientID:   "3",
+				OktaAppID:      "4",
+				OktaURL:        "5",
+				OktaACRValues:  "phr",
+				OktaMaxAge:     "0s",
+			},
+		},
+		{
+			name: "failure (invalid OktaMaxAge)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				OktaClientID:   "3",
+				OktaAppID:      "4",
+				OktaURL:        "5",
+				OktaMaxAge:     "900",
+			},
+			wantErr: true,
+		},
+		{
+			name: "failure (OktaACRValues with client_credentials)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				OktaClientID:   "3",
+				OktaAppID:      "4",
+				OktaURL:        "5",
+				AuthFlow:       AuthFlowClientCredentials,
+				OktaPrivateKey: "key.pem",
+				OktaACRValues:  "phr",
+			},
+			wantErr: true,
+		},
+		{
+			name: "success (saml_loopback without Okta values)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				AuthFlow:       AuthFlowSAMLLoopback,
+				SAMLLoginURL:   "https://idp/app/aws",
+			},
+		},
+		{
+			name: "failure (saml_loopback with both SAMLLoginURL and SAMLSSOURL)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				AuthFlow:       AuthFlowSAMLLoopback,
+				SAMLLoginURL:   "https://idp/app/aws",
+				SAMLSSOURL:     "https://idp/saml2",
+			},
+			wantErr: true,
+		},
+		{
+			name: "failure (saml_loopback without AWSProviderARN)",
+			fields: fields{
+				AWSRoleARN: "2",
+				AuthFlow:   AuthFlowSAMLLoopback,
+				SAMLSSOURL: "https://idp/saml2",
+			},
+			wantErr: true,
+		},
+		{
+			name: "success (saml Provider without AuthFlow)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				Provider:       ProviderSAML,
+				SAMLSSOURL:     "https://idp/saml2",
+			},
+		},
+		{
+			name: "failure (saml Provider with Okta AuthFlow)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				Provider:       ProviderSAML,
+				AuthFlow:       AuthFlowDevice,
+				SAMLSSOURL:     "https://idp/saml2",
+			},
+			wantErr: true,
+		},
+		{
+			name: "success (oidc Provider with issuer)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				Provider:       ProviderOIDC,
+				OIDCIssuer:     "https://keycloak/realms/aws",
+				OIDCClientID:   "3",
+				OIDCAudience:   "urn:amazon:webservices",
+			},
+		},
+		{
+			name: "success (oidc Provider with endpoints and web_identity ProfileType)",
+			fields: fields{
+				AWSRoleARN:   "2",
+				ProfileType:  ProfileTypeWebIdentity,
+				Provider:     ProviderOIDC,
+				OIDCClientID: "3",
+
+				OIDCDeviceAuthorizationEndpoint: "https://auth0/oauth/device/code",
+				OIDCTokenEndpoint:               "https://auth0/oauth/token",
+			},
+		},
+		{
+			name: "failure (oidc Provider without OIDCClientID)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				Provider:       ProviderOIDC,
+				OIDCIssuer:     "https://keycloak/realms/aws",
+				OIDCAudience:   "urn:amazon:webservices",
+			},
+			wantErr: true,
+		},
+		{
+			name: "failure (oidc Provider without issuer and token endpoint)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				Provider:       ProviderOIDC,
+				OIDCClientID:   "3",
+				OIDCAudience:   "urn:amazon:webservices",
+
+				OIDCDeviceAuthorizationEndpoint: "https://auth0/oauth/device/code",
+			},
+			wantErr: true,
+		},
+		{
+			name: "failure (oidc Provider without OIDCAudience for saml ProfileType)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				Provider:       ProviderOIDC,
+				OIDCIssuer:     "https://keycloak/realms/aws",
+				OIDCClientID:   "3",
+			},
+			wantErr: true,
+		},
+		{
+			name: "failure (oidc Provider with pkce AuthFlow)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				Provider:       ProviderOIDC,
+				AuthFlow:       AuthFlowPKCE,
+				OIDCIssuer:     "https://keycloak/realms/aws",
+				OIDCClientID:   "3",
+				OIDCAudience:   "urn:amazon:webservices",
+			},
+			wantErr: true,
+		},
+		{
+			name: "success (ProviderCommand without Provider)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+
+				ProviderCommand: "/usr/local/bin/internal-idp",
+			},
+		},
+		{
+			name: "failure (plugin Provider without ProviderCommand)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				Provider:       ProviderPlugin,
+			},
+			wantErr: true,
+		},
+		{
+			name: "failure (ProviderCommand with device AuthFlow)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				AuthFlow:       AuthFlowDevice,
+
+				ProviderCommand: "/usr/local/bin/internal-idp",
+			},
+			wantErr: true,
+		},
+		{
+			name: "success (SSOStartURL without role ARNs)",
+			fields: fields{
+				SSOStartURL:  "https://example.awsapps.com/start",
+				SSORegion:    "eu-west-1",
+				SSOAccountID: "123456789012",
+				SSORoleName:  "Developer",
+			},
+		},
+		{
+			name: "failure (sso Provider without SSOStartURL)",
+			fields: fields{
+				Provider:     ProviderSSO,
+				SSORegion:    "eu-west-1",
+				SSOAccountID: "123456789012",
+				SSORoleName:  "Developer",
+			},
+			wantErr: true,
+		},
+		{
+			name: "failure (SSOStartURL without SSORoleName)",
+			fields: fields{
+				SSOStartURL:  "https://example.awsapps.com/start",
+				SSORegion:    "eu-west-1",
+				SSOAccountID: "123456789012",
+			},
+			wantErr: true,
+		},
+		{
+			name: "failure (SSOStartURL with web_identity ProfileType)",
+			fields: fields{
+				ProfileType:  ProfileTypeWebIdentity,
+				SSOStartURL:  "https://example.awsapps.com/start",
+				SSORegion:    "eu-west-1",
+				SSOAccountID: "123456789012",
+				SSORoleName:  "Developer",
+			},
+			wantErr: true,
+		},
+		{
+			name: "success (other Provider checks its own values)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				Provider:       "keycloak",
+			},
+		},
+		{
+			name: "failure (other Provider without AWSRoleARN)",
+			fields: fields{
+				AWSProviderARN: "1",
+				Provider:       "keycloak",
+			},
+			wantErr: true,
+		},
+		{
+			name: "failure (unknown AuthFlow)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				OktaClientID:   "3",
+				OktaAppID:      "4",
+				OktaURL:        "5",
+				AuthFlow:       "password",
+			},
+			wantErr: true,
+		},
+		{
+			name: "failure (unknown ProfileType)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				OktaClientID:   "3",
+				OktaAppID:      "4",
+				OktaURL:        "5",
+				ProfileType:    "kerberos",
+			},
+			wantErr: true,
+		},
+		{
+			name: "success (retry policy)",
+			fields: fields{
+				AWSProviderARN:   "1",
+				AWSRoleARN:       "2",
+				OktaClientID:     "3",
+				OktaAppID:        "4",
+				OktaURL:          "5",
+				RetryMaxAttempts: 5,
+				RetryBaseDelay:   "250ms",
+				RetryMaxDelay:    "1m",
+			},
+		},
+		{
+			name: "failure (negative RetryMaxAttempts)",
+			fields: fields{
+				AWSProviderARN:   "1",
+				AWSRoleARN:       "2",
+				OktaClientID:     "3",
+				OktaAppID:        "4",
+				OktaURL:          "5",
+				RetryMaxAttempts: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "failure (invalid RetryBaseDelay)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				OktaClientID:   "3",
+				OktaAppID:      "4",
+				OktaURL:        "5",
+				RetryBaseDelay: "500",
+			},
+			wantErr: true,
+		},
+		{
+			name: "failure (negative RetryMaxDelay)",
+			fields: fields{
+				AWSRoleARN:    "2",
+				ProfileType:   ProfileTypeCI,
+				RetryMaxDelay: "-1s",
+			},
+			wantErr: true,
+		},
+		{
+			name: "success (corporate network)",
+			fields: fields{
+				AWSProviderARN:    "1",
+				AWSRoleARN:        "2",
+				OktaClientID:      "3",
+				OktaAppID:         "4",
+				OktaURL:           "5",
+				HTTPSProxy:        "http://proxy.corp:3128",
+				ClientCertificate: "client.pem",
+				ClientKey:         "client-key.pem",
+			},
+		},
+		{
+			name: "failure (HTTPSProxy without scheme)",
+			fields: fields{
+				AWSProviderARN: "1",
+				AWSRoleARN:     "2",
+				OktaClientID:   "3",
+				OktaAppID:      "4",
+				OktaURL:        "5",
+				HTTPSProxy:     "proxy.corp:3128",
+			},
+			wantErr: true,
+		},
+		{
+			name: "failure (ClientCertificate without ClientKey)",
+			fields: fields{
+				AWSProviderARN:    "1",
+				AWSRoleARN:        "2",
+				OktaClientID:      "3",
+				OktaAppID:         "4",
+				OktaURL:           "5",
+				ClientCertificate: "client.pem",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -434,6 +989,36 @@ func TestConfiguration_Validate(t *testing.T) {
 				OktaClientID:   tt.fields.OktaClientID,
 				OktaAppID:      tt.fields.OktaAppID,
 				OktaURL:        tt.fields.OktaURL,
+				ProfileType:    tt.fields.ProfileType,
+				AuthFlow:       tt.fields.AuthFlow,
+				OktaPrivateKey: tt.fields.OktaPrivateKey,
+				OktaAppURL:     tt.fields.OktaAppURL,
+				OktaACRValues:  tt.fields.OktaACRValues,
+				OktaMaxAge:     tt.fields.OktaMaxAge,
+				SAMLLoginURL:   tt.fields.SAMLLoginURL,
+				SAMLSSOURL:     tt.fields.SAMLSSOURL,
+				Provider:       tt.fields.Provider,
+				OIDCIssuer:     tt.fields.OIDCIssuer,
+				OIDCClientID:   tt.fields.OIDCClientID,
+				OIDCAudience:   tt.fields.OIDCAudience,
+
+				OIDCDeviceAuthorizationEndpoint: tt.fields.OIDCDeviceAuthorizationEndpoint,
+				OIDCTokenEndpoint:               tt.fields.OIDCTokenEndpoint,
+
+				ProviderCommand: tt.fields.ProviderCommand,
+
+				SSOStartURL:  tt.fields.SSOStartURL,
+				SSORegion:    tt.fields.SSORegion,
+				SSOAccountID: tt.fields.SSOAccountID,
+				SSORoleName:  tt.fields.SSORoleName,
+
+				RetryMaxAttempts: tt.fields.RetryMaxAttempts,
+				RetryBaseDelay:   tt.fields.RetryBaseDelay,
+				RetryMaxDelay:    tt.fields.RetryMaxDelay,
+
+				HTTPSProxy:        tt.fields.HTTPSProxy,
+				ClientCertificate: tt.fields.ClientCertificate,
+				ClientKey:         tt.fields.ClientKey,
 			}
 
 			if err := c.Validate(); (err != nil) != tt.wantErr {
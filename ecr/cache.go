@@ -0,0 +1,81 @@
+package ecr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	CacheDirectory = ".fox-tech"
+	CacheFileName  = "ecr-cache.json"
+
+	// expirationMargin is how long before their expiration cached credentials
+	// are renewed, so docker never gets credentials about to expire.
+	expirationMargin = 5 * time.Minute
+)
+
+var (
+	ErrCacheFailed = errors.New("ecr credentials cache failed")
+
+	timeNow = time.Now
+)
+
+// fileSystemManager defines the methods that the cache needs a file system
+// manager to have
+type fileSystemManager interface {
+	ReadCacheFile(dir, filename string) ([]byte, error)
+	WriteCacheFile(dir, filename string, data []byte) error
+}
+
+// cache stores the registry credentials in a file by registry host.
+type cache struct {
+	fs fileSystemManager
+}
+
+// read returns the cached credentials by registry host, none when there's no
+// cache file.
+func (c cache) read() (map[string]Credentials, error) {
+	entries := map[string]Credentials{}
+
+	data, err := c.fs.ReadCacheFile(CacheDirectory, CacheFileName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCacheFailed, err)
+	}
+
+	if len(data) == 0 {
+		return entries, nil
+	}
+
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCacheFailed, err)
+	}
+
+	return entries, nil
+}
+
+// write saves the credentials by registry host, dropping the expired ones.
+func (c cache) write(entries map[string]Credentials) error {
+	for host, cred := range entries {
+		if !cred.valid() {
+			delete(entries, host)
+		}
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCacheFailed, err)
+	}
+
+	if err := c.fs.WriteCacheFile(CacheDirectory, CacheFileName, data); err != nil {
+		return fmt.Errorf("%w: %v", ErrCacheFailed, err)
+	}
+
+	return nil
+}
+
+// valid returns whether the credentials can still be used.
+func (c Credentials) valid() bool {
+	return timeNow().Add(expirationMargin).Before(c.ExpiresAt)
+}
@@ -0,0 +1,235 @@
+// Package ecr implements the docker credential helper protocol for Amazon ECR
+// registries.
+//
+// More at https://github.com/docker/docker-credential-helpers.
+package ecr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/fsmanager"
+)
+
+const (
+	ActionGet   = "get"
+	ActionStore = "store"
+	ActionErase = "erase"
+	ActionList  = "list"
+
+	// notFoundMessage is the message docker expects when a helper has no
+	// credentials for a registry.
+	notFoundMessage = "credentials not found in native keychain"
+)
+
+var (
+	ErrCredentialsNotFound = errors.New(notFoundMessage)
+	ErrNotECRRegistry      = errors.New("not an ECR registry")
+	ErrUnknownAction       = errors.New("unknown credential helper action")
+	ErrInvalidInput        = errors.New("invalid credential helper input")
+
+	registryRe = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+)
+
+// Registry represents an ECR registry host and the account and region it
+// belongs to.
+type Registry struct {
+	Host    string
+	Account string
+	Region  string
+}
+
+// Credentials represents the username and password to log in to a registry
+// and the time they stop being valid.
+type Credentials struct {
+	Username  string    `json:"username"`
+	Secret    string    `json:"secret"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Source is the interface that wraps the Credentials method.
+//
+// Credentials returns new credentials for the given registry. It's only called
+// when there are no valid cached credentials for the registry.
+type Source interface {
+	Credentials(reg Registry) (Credentials, error)
+}
+
+// dockerCredentials represents the payload exchanged with docker.
+type dockerCredentials struct {
+	ServerURL string
+	Username  string
+	Secret    string
+}
+
+// Helper implements the get, store, erase and list actions of the docker
+// credential helper protocol, caching the credentials until they expire.
+type Helper struct {
+	source Source
+	cache  cache
+}
+
+// New returns a Helper getting new credentials from the given source.
+func New(source Source, opts ...Option) Helper {
+	h := Helper{
+		source: source,
+		cache:  cache{fs: fsmanager.NewDefault()},
+	}
+
+	for _, opt := range opts {
+		opt(&h)
+	}
+
+	return h
+}
+
+// ParseRegistry returns the ECR registry of a docker server URL. The URL can
+// be a bare host name or include scheme and path. Returns ErrNotECRRegistry if
+// the host is not an ECR registry.
+func ParseRegistry(serverURL string) (Registry, error) {
+	host := strings.TrimSpace(serverURL)
+	if strings.Contains(host, "://") {
+		u, err := url.Parse(host)
+		if err != nil {
+			return Registry{}, fmt.Errorf("%w: %v", ErrNotECRRegistry, err)
+		}
+		host = u.Host
+	}
+	host = strings.SplitN(host, "/", 2)[0]
+
+	m := registryRe.FindStringSubmatch(host)
+	if m == nil {
+		return Registry{}, fmt.Errorf("%w: %s", ErrNotECRRegistry, host)
+	}
+
+	return Registry{Host: host, Account: m[1], Region: m[2]}, nil
+}
+
+// Execute runs the given action reading its input from in and writing its
+// output to out, as docker expects from credential helpers.
+func (h Helper) Execute(action string, in io.Reader, out io.Writer) error {
+	switch action {
+	case ActionGet:
+		return h.get(in, out)
+	case ActionStore:
+		return h.store(in)
+	case ActionErase:
+		return h.erase(in)
+	case ActionList:
+		return h.list(out)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownAction, action)
+	}
+}
+
+// get writes the credentials for the server URL read from in.
+func (h Helper) get(in io.Reader, out io.Writer) error {
+	serverURL, err := readServerURL(in)
+	if err != nil {
+		return err
+	}
+
+	reg, err := ParseRegistry(serverURL)
+	if err != nil {
+		fmt.Fprintln(out, notFoundMessage)
+		return fmt.Errorf("%w: %v", ErrCredentialsNotFound, err)
+	}
+
+	entries, err := h.cache.read()
+	if err != nil {
+		return err
+	}
+
+	cred, ok := entries[reg.Host]
+	if !ok || !cred.valid() {
+		if cred, err = h.source.Credentials(reg); err != nil {
+			return err
+		}
+
+		entries[reg.Host] = cred
+		if err := h.cache.write(entries); err != nil {
+			return err
+		}
+	}
+
+	return json.NewEncoder(out).Encode(dockerCredentials{
+		ServerURL: serverURL,
+		Username:  cred.Username,
+		Secret:    cred.Secret,
+	})
+}
+
+// store accepts the credentials docker sends after a manual login. ECR
+// credentials are always generated from the profile, so they are discarded.
+func (h Helper) store(in io.Reader) error {
+	var dc dockerCredentials
+	if err := json.NewDecoder(in).Decode(&dc); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	return nil
+}
+
+// erase removes the cached credentials for the server URL read from in.
+func (h Helper) erase(in io.Reader) error {
+	serverURL, err := readServerURL(in)
+	if err != nil {
+		return err
+	}
+
+	reg, err := ParseRegistry(serverURL)
+	if err != nil {
+		return nil
+	}
+
+	entries, err := h.cache.read()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := entries[reg.Host]; !ok {
+		return nil
+	}
+
+	delete(entries, reg.Host)
+	return h.cache.write(entries)
+}
+
+// list writes the registries with valid cached credentials and their
+// usernames.
+func (h Helper) list(out io.Writer) error {
+	entries, err := h.cache.read()
+	if err != nil {
+		return err
+	}
+
+	res := map[string]string{}
+	for host, cred := range entries {
+		if cred.valid() {
+			res[host] = cred.Username
+		}
+	}
+
+	return json.NewEncoder(out).Encode(res)
+}
+
+// readServerURL reads the server URL docker sends for get and erase.
+func readServerURL(in io.Reader) (string, error) {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	serverURL := strings.TrimSpace(string(data))
+	if serverURL == "" {
+		return "", fmt.Errorf("%w: missing server URL", ErrInvalidInput)
+	}
+
+	return serverURL, nil
+}
@@ -0,0 +1,194 @@
+package ecr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/fsmanager"
+)
+
+type mockSource struct {
+	calls int
+	cred  Credentials
+	err   error
+}
+
+func (m *mockSource) Credentials(reg Registry) (Credentials, error) {
+	m.calls++
+	return m.cred, m.err
+}
+
+func TestParseRegistry(t *testing.T) {
+	tests := []struct {
+		name   string
+		arg    string
+		expect Registry
+		err    error
+	}{
+		{
+			name:   "bare host",
+			arg:    "123456789012.dkr.ecr.us-east-1.amazonaws.com",
+			expect: Registry{Host: "123456789012.dkr.ecr.us-east-1.amazonaws.com", Account: "123456789012", Region: "us-east-1"},
+		},
+		{
+			name:   "url with scheme and path",
+			arg:    "https://123456789012.dkr.ecr.eu-west-2.amazonaws.com/v2/",
+			expect: Registry{Host: "123456789012.dkr.ecr.eu-west-2.amazonaws.com", Account: "123456789012", Region: "eu-west-2"},
+		},
+		{
+			name:   "fips host",
+			arg:    "123456789012.dkr.ecr-fips.us-gov-west-1.amazonaws.com\n",
+			expect: Registry{Host: "123456789012.dkr.ecr-fips.us-gov-west-1.amazonaws.com", Account: "123456789012", Region: "us-gov-west-1"},
+		},
+		{
+			name: "not an ECR registry",
+			arg:  "https://index.docker.io/v1/",
+			err:  ErrNotECRRegistry,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, err := ParseRegistry(tt.arg)
+
+			if !errors.Is(err, tt.err) {
+				t.Errorf("ParseRegistry() expected error: %v, got: %v", tt.err, err)
+			}
+
+			if reg != tt.expect {
+				t.Errorf("ParseRegistry() expected: %v, got: %v", tt.expect, reg)
+			}
+		})
+	}
+}
+
+func TestHelperExecute(t *testing.T) {
+	now := time.Date(2022, 6, 7, 10, 0, 0, 0, time.UTC)
+	prevNow := timeNow
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = prevNow }()
+
+	host := "123456789012.dkr.ecr.us-east-1.amazonaws.com"
+	cachePath := filepath.Join(CacheDirectory, CacheFileName)
+
+	valid := Credentials{Username: "AWS", Secret: "cached", ExpiresAt: now.Add(time.Hour)}
+	expired := Credentials{Username: "AWS", Secret: "expired", ExpiresAt: now.Add(time.Minute)}
+	fresh := Credentials{Username: "AWS", Secret: "fresh", ExpiresAt: now.Add(12 * time.Hour)}
+
+	cacheWith := func(c Credentials) []byte {
+		data, _ := json.Marshal(map[string]Credentials{host: c})
+		return data
+	}
+
+	tests := []struct {
+		name        string
+		action      string
+		input       string
+		cache       []byte
+		sourceErr   error
+		expectOut   string
+		expectCalls int
+		err         error
+	}{
+		{
+			name:        "get without cache: credentials from source",
+			action:      ActionGet,
+			input:       host,
+			expectOut:   `{"ServerURL":"` + host + `","Username":"AWS","Secret":"fresh"}`,
+			expectCalls: 1,
+		},
+		{
+			name:        "get with valid cache: cached credentials",
+			action:      ActionGet,
+			input:       host,
+			cache:       cacheWith(valid),
+			expectOut:   `{"ServerURL":"` + host + `","Username":"AWS","Secret":"cached"}`,
+			expectCalls: 0,
+		},
+		{
+			name:        "get with credentials about to expire: credentials from source",
+			action:      ActionGet,
+			input:       host,
+			cache:       cacheWith(expired),
+			expectOut:   `{"ServerURL":"` + host + `","Username":"AWS","Secret":"fresh"}`,
+			expectCalls: 1,
+		},
+		{
+			name:      "get for other registry: not found",
+			action:    ActionGet,
+			input:     "index.docker.io",
+			expectOut: notFoundMessage,
+			err:       ErrCredentialsNotFound,
+		},
+		{
+			name:        "get with source error: error is returned",
+			action:      ActionGet,
+			input:       host,
+			sourceErr:   errors.New("no profile"),
+			expectCalls: 1,
+			err:         errors.New("no profile"),
+		},
+		{
+			name:      "list: valid cached registries",
+			action:    ActionList,
+			cache:     cacheWith(valid),
+			expectOut: `{"` + host + `":"AWS"}`,
+		},
+		{
+			name:   "erase: cached credentials are removed",
+			action: ActionErase,
+			input:  host,
+			cache:  cacheWith(valid),
+		},
+		{
+			name:   "store: credentials are accepted",
+			action: ActionStore,
+			input:  `{"ServerURL":"` + host + `","Username":"AWS","Secret":"manual"}`,
+		},
+		{
+			name:   "unknown action: error is returned",
+			action: "delete",
+			err:    ErrUnknownAction,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := fsmanager.NewMock()
+			if tt.cache != nil {
+				fs.Files[cachePath] = tt.cache
+			}
+
+			src := &mockSource{cred: fresh, err: tt.sourceErr}
+			h := New(src, setFileManager(fs))
+
+			out := new(bytes.Buffer)
+			err := h.Execute(tt.action, strings.NewReader(tt.input), out)
+
+			if tt.err != nil && (err == nil || !strings.Contains(err.Error(), tt.err.Error())) {
+				t.Fatalf("Execute() expected error: %v, got: %v", tt.err, err)
+			}
+
+			if tt.err == nil && err != nil {
+				t.Fatalf("Execute() unexpected error: %v", err)
+			}
+
+			if got := strings.TrimSpace(out.String()); got != tt.expectOut {
+				t.Errorf("Execute() expected output: %s, got: %s", tt.expectOut, got)
+			}
+
+			if src.calls != tt.expectCalls {
+				t.Errorf("Execute() expected %d source calls, got: %d", tt.expectCalls, src.calls)
+			}
+
+			if tt.action == ActionErase && strings.Contains(string(fs.Files[cachePath]), host) {
+				t.Errorf("Execute() expected %s to be erased from cache, got: %s", host, fs.Files[cachePath])
+			}
+		})
+	}
+}
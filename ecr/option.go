@@ -0,0 +1,13 @@
+package ecr
+
+// Option represents an optional configuration value passed to the helper
+// object to change the default value set during initialization.
+type Option func(*Helper)
+
+// setFileManager returns a function to assign the passed fileSystemManager
+// to the helper cache.
+func setFileManager(fm fileSystemManager) Option {
+	return func(h *Helper) {
+		h.cache.fs = fm
+	}
+}
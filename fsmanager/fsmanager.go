@@ -69,6 +69,71 @@ func (defaultFileSystemManager) ReadFile(dir, filename string) ([]byte, error) {
 	return data, nil
 }
 
+// ReadCacheFile reads the given filename of the cache directory dir, relative
+// to the home directory. A missing file is a cache miss, no data and no error
+// are returned and nothing is created.
+func (defaultFileSystemManager) ReadCacheFile(dir, filename string) ([]byte, error) {
+	hd, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to get home dir: %v", ErrCouldNotReadFile, err)
+	}
+
+	fp := filepath.Join(hd, dir, filename)
+	data, err := os.ReadFile(fp)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("%w: failed to read file %s: %v", ErrCouldNotReadFile, fp, err)
+	}
+
+	return data, nil
+}
+
+// WriteCacheFile writes data to the given filename of the cache directory dir,
+// relative to the home directory, with WriteFile. The directory is created
+// when it doesn't exist, only the user can read it as caches hold tokens.
+func (m defaultFileSystemManager) WriteCacheFile(dir, filename string, data []byte) error {
+	hd, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("%w: failed to get home dir: %v", ErrCouldNotWriteFile, err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(hd, dir), 0700); err != nil {
+		return fmt.Errorf("%w: failed to create dir: %v", ErrCouldNotWriteFile, err)
+	}
+
+	return m.WriteFile(filepath.Join(hd, dir, filename), data)
+}
+
+// WriteFile writes data to a temporary file next to name and then renames it
+// to name, so the file is never left half-written if the process is stopped
 func (defaultFileSystemManager) WriteFile(name string, data []byte) error {
-	return os.WriteFile(name, data, 0600)
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("%w: failed to create temporary file: %v", ErrCouldNotWriteFile, err)
+	}
+	// the temporary file is only left if the rename failed
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("%w: failed to write temporary file: %v", ErrCouldNotWriteFile, err)
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("%w: failed to sync temporary file: %v", ErrCouldNotWriteFile, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("%w: failed to close temporary file: %v", ErrCouldNotWriteFile, err)
+	}
+
+	if err := os.Rename(f.Name(), name); err != nil {
+		return fmt.Errorf("%w: failed to replace %s: %v", ErrCouldNotWriteFile, name, err)
+	}
+
+	return nil
 }
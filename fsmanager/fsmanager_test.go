@@ -90,3 +90,93 @@ func TestReadFile(t *testing.T) {
 	os.RemoveAll(tempExistingDir)
 	os.RemoveAll("tempTestDir")
 }
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name   string
+		file   string
+		data   []byte
+		expect error
+	}{
+		{
+			name: "new file: data is written",
+			file: filepath.Join(dir, "credentials"),
+			data: []byte("new credentials"),
+		},
+		{
+			name: "existing file: data is replaced",
+			file: filepath.Join(dir, "credentials"),
+			data: []byte("replaced"),
+		},
+		{
+			name:   "missing directory: error is returned",
+			file:   filepath.Join(dir, "missing", "credentials"),
+			data:   []byte("new credentials"),
+			expect: ErrCouldNotWriteFile,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewDefault().WriteFile(tt.file, tt.data)
+
+			if !errors.Is(err, tt.expect) {
+				t.Fatalf("WriteFile() expected error: %v, got: %v", tt.expect, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			data, _ := os.ReadFile(tt.file)
+			if !bytes.Equal(data, tt.data) {
+				t.Errorf("WriteFile() expected data: %s, got: %s", tt.data, data)
+			}
+
+			info, _ := os.Stat(tt.file)
+			if info.Mode().Perm() != 0600 {
+				t.Errorf("WriteFile() expected mode: 0600, got: %v", info.Mode().Perm())
+			}
+
+			// no temporary files are left
+			entries, _ := os.ReadDir(dir)
+			for _, e := range entries {
+				if e.Name() != "credentials" && !e.IsDir() {
+					t.Errorf("WriteFile() left file: %s", e.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestCacheFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	dfs := NewDefault()
+
+	data, err := dfs.ReadCacheFile(".cache", "tokens.json")
+	if err != nil || data != nil {
+		t.Fatalf("ReadCacheFile() expected a miss without error, got data: %s, error: %v", data, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(home, ".cache")); !os.IsNotExist(err) {
+		t.Errorf("ReadCacheFile() expected no directory created, got: %v", err)
+	}
+
+	if err := dfs.WriteCacheFile(".cache", "tokens.json", []byte("tokens")); err != nil {
+		t.Fatalf("WriteCacheFile() unexpected error: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(home, ".cache"))
+	if err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("WriteCacheFile() expected directory with mode: 0700, got: %v, error: %v", info, err)
+	}
+
+	data, err = dfs.ReadCacheFile(".cache", "tokens.json")
+	if err != nil || string(data) != "tokens" {
+		t.Errorf("ReadCacheFile() expected data: tokens, got: %s, error: %v", data, err)
+	}
+}
@@ -37,3 +37,15 @@ func (m MockFileSystem) WriteFile(name string, data []byte) error {
 	m.Files[name] = data
 	return nil
 }
+
+func (m MockFileSystem) ReadCacheFile(dir, filename string) ([]byte, error) {
+	if m.ReadErr != nil {
+		return nil, m.ReadErr
+	}
+
+	return m.Files[path.Join(dir, filename)], nil
+}
+
+func (m MockFileSystem) WriteCacheFile(dir, filename string, data []byte) error {
+	return m.WriteFile(path.Join(dir, filename), data)
+}
@@ -5,4 +5,11 @@ go 1.18
 require (
 	github.com/BurntSushi/toml v1.1.0
 	golang.org/x/net v0.7.0
+	golang.org/x/term v0.5.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	golang.org/x/sys v0.5.0 // indirect
+	golang.org/x/text v0.7.0 // indirect
 )
@@ -0,0 +1,128 @@
+// Package idp defines the identity providers users log in with to get AWS
+// credentials, independently of the provider, and a registry of them by the
+// name set in the provider field of the profiles.
+package idp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/aws"
+	"github.com/fox-tech/creds-fetcher/client"
+	cfg "github.com/fox-tech/creds-fetcher/configuration"
+)
+
+var (
+	// ErrUnknownProvider is returned by New when no provider is registered
+	// with the name.
+	ErrUnknownProvider = errors.New("unknown identity provider")
+
+	// ErrNoCredential is returned when a login completed without a SAML
+	// assertion, web identity token or AWS credentials.
+	ErrNoCredential = errors.New("login returned no credential")
+
+	// ErrLoginNotStarted is returned by Wait when the login was not started
+	// by the same provider.
+	ErrLoginNotStarted = errors.New("login not started by the provider")
+)
+
+// Provider is the interface of the identity providers.
+//
+// Start starts the login of the user and returns what the user must do to
+// complete it, e.g. open a URL. Logins with no user interaction return an
+// empty Login.
+//
+// Wait waits for the started login to complete and returns the credential it
+// produced. Both stop when ctx is done, returning its error.
+type Provider interface {
+	Start(ctx context.Context) (Login, error)
+	Wait(ctx context.Context, login Login) (Credential, error)
+}
+
+// Login is a login started by a provider. The user must open URL, when it's
+// set, and confirm UserCode in it, when it's set, before ExpiresAt. State is
+// kept by the provider between Start and Wait.
+type Login struct {
+	URL       string
+	UserCode  string
+	ExpiresAt time.Time
+
+	State interface{}
+}
+
+// Credential is what a login produced to get AWS credentials with, the SAML
+// assertion of the AWS app or the web identity token of the user, or the AWS
+// credentials themselves, which are stored without requesting STS.
+type Credential struct {
+	SAMLAssertion    string
+	WebIdentityToken string
+	AWSCredentials   *aws.Credentials
+}
+
+// Options are the values shared by the providers of a command.
+type Options struct {
+	// Profile is the name of the AWS profile, e.g. to cache tokens by it
+	Profile string
+	// HTTPClient is the client of the profile for the requests of the
+	// provider
+	HTTPClient client.HTTPClient
+	// In and Out are where providers read the answers of the user, e.g. a
+	// password, and write their prompts
+	In  io.Reader
+	Out io.Writer
+}
+
+// Factory returns the provider for the profile configuration.
+type Factory func(config *cfg.Configuration, opts Options) (Provider, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{}
+)
+
+// Register makes the provider of the factory available with the name, usually
+// from the init function of the package implementing it. It panics if the
+// name is registered twice.
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := factories[name]; ok {
+		panic("idp: provider registered twice: " + name)
+	}
+
+	factories[name] = f
+}
+
+// New returns the provider registered with the name for the profile
+// configuration.
+func New(name string, config *cfg.Configuration, opts Options) (Provider, error) {
+	mu.RLock()
+	f, ok := factories[name]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, name)
+	}
+
+	return f(config, opts)
+}
+
+// Names returns the sorted names of the registered providers.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
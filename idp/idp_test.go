@@ -0,0 +1,75 @@
+package idp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	cfg "github.com/fox-tech/creds-fetcher/configuration"
+)
+
+type testProvider struct {
+	profile string
+}
+
+func (p testProvider) Start(ctx context.Context) (Login, error) {
+	return Login{URL: "https://idp.example.com/login"}, nil
+}
+
+func (p testProvider) Wait(ctx context.Context, login Login) (Credential, error) {
+	return Credential{WebIdentityToken: p.profile}, nil
+}
+
+func TestRegistry(t *testing.T) {
+	Register("test", func(config *cfg.Configuration, opts Options) (Provider, error) {
+		return testProvider{profile: opts.Profile}, nil
+	})
+	defer func() {
+		mu.Lock()
+		delete(factories, "test")
+		mu.Unlock()
+	}()
+
+	tests := []struct {
+		name      string
+		provider  string
+		expect    Provider
+		expectErr error
+	}{
+		{
+			name:     "registered provider: it's created",
+			provider: "test",
+			expect:   testProvider{profile: "dev"},
+		},
+		{
+			name:      "unknown provider: error is returned",
+			provider:  "other",
+			expectErr: ErrUnknownProvider,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.provider, &cfg.Configuration{}, Options{Profile: "dev"})
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("New() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if !reflect.DeepEqual(p, tt.expect) {
+				t.Errorf("New() expected provider: %v, got: %v", tt.expect, p)
+			}
+		})
+	}
+
+	if names := Names(); !reflect.DeepEqual(names, []string{"test"}) {
+		t.Errorf("Names() expected: %q, got: %q", []string{"test"}, names)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Register() expected panic for a provider registered twice")
+		}
+	}()
+	Register("test", nil)
+}
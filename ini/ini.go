@@ -28,7 +28,7 @@ func Unmarshal(data []byte, v interface{}) error {
 		return fmt.Errorf("%w: value to unmarshall is not map", ErrUnsupportedType)
 	}
 
-	nameRe := regexp.MustCompile("\\[[[:graph:]]+\\]")
+	nameRe := regexp.MustCompile("\\[[[:blank:]]*[[:graph:]]+[[:blank:]]*\\]")
 
 	vt := reflect.TypeOf(v).Elem()
 	kt := reflect.TypeOf(v).Key()
@@ -79,22 +79,32 @@ func Marshal(v interface{}) ([]byte, error) {
 }
 
 // parseName transforms bytes into a string name, it expects name to be in
-// the format [name]
+// the format [name], spaces around the name are ignored
 func parseName(data []byte, v reflect.Value) error {
 	if v.Elem().Kind() != reflect.String {
 		return fmt.Errorf("%w: expected name to be string, but is %s", ErrUnsupportedType, v.Elem().Kind().String())
 	}
 
-	l := len(data)
-	if l < 3 || string(data[0]) != "[" || string(data[l-1]) != "]" {
+	s, ok := sectionName(string(data))
+	if !ok {
 		return fmt.Errorf("%w: name should be enclosed in []", ErrInvalidContent)
 	}
-	s := string(data[1 : l-1])
 
 	v.Elem().SetString(s)
 	return nil
 }
 
+// sectionName returns the name of the section header, without the spaces
+// around it, and whether it is a header, e.g. [name] or [ name ]
+func sectionName(header string) (string, bool) {
+	l := len(header)
+	if l < 3 || header[0] != '[' || header[l-1] != ']' {
+		return "", false
+	}
+
+	return strings.TrimSpace(header[1 : l-1]), true
+}
+
 // parseAttributes takes a bytes array, reads the fields in them and assigns
 // those to the passed value.
 // currently in only supports assigning to struct
@@ -113,7 +123,7 @@ func parseAttributes(data []byte, v reflect.Value) error {
 
 	for i := 0; i < lf; i++ {
 		f := t.Field(i)
-		tag := f.Tag.Get(reflectTag)
+		tag, _ := parseTag(f)
 		if tag == "" {
 			return fmt.Errorf("%w: %s", ErrMissingTag, f.Name)
 		}
@@ -194,6 +204,7 @@ func createSplit(idx [][]int, totalBytes int) func(data []byte, atEOF bool) (adv
 // k currently only supports string and will be written as: [k]
 // v currently only supports struct and will be written as lines of:
 // field_tag = value
+// fields tagged with omitempty are not written when empty
 func write(w io.Writer, k reflect.Value, v reflect.Value) error {
 	if k.Kind() != reflect.String {
 		return fmt.Errorf("%w: name is not a string", ErrUnsupportedType)
@@ -214,11 +225,15 @@ func write(w io.Writer, k reflect.Value, v reflect.Value) error {
 
 	for i := 0; i < lf; i++ {
 		f := t.Field(i)
-		tag := f.Tag.Get("ini")
+		tag, omitEmpty := parseTag(f)
 		if tag == "" {
 			return fmt.Errorf("%w: %s", ErrMissingTag, f.Name)
 		}
 
+		if omitEmpty && v.Field(i).String() == "" {
+			continue
+		}
+
 		vs := fmt.Sprintf("%s = %s\n", tag, v.Field(i).String())
 		w.Write([]byte(vs))
 	}
@@ -227,3 +242,47 @@ func write(w io.Writer, k reflect.Value, v reflect.Value) error {
 
 	return nil
 }
+
+// parseTag returns the field name from the ini tag of the struct field and
+// whether the omitempty option is set, in which case the field is not written
+// when empty. e.g. `ini:"name,omitempty"`
+func parseTag(f reflect.StructField) (string, bool) {
+	parts := strings.Split(f.Tag.Get(reflectTag), ",")
+
+	omitEmpty := false
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+
+	return parts[0], omitEmpty
+}
+
+// RemoveSection returns data without the section [name], from its name up to
+// the next section, and whether it was found. Headers are matched like
+// Unmarshal reads them, ignoring the spaces around the name. The rest of data
+// is returned unchanged, byte for byte, so other sections keep their order and
+// format.
+func RemoveSection(data []byte, name string) ([]byte, bool) {
+	out := make([]byte, 0, len(data))
+	found, skip := false, false
+	for len(data) > 0 {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line = data[:i+1]
+		}
+		data = data[len(line):]
+
+		if section, ok := sectionName(string(bytes.TrimSpace(line))); ok {
+			skip = section == name
+			found = found || skip
+		}
+
+		if !skip {
+			out = append(out, line...)
+		}
+	}
+
+	return out, found
+}
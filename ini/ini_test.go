@@ -18,6 +18,11 @@ type noTagStruct struct {
 	Fish string
 }
 
+type omitEmptyStruct struct {
+	Fox  string `ini:"fox"`
+	Bird string `ini:"bird,omitempty"`
+}
+
 const testDataFull = "myFirstPet"
 const testDataEmptyField = "mySecondPet"
 
@@ -162,6 +167,30 @@ func TestWriteData(t *testing.T) {
 				err:  ErrUnsupportedType,
 			},
 		},
+		{
+			name: "send data with empty omitempty field: should not write it",
+			args: args{
+				w: bytes.NewBuffer([]byte{}),
+				k: reflect.ValueOf("myPet"),
+				v: reflect.ValueOf(omitEmptyStruct{Fox: "quick"}),
+			},
+			expect: expect{
+				data: []byte("[myPet]\nfox = quick\n\n"),
+				err:  nil,
+			},
+		},
+		{
+			name: "send data with set omitempty field: should write it",
+			args: args{
+				w: bytes.NewBuffer([]byte{}),
+				k: reflect.ValueOf("myPet"),
+				v: reflect.ValueOf(omitEmptyStruct{Fox: "quick", Bird: "tweet"}),
+			},
+			expect: expect{
+				data: []byte("[myPet]\nfox = quick\nbird = tweet\n\n"),
+				err:  nil,
+			},
+		},
 		{
 			name: "send data with field without tag: should return error",
 			args: args{
@@ -431,6 +460,17 @@ func TestParseAttributes(t *testing.T) {
 				err: ErrInvalidContent,
 			},
 		},
+		{
+			name: "send data with omitempty tag: attributes are loaded into value",
+			args: args{
+				data: []byte("\nfox = quick\nbird = tweet\n\n"),
+				v:    reflect.New(reflect.TypeOf(omitEmptyStruct{})).Elem(),
+			},
+			expect: expect{
+				v:   reflect.ValueOf(omitEmptyStruct{Fox: "quick", Bird: "tweet"}),
+				err: nil,
+			},
+		},
 		{
 			name: "send struct without tag: error is returned",
 			args: args{
@@ -500,6 +540,17 @@ func TestUnmarshall(t *testing.T) {
 				err:  nil,
 			},
 		},
+		{
+			name: "send data with spaces around the name: name is trimmed",
+			args: args{
+				data: []byte("[ myFirstPet ]\n"),
+				v:    map[string]testStruct{},
+			},
+			expect: expect{
+				data: map[string]testStruct{"myFirstPet": {}},
+				err:  nil,
+			},
+		},
 		{
 			name: "send unsupported data: error is returned",
 			args: args{
@@ -599,3 +650,68 @@ func TestCreateSplit(t *testing.T) {
 		}
 	}
 }
+
+func TestRemoveSection(t *testing.T) {
+	data := []byte("[first]\nfox = the lazy fox\n\n[second]\r\nfox   =  the not lazy fox\r\n\r\n[third]\ndog = woof\n")
+
+	tests := []struct {
+		name        string
+		data        []byte
+		section     string
+		expect      []byte
+		expectFound bool
+	}{
+		{
+			name:        "first section",
+			section:     "first",
+			expect:      []byte("[second]\r\nfox   =  the not lazy fox\r\n\r\n[third]\ndog = woof\n"),
+			expectFound: true,
+		},
+		{
+			name:        "section between others keeps their format",
+			section:     "second",
+			expect:      []byte("[first]\nfox = the lazy fox\n\n[third]\ndog = woof\n"),
+			expectFound: true,
+		},
+		{
+			name:        "last section",
+			section:     "third",
+			expect:      []byte("[first]\nfox = the lazy fox\n\n[second]\r\nfox   =  the not lazy fox\r\n\r\n"),
+			expectFound: true,
+		},
+		{
+			name:    "missing section: data is unchanged",
+			section: "fourth",
+			expect:  data,
+		},
+		{
+			name:    "name is matched exactly",
+			section: "fir",
+			expect:  data,
+		},
+		{
+			name:        "spaces around the name",
+			data:        []byte("[first]\nfox = the lazy fox\n\n[ second ]\nfox = the not lazy fox\n"),
+			section:     "second",
+			expect:      []byte("[first]\nfox = the lazy fox\n\n"),
+			expectFound: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.data == nil {
+				tt.data = data
+			}
+
+			got, found := RemoveSection(tt.data, tt.section)
+			if found != tt.expectFound {
+				t.Errorf("RemoveSection() expected found: %t, got: %t", tt.expectFound, found)
+			}
+
+			if !bytes.Equal(got, tt.expect) {
+				t.Errorf("RemoveSection() expected: %q, got: %q", tt.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/client"
+)
+
+var (
+	// defaultInterval is the polling interval when the server sets none.
+	defaultInterval = 5 * time.Second
+
+	// SlowDownIncrease is added to the polling interval on each slow_down
+	// error. It's a variable so tests don't wait for it.
+	SlowDownIncrease = 5 * time.Second
+)
+
+// DeviceAuthorization starts a login with the device authorization grant at
+// the endpoint in uri, sending the values, e.g. the scope, with the client
+// ID. The returned device authorization is shown to the user while its token
+// is polled.
+func (c Client) DeviceAuthorization(ctx context.Context, uri string, values url.Values) (Device, error) {
+	values.Set("client_id", c.ID)
+
+	resp, err := c.PostForm(ctx, uri, values)
+	if err != nil {
+		return Device{}, fmt.Errorf("%w: %v", ErrDeviceAuthorizationRequest, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Device{}, ErrorFromResponse(ErrDeviceAuthorizationRequest, resp)
+	}
+
+	var device Device
+	if err := json.NewDecoder(resp.Body).Decode(&device); err != nil {
+		return Device{}, fmt.Errorf("%w: %v", ErrJSONDecode, err)
+	}
+
+	return device, nil
+}
+
+// DeviceToken requests the tokens of the device code to the token endpoint in
+// uri, returning the device authorization error of the response, if any.
+func (c Client) DeviceToken(ctx context.Context, uri string, device Device) (Token, error) {
+	req, err := NewFormRequest(ctx, uri, url.Values{
+		"client_id":   []string{c.ID},
+		"device_code": []string{device.DeviceCode},
+		"grant_type":  []string{GrantTypeDeviceCode},
+	})
+	if err != nil {
+		return Token{}, fmt.Errorf("%w: %v", ErrTokenRequest, err)
+	}
+	// the device code is polled until it's used, a poll sent again after a
+	// 5xx error is at worst answered as pending or with the same token
+	client.SetIdempotent(req)
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return Token{}, fmt.Errorf("%w: %v", ErrTokenRequest, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errRes ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errRes); err != nil {
+			return Token{}, fmt.Errorf("%w: statusCode %d", ErrTokenRequest, resp.StatusCode)
+		}
+
+		if err := DeviceError(errRes.Error, errRes.Description); err != nil {
+			return Token{}, err
+		}
+
+		return Token{}, fmt.Errorf("%w: statusCode %d/%s: %s", ErrTokenRequest, resp.StatusCode, errRes.Error, errRes.Description)
+	}
+
+	var token Token
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return Token{}, fmt.Errorf("%w: %v", ErrJSONDecode, err)
+	}
+
+	return token, nil
+}
+
+// DeviceError returns the device authorization error of the error code, with
+// its description, or nil if it's another error.
+func DeviceError(code, description string) error {
+	switch code {
+	case "authorization_pending":
+		return ErrAuthorizationPending
+	case "slow_down":
+		return ErrSlowDown
+	case "access_denied":
+		return fmt.Errorf("%w: %s", ErrAccessDenied, description)
+	case "expired_token":
+		return fmt.Errorf("%w: %s", ErrExpiredToken, description)
+	}
+
+	return nil
+}
+
+// Poll calls poll every interval seconds, or a default interval when it's not
+// positive, while it returns ErrAuthorizationPending, increasing the interval
+// when it returns ErrSlowDown, and returns its result otherwise. It returns
+// ErrDeviceAuthorizationExpired after expiresIn seconds and the error of ctx
+// when it's done.
+func Poll(ctx context.Context, interval, expiresIn int64, poll func() error) error {
+	every := time.Duration(interval) * time.Second
+	if every <= 0 {
+		every = defaultInterval
+	}
+	tick := time.NewTicker(every)
+	defer tick.Stop()
+
+	timeout := time.After(time.Duration(expiresIn+1) * time.Second)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return ErrDeviceAuthorizationExpired
+		case <-tick.C:
+			err := poll()
+			switch {
+			case errors.Is(err, ErrAuthorizationPending):
+				continue
+			case errors.Is(err, ErrSlowDown):
+				every += SlowDownIncrease
+				tick.Reset(every)
+				continue
+			}
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPoll(t *testing.T) {
+	prevInterval, prevIncrease := defaultInterval, SlowDownIncrease
+	defaultInterval, SlowDownIncrease = time.Millisecond, 0
+	defer func() { defaultInterval, SlowDownIncrease = prevInterval, prevIncrease }()
+
+	errTest := errors.New("test error")
+
+	tests := []struct {
+		name      string
+		results   []error
+		expiresIn int64
+		expect    error
+		calls     int
+	}{
+		{
+			name:      "success after pending",
+			results:   []error{ErrAuthorizationPending, ErrAuthorizationPending, nil},
+			expiresIn: 10,
+			calls:     3,
+		},
+		{
+			name:      "success after slow down",
+			results:   []error{ErrSlowDown, nil},
+			expiresIn: 10,
+			calls:     2,
+		},
+		{
+			name:      "error stops polling",
+			results:   []error{ErrAuthorizationPending, errTest},
+			expiresIn: 10,
+			expect:    errTest,
+			calls:     2,
+		},
+		{
+			name:    "expired",
+			results: []error{ErrAuthorizationPending},
+			expect:  ErrDeviceAuthorizationExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			err := Poll(context.Background(), 0, tt.expiresIn, func() error {
+				calls++
+				if calls > len(tt.results) {
+					return ErrAuthorizationPending
+				}
+				return tt.results[calls-1]
+			})
+
+			if !errors.Is(err, tt.expect) {
+				t.Fatalf("Poll() expected error: %v, got: %v", tt.expect, err)
+			}
+			if tt.calls > 0 && calls != tt.calls {
+				t.Errorf("Poll() expected %d calls, got: %d", tt.calls, calls)
+			}
+		})
+	}
+}
+
+func TestDeviceError(t *testing.T) {
+	tests := []struct {
+		code   string
+		expect error
+	}{
+		{code: "authorization_pending", expect: ErrAuthorizationPending},
+		{code: "slow_down", expect: ErrSlowDown},
+		{code: "access_denied", expect: ErrAccessDenied},
+		{code: "expired_token", expect: ErrExpiredToken},
+		{code: "invalid_grant"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			err := DeviceError(tt.code, "description")
+			if tt.expect == nil {
+				if err != nil {
+					t.Fatalf("DeviceError() expected no error, got: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.expect) {
+				t.Fatalf("DeviceError() expected error: %v, got: %v", tt.expect, err)
+			}
+		})
+	}
+}
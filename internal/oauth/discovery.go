@@ -0,0 +1,33 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Discover decodes the OpenID configuration of the issuer into v. It returns
+// ErrNoOpenIDConfiguration when the issuer has none or it's not valid JSON.
+//
+// More at https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderConfig.
+func (c Client) Discover(ctx context.Context, issuer string, v interface{}) error {
+	uri := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	resp, err := c.Get(ctx, uri)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s: statusCode %d", ErrNoOpenIDConfiguration, uri, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrNoOpenIDConfiguration, uri, err)
+	}
+
+	return nil
+}
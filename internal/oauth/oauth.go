@@ -0,0 +1,102 @@
+// Package oauth implements the OAuth 2.0 requests shared by the clients of
+// the authorization servers: the device authorization grant of RFC 8628, with
+// the polling of its token, the token exchange of RFC 8693 and the OpenID
+// configuration discovery.
+package oauth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fox-tech/creds-fetcher/client"
+)
+
+const (
+	// GrantTypeDeviceCode and GrantTypeTokenExchange are the grant types of
+	// the token requests of the device authorization and the token exchange.
+	GrantTypeDeviceCode    = "urn:ietf:params:oauth:grant-type:device_code"
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+)
+
+var (
+	// ErrDiscovery is returned when the OpenID configuration of the issuer
+	// can't be requested.
+	ErrDiscovery = errors.New("openid configuration discovery")
+
+	// ErrNoOpenIDConfiguration is returned when the issuer has no valid
+	// OpenID configuration, it's an ErrDiscovery.
+	ErrNoOpenIDConfiguration = fmt.Errorf("%w: no valid configuration", ErrDiscovery)
+
+	// ErrDeviceAuthorizationRequest is returned when the device authorization
+	// request failed or the server responded with an error.
+	ErrDeviceAuthorizationRequest = errors.New("device authorization request")
+
+	// ErrDeviceAuthorizationExpired is returned when the device code expired
+	// while polling, the login must be started again.
+	ErrDeviceAuthorizationExpired = errors.New("device authorization expired")
+
+	// ErrTokenRequest is returned when the token request failed or the server
+	// responded with an error other than the device authorization errors.
+	ErrTokenRequest = errors.New("token request")
+
+	// Device authorization errors returned by the token endpoint while polling.
+	// ErrAuthorizationPending and ErrSlowDown are handled by Poll, which keeps
+	// polling, ErrAccessDenied is returned when the user denied the
+	// authorization and ErrExpiredToken when the device code expired.
+	//
+	// More at https://www.rfc-editor.org/rfc/rfc8628#section-3.5.
+	ErrAuthorizationPending = errors.New("authorization pending")
+	ErrSlowDown             = errors.New("slow down")
+	ErrAccessDenied         = errors.New("access denied")
+	ErrExpiredToken         = errors.New("device code expired")
+
+	// ErrTokenExchange is returned when the token exchange request failed or
+	// the server responded with an error.
+	ErrTokenExchange = errors.New("token exchange")
+
+	// ErrJSONDecode is returned when a response of the server can't be
+	// decoded.
+	ErrJSONDecode = errors.New("json decode")
+)
+
+// Client sends the requests of the public client ID to an authorization
+// server with HTTPClient, or a default client when it's nil.
+type Client struct {
+	ID         string
+	HTTPClient client.HTTPClient
+}
+
+// Device is the device authorization of a login, the user must open the
+// verification URI and confirm the user code in it. VerificationURIComplete,
+// which includes the user code, is optional. ExpiresIn and Interval are in
+// seconds, Interval is how long to wait between polls of the token endpoint.
+//
+// More at https://www.rfc-editor.org/rfc/rfc8628#section-3.2.
+type Device struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+// Token is a successful response of the token endpoint. The token issued by
+// token exchanges is in AccessToken, with its type in IssuedTokenType.
+type Token struct {
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+	AccessToken     string `json:"access_token"`
+	IDToken         string `json:"id_token"`
+	RefreshToken    string `json:"refresh_token"`
+	Scope           string `json:"scope"`
+	IssuedTokenType string `json:"issued_token_type"`
+}
+
+// ErrorResponse is an error of the authorization server.
+//
+// More at https://www.rfc-editor.org/rfc/rfc6749#section-5.2.
+type ErrorResponse struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
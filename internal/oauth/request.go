@@ -0,0 +1,71 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fox-tech/creds-fetcher/client"
+)
+
+// defaultHTTPClient is used by clients without HTTPClient.
+var defaultHTTPClient client.HTTPClient = client.NewDefault()
+
+// PostForm sends the values URL-encoded to uri, the request is cancelled when
+// ctx is done. It's not retried after 5xx errors, as the server may already
+// have used a one-time code or token of the values, requests that can be sent
+// again are built with NewFormRequest and marked with client.SetIdempotent.
+func (c Client) PostForm(ctx context.Context, uri string, values url.Values) (*http.Response, error) {
+	req, err := NewFormRequest(ctx, uri, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Do(req)
+}
+
+// NewFormRequest returns a POST request of the values URL-encoded to uri,
+// asking for a JSON response, which is cancelled when ctx is done.
+func NewFormRequest(ctx context.Context, uri string, values url.Values) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	return req, nil
+}
+
+// Get requests uri, the request is cancelled when ctx is done.
+func (c Client) Get(ctx context.Context, uri string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Do(req)
+}
+
+// Do sends the request with HTTPClient or the default client.
+func (c Client) Do(req *http.Request) (*http.Response, error) {
+	if c.HTTPClient == nil {
+		return defaultHTTPClient.Do(req)
+	}
+
+	return c.HTTPClient.Do(req)
+}
+
+// ErrorFromResponse returns err with the status and the error of the
+// unsuccessful response.
+func ErrorFromResponse(err error, resp *http.Response) error {
+	var errRes ErrorResponse
+	if decodeErr := json.NewDecoder(resp.Body).Decode(&errRes); decodeErr != nil {
+		return fmt.Errorf("%w: statusCode %d", err, resp.StatusCode)
+	}
+
+	return fmt.Errorf("%w: statusCode %d/%s: %s", err, resp.StatusCode, errRes.Error, errRes.Description)
+}
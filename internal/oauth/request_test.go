@@ -0,0 +1,43 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/fox-tech/creds-fetcher/client"
+)
+
+func TestClientIdempotentRequests(t *testing.T) {
+	idempotent := map[string]bool{}
+	c := Client{ID: "testid", HTTPClient: client.MockHandlerClient{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := r.Header["Idempotency-Key"]
+		idempotent[r.FormValue("grant_type")] = ok
+		w.Write([]byte(`{"access_token":"token"}`))
+	})}}
+
+	ctx := context.Background()
+	if _, err := c.DeviceToken(ctx, "https://idp.example.com/token", Device{DeviceCode: "device"}); err != nil {
+		t.Fatalf("DeviceToken() unexpected error: %v", err)
+	}
+	if _, err := c.ExchangeToken(ctx, "https://idp.example.com/token", url.Values{}); err != nil {
+		t.Fatalf("ExchangeToken() unexpected error: %v", err)
+	}
+	resp, err := c.PostForm(ctx, "https://idp.example.com/token", url.Values{"grant_type": []string{"authorization_code"}})
+	if err != nil {
+		t.Fatalf("PostForm() unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	expect := map[string]bool{
+		GrantTypeDeviceCode:    true,
+		GrantTypeTokenExchange: false,
+		"authorization_code":   false,
+	}
+	for grant, e := range expect {
+		if idempotent[grant] != e {
+			t.Errorf("expected %s request idempotent: %t, got: %t", grant, e, idempotent[grant])
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ExchangeToken sends a token exchange request with the values, e.g. the
+// subject token and the requested token type, and the client ID to the token
+// endpoint in uri. The issued token is in the AccessToken of the returned
+// Token.
+//
+// More at https://www.rfc-editor.org/rfc/rfc8693#section-2.1.
+func (c Client) ExchangeToken(ctx context.Context, uri string, values url.Values) (Token, error) {
+	values.Set("client_id", c.ID)
+	values.Set("grant_type", GrantTypeTokenExchange)
+
+	resp, err := c.PostForm(ctx, uri, values)
+	if err != nil {
+		return Token{}, fmt.Errorf("%w: %v", ErrTokenExchange, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Token{}, ErrorFromResponse(ErrTokenExchange, resp)
+	}
+
+	var issued Token
+	if err := json.NewDecoder(resp.Body).Decode(&issued); err != nil {
+		return Token{}, fmt.Errorf("%w: %v", ErrJSONDecode, err)
+	}
+
+	return issued, nil
+}
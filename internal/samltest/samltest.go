@@ -0,0 +1,17 @@
+// Package samltest provides SAML responses of a fake identity provider for
+// the tests of the packages receiving them.
+package samltest
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// StatusSuccess is the status code of successful SAML responses.
+const StatusSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+
+// Response returns a base64 encoded SAML response with the status code, e.g.
+// StatusSuccess, as posted to the ACS by identity providers.
+func Response(status string) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(`<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" ID="_response" Version="2.0"><samlp:Status><samlp:StatusCode Value=%q/></samlp:Status></samlp:Response>`, status)))
+}
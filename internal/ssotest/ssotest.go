@@ -0,0 +1,155 @@
+// Package ssotest provides a fake of the SSO OIDC service and the SSO portal
+// of AWS IAM Identity Center for the tests of the packages logging in to it.
+package ssotest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Values issued by the Server.
+const (
+	AccessToken     = "standin-access-token"
+	AccessKeyID     = "STANDINACCESSKEYID"
+	SecretAccessKey = "standin/secret/access/key"
+	SessionToken    = "standinsessiontoken"
+	// Expiration is the expiration of the credentials, 2030-01-01, in
+	// milliseconds since the epoch
+	Expiration = 1893456000000
+
+	// ClientName is the name the clients must register with.
+	ClientName = "creds-fetcher"
+)
+
+// Server is a local stand-in of the SSO OIDC service and the SSO portal,
+// both served by the same handler. Logins are approved after Pending
+// token requests, or denied when Deny is true, and get the credentials of the
+// role RoleName in AccountID.
+type Server struct {
+	AccountID string
+	RoleName  string
+	Pending   int
+	Deny      bool
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+// Requests returns the number of requests to the path, e.g. /client/register.
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[path]
+}
+
+// ServeHTTP serves the requests of the sso client.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if s.requests == nil {
+		s.requests = map[string]int{}
+	}
+	s.requests[r.URL.Path]++
+	tokenRequests := s.requests["/token"]
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var body map[string]interface{}
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			standInError(w, http.StatusBadRequest, "InvalidRequestException", "invalid_request")
+			return
+		}
+	}
+
+	switch r.URL.Path {
+	case "/client/register":
+		if body["clientType"] != "public" || body["clientName"] != ClientName {
+			standInError(w, http.StatusBadRequest, "InvalidClientMetadataException", "invalid_client_metadata")
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"clientId":              "standin-client",
+			"clientSecret":          "standin-secret",
+			"clientIdIssuedAt":      time.Now().Unix(),
+			"clientSecretExpiresAt": time.Now().Add(90 * 24 * time.Hour).Unix(),
+		})
+	case "/device_authorization":
+		if body["clientId"] != "standin-client" || body["clientSecret"] != "standin-secret" {
+			standInError(w, http.StatusBadRequest, "InvalidClientException", "invalid_client")
+			return
+		}
+
+		uri := "http://" + r.Host + "/start/#/device"
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"deviceCode":              "standin-device",
+			"userCode":                "STAN-DIN1",
+			"verificationUri":         uri,
+			"verificationUriComplete": uri + "?user_code=STAN-DIN1",
+			"expiresIn":               600,
+			"interval":                1,
+		})
+	case "/token":
+		if body["deviceCode"] != "standin-device" || body["grantType"] != "urn:ietf:params:oauth:grant-type:device_code" {
+			standInError(w, http.StatusBadRequest, "InvalidGrantException", "invalid_grant")
+			return
+		}
+
+		// the exceptions without an error code in the body are identified
+		// by their type
+		if s.Deny {
+			standInError(w, http.StatusBadRequest, "AccessDeniedException", "")
+			return
+		}
+
+		if tokenRequests <= s.Pending {
+			standInError(w, http.StatusBadRequest, "AuthorizationPendingException", "authorization_pending")
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"accessToken": AccessToken,
+			"tokenType":   "Bearer",
+			"expiresIn":   28800,
+		})
+	case "/federation/credentials":
+		if r.Header.Get("x-amz-sso_bearer_token") != AccessToken {
+			standInError(w, http.StatusUnauthorized, "UnauthorizedException", "")
+			return
+		}
+
+		q := r.URL.Query()
+		if q.Get("account_id") != s.AccountID || q.Get("role_name") != s.RoleName {
+			standInError(w, http.StatusForbidden, "ForbiddenException", "")
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"roleCredentials": map[string]interface{}{
+				"accessKeyId":     AccessKeyID,
+				"secretAccessKey": SecretAccessKey,
+				"sessionToken":    SessionToken,
+				"expiration":      Expiration,
+			},
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// standInError writes the exception of the type, with the error code in the
+// body when it's set, like the SSO OIDC service does.
+func standInError(w http.ResponseWriter, status int, errorType, code string) {
+	w.Header().Set("x-amzn-ErrorType", errorType+":http://internal.amazon.com/coral/com.amazonaws.sso/")
+	w.WriteHeader(status)
+
+	res := map[string]string{"message": errorType}
+	if code != "" {
+		res = map[string]string{"error": code, "error_description": errorType}
+	}
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,192 @@
+// Package kubeconfig implements reading, merging and writing of Kubernetes
+// client configuration files.
+package kubeconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fox-tech/creds-fetcher/fsmanager"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ExecAPIVersion is the client authentication API version used by the
+	// exec credential plugins written to the configuration.
+	ExecAPIVersion = "client.authentication.k8s.io/v1beta1"
+)
+
+var (
+	ErrCouldNotRead   = errors.New("could not read kubeconfig")
+	ErrCouldNotWrite  = errors.New("could not write kubeconfig")
+	ErrInvalidContent = errors.New("invalid kubeconfig content")
+)
+
+// Config represents a kubeconfig file. Cluster, user and context values are
+// kept as generic maps so entries not managed by creds-fetcher are written
+// back unchanged.
+type Config struct {
+	APIVersion     string                 `yaml:"apiVersion"`
+	Kind           string                 `yaml:"kind"`
+	Preferences    map[string]interface{} `yaml:"preferences"`
+	Clusters       []NamedEntry           `yaml:"clusters"`
+	Users          []NamedEntry           `yaml:"users"`
+	Contexts       []NamedEntry           `yaml:"contexts"`
+	CurrentContext string                 `yaml:"current-context"`
+
+	Extra map[string]interface{} `yaml:",inline"`
+}
+
+// NamedEntry represents a named cluster, user or context of a kubeconfig.
+// Only one of the values is set depending on the list the entry is in, other
+// fields of the entry are kept in Extra.
+type NamedEntry struct {
+	Name    string                 `yaml:"name"`
+	Cluster map[string]interface{} `yaml:"cluster,omitempty"`
+	User    map[string]interface{} `yaml:"user,omitempty"`
+	Context map[string]interface{} `yaml:"context,omitempty"`
+
+	Extra map[string]interface{} `yaml:",inline"`
+}
+
+// Entry represents a cluster together with the user credentials and the
+// context to access it.
+type Entry struct {
+	Name                     string
+	Server                   string
+	CertificateAuthorityData string
+	Command                  string
+	Args                     []string
+}
+
+// Load reads the kubeconfig in the given path. A missing file returns an
+// empty configuration.
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+
+		return nil, fmt.Errorf("%w: %v", ErrCouldNotRead, err)
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidContent, err)
+	}
+
+	return cfg, nil
+}
+
+// Save writes the kubeconfig to the given path, creating the parent directory
+// if needed. The file is replaced atomically, so it's never left half-written.
+func (c *Config) Save(path string) error {
+	if c.APIVersion == "" {
+		c.APIVersion = "v1"
+	}
+
+	if c.Kind == "" {
+		c.Kind = "Config"
+	}
+
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidContent, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("%w: %v", ErrCouldNotWrite, err)
+	}
+
+	if err := fsmanager.NewDefault().WriteFile(path, data); err != nil {
+		return fmt.Errorf("%w: %v", ErrCouldNotWrite, err)
+	}
+
+	return nil
+}
+
+// caValues are the cluster values verifying the server other than
+// certificate-authority-data, kubectl rejects a cluster with both.
+var caValues = []string{"certificate-authority", "insecure-skip-tls-verify"}
+
+// Merge adds the cluster, user and context of the entry to the configuration,
+// updating the ones with the same name and leaving the rest untouched. The
+// values of existing clusters and contexts not set by the entry, such as a
+// proxy-url or a namespace, are kept, except the other ways of verifying the
+// server, users are replaced as their credentials must only be the exec
+// plugin. The context of the entry becomes the current context.
+func (c *Config) Merge(e Entry) {
+	c.Clusters = upsert(c.Clusters, NamedEntry{
+		Name: e.Name,
+		Cluster: map[string]interface{}{
+			"server":                     e.Server,
+			"certificate-authority-data": e.CertificateAuthorityData,
+		},
+	})
+	for _, cluster := range c.Clusters {
+		if cluster.Name == e.Name {
+			for _, k := range caValues {
+				delete(cluster.Cluster, k)
+			}
+		}
+	}
+
+	c.Users = upsert(c.Users, NamedEntry{
+		Name: e.Name,
+		User: map[string]interface{}{
+			"exec": map[string]interface{}{
+				"apiVersion":      ExecAPIVersion,
+				"command":         e.Command,
+				"args":            e.Args,
+				"interactiveMode": "IfAvailable",
+			},
+		},
+	})
+
+	c.Contexts = upsert(c.Contexts, NamedEntry{
+		Name: e.Name,
+		Context: map[string]interface{}{
+			"cluster": e.Name,
+			"user":    e.Name,
+		},
+	})
+
+	c.CurrentContext = e.Name
+}
+
+// upsert updates the entry with the same name or appends it to the list. The
+// cluster and context values of the entry are set in the existing ones, its
+// user replaces the existing one.
+func upsert(entries []NamedEntry, e NamedEntry) []NamedEntry {
+	for i := range entries {
+		if entries[i].Name == e.Name {
+			entries[i].Cluster = mergeValues(entries[i].Cluster, e.Cluster)
+			entries[i].Context = mergeValues(entries[i].Context, e.Context)
+			if e.User != nil {
+				entries[i].User = e.User
+			}
+
+			return entries
+		}
+	}
+
+	return append(entries, e)
+}
+
+// mergeValues sets the values in dst, which is returned, or returns values
+// when dst is nil.
+func mergeValues(dst, values map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		return values
+	}
+
+	for k, v := range values {
+		dst[k] = v
+	}
+
+	return dst
+}
@@ -0,0 +1,218 @@
+package kubeconfig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const existingConfig = `apiVersion: v1
+kind: Config
+preferences: {}
+clusters:
+- name: other
+  cluster:
+    server: https://other.example.com
+    insecure-skip-tls-verify: true
+users:
+- name: other
+  user:
+    token: secret
+contexts:
+- name: other
+  context:
+    cluster: other
+    user: other
+    namespace: tools
+current-context: other
+extensions:
+- name: team
+  extension:
+    owner: platform
+`
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		content  string
+		clusters int
+		err      error
+	}{
+		{
+			name:     "missing file: empty configuration",
+			clusters: 0,
+		},
+		{
+			name:     "existing file: configuration is read",
+			content:  existingConfig,
+			clusters: 1,
+		},
+		{
+			name:    "invalid file: error is returned",
+			content: "clusters: {{",
+			err:     ErrInvalidContent,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "config"+string(rune('a'+i)))
+			if tt.content != "" {
+				if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+					t.Fatalf("writing test file: %v", err)
+				}
+			}
+
+			cfg, err := Load(path)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Load() expected error: %v, got: %v", tt.err, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if len(cfg.Clusters) != tt.clusters {
+				t.Errorf("Load() expected %d clusters, got: %d", tt.clusters, len(cfg.Clusters))
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".kube", "config")
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatalf("creating test dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(existingConfig), 0600); err != nil {
+		t.Fatalf("writing test file: %v", err)
+	}
+
+	entry := Entry{
+		Name:                     "arn:aws:eks:us-east-1:123:cluster/dev",
+		Server:                   "https://dev.eks.amazonaws.com",
+		CertificateAuthorityData: "Y2VydA==",
+		Command:                  "creds-fetcher",
+		Args:                     []string{"eks-token", "-cluster", "dev"},
+	}
+
+	// merging twice must not duplicate the entry
+	for i := 0; i < 2; i++ {
+		cfg, err := Load(path)
+		if err != nil {
+			t.Fatalf("Load() unexpected error: %v", err)
+		}
+
+		cfg.Merge(entry)
+		if err := cfg.Save(path); err != nil {
+			t.Fatalf("Save() unexpected error: %v", err)
+		}
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	if len(cfg.Clusters) != 2 || len(cfg.Users) != 2 || len(cfg.Contexts) != 2 {
+		t.Fatalf("Merge() expected 2 clusters, users and contexts, got: %d, %d, %d", len(cfg.Clusters), len(cfg.Users), len(cfg.Contexts))
+	}
+
+	if cfg.CurrentContext != entry.Name {
+		t.Errorf("Merge() expected current context: %s, got: %s", entry.Name, cfg.CurrentContext)
+	}
+
+	other := cfg.Clusters[0]
+	if other.Name != "other" || other.Cluster["insecure-skip-tls-verify"] != true {
+		t.Errorf("Merge() expected unrelated cluster to be untouched, got: %v", other)
+	}
+
+	if ns := cfg.Contexts[0].Context["namespace"]; ns != "tools" {
+		t.Errorf("Merge() expected unrelated context namespace: tools, got: %v", ns)
+	}
+
+	if cfg.Clusters[1].Cluster["server"] != entry.Server {
+		t.Errorf("Merge() expected server: %s, got: %v", entry.Server, cfg.Clusters[1].Cluster["server"])
+	}
+
+	if _, ok := cfg.Extra["extensions"]; !ok {
+		t.Errorf("Save() expected unknown fields to be kept, got: %v", cfg.Extra)
+	}
+}
+
+func TestMergeKeepsEntryValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+
+	entry := Entry{
+		Name:    "dev",
+		Server:  "https://dev.eks.amazonaws.com",
+		Command: "creds-fetcher",
+	}
+
+	cfg := &Config{}
+	cfg.Merge(entry)
+	cfg.Clusters[0].Cluster["proxy-url"] = "http://proxy.corp:3128"
+	cfg.Contexts[0].Context["namespace"] = "payments"
+
+	// the server of the cluster changed
+	entry.Server = "https://new.eks.amazonaws.com"
+	cfg.Merge(entry)
+	if err := cfg.Save(path); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	cluster := cfg.Clusters[0].Cluster
+	if cluster["server"] != entry.Server || cluster["proxy-url"] != "http://proxy.corp:3128" {
+		t.Errorf("Merge() expected new server and kept proxy-url, got: %v", cluster)
+	}
+
+	if ns := cfg.Contexts[0].Context["namespace"]; ns != "payments" {
+		t.Errorf("Merge() expected kept namespace: payments, got: %v", ns)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Save() expected mode 0600, got: %v", info.Mode().Perm())
+	}
+}
+
+func TestMergeReplacesCertificateAuthority(t *testing.T) {
+	cfg := &Config{Clusters: []NamedEntry{{
+		Name: "dev",
+		Cluster: map[string]interface{}{
+			"server":                   "https://dev.eks.amazonaws.com",
+			"certificate-authority":    "/etc/kubernetes/ca.crt",
+			"insecure-skip-tls-verify": true,
+			"proxy-url":                "http://proxy.corp:3128",
+		},
+	}}}
+
+	cfg.Merge(Entry{
+		Name:                     "dev",
+		Server:                   "https://dev.eks.amazonaws.com",
+		CertificateAuthorityData: "Q0EgREFUQQ==",
+		Command:                  "creds-fetcher",
+	})
+
+	cluster := cfg.Clusters[0].Cluster
+	for _, k := range []string{"certificate-authority", "insecure-skip-tls-verify"} {
+		if _, ok := cluster[k]; ok {
+			t.Errorf("Merge() expected %s to be removed, got: %v", k, cluster)
+		}
+	}
+
+	if cluster["certificate-authority-data"] != "Q0EgREFUQQ==" || cluster["proxy-url"] != "http://proxy.corp:3128" {
+		t.Errorf("Merge() expected new certificate-authority-data and kept proxy-url, got: %v", cluster)
+	}
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"os"
+
 	"github.com/fox-tech/creds-fetcher/cli"
 )
 
 func main() {
-	cli.New().Execute()
+	if err := cli.New().Execute(); err != nil {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,58 @@
+package oidc
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/fox-tech/creds-fetcher/internal/oauth"
+)
+
+// Device is the device authorization of a login, the user must open the
+// verification URI and confirm the user code in it. VerificationURIComplete,
+// which includes the user code, is optional. ExpiresIn and Interval are in
+// seconds, Interval is how long to wait between polls of the token endpoint.
+//
+// More at https://www.rfc-editor.org/rfc/rfc8628#section-3.2.
+type Device = oauth.Device
+
+// Token is a successful response of the token endpoint. The token issued by
+// token exchanges is in AccessToken, with its type in IssuedTokenType.
+type Token = oauth.Token
+
+// DeviceAuthorization starts a login with the device authorization grant and
+// returns the device authorization to show to the user, which is then passed
+// to PollToken.
+func (c Client) DeviceAuthorization(ctx context.Context) (Device, error) {
+	e, err := c.resolveEndpoints(ctx)
+	if err != nil {
+		return Device{}, err
+	}
+
+	return c.oauth().DeviceAuthorization(ctx, e.DeviceAuthorization, url.Values{
+		"scope": []string{c.scopes},
+	})
+}
+
+// PollToken polls the token endpoint until the user approves the login of the
+// device and returns its tokens, increasing the interval when asked to slow
+// down. It returns ErrAccessDenied when the user denied it, ErrExpiredToken or
+// ErrDeviceAuthorizationExpired when the device code expired, and the error of
+// ctx when it's done.
+func (c Client) PollToken(ctx context.Context, device Device) (Token, error) {
+	e, err := c.resolveEndpoints(ctx)
+	if err != nil {
+		return Token{}, err
+	}
+
+	var token Token
+	err = oauth.Poll(ctx, device.Interval, device.ExpiresIn, func() error {
+		var err error
+		token, err = c.oauth().DeviceToken(ctx, e.Token, device)
+		return err
+	})
+	if err != nil {
+		return Token{}, err
+	}
+
+	return token, nil
+}
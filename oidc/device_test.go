@@ -0,0 +1,179 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/internal/oauth"
+)
+
+// deviceServer is an authorization server answering the token requests of
+// the device code with the pending error a number of times before the result.
+type deviceServer struct {
+	pending  string
+	count    int
+	result   string
+	status   int
+	requests int
+}
+
+func (s *deviceServer) start(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/device":
+			if r.FormValue("client_id") != "testid" || r.FormValue("scope") != "openid profile" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"invalid_client","error_description":"unknown client"}`))
+				return
+			}
+
+			fmt.Fprintf(w, `{"device_code":"devicecode","user_code":"ABCD-EFGH","verification_uri":"%s/activate","expires_in":5,"interval":1}`, "http://"+r.Host)
+		case "/token":
+			if r.FormValue("device_code") != "devicecode" || r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:device_code" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"invalid_grant"}`))
+				return
+			}
+
+			s.requests++
+			if s.requests <= s.count {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, `{"error":%q,"error_description":"device authorization error"}`, s.pending)
+				return
+			}
+
+			w.WriteHeader(s.status)
+			w.Write([]byte(s.result))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestClientDeviceAuthorization(t *testing.T) {
+	tests := []struct {
+		name      string
+		scopes    string
+		expect    Device
+		expectErr error
+	}{
+		{
+			name:   "success",
+			scopes: "openid profile",
+			expect: Device{DeviceCode: "devicecode", UserCode: "ABCD-EFGH", VerificationURI: "/activate", ExpiresIn: 5, Interval: 1},
+		},
+		{
+			name:      "error response: fails",
+			scopes:    "openid",
+			expectErr: ErrDeviceAuthorizationRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := (&deviceServer{}).start(t)
+
+			c, err := New("testid", SetEndpoints(srv.URL+"/device", srv.URL+"/token"), SetScopes(tt.scopes))
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			device, err := c.DeviceAuthorization(context.Background())
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("DeviceAuthorization() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if tt.expect.VerificationURI != "" {
+				tt.expect.VerificationURI = srv.URL + tt.expect.VerificationURI
+			}
+			if device != tt.expect {
+				t.Errorf("DeviceAuthorization() expected: %+v, got: %+v", tt.expect, device)
+			}
+		})
+	}
+}
+
+func TestClientPollToken(t *testing.T) {
+	prevIncrease := oauth.SlowDownIncrease
+	oauth.SlowDownIncrease = 0
+	defer func() { oauth.SlowDownIncrease = prevIncrease }()
+
+	tests := []struct {
+		name      string
+		server    deviceServer
+		expect    Token
+		expectErr error
+	}{
+		{
+			name:   "approved after pending: success",
+			server: deviceServer{pending: "authorization_pending", count: 1, status: http.StatusOK, result: `{"access_token":"accesstoken","id_token":"idtoken"}`},
+			expect: Token{AccessToken: "accesstoken", IDToken: "idtoken"},
+		},
+		{
+			name:   "approved after slow down: success",
+			server: deviceServer{pending: "slow_down", count: 1, status: http.StatusOK, result: `{"access_token":"accesstoken"}`},
+			expect: Token{AccessToken: "accesstoken"},
+		},
+		{
+			name:      "denied: fails",
+			server:    deviceServer{status: http.StatusBadRequest, result: `{"error":"access_denied","error_description":"denied"}`},
+			expectErr: ErrAccessDenied,
+		},
+		{
+			name:      "expired code: fails",
+			server:    deviceServer{status: http.StatusBadRequest, result: `{"error":"expired_token","error_description":"expired"}`},
+			expectErr: ErrExpiredToken,
+		},
+		{
+			name:      "unauthorized client: fails",
+			server:    deviceServer{status: http.StatusUnauthorized, result: `{"error":"invalid_client"}`},
+			expectErr: ErrTokenRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := tt.server.start(t)
+
+			c, err := New("testid", SetEndpoints(srv.URL+"/device", srv.URL+"/token"))
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			token, err := c.PollToken(context.Background(), Device{DeviceCode: "devicecode", ExpiresIn: 5, Interval: 1})
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("PollToken() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if token != tt.expect {
+				t.Errorf("PollToken() expected: %+v, got: %+v", tt.expect, token)
+			}
+		})
+	}
+}
+
+func TestClientPollTokenContextDone(t *testing.T) {
+	srv := (&deviceServer{pending: "authorization_pending", count: 10}).start(t)
+
+	c, err := New("testid", SetEndpoints(srv.URL+"/device", srv.URL+"/token"))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.PollToken(ctx, Device{DeviceCode: "devicecode", ExpiresIn: 5, Interval: 1}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("PollToken() expected error: %v, got: %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,60 @@
+package oidc
+
+import (
+	"context"
+	"fmt"
+)
+
+// Endpoints are the endpoints of the authorization server used by the client,
+// from its OpenID configuration.
+//
+// More at https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata.
+type Endpoints struct {
+	DeviceAuthorization string `json:"device_authorization_endpoint"`
+	Token               string `json:"token_endpoint"`
+}
+
+// resolveEndpoints returns the endpoints of the client, discovering the ones
+// that are not set once per client.
+func (c Client) resolveEndpoints(ctx context.Context) (Endpoints, error) {
+	if c.endpoints.DeviceAuthorization != "" && c.endpoints.Token != "" {
+		return c.endpoints, nil
+	}
+
+	c.discovery.mu.Lock()
+	defer c.discovery.mu.Unlock()
+
+	if c.discovery.endpoints == nil {
+		e, err := c.discover(ctx)
+		if err != nil {
+			return Endpoints{}, err
+		}
+
+		c.discovery.endpoints = &e
+	}
+
+	e := *c.discovery.endpoints
+	if c.endpoints.DeviceAuthorization != "" {
+		e.DeviceAuthorization = c.endpoints.DeviceAuthorization
+	}
+	if c.endpoints.Token != "" {
+		e.Token = c.endpoints.Token
+	}
+
+	return e, nil
+}
+
+// discover requests the OpenID configuration of the issuer.
+func (c Client) discover(ctx context.Context) (Endpoints, error) {
+	var e Endpoints
+	if err := c.oauth().Discover(ctx, c.issuer, &e); err != nil {
+		return Endpoints{}, err
+	}
+
+	// servers without the device grant leave out its endpoint
+	if (e.DeviceAuthorization == "" && c.endpoints.DeviceAuthorization == "") || (e.Token == "" && c.endpoints.Token == "") {
+		return Endpoints{}, fmt.Errorf("%w: no device authorization or token endpoint for %s", ErrDiscovery, c.issuer)
+	}
+
+	return e, nil
+}
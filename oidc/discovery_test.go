@@ -0,0 +1,100 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientResolveEndpoints(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		status      int
+		endpoints   Endpoints
+		expect      Endpoints
+		expectCalls int
+		expectErr   error
+	}{
+		{
+			name:        "discovered endpoints: success",
+			config:      `{"device_authorization_endpoint":"%[1]s/device","token_endpoint":"%[1]s/token"}`,
+			status:      http.StatusOK,
+			expect:      Endpoints{DeviceAuthorization: "/device", Token: "/token"},
+			expectCalls: 1,
+		},
+		{
+			name:        "set token endpoint: replaces the discovered one",
+			config:      `{"device_authorization_endpoint":"%[1]s/device","token_endpoint":"%[1]s/token"}`,
+			status:      http.StatusOK,
+			endpoints:   Endpoints{Token: "https://token.example.com"},
+			expect:      Endpoints{DeviceAuthorization: "/device", Token: "https://token.example.com"},
+			expectCalls: 1,
+		},
+		{
+			name:      "both endpoints set: no discovery",
+			endpoints: Endpoints{DeviceAuthorization: "https://device.example.com", Token: "https://token.example.com"},
+			expect:    Endpoints{DeviceAuthorization: "https://device.example.com", Token: "https://token.example.com"},
+		},
+		{
+			name:        "no device grant: fails",
+			config:      `{"token_endpoint":"%[1]s/token"}`,
+			status:      http.StatusOK,
+			expectCalls: 1,
+			expectErr:   ErrDiscovery,
+		},
+		{
+			name:        "no openid configuration: fails",
+			status:      http.StatusNotFound,
+			expectCalls: 1,
+			expectErr:   ErrDiscovery,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/realms/aws/.well-known/openid-configuration" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				calls++
+				w.WriteHeader(tt.status)
+				fmt.Fprintf(w, tt.config, "http://"+r.Host)
+			}))
+			defer srv.Close()
+
+			c, err := New("testid", SetIssuer(srv.URL+"/realms/aws/"), SetEndpoints(tt.endpoints.DeviceAuthorization, tt.endpoints.Token))
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			// the endpoints are discovered once per client
+			for i := 0; i < 2; i++ {
+				e, err := c.resolveEndpoints(context.Background())
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("resolveEndpoints() expected error: %v, got: %v", tt.expectErr, err)
+				}
+
+				if tt.expect.DeviceAuthorization == "/device" {
+					tt.expect.DeviceAuthorization = srv.URL + "/device"
+				}
+				if tt.expect.Token == "/token" {
+					tt.expect.Token = srv.URL + "/token"
+				}
+				if e != tt.expect {
+					t.Errorf("resolveEndpoints() expected: %+v, got: %+v", tt.expect, e)
+				}
+			}
+
+			if tt.expectErr == nil && calls != tt.expectCalls {
+				t.Errorf("resolveEndpoints() expected %d discovery requests, got: %d", tt.expectCalls, calls)
+			}
+		})
+	}
+}
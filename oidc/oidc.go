@@ -0,0 +1,124 @@
+// Package oidc implements logging in to any OpenID Connect authorization
+// server, such as Keycloak or Auth0, with the device authorization grant of
+// RFC 8628, and exchanging the tokens of the login for a SAML assertion or an
+// ID token for another audience with the token exchange of RFC 8693.
+package oidc
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/fox-tech/creds-fetcher/client"
+	"github.com/fox-tech/creds-fetcher/internal/oauth"
+)
+
+const (
+	// DefaultScopes are the scopes requested when none are set, the email
+	// names the AWS sessions of web identity logins.
+	DefaultScopes = "openid email"
+
+	// Token types of RFC 8693, the subject token of the exchanges is the
+	// access token of the login.
+	//
+	// More at https://www.rfc-editor.org/rfc/rfc8693#section-3.
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeIDToken     = "urn:ietf:params:oauth:token-type:id_token"
+	TokenTypeSAML2       = "urn:ietf:params:oauth:token-type:saml2"
+)
+
+var (
+	// ErrMissingClientConfig is returned by New without a client ID, or
+	// without an issuer or both endpoints.
+	ErrMissingClientConfig = errors.New("missing client ID, issuer or endpoints for Client")
+
+	// Errors of the requests to the authorization server, shared with the
+	// other OAuth 2.0 clients. ErrDiscovery is returned when the OpenID
+	// configuration of the issuer can't be requested or has no device
+	// authorization or token endpoint, ErrDeviceAuthorizationExpired when the
+	// device code expired while polling and ErrJSONDecode when a response
+	// can't be decoded.
+	ErrDiscovery                  = oauth.ErrDiscovery
+	ErrDeviceAuthorizationRequest = oauth.ErrDeviceAuthorizationRequest
+	ErrDeviceAuthorizationExpired = oauth.ErrDeviceAuthorizationExpired
+	ErrTokenRequest               = oauth.ErrTokenRequest
+	ErrTokenExchange              = oauth.ErrTokenExchange
+	ErrJSONDecode                 = oauth.ErrJSONDecode
+
+	// Device authorization errors returned by the token endpoint while polling.
+	// ErrAuthorizationPending and ErrSlowDown are handled by PollToken, which
+	// keeps polling, ErrAccessDenied is returned when the user denied the
+	// authorization and ErrExpiredToken when the device code expired.
+	//
+	// More at https://www.rfc-editor.org/rfc/rfc8628#section-3.5.
+	ErrAuthorizationPending = oauth.ErrAuthorizationPending
+	ErrSlowDown             = oauth.ErrSlowDown
+	ErrAccessDenied         = oauth.ErrAccessDenied
+	ErrExpiredToken         = oauth.ErrExpiredToken
+
+	// ErrNoIDToken is returned when the login returned no ID token to use as
+	// web identity.
+	ErrNoIDToken = errors.New("no id token")
+
+	// ErrInvalidSAML is returned when the token exchanged for a SAML assertion
+	// is not base64 encoded.
+	ErrInvalidSAML = errors.New("invalid saml token")
+)
+
+// Client logs in to an OpenID Connect authorization server as the public
+// client with the ID of the Client.
+type Client struct {
+	id string
+
+	// issuer is the URL of the authorization server, its OpenID configuration
+	// is requested for the endpoints that are not set
+	issuer    string
+	endpoints Endpoints
+	// discovery keeps the discovered endpoints, shared by the client copies
+	discovery *discovery
+
+	scopes   string
+	audience string
+
+	httpClient client.HTTPClient
+}
+
+// discovery keeps the endpoints discovered by a client.
+type discovery struct {
+	mu        sync.Mutex
+	endpoints *Endpoints
+}
+
+// New returns an initialized and validated Client. It returns a nil error if
+// the client has an ID and the issuer or both endpoints are set with
+// SetIssuer and SetEndpoints.
+func New(id string, opts ...Option) (Client, error) {
+	c := Client{
+		id:        id,
+		scopes:    DefaultScopes,
+		discovery: &discovery{},
+	}
+
+	for _, opt := range opts {
+		if err := opt(&c); err != nil {
+			return c, err
+		}
+	}
+
+	if err := c.validate(); err != nil {
+		return Client{}, err
+	}
+
+	return c, nil
+}
+
+func (c Client) validate() error {
+	if c.id == "" {
+		return ErrMissingClientConfig
+	}
+
+	if c.issuer == "" && (c.endpoints.DeviceAuthorization == "" || c.endpoints.Token == "") {
+		return ErrMissingClientConfig
+	}
+
+	return nil
+}
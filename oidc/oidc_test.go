@@ -0,0 +1,50 @@
+package oidc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        string
+		opts      []Option
+		expectErr error
+	}{
+		{
+			name: "issuer: success",
+			id:   "testid",
+			opts: []Option{SetIssuer("https://idp.example.com/realms/aws")},
+		},
+		{
+			name: "both endpoints: success",
+			id:   "testid",
+			opts: []Option{SetEndpoints("https://idp.example.com/device", "https://idp.example.com/token")},
+		},
+		{
+			name:      "no client ID: fails",
+			opts:      []Option{SetIssuer("https://idp.example.com/realms/aws")},
+			expectErr: ErrMissingClientConfig,
+		},
+		{
+			name:      "only the token endpoint: fails",
+			id:        "testid",
+			opts:      []Option{SetEndpoints("", "https://idp.example.com/token")},
+			expectErr: ErrMissingClientConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(tt.id, tt.opts...)
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("New() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if err == nil && c.scopes != DefaultScopes {
+				t.Errorf("New() expected scopes %q, got: %q", DefaultScopes, c.scopes)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package oidc
+
+import (
+	"github.com/fox-tech/creds-fetcher/client"
+)
+
+// Option represents a function that can set a configuration value to the OIDC
+// initialization function New. It can return a non-nil error.
+type Option func(*Client) error
+
+// SetIssuer sets the URL of the authorization server, the endpoints not set
+// with SetEndpoints are discovered from its OpenID configuration.
+func SetIssuer(issuer string) Option {
+	return func(c *Client) error {
+		c.issuer = issuer
+		return nil
+	}
+}
+
+// SetEndpoints sets the device authorization and token endpoints, for servers
+// without OpenID configuration or with other endpoints in it. Empty endpoints
+// are discovered from the issuer.
+func SetEndpoints(deviceAuthorization, token string) Option {
+	return func(c *Client) error {
+		c.endpoints.DeviceAuthorization = deviceAuthorization
+		c.endpoints.Token = token
+		return nil
+	}
+}
+
+// SetScopes sets the space-separated scopes of the login, DefaultScopes when
+// it's not used.
+func SetScopes(scopes string) Option {
+	return func(c *Client) error {
+		c.scopes = scopes
+		return nil
+	}
+}
+
+// SetAudience sets the audience the tokens are exchanged for, e.g. the client
+// ID of the SAML client of AWS in the authorization server.
+func SetAudience(audience string) Option {
+	return func(c *Client) error {
+		c.audience = audience
+		return nil
+	}
+}
+
+// SetHTTPClient sets the client used for the requests to the authorization
+// server, e.g. to share the client used for AWS with its proxy and retries.
+func SetHTTPClient(hc client.HTTPClient) Option {
+	return func(c *Client) error {
+		c.httpClient = hc
+		return nil
+	}
+}
@@ -0,0 +1,11 @@
+package oidc
+
+import (
+	"github.com/fox-tech/creds-fetcher/internal/oauth"
+)
+
+// oauth returns the client of the requests to the authorization server, with
+// the client set with SetHTTPClient or the default one.
+func (c Client) oauth() oauth.Client {
+	return oauth.Client{ID: c.id, HTTPClient: c.httpClient}
+}
@@ -0,0 +1,78 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ExchangeToken exchanges the access token of the login for a token of the
+// requested type, one of the TokenType constants, for the audience set with
+// SetAudience. The issued token is in the AccessToken of the returned Token.
+//
+// More at https://www.rfc-editor.org/rfc/rfc8693#section-2.1.
+func (c Client) ExchangeToken(ctx context.Context, token Token, requestedTokenType string) (Token, error) {
+	e, err := c.resolveEndpoints(ctx)
+	if err != nil {
+		return Token{}, err
+	}
+
+	values := url.Values{
+		"subject_token":        []string{token.AccessToken},
+		"subject_token_type":   []string{TokenTypeAccessToken},
+		"requested_token_type": []string{requestedTokenType},
+	}
+	if c.audience != "" {
+		values.Set("audience", c.audience)
+	}
+
+	issued, err := c.oauth().ExchangeToken(ctx, e.Token, values)
+	if err != nil {
+		return Token{}, err
+	}
+
+	if issued.AccessToken == "" {
+		return Token{}, fmt.Errorf("%w: no token issued", ErrTokenExchange)
+	}
+
+	return issued, nil
+}
+
+// ExchangeSAML exchanges the access token of the login for a SAML assertion of
+// the audience and returns it base64 encoded as AWS expects it. RFC 8693
+// issues it base64url encoded, with or without padding.
+func (c Client) ExchangeSAML(ctx context.Context, token Token) (string, error) {
+	issued, err := c.ExchangeToken(ctx, token, TokenTypeSAML2)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(issued.AccessToken, "="))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidSAML, err)
+	}
+
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// WebIdentityToken returns the ID token of the login, or the ID token it's
+// exchanged for when the client has an audience, e.g. the client ID trusted
+// by the OIDC identity provider in AWS.
+func (c Client) WebIdentityToken(ctx context.Context, token Token) (string, error) {
+	if c.audience == "" {
+		if token.IDToken == "" {
+			return "", ErrNoIDToken
+		}
+
+		return token.IDToken, nil
+	}
+
+	issued, err := c.ExchangeToken(ctx, token, TokenTypeIDToken)
+	if err != nil {
+		return "", err
+	}
+
+	return issued.AccessToken, nil
+}
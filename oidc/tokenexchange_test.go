@@ -0,0 +1,140 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// samlXML is the SAML response issued by the test exchanges.
+const samlXML = `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol"/>`
+
+// newExchangeServer returns a token endpoint exchanging the access token for
+// the audience for an ID token or a base64url SAML response without padding.
+func newExchangeServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:token-exchange" ||
+			r.FormValue("subject_token") != "accesstoken" ||
+			r.FormValue("subject_token_type") != TokenTypeAccessToken {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_request"}`))
+			return
+		}
+
+		if r.FormValue("audience") != "aws" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_target","error_description":"unknown audience"}`))
+			return
+		}
+
+		var token Token
+		switch r.FormValue("requested_token_type") {
+		case TokenTypeIDToken:
+			token = Token{AccessToken: "exchangedidtoken", IssuedTokenType: TokenTypeIDToken}
+		case TokenTypeSAML2:
+			token = Token{AccessToken: base64.RawURLEncoding.EncodeToString([]byte(samlXML)), IssuedTokenType: TokenTypeSAML2}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_request"}`))
+			return
+		}
+
+		json.NewEncoder(w).Encode(token)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestClientExchangeSAML(t *testing.T) {
+	tests := []struct {
+		name      string
+		audience  string
+		expect    string
+		expectErr error
+	}{
+		{
+			name:     "audience of the SAML client: success",
+			audience: "aws",
+			expect:   base64.StdEncoding.EncodeToString([]byte(samlXML)),
+		},
+		{
+			name:      "unknown audience: fails",
+			audience:  "other",
+			expectErr: ErrTokenExchange,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newExchangeServer(t)
+
+			c, err := New("testid", SetEndpoints(srv.URL+"/device", srv.URL), SetAudience(tt.audience))
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			saml, err := c.ExchangeSAML(context.Background(), Token{AccessToken: "accesstoken"})
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("ExchangeSAML() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if saml != tt.expect {
+				t.Errorf("ExchangeSAML() expected: %s, got: %s", tt.expect, saml)
+			}
+		})
+	}
+}
+
+func TestClientWebIdentityToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		audience  string
+		token     Token
+		expect    string
+		expectErr error
+	}{
+		{
+			name:   "no audience: ID token of the login",
+			token:  Token{AccessToken: "accesstoken", IDToken: "idtoken"},
+			expect: "idtoken",
+		},
+		{
+			name:      "no audience and no ID token: fails",
+			token:     Token{AccessToken: "accesstoken"},
+			expectErr: ErrNoIDToken,
+		},
+		{
+			name:     "audience: exchanged ID token",
+			audience: "aws",
+			token:    Token{AccessToken: "accesstoken", IDToken: "idtoken"},
+			expect:   "exchangedidtoken",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newExchangeServer(t)
+
+			c, err := New("testid", SetEndpoints(srv.URL+"/device", srv.URL), SetAudience(tt.audience))
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			token, err := c.WebIdentityToken(context.Background(), tt.token)
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("WebIdentityToken() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if token != tt.expect {
+				t.Errorf("WebIdentityToken() expected: %s, got: %s", tt.expect, token)
+			}
+		})
+	}
+}
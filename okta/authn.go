@@ -0,0 +1,328 @@
+package okta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Factor types of the MFA factors supported by AuthorizeAuthn.
+const (
+	FactorPush = "push"
+	FactorTOTP = "token:software:totp"
+	FactorSMS  = "sms"
+)
+
+var (
+	// ErrNoAppURL is returned by AuthorizeAuthn when the client has no app URL
+	// set with SetAppURL.
+	ErrNoAppURL = errors.New("no app URL set")
+
+	// ErrAuthnRequest is returned when the authn request failed or the server
+	// responded with an error, e.g. because the password is wrong.
+	ErrAuthnRequest = errors.New("authn request")
+
+	// ErrAuthnStatus is returned when the login needs a step AuthorizeAuthn
+	// can't do, e.g. the user is locked out or must enroll a factor.
+	ErrAuthnStatus = errors.New("authn status not supported")
+
+	// ErrNoSupportedFactor is returned when the user has no MFA factor
+	// supported by AuthorizeAuthn.
+	ErrNoSupportedFactor = errors.New("no supported MFA factor")
+
+	// ErrInvalidPassCode is returned when the passcode of the factor is still
+	// wrong after the last attempt.
+	ErrInvalidPassCode = errors.New("invalid passcode")
+
+	// ErrFactorRejected and ErrFactorTimeout are returned when the push was
+	// rejected by the user or not answered in time.
+	ErrFactorRejected = errors.New("MFA verification rejected")
+	ErrFactorTimeout  = errors.New("MFA verification timed out")
+)
+
+// maxPassCodeAttempts is the number of passcodes the user can enter before
+// the login fails.
+const maxPassCodeAttempts = 3
+
+// errCodeInvalidPassCode is the Okta error code of a wrong passcode.
+const errCodeInvalidPassCode = "E0000068"
+
+// pushPollInterval is the time between the checks of a push verification.
+var pushPollInterval = 4 * time.Second
+
+// Factor represents an MFA factor of the user, the phone number is only set
+// for SMS factors and the device name for push factors.
+//
+// More at https://developer.okta.com/docs/reference/api/authn/#factor-object.
+type Factor struct {
+	ID       string `json:"id"`
+	Type     string `json:"factorType"`
+	Provider string `json:"provider"`
+	Profile  struct {
+		PhoneNumber string `json:"phoneNumber"`
+		DeviceName  string `json:"name"`
+	} `json:"profile"`
+	Links struct {
+		Verify link `json:"verify"`
+	} `json:"_links"`
+}
+
+// String returns the description of the factor shown to the user.
+func (f Factor) String() string {
+	switch f.Type {
+	case FactorPush:
+		if f.Profile.DeviceName != "" {
+			return "Okta Verify push to " + f.Profile.DeviceName
+		}
+		return "Okta Verify push"
+	case FactorTOTP:
+		if f.Provider == "GOOGLE" {
+			return "Google Authenticator code"
+		}
+		return "Okta Verify code"
+	case FactorSMS:
+		return "SMS code to " + f.Profile.PhoneNumber
+	}
+
+	return f.Type
+}
+
+// AuthnPrompter asks the user for the values of the authn login.
+type AuthnPrompter interface {
+	// Credentials returns the username and password of the user.
+	Credentials() (username, password string, err error)
+	// ChooseFactor returns the factor, of the given ones, the login is
+	// verified with.
+	ChooseFactor(factors []Factor) (Factor, error)
+	// PassCode returns the code of a TOTP or SMS factor.
+	PassCode(factor Factor) (string, error)
+	// Notify shows a message about the verification, e.g. to approve a push.
+	Notify(msg string)
+}
+
+// link represents a link of the authn transaction.
+type link struct {
+	Href string `json:"href"`
+}
+
+// authnTransaction represents the state of an authn login, the session token
+// is set when its status is SUCCESS.
+//
+// More at https://developer.okta.com/docs/reference/api/authn/#transaction-model.
+type authnTransaction struct {
+	Status       string `json:"status"`
+	StateToken   string `json:"stateToken"`
+	SessionToken string `json:"sessionToken"`
+	FactorResult string `json:"factorResult"`
+	Embedded     struct {
+		Factors []Factor `json:"factors"`
+		Factor  struct {
+			Embedded struct {
+				Challenge struct {
+					CorrectAnswer int `json:"correctAnswer"`
+				} `json:"challenge"`
+			} `json:"_embedded"`
+		} `json:"factor"`
+	} `json:"_embedded"`
+	Links struct {
+		Next link `json:"next"`
+		Skip link `json:"skip"`
+	} `json:"_links"`
+}
+
+// authnError represents an error returned by the authn API.
+type authnError struct {
+	Code    string `json:"errorCode"`
+	Summary string `json:"errorSummary"`
+}
+
+// AuthorizeAuthn logs in with the username and password of the user and one
+// of their MFA factors, all asked with the prompter, and sends the SAML
+// assertion of the app set with SetAppURL to the provider. No browser is
+// needed, the session token of the login opens the app. Push verifications are
+// polled until they're approved or ctx is done.
+//
+// More at https://developer.okta.com/docs/reference/api/authn/.
+func (c Client) AuthorizeAuthn(ctx context.Context, p AuthnPrompter) error {
+	if c.appURL == "" {
+		return ErrNoAppURL
+	}
+
+	username, password, err := p.Credentials()
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.authn(ctx, c.uri+"/api/v1/authn", map[string]string{
+		"username": username,
+		"password": password,
+	})
+	if err != nil {
+		return err
+	}
+
+	for tx.Status != "SUCCESS" {
+		switch tx.Status {
+		case "MFA_REQUIRED":
+			tx, err = c.verifyFactor(ctx, tx, p)
+		case "PASSWORD_WARN":
+			// the password expires soon, it can still be used
+			p.Notify("Your password expires soon, change it in Okta")
+			tx, err = c.authn(ctx, tx.Links.Skip.Href, map[string]string{"stateToken": tx.StateToken})
+		default:
+			return fmt.Errorf("%w: %s", ErrAuthnStatus, tx.Status)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	u, err := url.Parse(c.appURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSAMLRequest, err)
+	}
+	q := u.Query()
+	q.Set("sessionToken", tx.SessionToken)
+	u.RawQuery = q.Encode()
+
+	saml, err := c.requestSAML(ctx, u.String())
+	if err != nil {
+		return err
+	}
+
+	return c.provider.GenerateCredentials(ctx, saml)
+}
+
+// verifyFactor verifies the login with the factor chosen by the user, of the
+// supported ones of the transaction.
+func (c Client) verifyFactor(ctx context.Context, tx authnTransaction, p AuthnPrompter) (authnTransaction, error) {
+	var factors []Factor
+	for _, f := range tx.Embedded.Factors {
+		switch {
+		case f.Type == FactorPush && f.Provider == "OKTA", f.Type == FactorTOTP, f.Type == FactorSMS:
+			factors = append(factors, f)
+		}
+	}
+	if len(factors) == 0 {
+		return authnTransaction{}, ErrNoSupportedFactor
+	}
+
+	factor, err := p.ChooseFactor(factors)
+	if err != nil {
+		return authnTransaction{}, err
+	}
+
+	switch factor.Type {
+	case FactorPush:
+		return c.verifyPush(ctx, factor, tx.StateToken, p)
+	case FactorSMS:
+		// verifying without a passcode sends the SMS
+		if _, err := c.authn(ctx, factor.Links.Verify.Href, map[string]string{"stateToken": tx.StateToken}); err != nil {
+			return authnTransaction{}, err
+		}
+
+		p.Notify("Code sent to " + factor.Profile.PhoneNumber)
+	}
+
+	return c.verifyPassCode(ctx, factor, tx.StateToken, p)
+}
+
+// verifyPassCode verifies the factor with the passcode entered by the user,
+// which is asked again when it's wrong up to maxPassCodeAttempts times.
+func (c Client) verifyPassCode(ctx context.Context, factor Factor, stateToken string, p AuthnPrompter) (authnTransaction, error) {
+	for attempt := 1; ; attempt++ {
+		code, err := p.PassCode(factor)
+		if err != nil {
+			return authnTransaction{}, err
+		}
+
+		tx, err := c.authn(ctx, factor.Links.Verify.Href, map[string]string{
+			"stateToken": stateToken,
+			"passCode":   code,
+		})
+		if errors.Is(err, ErrInvalidPassCode) && attempt < maxPassCodeAttempts {
+			p.Notify("Invalid code, try again")
+			continue
+		}
+
+		return tx, err
+	}
+}
+
+// verifyPush sends a push to Okta Verify and polls the transaction every
+// pushPollInterval until the user answers it. The number to tap is shown when
+// Okta asks for it.
+func (c Client) verifyPush(ctx context.Context, factor Factor, stateToken string, p AuthnPrompter) (authnTransaction, error) {
+	state := map[string]string{"stateToken": stateToken}
+
+	tx, err := c.authn(ctx, factor.Links.Verify.Href, state)
+	if err != nil {
+		return authnTransaction{}, err
+	}
+
+	p.Notify("Approve the push sent to Okta Verify")
+
+	shown := 0
+	for tx.Status == "MFA_CHALLENGE" {
+		switch tx.FactorResult {
+		case "WAITING":
+		case "REJECTED":
+			return authnTransaction{}, ErrFactorRejected
+		case "TIMEOUT":
+			return authnTransaction{}, ErrFactorTimeout
+		default:
+			return authnTransaction{}, fmt.Errorf("%w: factor result %s", ErrAuthnStatus, tx.FactorResult)
+		}
+
+		if answer := tx.Embedded.Factor.Embedded.Challenge.CorrectAnswer; answer != 0 && answer != shown {
+			p.Notify(fmt.Sprintf("Tap %d in Okta Verify", answer))
+			shown = answer
+		}
+
+		select {
+		case <-ctx.Done():
+			return authnTransaction{}, ctx.Err()
+		case <-time.After(pushPollInterval):
+		}
+
+		if tx, err = c.authn(ctx, tx.Links.Next.Href, state); err != nil {
+			return authnTransaction{}, err
+		}
+	}
+
+	return tx, nil
+}
+
+// authn sends the body to the authn endpoint in uri and returns the
+// transaction it responds with.
+func (c Client) authn(ctx context.Context, uri string, body map[string]string) (authnTransaction, error) {
+	resp, err := c.postJSON(ctx, uri, body)
+	if err != nil {
+		return authnTransaction{}, fmt.Errorf("%w: %v", ErrAuthnRequest, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errRes authnError
+		if err := json.NewDecoder(resp.Body).Decode(&errRes); err != nil {
+			return authnTransaction{}, fmt.Errorf("%w: statusCode %d", ErrAuthnRequest, resp.StatusCode)
+		}
+
+		if errRes.Code == errCodeInvalidPassCode {
+			return authnTransaction{}, fmt.Errorf("%w: %s", ErrInvalidPassCode, errRes.Summary)
+		}
+
+		return authnTransaction{}, fmt.Errorf("%w: statusCode %d/%s: %s", ErrAuthnRequest, resp.StatusCode, errRes.Code, errRes.Summary)
+	}
+
+	var tx authnTransaction
+	if err := json.NewDecoder(resp.Body).Decode(&tx); err != nil {
+		return authnTransaction{}, fmt.Errorf("%w: %v", ErrAuthnRequest, err)
+	}
+
+	return tx, nil
+}
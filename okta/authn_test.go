@@ -0,0 +1,333 @@
+package okta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/client"
+)
+
+// authnServer is a stand-in Okta server for the authn flow. The user has the
+// password "secret" and the given factors, with code 123456 for TOTP and SMS.
+// Push verifications answer the results in push, one per poll. The app embed
+// link sets a session cookie and redirects to the page with the SAML response.
+type authnServer struct {
+	status  string
+	factors []string
+	push    []string
+	answer  int
+	smsSent bool
+}
+
+func (s *authnServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	base := "http://" + r.Host
+
+	switch {
+	case r.URL.Path == "/api/v1/authn":
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+
+		if body["username"] != "jane" || body["password"] != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errorCode":"E0000004","errorSummary":"Authentication failed"}`))
+			return
+		}
+
+		if s.status == "SUCCESS" {
+			w.Write([]byte(`{"status":"SUCCESS","sessionToken":"session"}`))
+			return
+		}
+
+		factors := make([]string, len(s.factors))
+		for i, typ := range s.factors {
+			provider := "OKTA"
+			if typ == FactorTOTP {
+				provider = "GOOGLE"
+			}
+
+			factors[i] = fmt.Sprintf(`{"id":"f%d","factorType":%q,"provider":%q,"profile":{"phoneNumber":"+1 XXX-XXX-1234","name":"Pixel"},"_links":{"verify":{"href":"%s/api/v1/authn/factors/f%d/verify"}}}`, i, typ, provider, base, i)
+		}
+
+		fmt.Fprintf(w, `{"status":%q,"stateToken":"state","_embedded":{"factors":[%s]},"_links":{"skip":{"href":"%s/api/v1/authn/skip"}}}`, s.status, strings.Join(factors, ","), base)
+	case r.URL.Path == "/api/v1/authn/skip":
+		w.Write([]byte(`{"status":"SUCCESS","sessionToken":"session"}`))
+	case strings.HasPrefix(r.URL.Path, "/api/v1/authn/factors/"):
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+
+		var i int
+		fmt.Sscanf(path.Base(path.Dir(r.URL.Path)), "f%d", &i)
+
+		if body["stateToken"] != "state" || i >= len(s.factors) {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errorCode":"E0000011","errorSummary":"Invalid token provided"}`))
+			return
+		}
+
+		switch s.factors[i] {
+		case FactorPush:
+			result := s.push[0]
+			s.push = s.push[1:]
+			if result == "SUCCESS" {
+				w.Write([]byte(`{"status":"SUCCESS","sessionToken":"session"}`))
+				return
+			}
+
+			fmt.Fprintf(w, `{"status":"MFA_CHALLENGE","stateToken":"state","factorResult":%q,"_embedded":{"factor":{"_embedded":{"challenge":{"correctAnswer":%d}}}},"_links":{"next":{"href":"%s%s"}}}`, result, s.answer, base, r.URL.Path)
+			return
+		case FactorSMS:
+			if body["passCode"] == "" {
+				s.smsSent = true
+				w.Write([]byte(`{"status":"MFA_CHALLENGE","stateToken":"state","factorResult":"CHALLENGE"}`))
+				return
+			}
+
+			if !s.smsSent {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"errorCode":"E0000068","errorSummary":"Invalid Passcode/Answer"}`))
+				return
+			}
+		}
+
+		if body["passCode"] != "123456" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errorCode":"E0000068","errorSummary":"Invalid Passcode/Answer"}`))
+			return
+		}
+
+		w.Write([]byte(`{"status":"SUCCESS","sessionToken":"session"}`))
+	case r.URL.Path == "/home/amazon_aws/0oa1/272":
+		if r.FormValue("sessionToken") != "session" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "session-cookie", Path: "/"})
+		http.Redirect(w, r, "/app/amazon_aws/exk1/sso/saml", http.StatusFound)
+	case r.URL.Path == "/app/amazon_aws/exk1/sso/saml":
+		if c, err := r.Cookie("sid"); err != nil || c.Value != "session-cookie" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(samlData)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// mockPrompter answers the prompts of the authn flow, choosing the factor of
+// the given type and entering the codes in order.
+type mockPrompter struct {
+	password string
+	factor   string
+	codes    []string
+
+	offered  []string
+	notified []string
+}
+
+func (p *mockPrompter) Credentials() (string, string, error) {
+	return "jane", p.password, nil
+}
+
+func (p *mockPrompter) ChooseFactor(factors []Factor) (Factor, error) {
+	for _, f := range factors {
+		p.offered = append(p.offered, f.String())
+	}
+
+	for _, f := range factors {
+		if f.Type == p.factor {
+			return f, nil
+		}
+	}
+
+	return Factor{}, errors.New("factor not offered")
+}
+
+func (p *mockPrompter) PassCode(factor Factor) (string, error) {
+	if len(p.codes) == 0 {
+		return "", errors.New("no codes left")
+	}
+
+	code := p.codes[0]
+	p.codes = p.codes[1:]
+	return code, nil
+}
+
+func (p *mockPrompter) Notify(msg string) {
+	p.notified = append(p.notified, msg)
+}
+
+func TestClientAuthorizeAuthn(t *testing.T) {
+	prevInterval := pushPollInterval
+	pushPollInterval = time.Millisecond
+	defer func() { pushPollInterval = prevInterval }()
+
+	tests := []struct {
+		name           string
+		server         authnServer
+		prompter       mockPrompter
+		noAppURL       bool
+		expectOffered  []string
+		expectNotified []string
+		expectErr      error
+	}{
+		{
+			name:     "no MFA: SAML assertion is sent to the provider",
+			server:   authnServer{status: "SUCCESS"},
+			prompter: mockPrompter{password: "secret"},
+		},
+		{
+			name:           "password expires soon: warning is skipped",
+			server:         authnServer{status: "PASSWORD_WARN"},
+			prompter:       mockPrompter{password: "secret"},
+			expectNotified: []string{"Your password expires soon, change it in Okta"},
+		},
+		{
+			name:           "TOTP: wrong code is asked again",
+			server:         authnServer{status: "MFA_REQUIRED", factors: []string{FactorTOTP, "question", FactorSMS}},
+			prompter:       mockPrompter{password: "secret", factor: FactorTOTP, codes: []string{"000000", "123456"}},
+			expectOffered:  []string{"Google Authenticator code", "SMS code to +1 XXX-XXX-1234"},
+			expectNotified: []string{"Invalid code, try again"},
+		},
+		{
+			name:           "SMS: code is sent before it's asked",
+			server:         authnServer{status: "MFA_REQUIRED", factors: []string{FactorSMS}},
+			prompter:       mockPrompter{password: "secret", factor: FactorSMS, codes: []string{"123456"}},
+			expectOffered:  []string{"SMS code to +1 XXX-XXX-1234"},
+			expectNotified: []string{"Code sent to +1 XXX-XXX-1234"},
+		},
+		{
+			name:           "push: polled until approved with number challenge",
+			server:         authnServer{status: "MFA_REQUIRED", factors: []string{FactorPush}, push: []string{"WAITING", "WAITING", "SUCCESS"}, answer: 42},
+			prompter:       mockPrompter{password: "secret", factor: FactorPush},
+			expectOffered:  []string{"Okta Verify push to Pixel"},
+			expectNotified: []string{"Approve the push sent to Okta Verify", "Tap 42 in Okta Verify"},
+		},
+		{
+			name:           "push rejected: error is returned",
+			server:         authnServer{status: "MFA_REQUIRED", factors: []string{FactorPush}, push: []string{"WAITING", "REJECTED"}},
+			prompter:       mockPrompter{password: "secret", factor: FactorPush},
+			expectOffered:  []string{"Okta Verify push to Pixel"},
+			expectNotified: []string{"Approve the push sent to Okta Verify"},
+			expectErr:      ErrFactorRejected,
+		},
+		{
+			name:           "push not answered: error is returned",
+			server:         authnServer{status: "MFA_REQUIRED", factors: []string{FactorPush}, push: []string{"TIMEOUT"}},
+			prompter:       mockPrompter{password: "secret", factor: FactorPush},
+			expectOffered:  []string{"Okta Verify push to Pixel"},
+			expectNotified: []string{"Approve the push sent to Okta Verify"},
+			expectErr:      ErrFactorTimeout,
+		},
+		{
+			name:           "wrong codes: error after the last attempt",
+			server:         authnServer{status: "MFA_REQUIRED", factors: []string{FactorTOTP}},
+			prompter:       mockPrompter{password: "secret", factor: FactorTOTP, codes: []string{"1", "2", "3"}},
+			expectOffered:  []string{"Google Authenticator code"},
+			expectNotified: []string{"Invalid code, try again", "Invalid code, try again"},
+			expectErr:      ErrInvalidPassCode,
+		},
+		{
+			name:      "no supported factor: error is returned",
+			server:    authnServer{status: "MFA_REQUIRED", factors: []string{"question"}},
+			prompter:  mockPrompter{password: "secret"},
+			expectErr: ErrNoSupportedFactor,
+		},
+		{
+			name:      "wrong password: error is returned",
+			server:    authnServer{status: "SUCCESS"},
+			prompter:  mockPrompter{password: "wrong"},
+			expectErr: ErrAuthnRequest,
+		},
+		{
+			name:      "locked out: error is returned",
+			server:    authnServer{status: "LOCKED_OUT"},
+			prompter:  mockPrompter{password: "secret"},
+			expectErr: ErrAuthnStatus,
+		},
+		{
+			name:      "no app URL: error is returned",
+			server:    authnServer{status: "SUCCESS"},
+			prompter:  mockPrompter{password: "secret"},
+			noAppURL:  true,
+			expectErr: ErrNoAppURL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(&tt.server)
+			defer srv.Close()
+
+			jar, _ := cookiejar.New(nil)
+
+			var assertion string
+			opts := []Option{SetHTTPClient(client.New(client.SetCookieJar(jar)))}
+			if !tt.noAppURL {
+				opts = append(opts, SetAppURL(srv.URL+"/home/amazon_aws/0oa1/272"))
+			}
+
+			c, err := New("123", srv.URL, mockSAMLProvider{&assertion}, opts...)
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			err = c.AuthorizeAuthn(context.Background(), &tt.prompter)
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("AuthorizeAuthn() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if !reflect.DeepEqual(tt.prompter.offered, tt.expectOffered) {
+				t.Errorf("AuthorizeAuthn() expected factors: %q, got: %q", tt.expectOffered, tt.prompter.offered)
+			}
+
+			if !reflect.DeepEqual(tt.prompter.notified, tt.expectNotified) {
+				t.Errorf("AuthorizeAuthn() expected messages: %q, got: %q", tt.expectNotified, tt.prompter.notified)
+			}
+
+			expect := ""
+			if tt.expectErr == nil {
+				expect = "dGhpcyBpcyBhIHRlc3QgZm9yIGJhc2U2NA=="
+			}
+			if assertion != expect {
+				t.Errorf("AuthorizeAuthn() expected SAML assertion: %q, got: %q", expect, assertion)
+			}
+		})
+	}
+}
+
+func TestClientAuthorizeAuthnCancel(t *testing.T) {
+	prevInterval := pushPollInterval
+	pushPollInterval = time.Hour
+	defer func() { pushPollInterval = prevInterval }()
+
+	srv := httptest.NewServer(&authnServer{status: "MFA_REQUIRED", factors: []string{FactorPush}, push: []string{"WAITING"}})
+	defer srv.Close()
+
+	var assertion string
+	c, err := New("123", srv.URL, mockSAMLProvider{&assertion}, SetAppURL(srv.URL+"/home/amazon_aws/0oa1/272"))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err = c.AuthorizeAuthn(ctx, &mockPrompter{password: "secret", factor: FactorPush})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("AuthorizeAuthn() expected error: %v, got: %v", context.DeadlineExceeded, err)
+	}
+}
@@ -1,10 +1,11 @@
 package okta
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
 	"net/url"
+
+	"github.com/fox-tech/creds-fetcher/internal/oauth"
 )
 
 // Device represents the Device Authorization from Okta with the URL the user
@@ -16,65 +17,106 @@ import (
 //
 // More at
 // https://developer.okta.com/docs/guides/device-authorization-grant/main/#request-the-device-verification-code.
-type Device struct {
-	DeviceCode              string `json:"device_code"`
-	UserCode                string `json:"user_code"`
-	VerificationURI         string `json:"verification_uri"`
-	VerificationURIComplete string `json:"verification_uri_complete"`
-	ExpiresIn               int64  `json:"expires_in"`
-	Interval                int64  `json:"interval"`
-}
-
-// errorResponse represents an error returned by the Okta API.
-type errorResponse struct {
-	Error       string `json:"error"`
-	Description string `json:"error_description"`
-}
+type Device = oauth.Device
 
 // PreAuthorize returns a Device Authorization with a URL to be shown to the
 // user. This Device Authorization then is passed to the Authorize method to
 // exchange for SSO and web SSO tokens. Returns non-nil error if there's an
-// issue with the request to Okta or the parsing of Okta's response.
-func (c Client) PreAuthorize() (Device, error) {
-	uri := fmt.Sprintf("%s/oauth2/v1/device/authorize", c.uri)
-
-	resp, err := http.PostForm(uri,
-		url.Values{
-			"client_id": []string{c.id},
-			"scope":     []string{"openid okta.apps.sso"},
-		},
-	)
+// issue with the request to Okta, Okta responds with an error or the parsing
+// of Okta's response fails.
+func (c Client) PreAuthorize(ctx context.Context) (Device, error) {
+	e, err := c.endpoints(ctx)
 	if err != nil {
 		return Device{}, fmt.Errorf("%w: %v", ErrPreAuthorizeRequest, err)
 	}
-	defer resp.Body.Close()
 
-	var device Device
-	if err := json.NewDecoder(resp.Body).Decode(&device); err != nil {
-		return Device{}, fmt.Errorf("%w: %v", ErrPreAuthorizeJSONDecode, err)
+	values := url.Values{
+		"scope": []string{c.scope()},
+	}
+	c.addAuthPolicy(values)
+
+	return c.oauth().DeviceAuthorization(ctx, e.DeviceAuthorization, values)
+}
+
+// scope returns the scope requested for the user tokens.
+func (c Client) scope() string {
+	// the web identity session is named after the email of the ID token
+	if c.webIdentity {
+		return "openid email"
 	}
 
-	return device, nil
+	return "openid okta.apps.sso"
 }
 
 // Authorize takes a Device setup and runs all the final token exchanges,
 // sending the SAML assertion to the provider interface to generate credentials
-// on the provider side.
-func (c Client) Authorize(device Device) error {
-	token, err := c.accessTokenPoll(device)
+// on the provider side. With SetWebIdentity, the ID token is sent instead.
+// The ID token is verified before it's used. Polling and requests stop when
+// ctx is done, returning its error.
+func (c Client) Authorize(ctx context.Context, device Device) error {
+	token, err := c.accessTokenPoll(ctx, device)
 	if err != nil {
 		return err
 	}
 
-	ssoToken, err := c.ssoAccessToken(token)
+	if err := c.verifyToken(ctx, token, ""); err != nil {
+		return err
+	}
+
+	c.saveToken(token)
+
+	return c.generateCredentials(ctx, token)
+}
+
+// AuthorizeClientCredentials authenticates as a service app with the client
+// credentials flow, using the private key set with SetPrivateKeyJWT, and runs
+// the same final token exchanges as Authorize. No user interaction is needed.
+// Both exchanges need an ID token, ErrNoIDToken is returned when the
+// authorization server returned none, as the access token of the app is
+// neither meant for AWS nor the subject of the exchange for the SSO token.
+func (c Client) AuthorizeClientCredentials(ctx context.Context) error {
+	if c.privateKey == nil {
+		return ErrNoPrivateKey
+	}
+
+	token, err := c.clientCredentialsToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	if token.IDToken == "" {
+		return ErrNoIDToken
+	}
+
+	if err := c.verifyToken(ctx, token, ""); err != nil {
+		return err
+	}
+
+	c.saveToken(token)
+
+	return c.generateCredentials(ctx, token)
+}
+
+// generateCredentials sends the ID token of the OAuth2 token, or the SAML
+// assertion it's exchanged for, to the provider.
+func (c Client) generateCredentials(ctx context.Context, token accessToken) error {
+	if c.webIdentity {
+		if token.IDToken == "" {
+			return ErrNoIDToken
+		}
+
+		return c.provider.(WebIdentityProvider).GenerateCredentialsWithWebIdentity(ctx, token.IDToken)
+	}
+
+	ssoToken, err := c.ssoAccessToken(ctx, token)
 	if err != nil {
 		return err
 	}
 
-	saml, err := c.getSAML(ssoToken)
+	saml, err := c.getSAML(ctx, ssoToken)
 	if err != nil {
 		return err
 	}
 
-	return c.provider.GenerateCredentials(saml)
+	return c.provider.GenerateCredentials(ctx, saml)
 }
@@ -2,58 +2,66 @@ package okta
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/client"
+	"github.com/fox-tech/creds-fetcher/internal/oauth"
+	"github.com/fox-tech/creds-fetcher/internal/oktatest"
 )
 
+// testOktaURL is the organization URL of the clients of the tests answered by
+// a client.MockHandlerClient.
+const testOktaURL = "https://okta.example.com"
+
 type testClientPreAuthorize struct {
 	res      []byte
 	clientID string
+	badJSON  bool
 }
 
-func newServerClientPreAuthorize(input testClientPreAuthorize, status int) *httptest.Server {
-	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+func newHandlerClientPreAuthorize(input testClientPreAuthorize, status int) client.HTTPClient {
+	return client.MockHandlerClient{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		expectedURI := "/oauth2/v1/device/authorize"
-		if r.RequestURI != expectedURI {
+		expectedPath := "/oauth2/v1/device/authorize"
+		if r.URL.Path != expectedPath {
 			w.WriteHeader(http.StatusBadRequest)
-			errString := fmt.Sprintf(`{"error":"badURL","error_description":"requested %s and should be %s"}`, r.RequestURI, expectedURI)
+			errString := fmt.Sprintf(`{"error":"badURL","error_description":"requested %s and should be %s"}`, r.URL.Path, expectedPath)
 			w.Write([]byte(errString))
 			return
 		}
 
-		body, _ := ioutil.ReadAll(r.Body)
-		defer r.Body.Close()
-
-		expectedPayload := fmt.Sprintf("client_id=%s&scope=openid+okta.apps.sso", input.clientID)
-		if string(body) != expectedPayload {
+		if r.FormValue("client_id") != input.clientID || r.FormValue("scope") != "openid okta.apps.sso" {
 			w.WriteHeader(http.StatusNotAcceptable)
-			errString := fmt.Sprintf(`{"error":"badPayload","error_description":"received %s and should be %s"}`, string(body), expectedPayload)
+			errString := fmt.Sprintf(`{"error":"badPayload","error_description":"received client_id %s and scope %s"}`, r.FormValue("client_id"), r.FormValue("scope"))
 			w.Write([]byte(errString))
 			return
 		}
 
 		w.WriteHeader(status)
-		if status == http.StatusNotAcceptable {
+		if input.badJSON {
 			w.Write([]byte(`{{`))
 		}
 
 		w.Write(input.res)
-	}))
+	})}
 }
 
 func TestClientPreAuthorize(t *testing.T) {
 	cases := []struct {
 		name    string
 		expect  Device
-		errBody errorResponse
+		errBody oauth.ErrorResponse
+		badJSON bool
 		id      string
 		status  int
 		err     error
@@ -72,17 +80,22 @@ func TestClientPreAuthorize(t *testing.T) {
 			},
 		},
 		{
-			name:   "400: bad request",
-			status: http.StatusBadRequest,
+			name:    "400: bad request",
+			status:  http.StatusBadRequest,
+			errBody: oauth.ErrorResponse{Error: "invalid_client", Description: "Invalid value for 'client_id' parameter."},
+			err:     ErrPreAuthorizeRequest,
 		},
 		{
-			name:   "force json decoding error",
-			status: http.StatusNotAcceptable,
-			err:    ErrPreAuthorizeJSONDecode,
+			name:    "force json decoding error",
+			status:  http.StatusOK,
+			badJSON: true,
+			err:     ErrPreAuthorizeJSONDecode,
 		},
 		{
-			name:   "503: forcing server error",
-			status: http.StatusServiceUnavailable,
+			name:    "503: forcing server error",
+			status:  http.StatusServiceUnavailable,
+			badJSON: true,
+			err:     ErrPreAuthorizeRequest,
 		},
 	}
 
@@ -105,14 +118,13 @@ func TestClientPreAuthorize(t *testing.T) {
 			input := testClientPreAuthorize{
 				res:      buf.Bytes(),
 				clientID: tt.id,
+				badJSON:  tt.badJSON,
 			}
 
-			srv := newServerClientPreAuthorize(input, tt.status)
-			defer srv.Close()
-			c := Client{uri: srv.URL, id: tt.id}
+			c := Client{uri: testOktaURL, id: tt.id, httpClient: newHandlerClientPreAuthorize(input, tt.status)}
 
 			// actual test
-			res, err := c.PreAuthorize()
+			res, err := c.PreAuthorize(context.Background())
 			if err != nil && !errors.Is(err, tt.err) {
 				t.Fatalf("expected %v, received: %v", tt.err, err)
 			}
@@ -131,6 +143,8 @@ func TestClientPreAuthorize(t *testing.T) {
 type testClientAuthorize struct {
 	clientID     string
 	pollResponse accessToken
+	// signIDToken adds an ID token issued by the server to the poll response
+	signIDToken  bool
 	pollStatus   int
 	ssoResponse  accessToken
 	ssoStatus    int
@@ -138,22 +152,22 @@ type testClientAuthorize struct {
 	samlStatus   int
 }
 
-func newServerClientAuthorize(input testClientAuthorize) *httptest.Server {
-	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.RequestURI {
+func newHandlerClientAuthorize(input testClientAuthorize) client.HTTPClient {
+	return client.MockHandlerClient{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2/v1/keys":
+			w.Write([]byte(oktatest.JWKS()))
 		case "/oauth2/v1/token": // token poll or sso token
 			w.Header().Set("Content-Type", "application/json")
-			body, err := ioutil.ReadAll(r.Body)
-			defer r.Body.Close()
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(`{"error":"bad_payload","error_description":"` + err.Error() + `}`))
-				return
-			}
 
 			buf := new(bytes.Buffer)
-			if strings.Contains(string(body), "device_code") { // token poll
-				err := json.NewEncoder(buf).Encode(input.pollResponse)
+			if r.FormValue("device_code") != "" { // token poll
+				pollResponse := input.pollResponse
+				if input.signIDToken {
+					pollResponse.IDToken = testIDToken(testOktaURL, "testid", "")
+				}
+
+				err := json.NewEncoder(buf).Encode(pollResponse)
 				if err != nil {
 					w.WriteHeader(http.StatusInternalServerError)
 					w.Write([]byte(`{"error":"bad_encoding","error_description":"` + err.Error() + `}`))
@@ -172,17 +186,19 @@ func newServerClientAuthorize(input testClientAuthorize) *httptest.Server {
 				return
 			}
 
-		default:
+		case "/login/token/sso":
 			w.Header().Set("Content-Type", "text/html")
-			if !strings.Contains(r.RequestURI, "/login/token/sso?token=") {
-				w.WriteHeader(http.StatusNotFound)
+			if r.URL.Query().Get("token") == "" {
+				w.WriteHeader(http.StatusBadRequest)
 				return
 			}
 
 			w.WriteHeader(input.samlStatus)
 			w.Write(input.samlResponse)
+		default:
+			w.WriteHeader(http.StatusNotFound)
 		}
-	}))
+	})}
 }
 
 func TestClientAuthorize(t *testing.T) {
@@ -208,7 +224,8 @@ func TestClientAuthorize(t *testing.T) {
 			id:     "testid",
 			srvInput: testClientAuthorize{
 				clientID:     "testid",
-				pollResponse: accessToken{AccessToken: "random_accesstoken", IDToken: "random_idtoken"},
+				pollResponse: accessToken{AccessToken: "random_accesstoken"},
+				signIDToken:  true,
 				pollStatus:   http.StatusOK,
 				ssoResponse:  accessToken{AccessToken: "random_ssotoken"},
 				ssoStatus:    http.StatusOK,
@@ -222,7 +239,8 @@ func TestClientAuthorize(t *testing.T) {
 			id:     "testid",
 			srvInput: testClientAuthorize{
 				clientID:     "testid",
-				pollResponse: accessToken{AccessToken: "random_accesstoken", IDToken: "random_idtoken"},
+				pollResponse: accessToken{AccessToken: "random_accesstoken"},
+				signIDToken:  true,
 				pollStatus:   http.StatusInternalServerError,
 				ssoResponse:  accessToken{AccessToken: "random_ssotoken"},
 				ssoStatus:    http.StatusOK,
@@ -237,7 +255,8 @@ func TestClientAuthorize(t *testing.T) {
 			id:     "testid",
 			srvInput: testClientAuthorize{
 				clientID:     "testid",
-				pollResponse: accessToken{AccessToken: "random_accesstoken", IDToken: "random_idtoken"},
+				pollResponse: accessToken{AccessToken: "random_accesstoken"},
+				signIDToken:  true,
 				pollStatus:   http.StatusOK,
 				ssoResponse:  accessToken{AccessToken: "random_ssotoken"},
 				ssoStatus:    http.StatusInternalServerError,
@@ -252,7 +271,8 @@ func TestClientAuthorize(t *testing.T) {
 			id:     "testid",
 			srvInput: testClientAuthorize{
 				clientID:     "testid",
-				pollResponse: accessToken{AccessToken: "random_accesstoken", IDToken: "random_idtoken"},
+				pollResponse: accessToken{AccessToken: "random_accesstoken"},
+				signIDToken:  true,
 				pollStatus:   http.StatusOK,
 				ssoResponse:  accessToken{AccessToken: "random_ssotoken"},
 				ssoStatus:    http.StatusOK,
@@ -261,25 +281,225 @@ func TestClientAuthorize(t *testing.T) {
 			},
 			err: ErrSAMLRequest,
 		},
+		{
+			name:   "200 with unsigned ID token",
+			device: defaultDevice,
+			id:     "testid",
+			srvInput: testClientAuthorize{
+				clientID:     "testid",
+				pollResponse: accessToken{AccessToken: "random_accesstoken", IDToken: "random_idtoken"},
+				pollStatus:   http.StatusOK,
+				ssoResponse:  accessToken{AccessToken: "random_ssotoken"},
+				ssoStatus:    http.StatusOK,
+				samlResponse: samlData,
+				samlStatus:   http.StatusOK,
+			},
+			err: ErrInvalidIDToken,
+		},
+		{
+			name:   "200 with ID token for another client",
+			device: defaultDevice,
+			id:     "otherid",
+			srvInput: testClientAuthorize{
+				clientID:     "otherid",
+				pollResponse: accessToken{AccessToken: "random_accesstoken"},
+				signIDToken:  true,
+				pollStatus:   http.StatusOK,
+				ssoResponse:  accessToken{AccessToken: "random_ssotoken"},
+				ssoStatus:    http.StatusOK,
+				samlResponse: samlData,
+				samlStatus:   http.StatusOK,
+			},
+			err: ErrInvalidIDToken,
+		},
 	}
 
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
 			// pre-setup
-			srv := newServerClientAuthorize(tt.srvInput)
-			defer srv.Close()
-
-			c, err := New(tt.id, srv.URL, mockProvider{})
+			hc := newHandlerClientAuthorize(tt.srvInput)
+			c, err := New(tt.id, testOktaURL, mockProvider{}, SetHTTPClient(hc))
 			if err != nil {
 				t.Fatalf("unexpected error initializing Client: %v", err)
 			}
 
 			// actual test
-			err = c.Authorize(tt.device)
-			if err != nil && !errors.Is(err, tt.err) {
+			err = c.Authorize(context.Background(), tt.device)
+			if !errors.Is(err, tt.err) {
 				t.Fatalf("expected %v, received: %v", tt.err, err)
 			}
 		})
 	}
 
 }
+
+func TestClientAuthorizeWebIdentity(t *testing.T) {
+	device := Device{
+		DeviceCode: "b33fid-d3v1c3c0d3",
+		ExpiresIn:  5,
+		Interval:   1,
+	}
+
+	cases := []struct {
+		name     string
+		err      error
+		idToken  string
+		srvInput testClientAuthorize
+	}{
+		{
+			name:    "200: ID token is sent to the provider",
+			idToken: "signed",
+			srvInput: testClientAuthorize{
+				pollResponse: accessToken{AccessToken: "random_accesstoken"},
+				signIDToken:  true,
+				pollStatus:   http.StatusOK,
+				ssoStatus:    http.StatusInternalServerError,
+				samlStatus:   http.StatusInternalServerError,
+			},
+		},
+		{
+			name: "200 without ID token",
+			srvInput: testClientAuthorize{
+				pollResponse: accessToken{AccessToken: "random_accesstoken"},
+				pollStatus:   http.StatusOK,
+			},
+			err: ErrNoIDToken,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := newHandlerClientAuthorize(tt.srvInput)
+
+			var got string
+			c, err := New("testid", testOktaURL, mockWebIdentityProvider{idToken: &got}, SetWebIdentity(), SetHTTPClient(hc))
+			if err != nil {
+				t.Fatalf("unexpected error initializing Client: %v", err)
+			}
+
+			// the token is signed when the server answers, in the same second
+			if tt.idToken == "signed" {
+				now := time.Now()
+				prevNow := timeNow
+				timeNow = func() time.Time { return now }
+				defer func() { timeNow = prevNow }()
+
+				tt.idToken = testIDToken(testOktaURL, "testid", "")
+			}
+
+			// EC signatures are random, only the signed part is compared
+			unsigned := func(token string) string {
+				if i := strings.LastIndex(token, "."); i >= 0 {
+					return token[:i]
+				}
+				return token
+			}
+
+			err = c.Authorize(context.Background(), device)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, received: %v", tt.err, err)
+			}
+
+			if unsigned(got) != unsigned(tt.idToken) {
+				t.Errorf("expected ID token %q, provider received: %q", tt.idToken, got)
+			}
+		})
+	}
+}
+
+func TestClientAuthorizeClientCredentials(t *testing.T) {
+	idToken := testIDToken(testOktaURL, "testid", "")
+
+	newHandlerClient := func(withIDToken bool) client.HTTPClient {
+		return client.MockHandlerClient{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/login/token/sso":
+				w.Write(samlData)
+				return
+			case "/oauth2/v1/keys":
+				w.Write([]byte(oktatest.JWKS()))
+				return
+			}
+
+			if r.FormValue("client_assertion_type") != clientAssertionType || r.FormValue("client_assertion") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"invalid_client","error_description":"missing client assertion"}`))
+				return
+			}
+
+			switch r.FormValue("grant_type") {
+			case "client_credentials":
+				if !withIDToken {
+					w.Write([]byte(`{"access_token":"service_accesstoken"}`))
+					return
+				}
+				fmt.Fprintf(w, `{"access_token":"service_accesstoken","id_token":%q}`, idToken)
+			case "urn:ietf:params:oauth:grant-type:token-exchange":
+				// the ID token is the subject and the access token the actor,
+				// like in the exchanges of the user logins
+				if r.FormValue("subject_token") != idToken || r.FormValue("subject_token_type") != "urn:ietf:params:oauth:token-type:id_token" ||
+					r.FormValue("actor_token") != "service_accesstoken" || r.FormValue("actor_token_type") != "urn:ietf:params:oauth:token-type:access_token" {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"error":"invalid_grant","error_description":"bad subject or actor token"}`))
+					return
+				}
+				w.Write([]byte(`{"access_token":"random_ssotoken"}`))
+			}
+		})}
+	}
+
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	cases := []struct {
+		name        string
+		opts        []Option
+		noIDToken   bool
+		webIdentity string
+		err         error
+	}{
+		{
+			name: "SAML: ID token is exchanged and credentials are generated",
+			opts: []Option{SetPrivateKeyJWT(key, "")},
+		},
+		{
+			name:        "web identity: ID token is sent to the provider",
+			opts:        []Option{SetPrivateKeyJWT(key, ""), SetWebIdentity()},
+			webIdentity: idToken,
+		},
+		{
+			name:      "SAML without ID token: access token is not exchanged",
+			opts:      []Option{SetPrivateKeyJWT(key, "")},
+			noIDToken: true,
+			err:       ErrNoIDToken,
+		},
+		{
+			name:      "web identity without ID token: access token is not sent",
+			opts:      []Option{SetPrivateKeyJWT(key, ""), SetWebIdentity()},
+			noIDToken: true,
+			err:       ErrNoIDToken,
+		},
+		{
+			name: "no private key",
+			err:  ErrNoPrivateKey,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			c, err := New("testid", testOktaURL, mockWebIdentityProvider{idToken: &got}, append(tt.opts, SetHTTPClient(newHandlerClient(!tt.noIDToken)))...)
+			if err != nil {
+				t.Fatalf("unexpected error initializing Client: %v", err)
+			}
+
+			err = c.AuthorizeClientCredentials(context.Background())
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, received: %v", tt.err, err)
+			}
+
+			if got != tt.webIdentity {
+				t.Errorf("expected web identity token %q, provider received: %q", tt.webIdentity, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package okta
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrAuthPolicy is returned when the ID token of a login doesn't satisfy the
+// ACR values or max age set with SetACRValues and SetMaxAge, e.g. because the
+// user logged in without a phishing-resistant factor.
+var ErrAuthPolicy = errors.New("login does not satisfy the authentication policy")
+
+// hasAuthPolicy reports whether the client requires ACR values or a max age.
+func (c Client) hasAuthPolicy() bool {
+	return c.acrValues != "" || c.maxAge != nil
+}
+
+// addAuthPolicy adds the ACR values and max age of the client to the values
+// of an authorization request.
+//
+// More at https://developer.okta.com/docs/guides/step-up-authentication/main/.
+func (c Client) addAuthPolicy(values url.Values) {
+	if c.acrValues != "" {
+		values.Set("acr_values", c.acrValues)
+	}
+
+	if c.maxAge != nil {
+		values.Set("max_age", strconv.FormatInt(int64(*c.maxAge/time.Second), 10))
+	}
+}
+
+// checkAuthPolicy returns ErrAuthPolicy when the claims of the ID token don't
+// satisfy the authentication policy of the client. The acr claim must be one
+// of the ACR values, and the amr claim must list more than one factor when it
+// asks for two factors. The auth_time claim must be within the max age.
+func (c Client) checkAuthPolicy(claims Claims) error {
+	if c.acrValues != "" {
+		values := strings.Fields(c.acrValues)
+		if !contains(values, claims.ACR) {
+			return fmt.Errorf("%w: acr %q is not one of %s", ErrAuthPolicy, claims.ACR, strings.Join(values, ", "))
+		}
+
+		if requiresMFA(claims.ACR) && !contains(claims.AMR, "mfa") && len(claims.AMR) < 2 {
+			return fmt.Errorf("%w: authentication methods %v are a single factor", ErrAuthPolicy, claims.AMR)
+		}
+	}
+
+	if c.maxAge != nil {
+		if claims.AuthTime == 0 {
+			return fmt.Errorf("%w: no auth_time in the id token", ErrAuthPolicy)
+		}
+
+		authTime := time.Unix(claims.AuthTime, 0)
+		if timeNow().Sub(authTime) > *c.maxAge+clockSkew {
+			return fmt.Errorf("%w: authenticated at %s, more than %s ago", ErrAuthPolicy, authTime.UTC(), *c.maxAge)
+		}
+	}
+
+	return nil
+}
+
+// requiresMFA reports whether the ACR value asks for two factors, including
+// the phishing-resistant ones.
+func requiresMFA(acr string) bool {
+	return strings.Contains(acr, ":2fa:") || acr == "phr" || acr == "phrh"
+}
+
+// contains reports whether values includes v.
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,187 @@
+package okta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/internal/oktatest"
+)
+
+func TestClientCheckAuthPolicy(t *testing.T) {
+	now := time.Date(2023, 2, 1, 12, 0, 0, 0, time.UTC)
+	prevNow := timeNow
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = prevNow }()
+
+	hour := time.Hour
+	zero := time.Duration(0)
+
+	tests := []struct {
+		name      string
+		acrValues string
+		maxAge    *time.Duration
+		claims    Claims
+		expectErr error
+	}{
+		{
+			name:   "no policy: any login is accepted",
+			claims: Claims{ACR: "urn:okta:loa:1fa:pwd"},
+		},
+		{
+			name:      "phishing-resistant login: accepted",
+			acrValues: "phr phrh",
+			claims:    Claims{ACR: "phr", AMR: []string{"pop", "mfa"}},
+		},
+		{
+			name:      "two factor login with listed methods: accepted",
+			acrValues: "urn:okta:loa:2fa:any",
+			claims:    Claims{ACR: "urn:okta:loa:2fa:any", AMR: []string{"pwd", "otp"}},
+		},
+		{
+			name:      "password login for phishing-resistant policy: rejected",
+			acrValues: "phr",
+			claims:    Claims{ACR: "urn:okta:loa:1fa:pwd", AMR: []string{"pwd"}},
+			expectErr: ErrAuthPolicy,
+		},
+		{
+			name:      "two factor acr with a single method: rejected",
+			acrValues: "urn:okta:loa:2fa:any",
+			claims:    Claims{ACR: "urn:okta:loa:2fa:any", AMR: []string{"pwd"}},
+			expectErr: ErrAuthPolicy,
+		},
+		{
+			name:   "recent login: accepted",
+			maxAge: &hour,
+			claims: Claims{AuthTime: now.Add(-30 * time.Minute).Unix()},
+		},
+		{
+			name:   "new login for zero max age within the clock skew: accepted",
+			maxAge: &zero,
+			claims: Claims{AuthTime: now.Add(-time.Minute).Unix()},
+		},
+		{
+			name:      "old login: rejected",
+			maxAge:    &hour,
+			claims:    Claims{AuthTime: now.Add(-2 * time.Hour).Unix()},
+			expectErr: ErrAuthPolicy,
+		},
+		{
+			name:      "no auth_time: rejected",
+			maxAge:    &hour,
+			expectErr: ErrAuthPolicy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Client{acrValues: tt.acrValues, maxAge: tt.maxAge}
+
+			if err := c.checkAuthPolicy(tt.claims); !errors.Is(err, tt.expectErr) {
+				t.Errorf("checkAuthPolicy() expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestClientAuthorizeAuthPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		claims     map[string]interface{}
+		noIDToken  bool
+		expectSAML bool
+		expectErr  error
+	}{
+		{
+			name:       "policy satisfied: SAML step runs",
+			claims:     map[string]interface{}{"acr": "phr", "amr": []string{"pop", "mfa"}, "auth_time": time.Now().Unix()},
+			expectSAML: true,
+		},
+		{
+			name:      "password login: fails before the SAML step",
+			claims:    map[string]interface{}{"acr": "urn:okta:loa:1fa:pwd", "amr": []string{"pwd"}, "auth_time": time.Now().Unix()},
+			expectErr: ErrAuthPolicy,
+		},
+		{
+			name:      "old session: fails before the SAML step",
+			claims:    map[string]interface{}{"acr": "phr", "amr": []string{"pop", "mfa"}, "auth_time": time.Now().Add(-time.Hour).Unix()},
+			expectErr: ErrAuthPolicy,
+		},
+		{
+			name:      "no ID token: fails before the SAML step",
+			noIDToken: true,
+			expectErr: ErrAuthPolicy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samlRequested := false
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/oauth2/v1/device/authorize":
+					if r.FormValue("acr_values") != "phr" || r.FormValue("max_age") != "300" {
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write([]byte(`{"error":"invalid_request"}`))
+						return
+					}
+
+					w.Write([]byte(`{"device_code":"devicecode","user_code":"CODE","expires_in":5,"interval":1}`))
+				case "/oauth2/v1/keys":
+					w.Write([]byte(oktatest.JWKS()))
+				case "/oauth2/v1/token":
+					if r.FormValue("device_code") == "" {
+						w.Write([]byte(`{"access_token":"ssotoken"}`))
+						return
+					}
+
+					token := accessToken{AccessToken: "accesstoken"}
+					if !tt.noIDToken {
+						claims := map[string]interface{}{
+							"iss": "http://" + r.Host,
+							"sub": "00u1",
+							"aud": "testid",
+							"iat": time.Now().Unix(),
+							"exp": time.Now().Add(time.Hour).Unix(),
+						}
+						for k, v := range tt.claims {
+							claims[k] = v
+						}
+
+						token.IDToken = oktatest.Sign(map[string]string{"alg": "ES256", "kid": oktatest.KeyID}, claims)
+					}
+					json.NewEncoder(w).Encode(token)
+				case "/login/token/sso":
+					samlRequested = true
+					w.Write(samlData)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer srv.Close()
+
+			c, err := New("testid", srv.URL, mockProvider{}, SetACRValues("phr"), SetMaxAge(5*time.Minute))
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			device, err := c.PreAuthorize(context.Background())
+			if err != nil {
+				t.Fatalf("PreAuthorize() unexpected error: %v", err)
+			}
+
+			err = c.Authorize(context.Background(), device)
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("Authorize() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if samlRequested != tt.expectSAML {
+				t.Errorf("Authorize() expected SAML requested: %t, got: %t", tt.expectSAML, samlRequested)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package okta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/internal/oauth"
+)
+
+const (
+	CacheDirectory         = ".fox-tech"
+	DiscoveryCacheFileName = "okta-discovery.json"
+	JWKSCacheFileName      = "okta-jwks.json"
+
+	// discoveryTTL is how long the discovered endpoints and keys are used
+	// before they are requested again.
+	discoveryTTL = 24 * time.Hour
+)
+
+// ErrDiscovery is returned when the OpenID configuration of the authorization
+// server can't be requested.
+var ErrDiscovery = oauth.ErrDiscovery
+
+// fileSystemManager defines the methods that the discovery and token caches
+// need a file system manager to have
+type fileSystemManager interface {
+	ReadCacheFile(dir, filename string) ([]byte, error)
+	WriteCacheFile(dir, filename string, data []byte) error
+}
+
+// endpoints are the endpoints of an authorization server, from its OpenID
+// configuration.
+//
+// More at https://developer.okta.com/docs/reference/api/oidc/#well-known-openid-configuration.
+type endpoints struct {
+	Issuer              string    `json:"issuer"`
+	Authorization       string    `json:"authorization_endpoint"`
+	DeviceAuthorization string    `json:"device_authorization_endpoint"`
+	Token               string    `json:"token_endpoint"`
+	Revocation          string    `json:"revocation_endpoint"`
+	Userinfo            string    `json:"userinfo_endpoint"`
+	JWKS                string    `json:"jwks_uri"`
+	FetchedAt           time.Time `json:"fetched_at"`
+}
+
+// discovery keeps the endpoints and keys discovered by a client, shared by
+// its copies.
+type discovery struct {
+	mu        sync.Mutex
+	endpoints *endpoints
+	keys      []jwk
+}
+
+// issuer returns the URL of the authorization server of the client, the org
+// authorization server or the custom one set with SetAuthServerID.
+func (c Client) issuer() string {
+	uri := strings.TrimSuffix(c.uri, "/")
+	if c.authServerID == "" {
+		return uri
+	}
+
+	return uri + "/oauth2/" + c.authServerID
+}
+
+// defaultEndpoints returns the endpoints Okta uses for the authorization
+// server of the client, used when it has no OpenID configuration.
+func (c Client) defaultEndpoints() endpoints {
+	base := c.issuer() + "/oauth2"
+	if c.authServerID != "" {
+		base = c.issuer()
+	}
+
+	return endpoints{
+		Issuer:              c.issuer(),
+		Authorization:       base + "/v1/authorize",
+		DeviceAuthorization: base + "/v1/device/authorize",
+		Token:               base + "/v1/token",
+		Revocation:          base + "/v1/revoke",
+		Userinfo:            base + "/v1/userinfo",
+		JWKS:                base + "/v1/keys",
+	}
+}
+
+// endpoints returns the endpoints of the authorization server of the client.
+// They are discovered once per client and, when the client has a cache, kept
+// on disk for a day. The Okta defaults are used when the server has no valid
+// OpenID configuration.
+func (c Client) endpoints(ctx context.Context) (endpoints, error) {
+	if c.discovery != nil {
+		c.discovery.mu.Lock()
+		defer c.discovery.mu.Unlock()
+
+		if c.discovery.endpoints != nil {
+			return *c.discovery.endpoints, nil
+		}
+	}
+
+	e, err := c.discover(ctx)
+	if err != nil {
+		return endpoints{}, err
+	}
+
+	if c.discovery != nil {
+		c.discovery.endpoints = &e
+	}
+
+	return e, nil
+}
+
+// discover returns the cached endpoints of the issuer or requests its OpenID
+// configuration, caching it. The configuration must be of the issuer of the
+// client, the default endpoints are used and nothing is cached otherwise.
+//
+// More at https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderConfigurationValidation.
+func (c Client) discover(ctx context.Context) (endpoints, error) {
+	issuer := c.issuer()
+
+	cached := map[string]endpoints{}
+	useCache := c.readCache(DiscoveryCacheFileName, &cached)
+	if e, ok := cached[issuer]; ok && e.Issuer == issuer && timeNow().Before(e.FetchedAt.Add(discoveryTTL)) {
+		return e, nil
+	}
+
+	var e endpoints
+	if err := c.oauth().Discover(ctx, issuer, &e); err != nil {
+		if !errors.Is(err, oauth.ErrNoOpenIDConfiguration) {
+			return endpoints{}, err
+		}
+
+		log.Printf("%v, using the default endpoints", err)
+		return c.defaultEndpoints(), nil
+	}
+
+	if e.Issuer != issuer {
+		log.Printf("openid configuration of %s is for the issuer %q, using the default endpoints", issuer, e.Issuer)
+		return c.defaultEndpoints(), nil
+	}
+
+	if e.Token == "" {
+		log.Printf("invalid openid configuration for %s, using the default endpoints", issuer)
+		return c.defaultEndpoints(), nil
+	}
+
+	// Okta leaves out the endpoints of disabled grants, the device
+	// authorization is then rejected by the default endpoint
+	defaults := c.defaultEndpoints()
+	if e.DeviceAuthorization == "" {
+		e.DeviceAuthorization = defaults.DeviceAuthorization
+	}
+	if e.Authorization == "" {
+		e.Authorization = defaults.Authorization
+	}
+	if e.JWKS == "" {
+		e.JWKS = defaults.JWKS
+	}
+
+	if useCache {
+		e.FetchedAt = timeNow()
+		cached[issuer] = e
+		c.writeCache(DiscoveryCacheFileName, cached)
+	}
+
+	return e, nil
+}
+
+// readCache decodes the cache file name into v, it returns false if the
+// client has no cache or it can't be read. A missing file is an empty cache.
+func (c Client) readCache(name string, v interface{}) bool {
+	if c.fs == nil {
+		return false
+	}
+
+	data, err := c.fs.ReadCacheFile(CacheDirectory, name)
+	if err != nil {
+		log.Printf("could not read the cache: %v", err)
+		return false
+	}
+
+	// a corrupted cache is replaced when it's written
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, v); err != nil {
+			log.Printf("could not decode the cache %s: %v", name, err)
+		}
+	}
+
+	return true
+}
+
+// writeCache saves v in the cache file name. The cache is only an
+// optimization, errors are logged.
+func (c Client) writeCache(name string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("could not encode the cache %s: %v", name, err)
+		return
+	}
+
+	if err := c.fs.WriteCacheFile(CacheDirectory, name, data); err != nil {
+		log.Printf("could not write the cache %s: %v", name, err)
+	}
+}
@@ -0,0 +1,185 @@
+package okta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/fsmanager"
+)
+
+func TestClientEndpoints(t *testing.T) {
+	now := time.Date(2022, 6, 7, 10, 0, 0, 0, time.UTC)
+	prevNow := timeNow
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = prevNow }()
+
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			fmt.Fprintf(w, `{"issuer":"http://%[1]s","device_authorization_endpoint":"http://%[1]s/oauth2/v1/device/authorize","token_endpoint":"http://%[1]s/oauth2/v1/token","revocation_endpoint":"http://%[1]s/oauth2/v1/revoke","userinfo_endpoint":"http://%[1]s/oauth2/v1/userinfo"}`, r.Host)
+		case "/oauth2/aus1/.well-known/openid-configuration":
+			fmt.Fprintf(w, `{"issuer":"http://%[1]s/oauth2/aus1","token_endpoint":"http://%[1]s/oauth2/aus1/v1/token"}`, r.Host)
+		case "/oauth2/noissuer/.well-known/openid-configuration":
+			w.Write([]byte(`{"token_endpoint":"https://attacker.example.com/token"}`))
+		case "/oauth2/other/.well-known/openid-configuration":
+			w.Write([]byte(`{"issuer":"https://attacker.example.com","token_endpoint":"https://attacker.example.com/token"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	cacheFile := path.Join(CacheDirectory, DiscoveryCacheFileName)
+
+	tests := []struct {
+		name           string
+		authServerID   string
+		cache          map[string]endpoints
+		expectToken    string
+		expectDevice   string
+		expectRevoke   string
+		expectRequests int
+		expectCached   bool
+	}{
+		{
+			name:           "org authorization server: endpoints are discovered",
+			expectToken:    srv.URL + "/oauth2/v1/token",
+			expectDevice:   srv.URL + "/oauth2/v1/device/authorize",
+			expectRevoke:   srv.URL + "/oauth2/v1/revoke",
+			expectRequests: 1,
+			expectCached:   true,
+		},
+		{
+			name:           "custom authorization server: missing endpoints use the defaults",
+			authServerID:   "aus1",
+			expectToken:    srv.URL + "/oauth2/aus1/v1/token",
+			expectDevice:   srv.URL + "/oauth2/aus1/v1/device/authorize",
+			expectRequests: 1,
+			expectCached:   true,
+		},
+		{
+			name:           "no openid configuration: default endpoints",
+			authServerID:   "default",
+			expectToken:    srv.URL + "/oauth2/default/v1/token",
+			expectDevice:   srv.URL + "/oauth2/default/v1/device/authorize",
+			expectRevoke:   srv.URL + "/oauth2/default/v1/revoke",
+			expectRequests: 1,
+		},
+		{
+			name:           "no issuer in the openid configuration: default endpoints, not cached",
+			authServerID:   "noissuer",
+			expectToken:    srv.URL + "/oauth2/noissuer/v1/token",
+			expectDevice:   srv.URL + "/oauth2/noissuer/v1/device/authorize",
+			expectRevoke:   srv.URL + "/oauth2/noissuer/v1/revoke",
+			expectRequests: 1,
+		},
+		{
+			name:           "another issuer in the openid configuration: default endpoints, not cached",
+			authServerID:   "other",
+			expectToken:    srv.URL + "/oauth2/other/v1/token",
+			expectDevice:   srv.URL + "/oauth2/other/v1/device/authorize",
+			expectRevoke:   srv.URL + "/oauth2/other/v1/revoke",
+			expectRequests: 1,
+		},
+		{
+			name: "cached: endpoints are not requested",
+			cache: map[string]endpoints{
+				srv.URL: {Issuer: srv.URL, Token: "https://cached/token", DeviceAuthorization: "https://cached/device", FetchedAt: now.Add(-time.Hour)},
+			},
+			expectToken:  "https://cached/token",
+			expectDevice: "https://cached/device",
+			expectCached: true,
+		},
+		{
+			name: "cached for another issuer: endpoints are requested again",
+			cache: map[string]endpoints{
+				srv.URL: {Issuer: "https://attacker.example.com", Token: "https://attacker.example.com/token", FetchedAt: now.Add(-time.Hour)},
+			},
+			expectToken:    srv.URL + "/oauth2/v1/token",
+			expectDevice:   srv.URL + "/oauth2/v1/device/authorize",
+			expectRevoke:   srv.URL + "/oauth2/v1/revoke",
+			expectRequests: 1,
+			expectCached:   true,
+		},
+		{
+			name: "cache expired: endpoints are requested again",
+			cache: map[string]endpoints{
+				srv.URL: {Token: "https://cached/token", FetchedAt: now.Add(-2 * discoveryTTL)},
+			},
+			expectToken:    srv.URL + "/oauth2/v1/token",
+			expectDevice:   srv.URL + "/oauth2/v1/device/authorize",
+			expectRevoke:   srv.URL + "/oauth2/v1/revoke",
+			expectRequests: 1,
+			expectCached:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = nil
+
+			fs := fsmanager.NewMock()
+			if tt.cache != nil {
+				fs.Files[cacheFile], _ = json.Marshal(tt.cache)
+			}
+
+			c, err := New("testid", srv.URL, mockProvider{}, SetAuthServerID(tt.authServerID), setFileManager(fs))
+			if err != nil {
+				t.Fatalf("unexpected error initializing Client: %v", err)
+			}
+
+			// the endpoints are only discovered once by the client
+			for i := 0; i < 2; i++ {
+				e, err := c.endpoints(context.Background())
+				if err != nil {
+					t.Fatalf("endpoints() unexpected error: %v", err)
+				}
+
+				if e.Token != tt.expectToken || e.DeviceAuthorization != tt.expectDevice || e.Revocation != tt.expectRevoke {
+					t.Errorf("endpoints() expected token: %s, device: %s and revocation: %s, got: %+v", tt.expectToken, tt.expectDevice, tt.expectRevoke, e)
+				}
+			}
+
+			if len(requests) != tt.expectRequests {
+				t.Errorf("endpoints() expected %d requests, got: %v", tt.expectRequests, requests)
+			}
+
+			var cached map[string]endpoints
+			json.Unmarshal(fs.Files[cacheFile], &cached)
+			if e, ok := cached[c.issuer()]; ok != tt.expectCached || ok && e.Issuer != c.issuer() {
+				t.Errorf("endpoints() expected cached: %t, got cache: %s", tt.expectCached, fs.Files[cacheFile])
+			}
+		})
+	}
+}
+
+func TestClientEndpointsRequestError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	c, err := New("testid", srv.URL, mockProvider{})
+	if err != nil {
+		t.Fatalf("unexpected error initializing Client: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := c.endpoints(ctx); !errors.Is(err, ErrDiscovery) {
+		t.Errorf("endpoints() expected error: %v, got: %v", ErrDiscovery, err)
+	}
+
+	if _, err := c.PreAuthorize(ctx); !errors.Is(err, ErrPreAuthorizeRequest) {
+		t.Errorf("PreAuthorize() expected error: %v, got: %v", ErrPreAuthorizeRequest, err)
+	}
+}
@@ -0,0 +1,332 @@
+package okta
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// clockSkew is the difference tolerated between the clocks of Okta and the
+// machine when checking the times of a token.
+const clockSkew = 2 * time.Minute
+
+var (
+	// ErrInvalidIDToken is returned when an ID token is malformed, its
+	// signature can't be verified or its claims are not valid for the client.
+	ErrInvalidIDToken = errors.New("invalid id token")
+
+	// ErrJWKS is returned when the keys of the authorization server can't be
+	// requested.
+	ErrJWKS = errors.New("jwks request")
+)
+
+// Claims are the claims of a verified ID token, identifying the user who
+// logged in.
+//
+// More at https://developer.okta.com/docs/reference/api/oidc/#id-token-claims.
+type Claims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          Audience `json:"aud"`
+	ExpiresAt         int64    `json:"exp"`
+	IssuedAt          int64    `json:"iat"`
+	Nonce             string   `json:"nonce,omitempty"`
+	Email             string   `json:"email,omitempty"`
+	Name              string   `json:"name,omitempty"`
+	PreferredUsername string   `json:"preferred_username,omitempty"`
+	Groups            []string `json:"groups,omitempty"`
+	ACR               string   `json:"acr,omitempty"`
+	AMR               []string `json:"amr,omitempty"`
+	AuthTime          int64    `json:"auth_time,omitempty"`
+}
+
+// Audience is the aud claim of a token, a single value or a list of them.
+type Audience []string
+
+// UnmarshalJSON decodes the audience from a string or a list of strings.
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+
+	*a = list
+	return nil
+}
+
+// contains reports whether the audience includes v.
+func (a Audience) contains(v string) bool {
+	return contains(a, v)
+}
+
+// jwk is a public key of the authorization server, RSA or EC.
+//
+// More at https://www.rfc-editor.org/rfc/rfc7517.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// jwksEntry is the key set of a JWKS URI kept in the cache.
+type jwksEntry struct {
+	Keys      []jwk     `json:"keys"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// VerifyIDToken verifies the RS256 or ES256 signature of the ID token with the
+// keys of the authorization server and returns its claims. The token must be
+// issued by the authorization server for the client and be valid now, with a
+// small clock skew allowed. The nonce is checked when it's not empty.
+func (c Client) VerifyIDToken(ctx context.Context, idToken, nonce string) (Claims, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return Claims{}, fmt.Errorf("%w: malformed token", ErrInvalidIDToken)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, fmt.Errorf("%w: header: %v", ErrInvalidIDToken, err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: signature: %v", ErrInvalidIDToken, err)
+	}
+
+	e, err := c.endpoints(ctx)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	key, err := c.publicKey(ctx, e.JWKS, header.Kid)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	if err := verifySignature(key, header.Alg, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, fmt.Errorf("%w: claims: %v", ErrInvalidIDToken, err)
+	}
+
+	// the issuer is the configured one, not the one of the discovered
+	// configuration
+	now := timeNow()
+	switch {
+	case claims.Issuer != c.issuer():
+		return Claims{}, fmt.Errorf("%w: issuer %q is not %s", ErrInvalidIDToken, claims.Issuer, c.issuer())
+	case !claims.Audience.contains(c.id):
+		return Claims{}, fmt.Errorf("%w: audience %v is not the client", ErrInvalidIDToken, claims.Audience)
+	case now.Add(-clockSkew).After(time.Unix(claims.ExpiresAt, 0)):
+		return Claims{}, fmt.Errorf("%w: expired at %s", ErrInvalidIDToken, time.Unix(claims.ExpiresAt, 0).UTC())
+	case now.Add(clockSkew).Before(time.Unix(claims.IssuedAt, 0)):
+		return Claims{}, fmt.Errorf("%w: issued in the future at %s", ErrInvalidIDToken, time.Unix(claims.IssuedAt, 0).UTC())
+	case nonce != "" && claims.Nonce != nonce:
+		return Claims{}, fmt.Errorf("%w: nonce does not match", ErrInvalidIDToken)
+	}
+
+	return claims, nil
+}
+
+// verifyToken verifies the ID token of the OAuth2 token, if it has one, and
+// checks it satisfies the authentication policy of the client, which needs
+// one.
+func (c Client) verifyToken(ctx context.Context, token accessToken, nonce string) error {
+	if token.IDToken == "" {
+		if c.hasAuthPolicy() {
+			return fmt.Errorf("%w: no id token to check", ErrAuthPolicy)
+		}
+
+		return nil
+	}
+
+	claims, err := c.VerifyIDToken(ctx, token.IDToken, nonce)
+	if err != nil {
+		return err
+	}
+
+	return c.checkAuthPolicy(claims)
+}
+
+// publicKey returns the key with the kid from the JWKS in uri. The keys are
+// requested again when none matches, as Okta rotates them.
+func (c Client) publicKey(ctx context.Context, uri, kid string) (crypto.PublicKey, error) {
+	for _, refresh := range []bool{false, true} {
+		keys, err := c.jwks(ctx, uri, refresh)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, k := range keys {
+			if k.Kid == kid {
+				return k.publicKey()
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no key with kid %q", ErrInvalidIDToken, kid)
+}
+
+// jwks returns the keys of the JWKS in uri. They are requested once per
+// client and, when the client has a cache, kept on disk for a day, unless
+// refresh is true.
+func (c Client) jwks(ctx context.Context, uri string, refresh bool) ([]jwk, error) {
+	if c.discovery != nil {
+		c.discovery.mu.Lock()
+		defer c.discovery.mu.Unlock()
+
+		if c.discovery.keys != nil && !refresh {
+			return c.discovery.keys, nil
+		}
+	}
+
+	cached := map[string]jwksEntry{}
+	useCache := c.readCache(JWKSCacheFileName, &cached)
+	if e, ok := cached[uri]; ok && !refresh && timeNow().Before(e.FetchedAt.Add(discoveryTTL)) {
+		if c.discovery != nil {
+			c.discovery.keys = e.Keys
+		}
+
+		return e.Keys, nil
+	}
+
+	resp, err := c.oauth().Get(ctx, uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrJWKS, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: statusCode %d", ErrJWKS, resp.StatusCode)
+	}
+
+	var set jwksEntry
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrJWKS, err)
+	}
+
+	if c.discovery != nil {
+		c.discovery.keys = set.Keys
+	}
+
+	if useCache {
+		set.FetchedAt = timeNow()
+		cached[uri] = set
+		c.writeCache(JWKSCacheFileName, cached)
+	}
+
+	return set.Keys, nil
+}
+
+// publicKey returns the RSA or EC P-256 public key of the JWK.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %s: %v", ErrInvalidIDToken, k.Kid, err)
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %s: %v", ErrInvalidIDToken, k.Kid, err)
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("%w: key %s: unsupported curve %s", ErrInvalidIDToken, k.Kid, k.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %s: %v", ErrInvalidIDToken, k.Kid, err)
+		}
+
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %s: %v", ErrInvalidIDToken, k.Kid, err)
+		}
+
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("%w: key %s: point is not on the curve", ErrInvalidIDToken, k.Kid)
+		}
+
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: key %s: unsupported key type %s", ErrInvalidIDToken, k.Kid, k.Kty)
+}
+
+// verifySignature verifies the JWS signature of data with the key. Only
+// RS256 and ES256 are accepted, the algorithm must match the key type.
+func verifySignature(key crypto.PublicKey, alg string, data, sig []byte) error {
+	sum := sha256.Sum256(data)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if alg != "RS256" {
+			break
+		}
+
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig)
+	case *ecdsa.PublicKey:
+		if alg != "ES256" {
+			break
+		}
+
+		// the signature is the concatenated r and s values
+		if len(sig) != 64 {
+			return errors.New("invalid ES256 signature length")
+		}
+
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(k, sum[:], r, s) {
+			return errors.New("ES256 signature verification failed")
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unsupported algorithm %s for key %T", alg, key)
+}
+
+// decodeSegment decodes the base64url JSON segment of a JWT into v.
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
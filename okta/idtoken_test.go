@@ -0,0 +1,337 @@
+package okta
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/fsmanager"
+	"github.com/fox-tech/creds-fetcher/internal/oktatest"
+)
+
+// testIDToken returns an ID token signed with the key of oktatest.JWKS issued
+// at timeNow, so it's valid for the clients of the tests.
+func testIDToken(issuer, audience, nonce string) string {
+	return oktatest.IDToken(issuer, audience, nonce, timeNow())
+}
+
+func TestClientVerifyIDToken(t *testing.T) {
+	now := time.Unix(1654684454, 0)
+	prevNow := timeNow
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = prevNow }()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate RSA key: %v", err)
+	}
+
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauth2/v1/keys" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var set struct {
+			Keys []json.RawMessage `json:"keys"`
+		}
+		json.Unmarshal([]byte(oktatest.JWKS()), &set)
+		for _, k := range keys {
+			set.Keys = append(set.Keys, json.RawMessage(k))
+		}
+		json.NewEncoder(w).Encode(set)
+	}))
+	defer srv.Close()
+
+	keys = []string{fmt.Sprintf(`{"kty":"RSA","kid":"rsakey","n":%q,"e":%q}`,
+		base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+	)}
+
+	claims := func(change func(map[string]interface{})) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss":   srv.URL,
+			"sub":   "00u1",
+			"aud":   "testid",
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+			"email": "mail@mail.com",
+		}
+		if change != nil {
+			change(c)
+		}
+		return c
+	}
+
+	es256 := map[string]string{"alg": "ES256", "kid": oktatest.KeyID}
+
+	signRS256 := func(header map[string]string, claims map[string]interface{}) string {
+		h, _ := json.Marshal(header)
+		p, _ := json.Marshal(claims)
+		signingInput := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+		sig, err := sign(rsaKey, "RS256", []byte(signingInput))
+		if err != nil {
+			t.Fatalf("could not sign token: %v", err)
+		}
+		return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+	}
+
+	tests := []struct {
+		name        string
+		token       string
+		nonce       string
+		expectEmail string
+		expectErr   error
+	}{
+		{
+			name:        "ES256: claims are returned",
+			token:       oktatest.Sign(es256, claims(nil)),
+			expectEmail: "mail@mail.com",
+		},
+		{
+			name:        "RS256: claims are returned",
+			token:       signRS256(map[string]string{"alg": "RS256", "kid": "rsakey"}, claims(nil)),
+			expectEmail: "mail@mail.com",
+		},
+		{
+			name: "audience list with the client",
+			token: oktatest.Sign(es256, claims(func(c map[string]interface{}) {
+				c["aud"] = []string{"other", "testid"}
+			})),
+			expectEmail: "mail@mail.com",
+		},
+		{
+			name: "nonce matches",
+			token: oktatest.Sign(es256, claims(func(c map[string]interface{}) {
+				c["nonce"] = "n0nc3"
+			})),
+			nonce:       "n0nc3",
+			expectEmail: "mail@mail.com",
+		},
+		{
+			name: "expired within the clock skew",
+			token: oktatest.Sign(es256, claims(func(c map[string]interface{}) {
+				c["exp"] = now.Add(-time.Minute).Unix()
+			})),
+			expectEmail: "mail@mail.com",
+		},
+		{
+			name: "expired",
+			token: oktatest.Sign(es256, claims(func(c map[string]interface{}) {
+				c["exp"] = now.Add(-10 * time.Minute).Unix()
+			})),
+			expectErr: ErrInvalidIDToken,
+		},
+		{
+			name: "issued in the future",
+			token: oktatest.Sign(es256, claims(func(c map[string]interface{}) {
+				c["iat"] = now.Add(10 * time.Minute).Unix()
+			})),
+			expectErr: ErrInvalidIDToken,
+		},
+		{
+			name: "other issuer",
+			token: oktatest.Sign(es256, claims(func(c map[string]interface{}) {
+				c["iss"] = "https://evil.example.com"
+			})),
+			expectErr: ErrInvalidIDToken,
+		},
+		{
+			name: "no issuer",
+			token: oktatest.Sign(es256, claims(func(c map[string]interface{}) {
+				delete(c, "iss")
+			})),
+			expectErr: ErrInvalidIDToken,
+		},
+		{
+			name: "other audience",
+			token: oktatest.Sign(es256, claims(func(c map[string]interface{}) {
+				c["aud"] = "otherid"
+			})),
+			expectErr: ErrInvalidIDToken,
+		},
+		{
+			name: "nonce does not match",
+			token: oktatest.Sign(es256, claims(func(c map[string]interface{}) {
+				c["nonce"] = "other"
+			})),
+			nonce:     "n0nc3",
+			expectErr: ErrInvalidIDToken,
+		},
+		{
+			name:      "algorithm does not match the key",
+			token:     signRS256(map[string]string{"alg": "ES256", "kid": "rsakey"}, claims(nil)),
+			expectErr: ErrInvalidIDToken,
+		},
+		{
+			name: "unsigned token",
+			token: func() string {
+				parts := strings.Split(oktatest.Sign(map[string]string{"alg": "none", "kid": oktatest.KeyID}, claims(nil)), ".")
+				return parts[0] + "." + parts[1] + "."
+			}(),
+			expectErr: ErrInvalidIDToken,
+		},
+		{
+			name:      "unknown key",
+			token:     oktatest.Sign(map[string]string{"alg": "ES256", "kid": "otherkey"}, claims(nil)),
+			expectErr: ErrInvalidIDToken,
+		},
+		{
+			name: "tampered claims",
+			token: func() string {
+				parts := strings.Split(oktatest.Sign(es256, claims(nil)), ".")
+				p, _ := json.Marshal(claims(func(c map[string]interface{}) { c["email"] = "admin@mail.com" }))
+				return parts[0] + "." + base64.RawURLEncoding.EncodeToString(p) + "." + parts[2]
+			}(),
+			expectErr: ErrInvalidIDToken,
+		},
+		{
+			name:      "malformed token",
+			token:     "random_idtoken",
+			expectErr: ErrInvalidIDToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New("testid", srv.URL, mockProvider{})
+			if err != nil {
+				t.Fatalf("unexpected error initializing Client: %v", err)
+			}
+
+			claims, err := c.VerifyIDToken(context.Background(), tt.token, tt.nonce)
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("VerifyIDToken() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if claims.Email != tt.expectEmail {
+				t.Errorf("VerifyIDToken() expected email: %q, got: %q", tt.expectEmail, claims.Email)
+			}
+		})
+	}
+}
+
+func TestClientVerifyIDTokenDiscoveredIssuer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(oktatest.JWKS()))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		discovered string
+		issuer     string
+		expectErr  error
+	}{
+		{
+			name:       "no discovered issuer: token without issuer is rejected",
+			discovered: "",
+			issuer:     "",
+			expectErr:  ErrInvalidIDToken,
+		},
+		{
+			name:       "other discovered issuer: token of that issuer is rejected",
+			discovered: "https://evil.example.com",
+			issuer:     "https://evil.example.com",
+			expectErr:  ErrInvalidIDToken,
+		},
+		{
+			name:       "other discovered issuer: token of the configured issuer is accepted",
+			discovered: "https://evil.example.com",
+			issuer:     srv.URL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New("testid", srv.URL, mockProvider{})
+			if err != nil {
+				t.Fatalf("unexpected error initializing Client: %v", err)
+			}
+			c.discovery.endpoints = &endpoints{Issuer: tt.discovered, JWKS: srv.URL + "/oauth2/v1/keys"}
+
+			claims := map[string]interface{}{
+				"aud": "testid",
+				"iat": timeNow().Unix(),
+				"exp": timeNow().Add(time.Hour).Unix(),
+			}
+			if tt.issuer != "" {
+				claims["iss"] = tt.issuer
+			}
+			token := oktatest.Sign(map[string]string{"alg": "ES256", "kid": oktatest.KeyID}, claims)
+
+			if _, err := c.VerifyIDToken(context.Background(), token, ""); !errors.Is(err, tt.expectErr) {
+				t.Errorf("VerifyIDToken() expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestClientJWKS(t *testing.T) {
+	now := time.Unix(1654684454, 0)
+	prevNow := timeNow
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = prevNow }()
+
+	// the first key set is stale, the key of the token was rotated in
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauth2/v1/keys" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		requests++
+		if requests == 1 {
+			w.Write([]byte(`{"keys":[]}`))
+			return
+		}
+		w.Write([]byte(oktatest.JWKS()))
+	}))
+	defer srv.Close()
+
+	fs := fsmanager.NewMock()
+	c, err := New("testid", srv.URL, mockProvider{}, setFileManager(fs))
+	if err != nil {
+		t.Fatalf("unexpected error initializing Client: %v", err)
+	}
+
+	token := testIDToken(srv.URL, "testid", "")
+	for i := 0; i < 2; i++ {
+		if _, err := c.VerifyIDToken(context.Background(), token, ""); err != nil {
+			t.Fatalf("VerifyIDToken() unexpected error: %v", err)
+		}
+	}
+
+	if requests != 2 {
+		t.Errorf("VerifyIDToken() expected the keys to be requested again once, got %d requests", requests)
+	}
+
+	// a new client uses the keys cached on disk
+	c, _ = New("testid", srv.URL, mockProvider{}, setFileManager(fs))
+	if _, err := c.VerifyIDToken(context.Background(), token, ""); err != nil {
+		t.Fatalf("VerifyIDToken() unexpected error: %v", err)
+	}
+
+	if requests != 2 {
+		t.Errorf("VerifyIDToken() expected cached keys, got %d requests", requests)
+	}
+
+	var cached map[string]jwksEntry
+	json.Unmarshal(fs.Files[path.Join(CacheDirectory, JWKSCacheFileName)], &cached)
+	if e, ok := cached[srv.URL+"/oauth2/v1/keys"]; !ok || len(e.Keys) != 1 || !e.FetchedAt.Equal(now) {
+		t.Errorf("VerifyIDToken() expected keys in cache, got: %+v", cached)
+	}
+}
@@ -0,0 +1,180 @@
+package okta
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// clientAssertionType is the client_assertion_type of private_key_jwt
+	// client authentication.
+	clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+	// clientAssertionExpiration is how long a client assertion is valid, Okta
+	// rejects assertions valid for more than an hour.
+	clientAssertionExpiration = 5 * time.Minute
+)
+
+var (
+	// ErrPrivateKey is returned when the private key for private_key_jwt
+	// client authentication can't be read or is not a supported RSA or EC key.
+	ErrPrivateKey = errors.New("private key")
+
+	// ErrClientAssertion is returned when the client assertion JWT can't be
+	// signed.
+	ErrClientAssertion = errors.New("client assertion")
+
+	timeNow = time.Now
+)
+
+// LoadPrivateKey reads a PEM encoded RSA or EC private key, in PKCS #8, PKCS #1
+// or SEC 1 form, from the file in path.
+func LoadPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPrivateKey, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM data found in %s", ErrPrivateKey, path)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPrivateKey, err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported key type %T", ErrPrivateKey, key)
+	}
+
+	if _, err := signingAlgorithm(signer); err != nil {
+		return nil, err
+	}
+
+	return signer, nil
+}
+
+// signingAlgorithm returns the JWS algorithm used to sign with the key.
+func signingAlgorithm(key crypto.Signer) (string, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return "RS256", nil
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return "ES256", nil
+		case elliptic.P384():
+			return "ES384", nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: only RSA and EC P-256 or P-384 keys are supported", ErrPrivateKey)
+}
+
+// clientAssertion returns a JWT signed with the client private key to
+// authenticate the client in requests to the audience endpoint.
+//
+// More at https://developer.okta.com/docs/reference/api/oidc/#jwt-with-private-key.
+func (c Client) clientAssertion(audience string) (string, error) {
+	alg, err := signingAlgorithm(c.privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrClientAssertion, err)
+	}
+
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if c.keyID != "" {
+		header["kid"] = c.keyID
+	}
+
+	now := timeNow()
+	claims := map[string]interface{}{
+		"iss": c.id,
+		"sub": c.id,
+		"aud": audience,
+		"iat": now.Unix(),
+		"exp": now.Add(clientAssertionExpiration).Unix(),
+		"jti": hex.EncodeToString(jti),
+	}
+
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrClientAssertion, err)
+	}
+
+	p, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrClientAssertion, err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+	sig, err := sign(c.privateKey, alg, []byte(signingInput))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrClientAssertion, err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// sign returns the JWS signature of data. EC signatures are the concatenated
+// r and s values instead of the ASN.1 form returned by the key.
+func sign(key crypto.Signer, alg string, data []byte) ([]byte, error) {
+	switch alg {
+	case "RS256":
+		sum := sha256.Sum256(data)
+		return rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, sum[:])
+	case "ES256", "ES384":
+		k := key.(*ecdsa.PrivateKey)
+
+		var digest []byte
+		if alg == "ES256" {
+			sum := sha256.Sum256(data)
+			digest = sum[:]
+		} else {
+			sum := sha512.Sum384(data)
+			digest = sum[:]
+		}
+
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest)
+		if err != nil {
+			return nil, err
+		}
+
+		size := (k.Curve.Params().BitSize + 7) / 8
+		sig := make([]byte, 2*size)
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+
+		return sig, nil
+	}
+
+	return nil, fmt.Errorf("unsupported algorithm %s", alg)
+}
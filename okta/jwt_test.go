@@ -0,0 +1,165 @@
+package okta
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeKey(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatalf("writing test key: %v", err)
+	}
+
+	return path
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p224Key, _ := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+
+	ecDER, _ := x509.MarshalECPrivateKey(ecKey)
+	pkcs8DER, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+	p224DER, _ := x509.MarshalECPrivateKey(p224Key)
+
+	cases := []struct {
+		name string
+		path string
+		err  error
+	}{
+		{
+			name: "RSA PKCS #1 key",
+			path: writeKey(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)),
+		},
+		{
+			name: "EC SEC 1 key",
+			path: writeKey(t, "EC PRIVATE KEY", ecDER),
+		},
+		{
+			name: "PKCS #8 key",
+			path: writeKey(t, "PRIVATE KEY", pkcs8DER),
+		},
+		{
+			name: "unsupported curve",
+			path: writeKey(t, "EC PRIVATE KEY", p224DER),
+			err:  ErrPrivateKey,
+		},
+		{
+			name: "invalid key",
+			path: writeKey(t, "PRIVATE KEY", []byte("notakey")),
+			err:  ErrPrivateKey,
+		},
+		{
+			name: "missing file",
+			path: filepath.Join(t.TempDir(), "missing.pem"),
+			err:  ErrPrivateKey,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadPrivateKey(tt.path)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, received: %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestClientAssertion(t *testing.T) {
+	prevNow := timeNow
+	timeNow = func() time.Time { return time.Unix(1654684454, 0) }
+	defer func() { timeNow = prevNow }()
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	cases := []struct {
+		name   string
+		key    crypto.Signer
+		alg    string
+		verify func(digest, sig []byte) bool
+	}{
+		{
+			name: "RS256",
+			key:  rsaKey,
+			alg:  "RS256",
+			verify: func(digest, sig []byte) bool {
+				return rsa.VerifyPKCS1v15(&rsaKey.PublicKey, crypto.SHA256, digest, sig) == nil
+			},
+		},
+		{
+			name: "ES256",
+			key:  ecKey,
+			alg:  "ES256",
+			verify: func(digest, sig []byte) bool {
+				if len(sig) != 64 {
+					return false
+				}
+				r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+				return ecdsa.Verify(&ecKey.PublicKey, digest, r, s)
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New("testid", "https://okta.example.com", mockProvider{}, SetPrivateKeyJWT(tt.key, "key1"))
+			if err != nil {
+				t.Fatalf("unexpected error initializing Client: %v", err)
+			}
+
+			jwt, err := c.clientAssertion("https://okta.example.com/oauth2/v1/token")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			parts := strings.Split(jwt, ".")
+			if len(parts) != 3 {
+				t.Fatalf("expected a JWT, received: %s", jwt)
+			}
+
+			header := map[string]string{}
+			h, _ := base64.RawURLEncoding.DecodeString(parts[0])
+			json.Unmarshal(h, &header)
+			if header["alg"] != tt.alg || header["kid"] != "key1" {
+				t.Errorf("expected alg %s and kid key1, received: %v", tt.alg, header)
+			}
+
+			claims := map[string]interface{}{}
+			p, _ := base64.RawURLEncoding.DecodeString(parts[1])
+			json.Unmarshal(p, &claims)
+			if claims["iss"] != "testid" || claims["sub"] != "testid" || claims["aud"] != "https://okta.example.com/oauth2/v1/token" {
+				t.Errorf("expected client ID as issuer and subject, token endpoint as audience, received: %v", claims)
+			}
+
+			if claims["exp"] != float64(1654684454+300) {
+				t.Errorf("expected expiration in 5 minutes, received: %v", claims["exp"])
+			}
+
+			sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+			digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			if !tt.verify(digest[:], sig) {
+				t.Errorf("expected a valid %s signature", tt.alg)
+			}
+		})
+	}
+}
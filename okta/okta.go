@@ -1,19 +1,27 @@
 // Package okta implements logic for Okta's OIE flow.
 package okta
 
-import "errors"
+import (
+	"crypto"
+	"errors"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/client"
+	"github.com/fox-tech/creds-fetcher/internal/oauth"
+)
 
 var (
 	// Errors returned by New in case the parameters passed are incomplete.
 	ErrMissingClientConfig = errors.New("missing client ID or organization URL for Client")
 	ErrNoProvider          = errors.New("no provider set")
+	ErrNoWebIdentity       = errors.New("provider does not support web identity")
 
 	// ErrPreAuthorizeJSONDecode is returned when the JSON response from
 	// PreAuthorize failed to decode.
-	ErrPreAuthorizeJSONDecode = errors.New("json decode")
+	ErrPreAuthorizeJSONDecode = oauth.ErrJSONDecode
 
 	// ErrPreAuthorizeRequest is returned when the PreAuthorize request failed.
-	ErrPreAuthorizeRequest = errors.New("preauthorize request")
+	ErrPreAuthorizeRequest = oauth.ErrDeviceAuthorizationRequest
 
 	// ErrSAMLRequest is returned when there's a failure related to the request.
 	// The response is not the expected response or it failed to complete the
@@ -37,24 +45,48 @@ var (
 	// ErrDeviceAuthorizationExpired is returned when the Device Authorization
 	// credentials expired. In this case, the PreAuthorize has to be run again and
 	// the user will be provided with a new verification URL.
-	ErrDeviceAuthorizationExpired = errors.New("device authorization expired")
+	ErrDeviceAuthorizationExpired = oauth.ErrDeviceAuthorizationExpired
 
 	// ErrAccessTokenRequest is returned when the server fails to fulfill the
-	// device code authorization exchange request or the response is different to
-	// http.StatusOK and http.StatusBadRequest.
-	ErrAccessTokenRequest = errors.New("accessToken request")
+	// device code authorization exchange request or the response is an error
+	// other than the device authorization errors below.
+	ErrAccessTokenRequest = oauth.ErrTokenRequest
+
+	// Device authorization errors returned by the token endpoint while polling,
+	// from RFC 8628. ErrAuthorizationPending and ErrSlowDown are handled by
+	// Authorize, which keeps polling, ErrAccessDenied is returned when the user
+	// denied the authorization and ErrExpiredToken when the device code expired.
+	//
+	// More at https://www.rfc-editor.org/rfc/rfc8628#section-3.5.
+	ErrAuthorizationPending = oauth.ErrAuthorizationPending
+	ErrSlowDown             = oauth.ErrSlowDown
+	ErrAccessDenied         = oauth.ErrAccessDenied
+	ErrExpiredToken         = oauth.ErrExpiredToken
 
 	// ErrAccessTokenJSONDecode is returned when the JSON response from the device
 	// code authorization exchange can't be properly decoded.
-	ErrAccessTokenJSONDecode = errors.New("json decode")
+	ErrAccessTokenJSONDecode = oauth.ErrJSONDecode
 
 	// ErrSSOJSONDecode is returned when the response for the web SSO token can't
 	// be decoded into JSON.
-	ErrSSOJSONDecode = errors.New("sso json decode")
+	ErrSSOJSONDecode = oauth.ErrJSONDecode
+
+	// ErrSSORequest is returned when the server fails to fulfill the exchange
+	// of the SSO token or the response is different to http.StatusOK.
+	ErrSSORequest = oauth.ErrTokenExchange
+
+	// ErrNoPrivateKey is returned when the client credentials flow is used
+	// without a private key set with SetPrivateKeyJWT.
+	ErrNoPrivateKey = errors.New("no private key set")
 
-	// ErrSSORequest is returned when the server fails to fulfill the SSO token
-	// request or the response is different to http.StatusOK.
-	ErrSSORequest = errors.New("sso request")
+	// ErrClientCredentialsRequest is returned when the server fails to fulfill
+	// the client credentials request or the response is different to
+	// http.StatusOK.
+	ErrClientCredentialsRequest = errors.New("client credentials request")
+
+	// ErrNoIDToken is returned when the login returned no ID token to use as
+	// web identity or as subject of the exchange for the SSO token.
+	ErrNoIDToken = errors.New("no id token")
 )
 
 // Client represents an Okta OIE Client used to communicate with Okta for
@@ -65,16 +97,45 @@ type Client struct {
 	id    string
 	appID string
 	uri   string
+	// appURL is the embed link of the app used by AuthorizeAuthn
+	appURL string
+
+	// authServerID is the ID of the custom authorization server, the org
+	// authorization server is used when it's empty
+	authServerID string
+	// discovery keeps the endpoints of the authorization server and fs caches
+	// them on disk when it's set
+	discovery *discovery
+	fs        fileSystemManager
+	// tokenCacheKey is the key of the tokens of the logins in the cache, they
+	// are not kept when it's empty
+	tokenCacheKey string
+
+	// acrValues and maxAge are the authentication policy the logins of the
+	// user must satisfy, maxAge is nil when there's no max age
+	acrValues string
+	maxAge    *time.Duration
+
+	// webIdentity indicates the ID token is given to the provider instead of
+	// a SAML assertion
+	webIdentity bool
+
+	// privateKey and keyID authenticate the client with private_key_jwt
+	privateKey crypto.Signer
+	keyID      string
+
+	httpClient client.HTTPClient
 }
 
 // New returns an initialized and validated Client. It returns a nil error if
 // all the elements needed for a valid Client are present.
 func New(id, uri string, p Provider, opts ...Option) (Client, error) {
 	c := Client{
-		id:       id,
-		appID:    id,
-		uri:      uri,
-		provider: p,
+		id:        id,
+		appID:     id,
+		uri:       uri,
+		provider:  p,
+		discovery: &discovery{},
 	}
 
 	for _, opt := range opts {
@@ -99,5 +160,9 @@ func (c Client) validate() error {
 		return ErrNoProvider
 	}
 
+	if _, ok := c.provider.(WebIdentityProvider); c.webIdentity && !ok {
+		return ErrNoWebIdentity
+	}
+
 	return nil
 }
@@ -40,6 +40,21 @@ func TestNew(t *testing.T) {
 			provider: mockProvider{},
 			opts:     []Option{SetAppID("anAppID")},
 		},
+		{
+			name:     "web identity",
+			id:       "b33fid",
+			uri:      "http://random.com",
+			provider: mockWebIdentityProvider{},
+			opts:     []Option{SetWebIdentity()},
+		},
+		{
+			name:     "web identity without web identity provider",
+			id:       "b33fid",
+			uri:      "http://random.com",
+			provider: mockProvider{},
+			opts:     []Option{SetWebIdentity()},
+			err:      ErrNoWebIdentity,
+		},
 	}
 
 	for _, tt := range cases {
@@ -1,5 +1,13 @@
 package okta
 
+import (
+	"crypto"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/client"
+	"github.com/fox-tech/creds-fetcher/fsmanager"
+)
+
 // Option represents a function that can set a configuration value to the Okta
 // initialization function New. It can return a non-nil error.
 type Option func(*Client) error
@@ -13,3 +21,109 @@ func SetAppID(id string) Option {
 		return nil
 	}
 }
+
+// SetPrivateKeyJWT makes the client authenticate with a JWT signed with the
+// given RSA or EC private key instead of as a public client, as needed by the
+// client credentials flow of service apps. The key ID is optional and sent as
+// the kid of the JWT.
+func SetPrivateKeyJWT(key crypto.Signer, keyID string) Option {
+	return func(c *Client) error {
+		if _, err := signingAlgorithm(key); err != nil {
+			return err
+		}
+
+		c.privateKey = key
+		c.keyID = keyID
+		return nil
+	}
+}
+
+// SetWebIdentity makes Authorize hand the ID token of the device flow to the
+// provider instead of exchanging it for a SAML assertion. The provider must
+// implement WebIdentityProvider.
+func SetWebIdentity() Option {
+	return func(c *Client) error {
+		c.webIdentity = true
+		return nil
+	}
+}
+
+// SetAppURL sets the embed link of the Okta app, e.g.
+// https://example.okta.com/home/amazon_aws/0oa.../272, where AuthorizeAuthn
+// gets the SAML assertion.
+func SetAppURL(uri string) Option {
+	return func(c *Client) error {
+		c.appURL = uri
+		return nil
+	}
+}
+
+// SetACRValues makes the client ask for the given space-separated ACR values,
+// e.g. phr for phishing-resistant factors, when the user logs in, failing with
+// ErrAuthPolicy when the ID token has none of them.
+//
+// More at https://developer.okta.com/docs/guides/step-up-authentication/main/.
+func SetACRValues(values string) Option {
+	return func(c *Client) error {
+		c.acrValues = values
+		return nil
+	}
+}
+
+// SetMaxAge makes the client ask for a login of the user within d, even when
+// their Okta session is older, failing with ErrAuthPolicy when the ID token was
+// authenticated before. Zero asks for a new login every time.
+func SetMaxAge(d time.Duration) Option {
+	return func(c *Client) error {
+		c.maxAge = &d
+		return nil
+	}
+}
+
+// SetHTTPClient sets the client used for the requests to Okta, e.g. to share
+// the client used for AWS with its timeouts, proxy and headers.
+func SetHTTPClient(hc client.HTTPClient) Option {
+	return func(c *Client) error {
+		c.httpClient = hc
+		return nil
+	}
+}
+
+// SetAuthServerID makes the client log in with the custom authorization server
+// with the given ID instead of the org authorization server.
+//
+// More at https://developer.okta.com/docs/concepts/auth-servers/.
+func SetAuthServerID(id string) Option {
+	return func(c *Client) error {
+		c.authServerID = id
+		return nil
+	}
+}
+
+// SetDiscoveryCache makes the client keep the endpoints discovered from the
+// OpenID configuration of the authorization server in ~/.fox-tech for a day,
+// instead of requesting it on every run.
+func SetDiscoveryCache() Option {
+	return setFileManager(fsmanager.NewDefault())
+}
+
+// SetTokenCache makes the client keep the tokens of its logins in ~/.fox-tech
+// under the given key, e.g. the profile name, so Logout can revoke them.
+func SetTokenCache(key string) Option {
+	return func(c *Client) error {
+		c.tokenCacheKey = key
+		if c.fs == nil {
+			c.fs = fsmanager.NewDefault()
+		}
+		return nil
+	}
+}
+
+// setFileManager returns a function to assign the passed fileSystemManager
+// to the discovery and token caches.
+func setFileManager(fm fileSystemManager) Option {
+	return func(c *Client) error {
+		c.fs = fm
+		return nil
+	}
+}
@@ -0,0 +1,244 @@
+package okta
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/internal/oauth"
+)
+
+const (
+	// pkceCallbackPath is the path of the loopback redirect URI, it must be
+	// added with the port as sign-in redirect URI of the Okta app, e.g.
+	// http://127.0.0.1:8085/authorization-code/callback.
+	pkceCallbackPath = "/authorization-code/callback"
+	// pkceTimeout is how long AuthorizePKCE waits for the browser redirect.
+	pkceTimeout = 5 * time.Minute
+)
+
+var (
+	// ErrLoopbackListener is returned when the local HTTP listener for the
+	// redirect can't be started.
+	ErrLoopbackListener = errors.New("loopback listener")
+
+	// ErrAuthorizationCallback is returned when the redirect to the loopback
+	// listener has an error or no code.
+	ErrAuthorizationCallback = errors.New("authorization callback")
+
+	// ErrAuthorizationTimeout is returned when the browser didn't redirect to
+	// the loopback listener in time.
+	ErrAuthorizationTimeout = errors.New("authorization timed out")
+
+	// ErrAuthorizationCodeRequest is returned when the server fails to fulfill
+	// the authorization code exchange or the response is different to
+	// http.StatusOK.
+	ErrAuthorizationCodeRequest = errors.New("authorization code request")
+)
+
+// PKCEAuthorization represents a started authorization code flow with PKCE.
+// The user must open URL in a browser, which is redirected with the code to
+// the loopback listener started for the flow.
+//
+// More at https://developer.okta.com/docs/guides/implement-grant-type/authcodepkce/main/.
+type PKCEAuthorization struct {
+	URL string
+
+	redirectURI string
+	verifier    string
+	// nonce is sent in the authorization URL and must be in the ID token
+	nonce  string
+	server *http.Server
+	result chan pkceResult
+}
+
+// pkceResult is the code or error received by the loopback listener.
+type pkceResult struct {
+	code string
+	err  error
+}
+
+// StartPKCE starts a loopback listener in 127.0.0.1 and the given port, or a
+// random one if port is 0, and returns the authorization URL to be opened by
+// the user. The authorization endpoint is discovered first, stopping when ctx
+// is done. The authorization is then passed to AuthorizePKCE to wait for the
+// redirect and exchange the code.
+func (c Client) StartPKCE(ctx context.Context, port int) (PKCEAuthorization, error) {
+	e, err := c.endpoints(ctx)
+	if err != nil {
+		return PKCEAuthorization{}, err
+	}
+
+	verifier, err := randomString(32)
+	if err != nil {
+		return PKCEAuthorization{}, err
+	}
+
+	state, err := randomString(16)
+	if err != nil {
+		return PKCEAuthorization{}, err
+	}
+
+	nonce, err := randomString(16)
+	if err != nil {
+		return PKCEAuthorization{}, err
+	}
+
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return PKCEAuthorization{}, fmt.Errorf("%w: %v", ErrLoopbackListener, err)
+	}
+
+	a := PKCEAuthorization{
+		redirectURI: fmt.Sprintf("http://%s%s", ln.Addr().String(), pkceCallbackPath),
+		verifier:    verifier,
+		nonce:       nonce,
+		result:      make(chan pkceResult, 1),
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	values := url.Values{
+		"client_id":             []string{c.id},
+		"response_type":         []string{"code"},
+		"scope":                 []string{c.scope()},
+		"redirect_uri":          []string{a.redirectURI},
+		"state":                 []string{state},
+		"nonce":                 []string{nonce},
+		"code_challenge":        []string{base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": []string{"S256"},
+	}
+	c.addAuthPolicy(values)
+	a.URL = fmt.Sprintf("%s?%s", e.Authorization, values.Encode())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(pkceCallbackPath, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+		// requests without the state are not the redirect of the
+		// authorization, any local process can send them, so they're
+		// rejected and the listener keeps waiting
+		if q.Get("state") != state {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, "Invalid authorization callback, state does not match.")
+			return
+		}
+
+		var res pkceResult
+		switch {
+		case q.Get("error") != "":
+			res.err = fmt.Errorf("%w: %s: %s", ErrAuthorizationCallback, q.Get("error"), q.Get("error_description"))
+		case q.Get("code") == "":
+			res.err = fmt.Errorf("%w: no code received", ErrAuthorizationCallback)
+		default:
+			res.code = q.Get("code")
+		}
+
+		if res.err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "Authentication failed: %v\n", res.err)
+		} else {
+			fmt.Fprintln(w, "Authentication completed, you can close this window.")
+		}
+
+		// only the first redirect is used
+		select {
+		case a.result <- res:
+		default:
+		}
+	})
+
+	a.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go a.server.Serve(ln)
+
+	return a, nil
+}
+
+// AuthorizePKCE waits for the browser redirect of the started authorization,
+// exchanges the code for an OAuth2 token, verifying its ID token has the nonce
+// of the authorization, and then runs the same final token exchanges as
+// Authorize. The loopback listener is always stopped, also when
+// ctx is done, returning its error.
+func (c Client) AuthorizePKCE(ctx context.Context, a PKCEAuthorization) error {
+	defer a.server.Close()
+
+	var res pkceResult
+	select {
+	case res = <-a.result:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(pkceTimeout):
+		return ErrAuthorizationTimeout
+	}
+
+	if res.err != nil {
+		return res.err
+	}
+
+	token, err := c.authorizationCodeToken(ctx, a, res.code)
+	if err != nil {
+		return err
+	}
+
+	if err := c.verifyToken(ctx, token, a.nonce); err != nil {
+		return err
+	}
+
+	c.saveToken(token)
+
+	return c.generateCredentials(ctx, token)
+}
+
+// authorizationCodeToken returns the OAuth2 token exchanged for the code of
+// the authorization.
+func (c Client) authorizationCodeToken(ctx context.Context, a PKCEAuthorization, code string) (accessToken, error) {
+	e, err := c.endpoints(ctx)
+	if err != nil {
+		return accessToken{}, fmt.Errorf("%w: %v", ErrAuthorizationCodeRequest, err)
+	}
+
+	resp, err := c.oauth().PostForm(ctx, e.Token,
+		url.Values{
+			"client_id":     []string{c.id},
+			"grant_type":    []string{"authorization_code"},
+			"code":          []string{code},
+			"redirect_uri":  []string{a.redirectURI},
+			"code_verifier": []string{a.verifier},
+		},
+	)
+	if err != nil {
+		return accessToken{}, fmt.Errorf("%w: %v", ErrAuthorizationCodeRequest, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return accessToken{}, oauth.ErrorFromResponse(ErrAuthorizationCodeRequest, resp)
+	}
+
+	var token accessToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return token, fmt.Errorf("%w: %v", ErrAccessTokenJSONDecode, err)
+	}
+
+	return token, nil
+}
+
+// randomString returns n random bytes encoded as base64url.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
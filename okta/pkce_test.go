@@ -0,0 +1,141 @@
+package okta
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/fox-tech/creds-fetcher/internal/oktatest"
+)
+
+func TestClientPKCE(t *testing.T) {
+	var challenge, nonce string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login/token/sso":
+			w.Write(samlData)
+		case "/oauth2/v1/keys":
+			w.Write([]byte(oktatest.JWKS()))
+		case "/oauth2/v1/token":
+			if r.FormValue("grant_type") != "authorization_code" {
+				// sso token exchange
+				w.Write([]byte(`{"access_token":"random_ssotoken"}`))
+				return
+			}
+
+			sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+			if r.FormValue("code") != "authcode" || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"invalid_grant","error_description":"PKCE verification failed"}`))
+				return
+			}
+
+			fmt.Fprintf(w, `{"access_token":"random_accesstoken","id_token":%q}`, testIDToken("http://"+r.Host, "testid", nonce))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		name     string
+		callback func(q url.Values) url.Values
+		// stray sends a callback with another state before the redirect
+		stray bool
+		err   error
+	}{
+		{
+			name: "redirect with code",
+			callback: func(q url.Values) url.Values {
+				return url.Values{"code": []string{"authcode"}, "state": q["state"]}
+			},
+		},
+		{
+			name: "callback with different state before the redirect: ignored",
+			callback: func(q url.Values) url.Values {
+				return url.Values{"code": []string{"authcode"}, "state": q["state"]}
+			},
+			stray: true,
+		},
+		{
+			name: "redirect with error",
+			callback: func(q url.Values) url.Values {
+				return url.Values{"error": []string{"access_denied"}, "state": q["state"]}
+			},
+			err: ErrAuthorizationCallback,
+		},
+		{
+			name: "redirect with invalid code",
+			callback: func(q url.Values) url.Values {
+				return url.Values{"code": []string{"othercode"}, "state": q["state"]}
+			},
+			err: ErrAuthorizationCodeRequest,
+		},
+		{
+			name: "ID token with another nonce",
+			callback: func(q url.Values) url.Values {
+				nonce = "othernonce"
+				return url.Values{"code": []string{"authcode"}, "state": q["state"]}
+			},
+			err: ErrInvalidIDToken,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New("testid", srv.URL, mockProvider{})
+			if err != nil {
+				t.Fatalf("unexpected error initializing Client: %v", err)
+			}
+
+			auth, err := c.StartPKCE(context.Background(), 0)
+			if err != nil {
+				t.Fatalf("unexpected error starting PKCE: %v", err)
+			}
+
+			u, err := url.Parse(auth.URL)
+			if err != nil {
+				t.Fatalf("invalid authorization URL: %v", err)
+			}
+			q := u.Query()
+			challenge = q.Get("code_challenge")
+			nonce = q.Get("nonce")
+
+			if u.Path != "/oauth2/v1/authorize" || q.Get("code_challenge_method") != "S256" || q.Get("client_id") != "testid" {
+				t.Errorf("unexpected authorization URL: %s", auth.URL)
+			}
+
+			if tt.stray {
+				stray := url.Values{"error": []string{"access_denied"}, "state": []string{"other"}}
+				resp, err := http.Get(q.Get("redirect_uri") + "?" + stray.Encode())
+				if err != nil {
+					t.Fatalf("unexpected error calling loopback listener: %v", err)
+				}
+				resp.Body.Close()
+
+				if resp.StatusCode != http.StatusBadRequest {
+					t.Errorf("expected status %d for a callback with another state, got: %d", http.StatusBadRequest, resp.StatusCode)
+				}
+			}
+
+			// the browser following the redirect of Okta
+			resp, err := http.Get(q.Get("redirect_uri") + "?" + tt.callback(q).Encode())
+			if err != nil {
+				t.Fatalf("unexpected error calling loopback listener: %v", err)
+			}
+			resp.Body.Close()
+
+			err = c.AuthorizePKCE(context.Background(), auth)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, received: %v", tt.err, err)
+			}
+		})
+	}
+}
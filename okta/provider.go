@@ -1,11 +1,24 @@
 package okta
 
+import "context"
+
 // Provider is the interface that wraps the GenerateCredentials method.
 //
 // GenerateCredentials implements the logic on the provider-side to convert the
 // passed SAML assertion into provider-ready credentials. It must save the
 // generated credentials in a way the provider's client will be able to use it.
-// Must return nil error un success.
+// Requests are cancelled when ctx is done. Must return nil error un success.
 type Provider interface {
-	GenerateCredentials(assertion string) error
+	GenerateCredentials(ctx context.Context, assertion string) error
+}
+
+// WebIdentityProvider is the interface that wraps the
+// GenerateCredentialsWithWebIdentity method.
+//
+// GenerateCredentialsWithWebIdentity implements the logic on the provider-side
+// to convert the passed OIDC ID token into provider-ready credentials, the
+// same way GenerateCredentials does with a SAML assertion. Must return nil
+// error on success.
+type WebIdentityProvider interface {
+	GenerateCredentialsWithWebIdentity(ctx context.Context, idToken string) error
 }
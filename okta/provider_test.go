@@ -1,9 +1,30 @@
 package okta
 
+import "context"
+
 type mockProvider struct {
 	Provider
 }
 
-func (m mockProvider) GenerateCredentials(assert string) error {
+func (m mockProvider) GenerateCredentials(ctx context.Context, assert string) error {
+	return nil
+}
+
+type mockWebIdentityProvider struct {
+	mockProvider
+	idToken *string
+}
+
+func (m mockWebIdentityProvider) GenerateCredentialsWithWebIdentity(ctx context.Context, idToken string) error {
+	*m.idToken = idToken
+	return nil
+}
+
+type mockSAMLProvider struct {
+	assertion *string
+}
+
+func (m mockSAMLProvider) GenerateCredentials(ctx context.Context, assertion string) error {
+	*m.assertion = assertion
 	return nil
 }
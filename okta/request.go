@@ -0,0 +1,36 @@
+package okta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/fox-tech/creds-fetcher/internal/oauth"
+)
+
+// oauth returns the client of the requests to Okta, with the client set with
+// SetHTTPClient or the default one.
+func (c Client) oauth() oauth.Client {
+	return oauth.Client{ID: c.id, HTTPClient: c.httpClient}
+}
+
+// postJSON sends v encoded as JSON to uri, the request is cancelled when ctx
+// is done.
+func (c Client) postJSON(ctx context.Context, uri string, v interface{}) (*http.Response, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	// not marked idempotent, wrong passwords and passcodes count towards the
+	// lockout of the user and verifying a push again sends another one
+
+	return c.oauth().Do(req)
+}
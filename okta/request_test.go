@@ -0,0 +1,54 @@
+package okta
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/fox-tech/creds-fetcher/client"
+	"github.com/fox-tech/creds-fetcher/internal/oktatest"
+)
+
+func TestClientSetHTTPClient(t *testing.T) {
+	var paths []string
+	hc := client.MockHandlerClient{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+
+		switch {
+		case r.URL.Path == "/oauth2/v1/device/authorize":
+			w.Write([]byte(`{"device_code":"b33fid","user_code":"JU9992JU","expires_in":5,"interval":1}`))
+		case r.URL.Path == "/login/token/sso":
+			w.Write(samlData)
+		case r.URL.Path == "/oauth2/v1/keys":
+			w.Write([]byte(oktatest.JWKS()))
+		case r.FormValue("device_code") == "b33fid":
+			fmt.Fprintf(w, `{"access_token":"random_accesstoken","id_token":%q}`, testIDToken("https://example.okta.com", "testid", ""))
+		case r.FormValue("subject_token_type") == "urn:ietf:params:oauth:token-type:id_token":
+			w.Write([]byte(`{"access_token":"random_ssotoken"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_request"}`))
+		}
+	})}
+
+	c, err := New("testid", "https://example.okta.com", mockProvider{}, SetHTTPClient(hc))
+	if err != nil {
+		t.Fatalf("unexpected error initializing Client: %v", err)
+	}
+
+	device, err := c.PreAuthorize(context.Background())
+	if err != nil {
+		t.Fatalf("PreAuthorize() unexpected error: %v", err)
+	}
+
+	if err := c.Authorize(context.Background(), device); err != nil {
+		t.Fatalf("Authorize() unexpected error: %v", err)
+	}
+
+	// discovery, device authorization, token poll, keys, SSO token exchange
+	// and SAML
+	if len(paths) != 6 {
+		t.Errorf("expected all requests sent with the client, got: %v", paths)
+	}
+}
@@ -2,6 +2,7 @@ package okta
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -13,9 +14,14 @@ import (
 // login endpoint of Okta. Returns non-nil error if the request goes wrong,
 // there's an issue reading the response body or can't find a SAML response to
 // extract.
-func (c Client) getSAML(sso accessToken) (string, error) {
-	uri := fmt.Sprintf("%s/login/token/sso?token=%s", c.uri, sso.AccessToken)
-	resp, err := http.Get(uri)
+func (c Client) getSAML(ctx context.Context, sso accessToken) (string, error) {
+	return c.requestSAML(ctx, fmt.Sprintf("%s/login/token/sso?token=%s", c.uri, sso.AccessToken))
+}
+
+// requestSAML returns the SAML response in the HTML page of uri, the page of
+// an Okta app posting the assertion to AWS.
+func (c Client) requestSAML(ctx context.Context, uri string) (string, error) {
+	resp, err := c.oauth().Get(ctx, uri)
 	if err != nil {
 		return "", fmt.Errorf("%w: %v", ErrSAMLRequest, err)
 	}
@@ -1,11 +1,13 @@
 package okta
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
-	"net/http/httptest"
 	"testing"
+
+	"github.com/fox-tech/creds-fetcher/client"
 )
 
 type testClientInternalGetSAML struct {
@@ -14,19 +16,19 @@ type testClientInternalGetSAML struct {
 	token  accessToken
 }
 
-func newServerClientInternalGetSAML(input testClientInternalGetSAML) *httptest.Server {
-	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+func newHandlerClientInternalGetSAML(input testClientInternalGetSAML) client.HTTPClient {
+	return client.MockHandlerClient{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html")
 
-		if r.RequestURI != fmt.Sprintf("/login/token/sso?token=%s", input.token.AccessToken) {
+		if r.URL.Path != "/login/token/sso" || r.URL.Query().Get("token") != input.token.AccessToken {
 			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(`<html><body>wrong URI, expected ` + r.RequestURI + `</body></html>`))
+			fmt.Fprintf(w, `<html><body>wrong URI %s, expected token %s</body></html>`, r.URL, input.token.AccessToken)
 			return
 		}
 
 		w.WriteHeader(input.status)
 		w.Write(input.res)
-	}))
+	})}
 }
 
 func TestClientInternalGetSAML(t *testing.T) {
@@ -73,12 +75,10 @@ func TestClientInternalGetSAML(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// pre-setup
 			tt.srvInput.token = tt.sso
-			srv := newServerClientInternalGetSAML(tt.srvInput)
-			defer srv.Close()
-			c := Client{uri: srv.URL}
+			c := Client{uri: testOktaURL, httpClient: newHandlerClientInternalGetSAML(tt.srvInput)}
 
 			// actual test
-			saml, err := c.getSAML(tt.sso)
+			saml, err := c.getSAML(context.Background(), tt.sso)
 			if err != nil && !errors.Is(err, tt.err) {
 				t.Fatalf("expected %v, received: %v", tt.err, err)
 			}
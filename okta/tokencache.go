@@ -0,0 +1,162 @@
+package okta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/internal/oauth"
+)
+
+// TokenCacheFileName is the file in CacheDirectory where the tokens of the
+// logins are kept, by the key set with SetTokenCache.
+const TokenCacheFileName = "okta-tokens.json"
+
+var (
+	// ErrRevokeRequest is returned when a token can't be revoked, the request
+	// failed or the server responded with an error.
+	ErrRevokeRequest = errors.New("revoke request")
+
+	// ErrNoCachedToken is returned when there is no cached token for the token
+	// cache key of the client.
+	ErrNoCachedToken = errors.New("no cached token, login first")
+)
+
+// cachedToken is the OAuth2 token of the last login with a token cache key.
+type cachedToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	IDToken      string    `json:"id_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// saveToken keeps the OAuth2 token of a login in the cache, replacing the one
+// of the previous login, when the client has a token cache key.
+func (c Client) saveToken(token accessToken) {
+	if c.tokenCacheKey == "" {
+		return
+	}
+
+	cached := map[string]cachedToken{}
+	if !c.readCache(TokenCacheFileName, &cached) {
+		return
+	}
+
+	cached[c.tokenCacheKey] = cachedToken{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		IDToken:      token.IDToken,
+		ExpiresAt:    timeNow().Add(time.Duration(token.ExpiresIn) * time.Second),
+	}
+	c.writeCache(TokenCacheFileName, cached)
+}
+
+// cachedToken returns the token cached for the token cache key of the client.
+func (c Client) cachedToken() (cachedToken, error) {
+	cached := map[string]cachedToken{}
+	if c.tokenCacheKey == "" || !c.readCache(TokenCacheFileName, &cached) {
+		return cachedToken{}, ErrNoCachedToken
+	}
+
+	token, ok := cached[c.tokenCacheKey]
+	if !ok {
+		return cachedToken{}, ErrNoCachedToken
+	}
+
+	return token, nil
+}
+
+// CachedClaims returns the claims of the ID token of the last login, without
+// any request, and when its access token expires. The ID token was verified
+// at login, it may have expired since.
+func (c Client) CachedClaims() (Claims, time.Time, error) {
+	token, err := c.cachedToken()
+	if err != nil {
+		return Claims{}, time.Time{}, err
+	}
+
+	var claims Claims
+	parts := strings.Split(token.IDToken, ".")
+	if len(parts) != 3 {
+		return Claims{}, token.ExpiresAt, fmt.Errorf("%w: no id token cached", ErrInvalidIDToken)
+	}
+
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, token.ExpiresAt, fmt.Errorf("%w: claims: %v", ErrInvalidIDToken, err)
+	}
+
+	return claims, token.ExpiresAt, nil
+}
+
+// Logout revokes the access and refresh tokens cached for the token cache key
+// of the client and removes them from the cache. It returns the type hints of
+// the revoked tokens, none if there were no cached tokens. The tokens are kept
+// when one can't be revoked, so Logout can be tried again.
+//
+// More at https://developer.okta.com/docs/reference/api/oidc/#revoke.
+func (c Client) Logout(ctx context.Context) ([]string, error) {
+	token, err := c.cachedToken()
+	if err != nil {
+		return nil, nil
+	}
+
+	e, err := c.endpoints(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRevokeRequest, err)
+	}
+
+	// the refresh token first, it could be used to get new access tokens
+	var revoked []string
+	for _, t := range []struct{ value, hint string }{
+		{token.RefreshToken, "refresh_token"},
+		{token.AccessToken, "access_token"},
+	} {
+		if t.value == "" {
+			continue
+		}
+
+		if err := c.revoke(ctx, e.Revocation, t.value, t.hint); err != nil {
+			return revoked, err
+		}
+
+		revoked = append(revoked, t.hint)
+	}
+
+	// the cache is read again, other logins could have changed it
+	cached := map[string]cachedToken{}
+	if c.readCache(TokenCacheFileName, &cached) {
+		delete(cached, c.tokenCacheKey)
+		c.writeCache(TokenCacheFileName, cached)
+	}
+
+	return revoked, nil
+}
+
+// revoke revokes the token at the revocation endpoint in uri. Okta responds
+// successfully for tokens that are already expired or revoked.
+func (c Client) revoke(ctx context.Context, uri, token, hint string) error {
+	values := url.Values{
+		"client_id":       []string{c.id},
+		"token":           []string{token},
+		"token_type_hint": []string{hint},
+	}
+	if err := c.authenticateClient(values, uri); err != nil {
+		return fmt.Errorf("%w: %v", ErrRevokeRequest, err)
+	}
+
+	resp, err := c.oauth().PostForm(ctx, uri, values)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRevokeRequest, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return oauth.ErrorFromResponse(ErrRevokeRequest, resp)
+	}
+
+	return nil
+}
@@ -0,0 +1,267 @@
+package okta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/fsmanager"
+)
+
+func TestClientSaveToken(t *testing.T) {
+	now := time.Unix(1654684454, 0)
+	prevNow := timeNow
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = prevNow }()
+
+	cacheFile := path.Join(CacheDirectory, TokenCacheFileName)
+
+	tests := []struct {
+		name        string
+		key         string
+		expectCache map[string]cachedToken
+	}{
+		{
+			name: "token cache: the token of the login is kept",
+			key:  "dev",
+			expectCache: map[string]cachedToken{
+				"other": {AccessToken: "other_accesstoken"},
+				"dev": {
+					AccessToken:  "random_accesstoken",
+					RefreshToken: "random_refreshtoken",
+					IDToken:      "random_idtoken",
+					ExpiresAt:    now.Add(time.Hour),
+				},
+			},
+		},
+		{
+			name: "no token cache: nothing is kept",
+			expectCache: map[string]cachedToken{
+				"other": {AccessToken: "other_accesstoken"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := fsmanager.NewMock()
+			fs.Files[cacheFile], _ = json.Marshal(map[string]cachedToken{"other": {AccessToken: "other_accesstoken"}})
+
+			opts := []Option{setFileManager(fs)}
+			if tt.key != "" {
+				opts = append(opts, SetTokenCache(tt.key))
+			}
+
+			c, err := New("testid", "https://okta.example.com", mockProvider{}, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error initializing Client: %v", err)
+			}
+
+			c.saveToken(accessToken{
+				AccessToken:  "random_accesstoken",
+				RefreshToken: "random_refreshtoken",
+				IDToken:      "random_idtoken",
+				ExpiresIn:    3600,
+			})
+
+			var cached map[string]cachedToken
+			json.Unmarshal(fs.Files[cacheFile], &cached)
+			if len(cached) != len(tt.expectCache) {
+				t.Fatalf("saveToken() expected cache: %+v, got: %+v", tt.expectCache, cached)
+			}
+
+			for k, expect := range tt.expectCache {
+				got := cached[k]
+				if got.AccessToken != expect.AccessToken || got.RefreshToken != expect.RefreshToken ||
+					got.IDToken != expect.IDToken || !got.ExpiresAt.Equal(expect.ExpiresAt) {
+					t.Errorf("saveToken() expected %s token: %+v, got: %+v", k, expect, got)
+				}
+			}
+		})
+	}
+}
+
+func TestClientLogout(t *testing.T) {
+	cacheFile := path.Join(CacheDirectory, TokenCacheFileName)
+
+	var revoked []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauth2/v1/revoke" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.FormValue("client_id") != "testid" || r.FormValue("token") == "invalid_token" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_client","error_description":"The client is not valid."}`))
+			return
+		}
+
+		revoked = append(revoked, r.FormValue("token_type_hint")+"="+r.FormValue("token"))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name          string
+		key           string
+		cache         map[string]cachedToken
+		expectHints   []string
+		expectRevoked []string
+		expectCached  []string
+		expectErr     error
+	}{
+		{
+			name: "cached tokens: they are revoked and removed",
+			key:  "dev",
+			cache: map[string]cachedToken{
+				"dev":   {AccessToken: "dev_access", RefreshToken: "dev_refresh", IDToken: "dev_id"},
+				"other": {AccessToken: "other_access"},
+			},
+			expectHints:   []string{"refresh_token", "access_token"},
+			expectRevoked: []string{"refresh_token=dev_refresh", "access_token=dev_access"},
+			expectCached:  []string{"other"},
+		},
+		{
+			name: "no refresh token: the access token is revoked",
+			key:  "dev",
+			cache: map[string]cachedToken{
+				"dev": {AccessToken: "dev_access"},
+			},
+			expectHints:   []string{"access_token"},
+			expectRevoked: []string{"access_token=dev_access"},
+			expectCached:  []string{},
+		},
+		{
+			name: "no cached tokens: nothing is revoked",
+			key:  "dev",
+			cache: map[string]cachedToken{
+				"other": {AccessToken: "other_access"},
+			},
+			expectCached: []string{"other"},
+		},
+		{
+			name: "no token cache: nothing is revoked",
+			cache: map[string]cachedToken{
+				"dev": {AccessToken: "dev_access"},
+			},
+			expectCached: []string{"dev"},
+		},
+		{
+			name: "revocation error: tokens are kept",
+			key:  "dev",
+			cache: map[string]cachedToken{
+				"dev": {AccessToken: "invalid_token", RefreshToken: "dev_refresh"},
+			},
+			expectHints:   []string{"refresh_token"},
+			expectRevoked: []string{"refresh_token=dev_refresh"},
+			expectCached:  []string{"dev"},
+			expectErr:     ErrRevokeRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked = nil
+
+			fs := fsmanager.NewMock()
+			fs.Files[cacheFile], _ = json.Marshal(tt.cache)
+
+			opts := []Option{setFileManager(fs)}
+			if tt.key != "" {
+				opts = append(opts, SetTokenCache(tt.key))
+			}
+
+			c, err := New("testid", srv.URL, mockProvider{}, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error initializing Client: %v", err)
+			}
+
+			hints, err := c.Logout(context.Background())
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("Logout() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if !reflect.DeepEqual(hints, tt.expectHints) {
+				t.Errorf("Logout() expected revoked: %v, got: %v", tt.expectHints, hints)
+			}
+
+			if !reflect.DeepEqual(revoked, tt.expectRevoked) {
+				t.Errorf("Logout() expected revocation requests: %v, got: %v", tt.expectRevoked, revoked)
+			}
+
+			var cached map[string]cachedToken
+			json.Unmarshal(fs.Files[cacheFile], &cached)
+			if len(cached) != len(tt.expectCached) {
+				t.Errorf("Logout() expected cached tokens of: %v, got: %+v", tt.expectCached, cached)
+			}
+			for _, k := range tt.expectCached {
+				if _, ok := cached[k]; !ok {
+					t.Errorf("Logout() expected cached tokens of: %v, got: %+v", tt.expectCached, cached)
+				}
+			}
+		})
+	}
+}
+
+func TestClientCachedClaims(t *testing.T) {
+	cacheFile := path.Join(CacheDirectory, TokenCacheFileName)
+	expiresAt := time.Unix(1654688054, 0)
+
+	tests := []struct {
+		name        string
+		key         string
+		token       cachedToken
+		expectEmail string
+		expectErr   error
+	}{
+		{
+			name:        "cached ID token: its claims are returned",
+			key:         "dev",
+			token:       cachedToken{AccessToken: "dev_access", IDToken: testIDToken("https://okta.example.com", "testid", ""), ExpiresAt: expiresAt},
+			expectEmail: "mail@mail.com",
+		},
+		{
+			name:      "no cached ID token: error is returned",
+			key:       "dev",
+			token:     cachedToken{AccessToken: "dev_access", ExpiresAt: expiresAt},
+			expectErr: ErrInvalidIDToken,
+		},
+		{
+			name:      "no cached token: error is returned",
+			key:       "other",
+			token:     cachedToken{AccessToken: "dev_access", ExpiresAt: expiresAt},
+			expectErr: ErrNoCachedToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := fsmanager.NewMock()
+			fs.Files[cacheFile], _ = json.Marshal(map[string]cachedToken{"dev": tt.token})
+
+			c, err := New("testid", "https://okta.example.com", mockProvider{}, setFileManager(fs), SetTokenCache(tt.key))
+			if err != nil {
+				t.Fatalf("unexpected error initializing Client: %v", err)
+			}
+
+			claims, exp, err := c.CachedClaims()
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("CachedClaims() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if claims.Email != tt.expectEmail {
+				t.Errorf("CachedClaims() expected email: %q, got: %q", tt.expectEmail, claims.Email)
+			}
+
+			if err == nil && !exp.Equal(expiresAt) {
+				t.Errorf("CachedClaims() expected expiration: %v, got: %v", expiresAt, exp)
+			}
+		})
+	}
+}
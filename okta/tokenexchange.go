@@ -1,81 +1,75 @@
 package okta
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
-	"time"
+
+	"github.com/fox-tech/creds-fetcher/internal/oauth"
 )
 
 // accessToken represents a succesful response from Okta's oauth2/v1/token
 // endpoint.
-type accessToken struct {
-	TokenType    string `json:"token_type"`
-	ExpiresIn    int64  `json:"expires_in"`
-	AccessToken  string `json:"access_token"`
-	Scope        string `json:"scope"`
-	RefreshToken string `json:"refresh_token"`
-	IDToken      string `json:"id_token"`
-}
+type accessToken = oauth.Token
 
 // accessTokenPoll returns a valid OAuth2 accessToken on a succesful device
-// code authorization exchange. Returns non-nil error in case of the Device
-// authorization expiring, in case the HTTP request receives an HTTP status
-// code different to http.StatusOK/http.StatusBadRequest or on a JSON decoding
-// failure.
-func (c Client) accessTokenPoll(device Device) (accessToken, error) {
-	tick := time.NewTicker(time.Duration(device.Interval) * time.Second)
-	defer tick.Stop()
-
-	timeout := time.After(time.Duration(device.ExpiresIn+1) * time.Second)
-
-	for {
-		select {
-		case <-timeout:
-			return accessToken{}, ErrDeviceAuthorizationExpired
-		case <-tick.C:
-			token, err := c.accessTokenRequest(device)
-			if err != nil {
-				return accessToken{}, err
-			}
-
-			if token.AccessToken != "" {
-				return token, nil
-			}
-		}
+// code authorization exchange. The token endpoint is polled while the
+// authorization is pending, increasing the interval when asked to slow down.
+// Returns non-nil error in case of the Device authorization expiring or being
+// denied, in case the HTTP request fails or on a JSON decoding failure. Returns
+// the error of ctx when it's done.
+func (c Client) accessTokenPoll(ctx context.Context, device Device) (accessToken, error) {
+	e, err := c.endpoints(ctx)
+	if err != nil {
+		return accessToken{}, fmt.Errorf("%w: %v", ErrAccessTokenRequest, err)
+	}
+
+	var token accessToken
+	err = oauth.Poll(ctx, device.Interval, device.ExpiresIn, func() error {
+		var err error
+		token, err = c.oauth().DeviceToken(ctx, e.Token, device)
+		return err
+	})
+	if err != nil {
+		return accessToken{}, err
 	}
+
+	return token, nil
 }
 
-// accessTokenRequest returns the OAuth2 token from a device code grant.
-// Returns non-nil error in case of the request failing with an HTTP status
-// code different to http.StatusOK/http.StatusBadRequest or in case the JSON
-// response failed to be decoded.
-func (c Client) accessTokenRequest(device Device) (accessToken, error) {
-	uri := fmt.Sprintf("%s/oauth2/v1/token", c.uri)
-	resp, err := http.PostForm(uri,
-		url.Values{
-			"client_id":   []string{c.id},
-			"device_code": []string{device.DeviceCode},
-			"grant_type":  []string{"urn:ietf:params:oauth:grant-type:device_code"},
-		},
-	)
+// clientCredentialsToken returns the OAuth2 token of a client credentials
+// grant, authenticating the client with private_key_jwt. The scope of the user
+// logins is requested, the token can only be used when the authorization
+// server returns an ID token with it.
+//
+// More at https://developer.okta.com/docs/guides/implement-grant-type/clientcreds/main/.
+func (c Client) clientCredentialsToken(ctx context.Context) (accessToken, error) {
+	e, err := c.endpoints(ctx)
 	if err != nil {
-		return accessToken{}, fmt.Errorf("%w: %v", ErrAccessTokenRequest, err)
+		return accessToken{}, fmt.Errorf("%w: %v", ErrClientCredentialsRequest, err)
+	}
+
+	uri := e.Token
+
+	values := url.Values{
+		"client_id":  []string{c.id},
+		"grant_type": []string{"client_credentials"},
+		"scope":      []string{c.scope()},
+	}
+	if err := c.authenticateClient(values, uri); err != nil {
+		return accessToken{}, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusBadRequest {
-		return accessToken{}, nil
+	resp, err := c.oauth().PostForm(ctx, uri, values)
+	if err != nil {
+		return accessToken{}, fmt.Errorf("%w: %v", ErrClientCredentialsRequest, err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		var errRes errorResponse
-		if err := json.NewDecoder(resp.Body).Decode(&errRes); err != nil {
-			return accessToken{}, fmt.Errorf("%w: error response %v", ErrAccessTokenJSONDecode, err)
-		}
-
-		return accessToken{}, fmt.Errorf("%w: statusCode %d/%s: %s", ErrAccessTokenRequest, resp.StatusCode, errRes.Error, errRes.Description)
+		return accessToken{}, oauth.ErrorFromResponse(ErrClientCredentialsRequest, resp)
 	}
 
 	var token accessToken
@@ -86,42 +80,47 @@ func (c Client) accessTokenRequest(device Device) (accessToken, error) {
 	return token, nil
 }
 
+// authenticateClient adds the private_key_jwt client assertion for the token
+// endpoint in uri to the request values, if the client has a private key.
+func (c Client) authenticateClient(values url.Values, uri string) error {
+	if c.privateKey == nil {
+		return nil
+	}
+
+	assertion, err := c.clientAssertion(uri)
+	if err != nil {
+		return err
+	}
+
+	values.Set("client_assertion_type", clientAssertionType)
+	values.Set("client_assertion", assertion)
+	return nil
+}
+
 // ssoAccessToken returns an SSO token exchanged from the OAuth2 token
-// generated by the user's explicit authorization.
+// generated by the user's explicit authorization, with its ID token as
+// subject.
 //
 // More at https://developer.okta.com/docs/guides/configure-native-sso/-/main/.
-func (c Client) ssoAccessToken(token accessToken) (accessToken, error) {
-	uri := fmt.Sprintf("%s/oauth2/v1/token", c.uri)
-	resp, err := http.PostForm(uri,
-		url.Values{
-			"client_id":            []string{c.id},
-			"actor_token":          []string{token.AccessToken},
-			"actor_token_type":     []string{"urn:ietf:params:oauth:token-type:access_token"},
-			"subject_token":        []string{token.IDToken},
-			"subject_token_type":   []string{"urn:ietf:params:oauth:token-type:id_token"},
-			"requested_token_type": []string{"urn:okta:oauth:token-type:web_sso_token"},
-			"audience":             []string{"urn:okta:apps:" + c.appID},
-			"grant_type":           []string{"urn:ietf:params:oauth:grant-type:token-exchange"},
-		},
-	)
+func (c Client) ssoAccessToken(ctx context.Context, token accessToken) (accessToken, error) {
+	e, err := c.endpoints(ctx)
 	if err != nil {
-		return accessToken{}, err
+		return accessToken{}, fmt.Errorf("%w: %v", ErrSSORequest, err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		var errRes errorResponse
-		if err := json.NewDecoder(resp.Body).Decode(&errRes); err != nil {
-			return accessToken{}, fmt.Errorf("%w: %v", ErrSSOJSONDecode, err)
-		}
+	uri := e.Token
 
-		return accessToken{}, fmt.Errorf("%w: statusCode %d/%s, %s", ErrSSORequest, resp.StatusCode, errRes.Error, errRes.Description)
+	values := url.Values{
+		"actor_token":          []string{token.AccessToken},
+		"actor_token_type":     []string{"urn:ietf:params:oauth:token-type:access_token"},
+		"subject_token":        []string{token.IDToken},
+		"subject_token_type":   []string{"urn:ietf:params:oauth:token-type:id_token"},
+		"requested_token_type": []string{"urn:okta:oauth:token-type:web_sso_token"},
+		"audience":             []string{"urn:okta:apps:" + c.appID},
 	}
-
-	var sso accessToken
-	if err := json.NewDecoder(resp.Body).Decode(&sso); err != nil {
-		return sso, fmt.Errorf("%w: %v", ErrSSOJSONDecode, err)
+	if err := c.authenticateClient(values, uri); err != nil {
+		return accessToken{}, err
 	}
 
-	return sso, err
+	return c.oauth().ExchangeToken(ctx, uri, values)
 }
@@ -2,14 +2,18 @@ package okta
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/client"
+	"github.com/fox-tech/creds-fetcher/internal/oauth"
 )
 
 type testClientInternalAccessTokenPoll struct {
@@ -17,19 +21,19 @@ type testClientInternalAccessTokenPoll struct {
 	clientID   string
 	deviceCode string
 	intervals  int
+	pending    string
 	status     int
-	srv        *httptest.Server
 	count      int
 }
 
-func (s *testClientInternalAccessTokenPoll) newServerClientInternalAccessTokenPoll() *httptest.Server {
-	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+func (s *testClientInternalAccessTokenPoll) newHandlerClientInternalAccessTokenPoll() client.HTTPClient {
+	return client.MockHandlerClient{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		expectedURI := "/oauth2/v1/token"
-		if r.RequestURI != expectedURI {
+		expectedPath := "/oauth2/v1/token"
+		if r.URL.Path != expectedPath {
 			w.WriteHeader(http.StatusBadRequest)
-			errString := fmt.Sprintf(`{"error":"badURL","error_description":"requested %s and should be %s"}`, r.RequestURI, expectedURI)
+			errString := fmt.Sprintf(`{"error":"badURL","error_description":"requested %s and should be %s"}`, r.URL.Path, expectedPath)
 			w.Write([]byte(errString))
 			return
 		}
@@ -52,13 +56,14 @@ func (s *testClientInternalAccessTokenPoll) newServerClientInternalAccessTokenPo
 
 		if s.count < s.intervals {
 			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"error":%q,"error_description":"device authorization error"}`, s.pending)
 			s.count++
 			return
 		}
 
 		w.WriteHeader(s.status)
 		w.Write(s.res)
-	}))
+	})}
 }
 
 func TestClientInternalAccessTokenPoll(t *testing.T) {
@@ -71,6 +76,7 @@ func TestClientInternalAccessTokenPoll(t *testing.T) {
 		errBody   string
 		status    int
 		intervals int
+		pending   string
 	}{
 		{
 			name: "200: good request in 3 intervals",
@@ -88,7 +94,7 @@ func TestClientInternalAccessTokenPoll(t *testing.T) {
 			intervals: 3,
 		},
 		{
-			name: "force bad json decode",
+			name: "undecodable error response: request error",
 			device: Device{
 				DeviceCode:              "b33fid",
 				UserCode:                "FJHQRTNB",
@@ -101,7 +107,7 @@ func TestClientInternalAccessTokenPoll(t *testing.T) {
 			id:        "testid",
 			status:    http.StatusNotAcceptable,
 			intervals: 1,
-			err:       ErrAccessTokenJSONDecode,
+			err:       ErrAccessTokenRequest,
 			errBody:   "{{{ //",
 		},
 		{
@@ -137,8 +143,65 @@ func TestClientInternalAccessTokenPoll(t *testing.T) {
 			intervals: 3,
 			err:       ErrDeviceAuthorizationExpired,
 		},
+		{
+			name: "200: good request after slowing down",
+			device: Device{
+				DeviceCode: "b33fid",
+				ExpiresIn:  3,
+				Interval:   1,
+			},
+			expect:    accessToken{IDToken: "randomness", AccessToken: "b33fid-access-token"},
+			id:        "testid",
+			status:    http.StatusOK,
+			intervals: 1,
+			pending:   "slow_down",
+		},
+		{
+			name: "denied: user denied the authorization",
+			device: Device{
+				DeviceCode: "b33fid",
+				ExpiresIn:  3,
+				Interval:   1,
+			},
+			id:        "testid",
+			status:    http.StatusOK,
+			intervals: 1,
+			pending:   "access_denied",
+			err:       ErrAccessDenied,
+		},
+		{
+			name: "expired: device code expired on the server",
+			device: Device{
+				DeviceCode: "b33fid",
+				ExpiresIn:  3,
+				Interval:   1,
+			},
+			id:        "testid",
+			status:    http.StatusOK,
+			intervals: 1,
+			pending:   "expired_token",
+			err:       ErrExpiredToken,
+		},
+		{
+			name: "invalid grant: unknown device code",
+			device: Device{
+				DeviceCode: "b33fid",
+				ExpiresIn:  3,
+				Interval:   1,
+			},
+			id:        "testid",
+			status:    http.StatusOK,
+			intervals: 1,
+			pending:   "invalid_grant",
+			err:       ErrAccessTokenRequest,
+		},
 	}
 
+	// slow_down adds no time so the test doesn't wait for it
+	prevIncrease := oauth.SlowDownIncrease
+	oauth.SlowDownIncrease = 0
+	defer func() { oauth.SlowDownIncrease = prevIncrease }()
+
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
 			// pre-setup
@@ -160,14 +223,16 @@ func TestClientInternalAccessTokenPoll(t *testing.T) {
 				clientID:   tt.id,
 				deviceCode: tt.device.DeviceCode,
 				intervals:  tt.intervals,
+				pending:    tt.pending,
 				status:     tt.status,
 			}
-			srv := input.newServerClientInternalAccessTokenPoll()
-			defer srv.Close()
-			c := Client{uri: srv.URL, id: tt.id}
+			if input.pending == "" {
+				input.pending = "authorization_pending"
+			}
+			c := Client{uri: testOktaURL, id: tt.id, httpClient: input.newHandlerClientInternalAccessTokenPoll()}
 
 			// actual test
-			token, err := c.accessTokenPoll(tt.device)
+			token, err := c.accessTokenPoll(context.Background(), tt.device)
 			if err != nil && !errors.Is(err, tt.err) {
 				t.Fatalf("expected %v, received: %v", tt.err, err)
 			}
@@ -191,14 +256,14 @@ type testClientInternalSSOAccessToken struct {
 	res         []byte
 }
 
-func newServerClientInternalSSOAccessToken(input testClientInternalSSOAccessToken) *httptest.Server {
-	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+func newHandlerClientInternalSSOAccessToken(input testClientInternalSSOAccessToken) client.HTTPClient {
+	return client.MockHandlerClient{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		expectedURI := "/oauth2/v1/token"
-		if r.RequestURI != expectedURI {
+		expectedPath := "/oauth2/v1/token"
+		if r.URL.Path != expectedPath {
 			w.WriteHeader(http.StatusBadRequest)
-			errString := fmt.Sprintf(`{"error":"badURL","error_description":"requested %s and should be %s"}`, r.RequestURI, expectedURI)
+			errString := fmt.Sprintf(`{"error":"badURL","error_description":"requested %s and should be %s"}`, r.URL.Path, expectedPath)
 			w.Write([]byte(errString))
 			return
 		}
@@ -226,7 +291,7 @@ func newServerClientInternalSSOAccessToken(input testClientInternalSSOAccessToke
 
 		w.WriteHeader(input.status)
 		w.Write(input.res)
-	}))
+	})}
 }
 
 func TestClientInternalSSOAccessToken(t *testing.T) {
@@ -262,7 +327,7 @@ func TestClientInternalSSOAccessToken(t *testing.T) {
 			status: http.StatusOK,
 		},
 		{
-			name: "forcing json decode error",
+			name: "undecodable error response: request error",
 			token: accessToken{
 				TokenType:    "Bearer",
 				ExpiresIn:    3600,
@@ -274,7 +339,7 @@ func TestClientInternalSSOAccessToken(t *testing.T) {
 			errBody: "{{{{ //",
 			id:      "testid",
 			status:  http.StatusNotAcceptable,
-			err:     ErrSSOJSONDecode,
+			err:     ErrSSORequest,
 		},
 		{
 			name: "forcing json decode error but 200 status",
@@ -317,12 +382,10 @@ func TestClientInternalSSOAccessToken(t *testing.T) {
 				accessToken: tt.token.AccessToken,
 				idToken:     tt.token.IDToken,
 			}
-			srv := newServerClientInternalSSOAccessToken(input)
-			defer srv.Close()
-			c := Client{uri: srv.URL, id: tt.id, appID: tt.id}
+			c := Client{uri: testOktaURL, id: tt.id, appID: tt.id, httpClient: newHandlerClientInternalSSOAccessToken(input)}
 
 			// actual test
-			sso, err := c.ssoAccessToken(tt.token)
+			sso, err := c.ssoAccessToken(context.Background(), tt.token)
 			if err != nil && !errors.Is(err, tt.err) {
 				t.Fatalf("expected %v, received: %v", tt.err, err)
 			}
@@ -337,3 +400,26 @@ func TestClientInternalSSOAccessToken(t *testing.T) {
 		})
 	}
 }
+
+func TestClientInternalAccessTokenPollCancel(t *testing.T) {
+	input := &testClientInternalAccessTokenPoll{
+		clientID:   "testid",
+		deviceCode: "b33fid",
+		intervals:  100,
+		pending:    "authorization_pending",
+	}
+	c := Client{uri: testOktaURL, id: "testid", httpClient: input.newHandlerClientInternalAccessTokenPoll()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.accessTokenPoll(ctx, Device{DeviceCode: "b33fid", ExpiresIn: 60, Interval: 1})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, received: %v", context.DeadlineExceeded, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected polling to stop when the context is done, took: %v", elapsed)
+	}
+}
@@ -0,0 +1,58 @@
+package okta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrUserinfoRequest is returned when the userinfo request failed or the
+// server responded with an error, e.g. because the access token expired.
+var ErrUserinfoRequest = errors.New("userinfo request")
+
+// UserInfo requests the claims of the user of the last login to the userinfo
+// endpoint with the cached access token, so they are only returned while the
+// login is still valid. Returns ErrNoCachedToken if there is no cached token.
+//
+// More at https://developer.okta.com/docs/reference/api/oidc/#userinfo.
+func (c Client) UserInfo(ctx context.Context) (Claims, error) {
+	token, err := c.cachedToken()
+	if err != nil {
+		return Claims{}, err
+	}
+
+	e, err := c.endpoints(ctx)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrUserinfoRequest, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.Userinfo, nil)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrUserinfoRequest, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+
+	resp, err := c.oauth().Do(req)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrUserinfoRequest, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// the error is in the WWW-Authenticate header for invalid tokens
+		if auth := resp.Header.Get("WWW-Authenticate"); auth != "" {
+			return Claims{}, fmt.Errorf("%w: statusCode %d: %s", ErrUserinfoRequest, resp.StatusCode, auth)
+		}
+
+		return Claims{}, fmt.Errorf("%w: statusCode %d", ErrUserinfoRequest, resp.StatusCode)
+	}
+
+	var claims Claims
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrUserinfoRequest, err)
+	}
+
+	return claims, nil
+}
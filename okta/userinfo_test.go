@@ -0,0 +1,80 @@
+package okta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/fox-tech/creds-fetcher/fsmanager"
+)
+
+func TestClientUserInfo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauth2/v1/userinfo" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer dev_access" {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token", error_description="The access token is invalid."`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Write([]byte(`{"sub":"00u1","name":"Jane Doe","email":"mail@mail.com","groups":["Everyone","Developers"]}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		key       string
+		token     string
+		expect    Claims
+		expectErr error
+	}{
+		{
+			name:   "valid access token: claims are returned",
+			key:    "dev",
+			token:  "dev_access",
+			expect: Claims{Subject: "00u1", Name: "Jane Doe", Email: "mail@mail.com", Groups: []string{"Everyone", "Developers"}},
+		},
+		{
+			name:      "invalid access token: error is returned",
+			key:       "dev",
+			token:     "expired_access",
+			expectErr: ErrUserinfoRequest,
+		},
+		{
+			name:      "no cached token: error is returned",
+			key:       "other",
+			token:     "dev_access",
+			expectErr: ErrNoCachedToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := fsmanager.NewMock()
+			fs.Files[path.Join(CacheDirectory, TokenCacheFileName)], _ = json.Marshal(map[string]cachedToken{"dev": {AccessToken: tt.token}})
+
+			c, err := New("testid", srv.URL, mockProvider{}, setFileManager(fs), SetTokenCache(tt.key))
+			if err != nil {
+				t.Fatalf("unexpected error initializing Client: %v", err)
+			}
+
+			claims, err := c.UserInfo(context.Background())
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("UserInfo() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if !reflect.DeepEqual(claims, tt.expect) {
+				t.Errorf("UserInfo() expected: %+v, got: %+v", tt.expect, claims)
+			}
+		})
+	}
+}
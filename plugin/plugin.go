@@ -0,0 +1,279 @@
+// Package plugin implements the protocol of the credential provider plugins,
+// commands that log in to identity providers creds-fetcher doesn't know, such
+// as internal ones, and return what the login produced.
+//
+// The plugin is started without arguments and reads one LoginRequest in JSON
+// from its standard input, which is closed afterwards. It then writes JSON
+// messages to its standard output, one per line:
+//
+//	{"version":1,"type":"capabilities","credentials":["saml","web_identity","aws_credentials"]}
+//	{"version":1,"type":"display_url","url":"https://idp.example.com/device","user_code":"ABCD","expires_in":600}
+//	{"version":1,"type":"credential","saml_assertion":"PHNhbWxwOlJlc3BvbnNlIC4uLg=="}
+//
+// The capabilities message comes first and lists the credential types the
+// plugin can return. The display_url message is optional and asks to show a
+// URL to the user, with a user code to confirm in it when it's set. The
+// credential message ends the login with one of saml_assertion, the base64
+// SAML response of the AWS app, web_identity_token, an OIDC token, or
+// aws_credentials. A plugin that can't log in writes an error message
+// instead, e.g. {"version":1,"type":"error","message":"user not found"}.
+// The standard error of the plugin is shown to the user.
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+// Version is the version of the protocol, sent in every message.
+const Version = 1
+
+// Message types of the protocol.
+const (
+	TypeLogin        = "login"
+	TypeCapabilities = "capabilities"
+	TypeDisplayURL   = "display_url"
+	TypeCredential   = "credential"
+	TypeError        = "error"
+)
+
+// Credential types listed in the capabilities of the plugins.
+const (
+	CredentialSAML           = "saml"
+	CredentialWebIdentity    = "web_identity"
+	CredentialAWSCredentials = "aws_credentials"
+)
+
+var (
+	// ErrStart is returned when the plugin command can't be started.
+	ErrStart = errors.New("plugin could not be started")
+
+	// ErrProtocol is returned when the plugin writes an invalid message, a
+	// message of another version, or a message out of order.
+	ErrProtocol = errors.New("plugin protocol error")
+
+	// ErrUnsupportedCredential is returned when the plugin can't return any of
+	// the credential types of the profile.
+	ErrUnsupportedCredential = errors.New("plugin does not support the credential of the profile")
+
+	// ErrLogin is returned with the message of the plugin when it couldn't log
+	// in.
+	ErrLogin = errors.New("plugin login failed")
+
+	// ErrExited is returned when the plugin exits without a credential.
+	ErrExited = errors.New("plugin exited without a credential")
+)
+
+// LoginRequest is the message sent to the plugin to start the login of the
+// profile.
+type LoginRequest struct {
+	Version int    `json:"version"`
+	Type    string `json:"type"`
+
+	Profile        string `json:"profile"`
+	ProfileType    string `json:"profile_type"`
+	AWSRoleARN     string `json:"aws_role_arn,omitempty"`
+	AWSProviderARN string `json:"aws_provider_arn,omitempty"`
+	AWSRegion      string `json:"aws_region,omitempty"`
+}
+
+// Message is a message written by the plugin, with the fields of its type.
+type Message struct {
+	Version int    `json:"version"`
+	Type    string `json:"type"`
+
+	// Credentials are the credential types of a capabilities message
+	Credentials []string `json:"credentials,omitempty"`
+
+	// URL, UserCode and ExpiresIn, in seconds, are shown to the user with a
+	// display_url message
+	URL       string `json:"url,omitempty"`
+	UserCode  string `json:"user_code,omitempty"`
+	ExpiresIn int64  `json:"expires_in,omitempty"`
+
+	// one of them is set in a credential message
+	SAMLAssertion    string          `json:"saml_assertion,omitempty"`
+	WebIdentityToken string          `json:"web_identity_token,omitempty"`
+	AWSCredentials   *AWSCredentials `json:"aws_credentials,omitempty"`
+
+	// Message is the reason of an error message
+	Message string `json:"message,omitempty"`
+}
+
+// AWSCredentials are the AWS credentials of a credential message, Expiration
+// is in RFC 3339 format.
+type AWSCredentials struct {
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	SessionToken    string `json:"session_token,omitempty"`
+	Expiration      string `json:"expiration,omitempty"`
+}
+
+// Process is a running plugin.
+type Process struct {
+	// Capabilities are the credential types the plugin can return
+	Capabilities []string
+
+	cmd *exec.Cmd
+	dec *json.Decoder
+}
+
+// Start runs the plugin command, sends it the login request and reads its
+// capabilities, checking it can return one of the accepted credential types.
+// The standard error of the plugin is written to stderr. The plugin is killed
+// when ctx is done.
+func Start(ctx context.Context, command string, req LoginRequest, accepted []string, stderr io.Writer) (*Process, error) {
+	cmd := exec.CommandContext(ctx, command)
+	cmd.Stderr = stderr
+
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStart, err)
+	}
+
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStart, err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStart, err)
+	}
+
+	p := &Process{cmd: cmd, dec: json.NewDecoder(out)}
+
+	req.Version = Version
+	req.Type = TypeLogin
+	err = json.NewEncoder(in).Encode(req)
+	in.Close()
+	if err != nil {
+		p.Close()
+		return nil, fmt.Errorf("%w: %v", ErrStart, err)
+	}
+
+	msg, err := p.read()
+	if err == nil && msg.Type == TypeError {
+		err = fmt.Errorf("%w: %s", ErrLogin, msg.Message)
+	}
+	if err == nil && msg.Type != TypeCapabilities {
+		err = fmt.Errorf("%w: expected %s message, got %q", ErrProtocol, TypeCapabilities, msg.Type)
+	}
+	if err == nil && !supports(msg.Credentials, accepted) {
+		err = fmt.Errorf("%w: %v can't return %v", ErrUnsupportedCredential, msg.Credentials, accepted)
+	}
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+
+	p.Capabilities = msg.Credentials
+	return p, nil
+}
+
+// Next returns the next display_url or credential message of the plugin. The
+// credential is one of its capabilities. Error messages are returned as
+// ErrLogin.
+func (p *Process) Next() (Message, error) {
+	msg, err := p.read()
+	if err != nil {
+		return Message{}, err
+	}
+
+	switch msg.Type {
+	case TypeDisplayURL:
+		if msg.URL == "" {
+			return Message{}, fmt.Errorf("%w: %s message without url", ErrProtocol, msg.Type)
+		}
+	case TypeCredential:
+		if err := p.checkCredential(msg); err != nil {
+			return Message{}, err
+		}
+	case TypeError:
+		return Message{}, fmt.Errorf("%w: %s", ErrLogin, msg.Message)
+	default:
+		return Message{}, fmt.Errorf("%w: unexpected %q message", ErrProtocol, msg.Type)
+	}
+
+	return msg, nil
+}
+
+// Close stops the plugin, killing it if it's still running, e.g. when it
+// didn't exit after its credential.
+func (p *Process) Close() {
+	if p.cmd.ProcessState != nil {
+		return
+	}
+
+	p.cmd.Process.Kill()
+	p.cmd.Wait()
+}
+
+// read decodes the next message of the plugin, checking its version. When the
+// plugin exits before writing it, ErrExited is returned with its exit status.
+func (p *Process) read() (Message, error) {
+	var msg Message
+	if err := p.dec.Decode(&msg); err != nil {
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			if err := p.cmd.Wait(); err != nil {
+				return Message{}, fmt.Errorf("%w: %v", ErrExited, err)
+			}
+
+			return Message{}, ErrExited
+		}
+
+		return Message{}, fmt.Errorf("%w: %v", ErrProtocol, err)
+	}
+
+	if msg.Version != Version {
+		return Message{}, fmt.Errorf("%w: version %d, expected %d", ErrProtocol, msg.Version, Version)
+	}
+
+	return msg, nil
+}
+
+// checkCredential checks the credential message has a single credential that
+// is one of the capabilities of the plugin.
+func (p *Process) checkCredential(msg Message) error {
+	var types []string
+	if msg.SAMLAssertion != "" {
+		types = append(types, CredentialSAML)
+	}
+	if msg.WebIdentityToken != "" {
+		types = append(types, CredentialWebIdentity)
+	}
+	if c := msg.AWSCredentials; c != nil {
+		if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+			return fmt.Errorf("%w: aws_credentials without access_key_id or secret_access_key", ErrProtocol)
+		}
+
+		types = append(types, CredentialAWSCredentials)
+	}
+
+	if len(types) != 1 {
+		return fmt.Errorf("%w: credential message with %d credentials, expected 1", ErrProtocol, len(types))
+	}
+
+	if !supports(p.Capabilities, types) {
+		return fmt.Errorf("%w: %s credential is not one of the capabilities %v", ErrProtocol, types[0], p.Capabilities)
+	}
+
+	return nil
+}
+
+// supports reports whether the capabilities include one of the credential
+// types.
+func supports(capabilities, types []string) bool {
+	for _, c := range capabilities {
+		for _, t := range types {
+			if c == t {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,199 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// buildReferencePlugin builds the reference plugin in testdata/reference into a
+// temporary directory and returns the path of the command.
+func buildReferencePlugin(t *testing.T) string {
+	t.Helper()
+
+	command := filepath.Join(t.TempDir(), "reference-plugin")
+	if runtime.GOOS == "windows" {
+		command += ".exe"
+	}
+
+	cmd := exec.Command("go", "build", "-o", command, ".")
+	cmd.Dir = filepath.Join("testdata", "reference")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("could not build the reference plugin: %v: %s", err, out)
+	}
+
+	return command
+}
+
+func TestReferencePlugin(t *testing.T) {
+	command := buildReferencePlugin(t)
+
+	tests := []struct {
+		name           string
+		credential     string
+		profileType    string
+		accepted       []string
+		expectURL      string
+		expectSAML     bool
+		expectToken    string
+		expectAWS      string
+		expectErr      error
+		expectStartErr error
+	}{
+		{
+			name:        "saml profile: SAML assertion",
+			profileType: "saml",
+			accepted:    []string{CredentialSAML, CredentialAWSCredentials},
+			expectURL:   "https://idp.example.com/device",
+			expectSAML:  true,
+		},
+		{
+			name:        "web_identity profile: web identity token",
+			profileType: "web_identity",
+			accepted:    []string{CredentialWebIdentity, CredentialAWSCredentials},
+			expectURL:   "https://idp.example.com/device",
+			expectToken: "reference-web-identity-token",
+		},
+		{
+			name:        "AWS credentials",
+			credential:  "aws_credentials",
+			profileType: "saml",
+			accepted:    []string{CredentialSAML, CredentialAWSCredentials},
+			expectURL:   "https://idp.example.com/device",
+			expectAWS:   "REFERENCEACCESSKEYID",
+		},
+		{
+			name:        "rejected login: fails",
+			credential:  "error",
+			profileType: "saml",
+			accepted:    []string{CredentialSAML},
+			expectURL:   "https://idp.example.com/device",
+			expectErr:   ErrLogin,
+		},
+		{
+			name:           "unsupported credential: fails",
+			profileType:    "saml",
+			accepted:       []string{"kerberos"},
+			expectStartErr: ErrUnsupportedCredential,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REFERENCE_PLUGIN_CREDENTIAL", tt.credential)
+
+			stderr := new(bytes.Buffer)
+			p, err := Start(context.Background(), command, LoginRequest{Profile: "internal", ProfileType: tt.profileType}, tt.accepted, stderr)
+			if !errors.Is(err, tt.expectStartErr) {
+				t.Fatalf("Start() expected error: %v, got: %v", tt.expectStartErr, err)
+			}
+			if err != nil {
+				return
+			}
+			defer p.Close()
+
+			msg, err := p.Next()
+			if err != nil || msg.Type != TypeDisplayURL || msg.URL != tt.expectURL || msg.UserCode != "REF-1234" {
+				t.Fatalf("Next() expected display_url %s, got: %+v, %v", tt.expectURL, msg, err)
+			}
+
+			msg, err = p.Next()
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("Next() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if (msg.SAMLAssertion != "") != tt.expectSAML {
+				t.Errorf("Next() expected SAML assertion: %t, got: %q", tt.expectSAML, msg.SAMLAssertion)
+			}
+			if msg.WebIdentityToken != tt.expectToken {
+				t.Errorf("Next() expected web identity token: %q, got: %q", tt.expectToken, msg.WebIdentityToken)
+			}
+			if tt.expectAWS != "" && (msg.AWSCredentials == nil || msg.AWSCredentials.AccessKeyID != tt.expectAWS) {
+				t.Errorf("Next() expected AWS credentials %s, got: %+v", tt.expectAWS, msg.AWSCredentials)
+			}
+
+			// the standard error is copied until the plugin exits
+			p.Close()
+			if !strings.Contains(stderr.String(), "logging in profile internal") {
+				t.Errorf("Start() expected the standard error of the plugin, got: %s", stderr.String())
+			}
+		})
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test plugins are shell scripts")
+	}
+
+	tests := []struct {
+		name      string
+		script    string
+		expectErr error
+	}{
+		{
+			name:      "other version: fails",
+			script:    `echo '{"version":2,"type":"capabilities","credentials":["saml"]}'`,
+			expectErr: ErrProtocol,
+		},
+		{
+			name:      "no capabilities first: fails",
+			script:    `echo '{"version":1,"type":"credential","saml_assertion":"PHNhbWxwOlJlc3BvbnNlLz4="}'`,
+			expectErr: ErrProtocol,
+		},
+		{
+			name: "credential not in the capabilities: fails",
+			script: `echo '{"version":1,"type":"capabilities","credentials":["saml"]}'
+echo '{"version":1,"type":"credential","web_identity_token":"token"}'`,
+			expectErr: ErrProtocol,
+		},
+		{
+			name: "two credentials: fails",
+			script: `echo '{"version":1,"type":"capabilities","credentials":["saml","web_identity"]}'
+echo '{"version":1,"type":"credential","saml_assertion":"PHNhbWxwOlJlc3BvbnNlLz4=","web_identity_token":"token"}'`,
+			expectErr: ErrProtocol,
+		},
+		{
+			name:      "exit without credential: fails",
+			script:    `echo '{"version":1,"type":"capabilities","credentials":["saml"]}'; exit 3`,
+			expectErr: ErrExited,
+		},
+		{
+			name:      "invalid JSON: fails",
+			script:    `echo '{"version":1,"type":"capabilities","credentials":["saml"]}'; echo 'login done'`,
+			expectErr: ErrProtocol,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command := filepath.Join(t.TempDir(), "plugin")
+			if err := os.WriteFile(command, []byte("#!/bin/sh\nread request\n"+tt.script+"\n"), 0700); err != nil {
+				t.Fatalf("could not write the plugin: %v", err)
+			}
+
+			p, err := Start(context.Background(), command, LoginRequest{Profile: "internal", ProfileType: "saml"}, []string{CredentialSAML, CredentialWebIdentity}, new(bytes.Buffer))
+			if err == nil {
+				defer p.Close()
+				_, err = p.Next()
+			}
+
+			if !errors.Is(err, tt.expectErr) {
+				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestStartNoCommand(t *testing.T) {
+	_, err := Start(context.Background(), filepath.Join(t.TempDir(), "missing"), LoginRequest{}, []string{CredentialSAML}, new(bytes.Buffer))
+	if !errors.Is(err, ErrStart) {
+		t.Errorf("Start() expected error: %v, got: %v", ErrStart, err)
+	}
+}
@@ -0,0 +1,86 @@
+// Command reference is the reference credential provider plugin, a starting
+// point for plugins of internal identity providers. It shows a URL to log in
+// and returns the credential the profile needs: a SAML assertion for saml
+// profiles and a web identity token for web_identity ones.
+//
+// REFERENCE_PLUGIN_CREDENTIAL sets the credential returned instead, saml,
+// web_identity, aws_credentials or error, to test every message.
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// request is the login request sent by creds-fetcher.
+type request struct {
+	Version     int    `json:"version"`
+	Type        string `json:"type"`
+	Profile     string `json:"profile"`
+	ProfileType string `json:"profile_type"`
+	AWSRoleARN  string `json:"aws_role_arn"`
+}
+
+// samlResponse is the SAML response returned for saml profiles.
+const samlResponse = `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol"><samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status></samlp:Response>`
+
+func main() {
+	var req request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintf(os.Stderr, "reference plugin: invalid request: %v\n", err)
+		os.Exit(1)
+	}
+
+	// messages of other versions are rejected by creds-fetcher, plugins
+	// should do the same
+	if req.Version != 1 || req.Type != "login" {
+		fmt.Fprintf(os.Stderr, "reference plugin: unsupported %s message version %d\n", req.Type, req.Version)
+		os.Exit(1)
+	}
+
+	out := json.NewEncoder(os.Stdout)
+	out.Encode(map[string]interface{}{
+		"version":     1,
+		"type":        "capabilities",
+		"credentials": []string{"saml", "web_identity", "aws_credentials"},
+	})
+
+	// the login of the identity provider would start here
+	fmt.Fprintf(os.Stderr, "reference plugin: logging in profile %s\n", req.Profile)
+	out.Encode(map[string]interface{}{
+		"version":    1,
+		"type":       "display_url",
+		"url":        "https://idp.example.com/device",
+		"user_code":  "REF-1234",
+		"expires_in": 600,
+	})
+
+	credential := os.Getenv("REFERENCE_PLUGIN_CREDENTIAL")
+	if credential == "" {
+		credential = "saml"
+		if req.ProfileType == "web_identity" {
+			credential = "web_identity"
+		}
+	}
+
+	msg := map[string]interface{}{"version": 1, "type": "credential"}
+	switch credential {
+	case "saml":
+		msg["saml_assertion"] = base64.StdEncoding.EncodeToString([]byte(samlResponse))
+	case "web_identity":
+		msg["web_identity_token"] = "reference-web-identity-token"
+	case "aws_credentials":
+		msg["aws_credentials"] = map[string]string{
+			"access_key_id":     "REFERENCEACCESSKEYID",
+			"secret_access_key": "reference/secret/access/key",
+			"session_token":     "referencesessiontoken",
+			"expiration":        "2030-01-01T00:00:00Z",
+		}
+	default:
+		msg = map[string]interface{}{"version": 1, "type": "error", "message": "login of " + req.Profile + " was rejected"}
+	}
+
+	out.Encode(msg)
+}
@@ -0,0 +1,596 @@
+// Package qrcode implements a QR code encoder for byte mode data, to render
+// URLs in a terminal so they can be opened on a phone.
+//
+// More at ISO/IEC 18004 and https://www.thonky.com/qr-code-tutorial/.
+package qrcode
+
+import (
+	"errors"
+	"strings"
+)
+
+// Level represents the error correction level of a QR code.
+type Level int
+
+const (
+	// Low recovers 7% of the data, Medium 15%, Quartile 25% and High 30%.
+	Low Level = iota
+	Medium
+	Quartile
+	High
+)
+
+const (
+	minVersion = 1
+	maxVersion = 40
+
+	// quietZone is the light border around the code in modules, smaller than
+	// the 4 modules of the spec to fit in terminals. Phone readers handle it.
+	quietZone = 2
+)
+
+var (
+	ErrDataTooLong = errors.New("data too long for a QR code")
+)
+
+// eccCodewordsPerBlock and numBlocks are indexed by level and version, the
+// first value is unused.
+var eccCodewordsPerBlock = [4][41]int{
+	{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+var numBlocks = [4][41]int{
+	{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// formatLevelBits are the bits of each level in the format information.
+var formatLevelBits = [4]int{1, 0, 3, 2}
+
+// Code represents an encoded QR code as a square of dark and light modules.
+type Code struct {
+	Version int
+	Size    int
+
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+// Encode returns the QR code of the data in byte mode with the given error
+// correction level, using the smallest version the data fits in. Returns
+// ErrDataTooLong if it doesn't fit in any version.
+func Encode(data []byte, level Level) (*Code, error) {
+	version := 0
+	for v := minVersion; v <= maxVersion; v++ {
+		if bitsNeeded(data, v) <= dataCodewords(v, level)*8 {
+			version = v
+			break
+		}
+	}
+
+	if version == 0 {
+		return nil, ErrDataTooLong
+	}
+
+	c := newCode(version)
+	c.drawFunctionPatterns()
+	c.drawCodewords(c.addECCAndInterleave(encodeData(data, version, level), level))
+
+	// the mask with the lowest penalty is applied
+	best, bestPenalty := 0, -1
+	for m := 0; m < 8; m++ {
+		c.applyMask(m)
+		c.drawFormatBits(level, m)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = m, p
+		}
+		c.applyMask(m) // masks are undone by applying them again
+	}
+	c.applyMask(best)
+	c.drawFormatBits(level, best)
+
+	return c, nil
+}
+
+// Black reports whether the module in column x and row y is dark.
+func (c *Code) Black(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// String renders the code with Unicode half blocks, two rows of modules per
+// line, surrounded by a quiet zone. Light modules are drawn with blocks so
+// the code reads on terminals with dark background.
+func (c *Code) String() string {
+	light := func(x, y int) bool {
+		x, y = x-quietZone, y-quietZone
+		if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+			return true
+		}
+		return !c.modules[y][x]
+	}
+
+	var b strings.Builder
+	size := c.Size + 2*quietZone
+	for y := 0; y < size; y += 2 {
+		for x := 0; x < size; x++ {
+			top, bottom := light(x, y), y+1 >= size || light(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteRune('█')
+			case top:
+				b.WriteRune('▀')
+			case bottom:
+				b.WriteRune('▄')
+			default:
+				b.WriteRune(' ')
+			}
+		}
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
+
+func newCode(version int) *Code {
+	size := version*4 + 17
+	c := &Code{
+		Version:    version,
+		Size:       size,
+		modules:    make([][]bool, size),
+		isFunction: make([][]bool, size),
+	}
+
+	for i := range c.modules {
+		c.modules[i] = make([]bool, size)
+		c.isFunction[i] = make([]bool, size)
+	}
+
+	return c
+}
+
+// bitsNeeded returns the bits used by the data segment in the version.
+func bitsNeeded(data []byte, version int) int {
+	return 4 + charCountBits(version) + len(data)*8
+}
+
+// charCountBits returns the length of the byte mode character count.
+func charCountBits(version int) int {
+	if version < 10 {
+		return 8
+	}
+	return 16
+}
+
+// rawDataModules returns the modules available for data and error correction
+// in the version, after removing the function patterns.
+func rawDataModules(version int) int {
+	result := (16*version+128)*version + 64
+	if version >= 2 {
+		numAlign := version/7 + 2
+		result -= (25*numAlign-10)*numAlign - 55
+		if version >= 7 {
+			result -= 36
+		}
+	}
+	return result
+}
+
+// dataCodewords returns the number of data codewords of the version and level.
+func dataCodewords(version int, level Level) int {
+	return rawDataModules(version)/8 - eccCodewordsPerBlock[level][version]*numBlocks[level][version]
+}
+
+// encodeData returns the data codewords: the byte mode segment followed by
+// the terminator and padding up to the capacity of the version.
+func encodeData(data []byte, version int, level Level) []byte {
+	bb := &bitBuffer{}
+	bb.append(0x4, 4)
+	bb.append(len(data), charCountBits(version))
+	for _, b := range data {
+		bb.append(int(b), 8)
+	}
+
+	capacity := dataCodewords(version, level) * 8
+	terminator := capacity - bb.len()
+	if terminator > 4 {
+		terminator = 4
+	}
+	bb.append(0, terminator)
+	bb.append(0, (8-bb.len()%8)%8)
+
+	for pad := 0xEC; bb.len() < capacity; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+
+	return bb.bytes()
+}
+
+// addECCAndInterleave splits the data in blocks, adds the error correction
+// codewords of each block and interleaves them.
+func (c *Code) addECCAndInterleave(data []byte, level Level) []byte {
+	blocks := numBlocks[level][c.Version]
+	eccLen := eccCodewordsPerBlock[level][c.Version]
+	rawCodewords := rawDataModules(c.Version) / 8
+	numShortBlocks := blocks - rawCodewords%blocks
+	shortBlockLen := rawCodewords / blocks
+
+	divisor := reedSolomonDivisor(eccLen)
+	dataBlocks := make([][]byte, blocks)
+	eccBlocks := make([][]byte, blocks)
+	for i, k := 0, 0; i < blocks; i++ {
+		n := shortBlockLen - eccLen
+		if i >= numShortBlocks {
+			n++
+		}
+		dataBlocks[i] = data[k : k+n]
+		eccBlocks[i] = reedSolomonRemainder(dataBlocks[i], divisor)
+		k += n
+	}
+
+	result := make([]byte, 0, rawCodewords)
+	for i := 0; i <= shortBlockLen-eccLen; i++ {
+		for _, b := range dataBlocks {
+			if i < len(b) {
+				result = append(result, b[i])
+			}
+		}
+	}
+	for i := 0; i < eccLen; i++ {
+		for _, b := range eccBlocks {
+			result = append(result, b[i])
+		}
+	}
+
+	return result
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.isFunction[y][x] = true
+}
+
+// drawFunctionPatterns draws the finder, timing and alignment patterns, the
+// version information and reserves the format information modules.
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.Size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	c.drawFinderPattern(3, 3)
+	c.drawFinderPattern(c.Size-4, 3)
+	c.drawFinderPattern(3, c.Size-4)
+
+	pos := alignmentPositions(c.Version)
+	last := len(pos) - 1
+	for i := range pos {
+		for j := range pos {
+			// the corners with finder patterns are skipped
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			c.drawAlignmentPattern(pos[i], pos[j])
+		}
+	}
+
+	c.drawFormatBits(Low, 0)
+	c.drawVersion()
+}
+
+// drawFinderPattern draws a finder pattern and its separator centered in x, y.
+func (c *Code) drawFinderPattern(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || yy < 0 || xx >= c.Size || yy >= c.Size {
+				continue
+			}
+
+			dist := max(abs(dx), abs(dy))
+			c.setFunction(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// drawAlignmentPattern draws an alignment pattern centered in x, y.
+func (c *Code) drawAlignmentPattern(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// alignmentPositions returns the row and column coordinates of the alignment
+// pattern centers of the version.
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+
+	numAlign := version/7 + 2
+	step := (version*8 + numAlign*3 + 5) / (numAlign*4 - 4) * 2
+	result := make([]int, numAlign)
+	result[0] = 6
+	for i, pos := numAlign-1, version*4+17-7; i >= 1; i, pos = i-1, pos-step {
+		result[i] = pos
+	}
+
+	return result
+}
+
+// drawFormatBits draws both copies of the format information of the level
+// and mask, and the dark module.
+func (c *Code) drawFormatBits(level Level, mask int) {
+	data := formatLevelBits[level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	bit := func(i int) bool { return (bits>>i)&1 != 0 }
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(i))
+	}
+	c.setFunction(8, c.Size-8, true)
+}
+
+// drawVersion draws both copies of the version information, only present
+// from version 7.
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+
+	rem := c.Version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := c.Version<<12 | rem
+
+	for i := 0; i < 18; i++ {
+		dark := (bits>>i)&1 != 0
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, dark)
+		c.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords places the codewords in the modules not used by function
+// patterns, in the zigzag order of the spec.
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+
+				if !c.isFunction[y][x] && i < len(data)*8 {
+					c.modules[y][x] = (data[i>>3]>>(7-i&7))&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask flips the data modules selected by the mask pattern.
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+
+			if invert && !c.isFunction[y][x] {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty returns the penalty score of the modules used to choose the mask.
+func (c *Code) penalty() int {
+	result := 0
+
+	get := func(x, y int, transpose bool) bool {
+		if transpose {
+			return c.modules[x][y]
+		}
+		return c.modules[y][x]
+	}
+
+	finderLike := [][]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+
+	for _, transpose := range []bool{false, true} {
+		for y := 0; y < c.Size; y++ {
+			// runs of five or more modules of the same color
+			run := 1
+			for x := 1; x < c.Size; x++ {
+				if get(x, y, transpose) == get(x-1, y, transpose) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					result += 3 + run - 5
+				}
+				run = 1
+			}
+			if run >= 5 {
+				result += 3 + run - 5
+			}
+
+			// patterns looking like finder patterns
+			for x := 0; x+len(finderLike[0]) <= c.Size; x++ {
+				for _, p := range finderLike {
+					match := true
+					for k := range p {
+						if get(x+k, y, transpose) != p[k] {
+							match = false
+							break
+						}
+					}
+					if match {
+						result += 40
+					}
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+
+			// 2x2 blocks of the same color
+			if x > 0 && y > 0 {
+				m := c.modules[y][x]
+				if m == c.modules[y-1][x] && m == c.modules[y][x-1] && m == c.modules[y-1][x-1] {
+					result += 3
+				}
+			}
+		}
+	}
+
+	// deviation of the dark modules from 50%, in steps of 5%
+	total := c.Size * c.Size
+	result += abs(dark*20-total*10) / total * 10
+
+	return result
+}
+
+// reedSolomonDivisor returns the generator polynomial of the given degree,
+// without its leading term.
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+
+	return result
+}
+
+// reedSolomonRemainder returns the error correction codewords of the data.
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= gfMultiply(divisor[i], factor)
+		}
+	}
+
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+
+	return byte(z)
+}
+
+// bitBuffer is a sequence of bits appended most significant bit first.
+type bitBuffer struct {
+	bits []bool
+}
+
+func (b *bitBuffer) append(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		b.bits = append(b.bits, (v>>i)&1 != 0)
+	}
+}
+
+func (b *bitBuffer) len() int {
+	return len(b.bits)
+}
+
+func (b *bitBuffer) bytes() []byte {
+	result := make([]byte, (len(b.bits)+7)/8)
+	for i, bit := range b.bits {
+		if bit {
+			result[i>>3] |= 1 << (7 - i&7)
+		}
+	}
+
+	return result
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
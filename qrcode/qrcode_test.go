@@ -0,0 +1,155 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		level   Level
+		version int
+		expect  error
+	}{
+		{
+			name:    "smallest version used",
+			data:    []byte("HELLO WORLD"),
+			level:   Medium,
+			version: 1,
+		},
+		{
+			name:    "verification URL",
+			data:    []byte("https://example.okta.com/activate?user_code=ABCD-EFGH"),
+			level:   Low,
+			version: 3,
+		},
+		{
+			name:    "version with version information",
+			data:    bytes.Repeat([]byte("a"), 200),
+			level:   Low,
+			version: 9,
+		},
+		{
+			name:    "data filling the largest version",
+			data:    bytes.Repeat([]byte("a"), 2953),
+			level:   Low,
+			version: 40,
+		},
+		{
+			name:   "error: data too long",
+			data:   bytes.Repeat([]byte("a"), 2954),
+			level:  Low,
+			expect: ErrDataTooLong,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Encode(tt.data, tt.level)
+
+			if !errors.Is(err, tt.expect) {
+				t.Fatalf("Encode() expected error: %v, got: %v", tt.expect, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if c.Version != tt.version {
+				t.Errorf("Encode() expected version: %d, got: %d", tt.version, c.Version)
+			}
+
+			if c.Size != tt.version*4+17 {
+				t.Errorf("Encode() expected size: %d, got: %d", tt.version*4+17, c.Size)
+			}
+
+			// finder patterns in three corners, with the dark ring and center
+			for _, corner := range [][2]int{{0, 0}, {c.Size - 7, 0}, {0, c.Size - 7}} {
+				x, y := corner[0], corner[1]
+				if !c.Black(x, y) || c.Black(x+1, y+1) || !c.Black(x+3, y+3) {
+					t.Errorf("Encode() expected finder pattern at %d,%d", x, y)
+				}
+			}
+
+			if !c.Black(8, c.Size-8) {
+				t.Errorf("Encode() expected dark module")
+			}
+		})
+	}
+}
+
+func TestEncodeFormatBits(t *testing.T) {
+	c, err := Encode([]byte("HELLO WORLD"), Quartile)
+	if err != nil {
+		t.Fatalf("Encode() unexpected error: %v", err)
+	}
+
+	// both copies of the format information are the same
+	var first, second int
+	for i := 0; i <= 5; i++ {
+		if c.Black(8, i) {
+			first |= 1 << i
+		}
+	}
+	for i := 0; i <= 5; i++ {
+		if c.Black(c.Size-1-i, 8) {
+			second |= 1 << i
+		}
+	}
+
+	if first != second {
+		t.Errorf("Encode() expected format information copies to match, got: %06b and %06b", first, second)
+	}
+}
+
+func TestReedSolomonRemainder(t *testing.T) {
+	// version 1-M codewords of HELLO WORLD in alphanumeric mode
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	expect := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	got := reedSolomonRemainder(data, reedSolomonDivisor(len(expect)))
+
+	if !bytes.Equal(got, expect) {
+		t.Errorf("reedSolomonRemainder() expected: %v, got: %v", expect, got)
+	}
+}
+
+func TestEncodeData(t *testing.T) {
+	got := encodeData([]byte("ab"), 1, Medium)
+
+	// byte mode, length 2, data, terminator and padding up to 16 codewords
+	expect := []byte{0x40, 0x26, 0x16, 0x20, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+
+	if !bytes.Equal(got, expect) {
+		t.Errorf("encodeData() expected: % x, got: % x", expect, got)
+	}
+}
+
+func TestCodeString(t *testing.T) {
+	c, err := Encode([]byte("HELLO WORLD"), Medium)
+	if err != nil {
+		t.Fatalf("Encode() unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(c.String(), "\n"), "\n")
+
+	size := c.Size + 2*quietZone
+	if len(lines) != (size+1)/2 {
+		t.Fatalf("String() expected %d lines, got: %d", (size+1)/2, len(lines))
+	}
+
+	for _, l := range lines {
+		if n := len([]rune(l)); n != size {
+			t.Errorf("String() expected lines of %d runes, got: %d", size, n)
+		}
+	}
+
+	// the quiet zone is light
+	if lines[0] != strings.Repeat("█", size) {
+		t.Errorf("String() expected light quiet zone, got: %s", lines[0])
+	}
+}
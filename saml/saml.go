@@ -0,0 +1,281 @@
+// Package saml implements logging in to any SAML identity provider in the
+// browser, receiving the SAML response of the login in a loopback assertion
+// consumer service (ACS) instead of the AWS sign-in page.
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// ACSPath is the path of the loopback ACS, it must be set with the port as
+	// ACS URL of the AWS app of the identity provider, e.g.
+	// http://127.0.0.1:21500/saml/acs.
+	ACSPath = "/saml/acs"
+	// DefaultACSPort is the port of the loopback ACS when none is set.
+	DefaultACSPort = 21500
+
+	// AWSEntityID is the entity ID of AWS as service provider, sent as issuer
+	// of the SP-initiated requests.
+	AWSEntityID = "urn:amazon:webservices"
+
+	// statusSuccess is the status code of successful SAML responses.
+	statusSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+
+	// loginTimeout is how long Wait waits for the SAML response.
+	loginTimeout = 5 * time.Minute
+)
+
+var (
+	// ErrLoopbackListener is returned when the local HTTP listener of the ACS
+	// can't be started.
+	ErrLoopbackListener = errors.New("loopback listener")
+
+	// ErrInvalidSAMLResponse is answered to the POSTs to the ACS with no SAML
+	// response, a different relay state or an unsuccessful status, which are
+	// ignored by the login.
+	ErrInvalidSAMLResponse = errors.New("invalid SAML response")
+
+	// ErrLoginTimeout is returned when the browser didn't post the SAML
+	// response to the ACS in time.
+	ErrLoginTimeout = errors.New("login timed out")
+)
+
+// timeNow is used to set the time of the SP-initiated requests.
+var timeNow = time.Now
+
+// Login represents a started login. The user must open URL in a browser,
+// which posts the SAML response of the login to the loopback ACS in ACSURL.
+type Login struct {
+	URL    string
+	ACSURL string
+
+	// relayState is sent in SP-initiated requests and must be posted back
+	relayState string
+	server     *http.Server
+	result     chan string
+}
+
+// Start starts the loopback ACS in 127.0.0.1 and the given port, or a random
+// one if port is 0, for an IdP-initiated login with loginURL, the URL of the
+// AWS app in the identity provider. The login is then passed to Wait for the
+// SAML response.
+func Start(loginURL string, port int) (Login, error) {
+	l, err := listen(port, "")
+	if err != nil {
+		return Login{}, err
+	}
+
+	l.URL = loginURL
+	return l, nil
+}
+
+// StartSPInitiated starts the loopback ACS like Start for an SP-initiated
+// login, sending an authentication request for AWS, with the ACS URL, to the
+// SSO URL of the identity provider with the HTTP-Redirect binding.
+//
+// More at http://docs.oasis-open.org/security/saml/v2.0/saml-bindings-2.0-os.pdf.
+func StartSPInitiated(ssoURL string, port int) (Login, error) {
+	u, err := url.Parse(ssoURL)
+	if err != nil {
+		return Login{}, err
+	}
+
+	relayState, err := randomID()
+	if err != nil {
+		return Login{}, err
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return Login{}, err
+	}
+
+	l, err := listen(port, relayState)
+	if err != nil {
+		return Login{}, err
+	}
+
+	request, err := authnRequest("_"+id, ssoURL, l.ACSURL)
+	if err != nil {
+		l.server.Close()
+		return Login{}, err
+	}
+
+	q := u.Query()
+	q.Set("SAMLRequest", request)
+	q.Set("RelayState", relayState)
+	u.RawQuery = q.Encode()
+
+	l.URL = u.String()
+	return l, nil
+}
+
+// Wait waits for a valid SAML response posted to the ACS of the login and
+// returns it base64 encoded, as AWS expects it. Invalid posts are rejected
+// and the login keeps waiting. The loopback listener is always stopped, also
+// when ctx is done, returning its error.
+func (l Login) Wait(ctx context.Context) (string, error) {
+	defer l.server.Close()
+
+	select {
+	case assertion := <-l.result:
+		return assertion, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-time.After(loginTimeout):
+		return "", ErrLoginTimeout
+	}
+}
+
+// listen starts the ACS in the port of 127.0.0.1, checking the relay state of
+// the SAML responses when it's not empty. Only valid SAML responses are sent
+// to the result of the login, other posts are answered with an error.
+func listen(port int, relayState string) (Login, error) {
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return Login{}, fmt.Errorf("%w: %v", ErrLoopbackListener, err)
+	}
+
+	l := Login{
+		ACSURL:     fmt.Sprintf("http://%s%s", ln.Addr().String(), ACSPath),
+		relayState: relayState,
+		result:     make(chan string, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(ACSPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+		// any local page can post to the ACS, the login keeps waiting for
+		// the response of the identity provider
+		assertion, err := l.samlResponse(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "Authentication failed: %v\n", err)
+			return
+		}
+
+		fmt.Fprintln(w, "Authentication completed, you can close this window.")
+
+		// only the first valid response is used
+		select {
+		case l.result <- assertion:
+		default:
+		}
+	})
+
+	l.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go l.server.Serve(ln)
+
+	return l, nil
+}
+
+// samlResponse returns the SAML response posted in the form of r, checking
+// its relay state and status.
+func (l Login) samlResponse(r *http.Request) (string, error) {
+	assertion := r.PostFormValue("SAMLResponse")
+	if assertion == "" {
+		return "", fmt.Errorf("%w: no SAMLResponse received", ErrInvalidSAMLResponse)
+	}
+
+	if l.relayState != "" && r.PostFormValue("RelayState") != l.relayState {
+		return "", fmt.Errorf("%w: relay state does not match", ErrInvalidSAMLResponse)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(assertion)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidSAMLResponse, err)
+	}
+
+	var resp struct {
+		Status struct {
+			StatusCode struct {
+				Value string `xml:"Value,attr"`
+			} `xml:"StatusCode"`
+		} `xml:"Status"`
+	}
+	if err := xml.Unmarshal(data, &resp); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidSAMLResponse, err)
+	}
+
+	if code := resp.Status.StatusCode.Value; code != statusSuccess {
+		return "", fmt.Errorf("%w: status %s", ErrInvalidSAMLResponse, code)
+	}
+
+	return assertion, nil
+}
+
+// authnRequest returns the authentication request with the id for the
+// identity provider in destination, deflated and base64 encoded as needed by
+// the HTTP-Redirect binding.
+func authnRequest(id, destination, acsURL string) (string, error) {
+	req := struct {
+		XMLName         xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol AuthnRequest"`
+		ID              string   `xml:"ID,attr"`
+		Version         string   `xml:"Version,attr"`
+		IssueInstant    string   `xml:"IssueInstant,attr"`
+		Destination     string   `xml:"Destination,attr"`
+		ACSURL          string   `xml:"AssertionConsumerServiceURL,attr"`
+		ProtocolBinding string   `xml:"ProtocolBinding,attr"`
+		Issuer          struct {
+			XMLName xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+			Value   string   `xml:",chardata"`
+		}
+	}{
+		ID:              id,
+		Version:         "2.0",
+		IssueInstant:    timeNow().UTC().Format(time.RFC3339),
+		Destination:     destination,
+		ACSURL:          acsURL,
+		ProtocolBinding: "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST",
+	}
+	req.Issuer.Value = AWSEntityID
+
+	data, err := xml.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	buf := new(bytes.Buffer)
+	w, err := flate.NewWriter(buf, flate.DefaultCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(data); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// randomID returns a random hex ID.
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,199 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/internal/samltest"
+)
+
+func TestStart(t *testing.T) {
+	valid := url.Values{"SAMLResponse": {samltest.Response(samltest.StatusSuccess)}}
+
+	tests := []struct {
+		name   string
+		form   url.Values
+		status int
+	}{
+		{
+			name:   "SAML response posted: it's returned",
+			form:   valid,
+			status: http.StatusOK,
+		},
+		{
+			name:   "unsuccessful status: rejected, login keeps waiting",
+			form:   url.Values{"SAMLResponse": {samltest.Response("urn:oasis:names:tc:SAML:2.0:status:Requester")}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "not base64: rejected, login keeps waiting",
+			form:   url.Values{"SAMLResponse": {"<Response/>"}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "no SAML response: rejected, login keeps waiting",
+			form:   url.Values{"RelayState": {"state"}},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := Start("https://idp.example.com/app/aws", 0)
+			if err != nil {
+				t.Fatalf("Start() unexpected error: %v", err)
+			}
+
+			if l.URL != "https://idp.example.com/app/aws" {
+				t.Errorf("Start() expected login URL as is, got: %s", l.URL)
+			}
+
+			resp, err := http.PostForm(l.ACSURL, tt.form)
+			if err != nil {
+				t.Fatalf("could not post to the ACS: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("ACS expected status: %d, got: %d", tt.status, resp.StatusCode)
+			}
+
+			// the response of the identity provider still completes the login
+			if tt.status != http.StatusOK {
+				resp, err := http.PostForm(l.ACSURL, valid)
+				if err != nil {
+					t.Fatalf("could not post to the ACS: %v", err)
+				}
+				resp.Body.Close()
+			}
+
+			assertion, err := l.Wait(context.Background())
+			if err != nil {
+				t.Fatalf("Wait() unexpected error: %v", err)
+			}
+
+			if expect := valid.Get("SAMLResponse"); assertion != expect {
+				t.Errorf("Wait() expected assertion: %q, got: %q", expect, assertion)
+			}
+		})
+	}
+}
+
+func TestStartSPInitiated(t *testing.T) {
+	now := time.Date(2023, 2, 1, 12, 0, 0, 0, time.UTC)
+	prevNow := timeNow
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = prevNow }()
+
+	tests := []struct {
+		name       string
+		relayState func(string) string
+		status     int
+		expectErr  error
+	}{
+		{
+			name:       "relay state posted back: SAML response is returned",
+			relayState: func(s string) string { return s },
+			status:     http.StatusOK,
+		},
+		{
+			name:       "other relay state: rejected, login times out",
+			relayState: func(string) string { return "other" },
+			status:     http.StatusBadRequest,
+			expectErr:  context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := StartSPInitiated("https://idp.example.com/saml2?tenant=1", 0)
+			if err != nil {
+				t.Fatalf("StartSPInitiated() unexpected error: %v", err)
+			}
+
+			u, err := url.Parse(l.URL)
+			if err != nil {
+				t.Fatalf("StartSPInitiated() invalid login URL: %v", err)
+			}
+
+			q := u.Query()
+			if u.Host != "idp.example.com" || u.Path != "/saml2" || q.Get("tenant") != "1" {
+				t.Errorf("StartSPInitiated() expected login URL to the SSO URL, got: %s", l.URL)
+			}
+
+			deflated, err := base64.StdEncoding.DecodeString(q.Get("SAMLRequest"))
+			if err != nil {
+				t.Fatalf("StartSPInitiated() SAMLRequest is not base64: %v", err)
+			}
+			data, _ := io.ReadAll(flate.NewReader(bytes.NewReader(deflated)))
+
+			var req struct {
+				ACSURL       string `xml:"AssertionConsumerServiceURL,attr"`
+				Destination  string `xml:"Destination,attr"`
+				IssueInstant string `xml:"IssueInstant,attr"`
+				Issuer       string `xml:"Issuer"`
+			}
+			if err := xml.Unmarshal(data, &req); err != nil {
+				t.Fatalf("StartSPInitiated() SAMLRequest is not XML: %v: %s", err, data)
+			}
+
+			expectReq := req
+			expectReq.ACSURL = l.ACSURL
+			expectReq.Destination = "https://idp.example.com/saml2?tenant=1"
+			expectReq.IssueInstant = "2023-02-01T12:00:00Z"
+			expectReq.Issuer = AWSEntityID
+			if req != expectReq {
+				t.Errorf("StartSPInitiated() expected request: %+v, got: %+v", expectReq, req)
+			}
+
+			resp, err := http.PostForm(l.ACSURL, url.Values{
+				"SAMLResponse": {samltest.Response(samltest.StatusSuccess)},
+				"RelayState":   {tt.relayState(q.Get("RelayState"))},
+			})
+			if err != nil {
+				t.Fatalf("could not post to the ACS: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("ACS expected status: %d, got: %d", tt.status, resp.StatusCode)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			if _, err := l.Wait(ctx); !errors.Is(err, tt.expectErr) {
+				t.Errorf("Wait() expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestLoginWaitCancel(t *testing.T) {
+	l, err := Start("https://idp.example.com/app/aws", 0)
+	if err != nil {
+		t.Fatalf("Start() unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() expected error: %v, got: %v", context.DeadlineExceeded, err)
+	}
+
+	// the listener is stopped once the login is over
+	if resp, err := http.PostForm(l.ACSURL, url.Values{}); err == nil {
+		resp.Body.Close()
+		t.Errorf("Wait() expected the ACS to be stopped")
+	}
+}
@@ -0,0 +1,114 @@
+package sso
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"path/filepath"
+	"time"
+)
+
+// fileSystemManager defines the methods that the token cache needs a file
+// system manager to have
+type fileSystemManager interface {
+	ReadCacheFile(dir, filename string) ([]byte, error)
+	WriteCacheFile(dir, filename string, data []byte) error
+}
+
+var (
+	// CacheDirectory is the token cache of the AWS CLI, in the home
+	// directory.
+	CacheDirectory = filepath.Join(".aws", "sso", "cache")
+
+	// tokenExpiryMargin is how long cached tokens must still be valid to be
+	// used, so they don't expire while getting the credentials.
+	tokenExpiryMargin = time.Minute
+)
+
+// Token is the token of a login, as cached by the AWS CLI. The client of the
+// login is kept with it to be reused until RegistrationExpiresAt.
+type Token struct {
+	StartURL    string    `json:"startUrl"`
+	Region      string    `json:"region"`
+	AccessToken string    `json:"accessToken"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+
+	ClientID              string     `json:"clientId,omitempty"`
+	ClientSecret          string     `json:"clientSecret,omitempty"`
+	RegistrationExpiresAt *time.Time `json:"registrationExpiresAt,omitempty"`
+}
+
+// CachedToken returns the cached token of the start URL of the client, it
+// returns false if there's none or it's about to expire.
+func (c Client) CachedToken() (Token, bool) {
+	token, ok := c.readCache()
+	if !ok || token.AccessToken == "" || !timeNow().Add(tokenExpiryMargin).Before(token.ExpiresAt) {
+		return Token{}, false
+	}
+
+	return token, true
+}
+
+// cachedRegistration returns the client of the cached login of the start URL,
+// also when its token expired, it returns false if there's none or its secret
+// is about to expire.
+func (c Client) cachedRegistration() (Registration, bool) {
+	token, ok := c.readCache()
+	if !ok || token.ClientID == "" || token.ClientSecret == "" || token.RegistrationExpiresAt == nil || !timeNow().Add(tokenExpiryMargin).Before(*token.RegistrationExpiresAt) {
+		return Registration{}, false
+	}
+
+	return Registration{
+		ClientID:     token.ClientID,
+		ClientSecret: token.ClientSecret,
+		ExpiresAt:    token.RegistrationExpiresAt.Unix(),
+	}, true
+}
+
+// readCache decodes the cache file of the start URL, it returns false if
+// there's none or it can't be read.
+func (c Client) readCache() (Token, bool) {
+	name := c.cacheFileName()
+
+	data, err := c.fs.ReadCacheFile(CacheDirectory, name)
+	if err != nil {
+		log.Printf("could not read the sso cache: %v", err)
+		return Token{}, false
+	}
+	if len(data) == 0 {
+		return Token{}, false
+	}
+
+	// a corrupted cache is replaced when it's written
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		log.Printf("could not decode the sso cache %s: %v", name, err)
+		return Token{}, false
+	}
+
+	return token, true
+}
+
+// saveToken saves the token in the cache file of the start URL. The cache is
+// only an optimization, errors are logged and the login continues.
+func (c Client) saveToken(token Token) {
+	name := c.cacheFileName()
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		log.Printf("could not encode the sso cache %s: %v", name, err)
+		return
+	}
+
+	if err := c.fs.WriteCacheFile(CacheDirectory, name, data); err != nil {
+		log.Printf("could not write the sso cache %s: %v", name, err)
+	}
+}
+
+// cacheFileName returns the name of the cache file of the start URL, the
+// SHA-1 of the start URL like the AWS CLI does.
+func (c Client) cacheFileName() string {
+	sum := sha1.Sum([]byte(c.startURL))
+	return hex.EncodeToString(sum[:]) + ".json"
+}
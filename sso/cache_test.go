@@ -0,0 +1,132 @@
+package sso
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/fsmanager"
+)
+
+// testCacheFile is the file of the start URL of the tests in the cache of the
+// AWS CLI.
+func testCacheFile() string {
+	sum := sha1.Sum([]byte("https://example.awsapps.com/start"))
+	return path.Join(CacheDirectory, hex.EncodeToString(sum[:])+".json")
+}
+
+func TestClientCachedToken(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name   string
+		data   string
+		expect bool
+	}{
+		{
+			name:   "valid token of the AWS CLI: cached",
+			data:   `{"startUrl":"https://example.awsapps.com/start","region":"eu-west-1","accessToken":"clitoken","expiresAt":"` + now.Add(time.Hour).Format(time.RFC3339) + `"}`,
+			expect: true,
+		},
+		{
+			name: "token about to expire: not cached",
+			data: `{"startUrl":"https://example.awsapps.com/start","region":"eu-west-1","accessToken":"clitoken","expiresAt":"` + now.Add(time.Second).Format(time.RFC3339) + `"}`,
+		},
+		{
+			name: "corrupted cache: not cached",
+			data: `{"accessToken":`,
+		},
+		{
+			name: "no cache: not cached",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := fsmanager.NewMock()
+			if tt.data != "" {
+				fs.Files[testCacheFile()] = []byte(tt.data)
+			}
+
+			c, err := New("https://example.awsapps.com/start", "eu-west-1", setFileManager(fs))
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			token, ok := c.CachedToken()
+			if ok != tt.expect {
+				t.Fatalf("CachedToken() expected cached: %v, got: %v", tt.expect, ok)
+			}
+
+			if ok && token.AccessToken != "clitoken" {
+				t.Errorf("CachedToken() expected token clitoken, got: %s", token.AccessToken)
+			}
+		})
+	}
+}
+
+func TestClientSaveToken(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name               string
+		token              Token
+		expectRegistration bool
+	}{
+		{
+			name:  "token without client: no registration expiration",
+			token: Token{StartURL: "https://example.awsapps.com/start", Region: "eu-west-1", AccessToken: "token", ExpiresAt: expiresAt},
+		},
+		{
+			name: "token with client: registration expiration",
+			token: Token{
+				StartURL:              "https://example.awsapps.com/start",
+				Region:                "eu-west-1",
+				AccessToken:           "token",
+				ExpiresAt:             expiresAt,
+				ClientID:              "client",
+				ClientSecret:          "secret",
+				RegistrationExpiresAt: &expiresAt,
+			},
+			expectRegistration: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := fsmanager.NewMock()
+			c, err := New("https://example.awsapps.com/start", "eu-west-1", setFileManager(fs))
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			c.saveToken(tt.token)
+
+			data, ok := fs.Files[testCacheFile()]
+			if !ok {
+				t.Fatalf("saveToken() expected the cache file %s", testCacheFile())
+			}
+
+			// the fields read by the AWS CLI
+			var cached map[string]interface{}
+			if err := json.Unmarshal(data, &cached); err != nil {
+				t.Fatalf("saveToken() unexpected cache: %v", err)
+			}
+			if cached["accessToken"] != "token" || cached["expiresAt"] != "2030-01-01T00:00:00Z" || cached["region"] != "eu-west-1" || cached["startUrl"] != "https://example.awsapps.com/start" {
+				t.Errorf("saveToken() unexpected cache: %s", data)
+			}
+
+			// a zero expiration isn't written in the cache shared with the AWS CLI
+			if _, ok := cached["registrationExpiresAt"]; ok != tt.expectRegistration {
+				t.Errorf("saveToken() expected registrationExpiresAt: %t, got cache: %s", tt.expectRegistration, data)
+			}
+
+			if token, ok := c.readCache(); !ok || token.AccessToken != "token" {
+				t.Errorf("readCache() expected the saved token, got: %+v, %t", token, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package sso
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RoleCredentials are the AWS credentials of a role, Expiration is in
+// milliseconds since the epoch.
+type RoleCredentials struct {
+	AccessKeyID     string `json:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey"`
+	SessionToken    string `json:"sessionToken"`
+	Expiration      int64  `json:"expiration"`
+}
+
+// ExpiresAt returns the expiration of the credentials.
+func (r RoleCredentials) ExpiresAt() time.Time {
+	return time.UnixMilli(r.Expiration).UTC()
+}
+
+// roleCredentialsResponse is a successful response of GetRoleCredentials.
+type roleCredentialsResponse struct {
+	RoleCredentials RoleCredentials `json:"roleCredentials"`
+}
+
+// GetRoleCredentials returns the credentials of the role in the account for
+// the token of a login. It returns ErrUnauthorized when the portal rejects
+// the token, and the error of ctx when it's done.
+func (c Client) GetRoleCredentials(ctx context.Context, token Token, accountID, roleName string) (RoleCredentials, error) {
+	query := url.Values{
+		"account_id": []string{accountID},
+		"role_name":  []string{roleName},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.portalEndpoint+"/federation/credentials?"+query.Encode(), nil)
+	if err != nil {
+		return RoleCredentials{}, fmt.Errorf("%w: %v", ErrRoleCredentials, err)
+	}
+	req.Header.Set("x-amz-sso_bearer_token", token.AccessToken)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return RoleCredentials{}, fmt.Errorf("%w: %v", ErrRoleCredentials, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return RoleCredentials{}, errorFromResponse(ErrUnauthorized, resp)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return RoleCredentials{}, errorFromResponse(ErrRoleCredentials, resp)
+	}
+
+	var res roleCredentialsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return RoleCredentials{}, fmt.Errorf("%w: %v", ErrJSONDecode, err)
+	}
+
+	return res.RoleCredentials, nil
+}
@@ -0,0 +1,66 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/internal/ssotest"
+)
+
+func TestClientGetRoleCredentials(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     string
+		roleName  string
+		expect    RoleCredentials
+		expectErr error
+	}{
+		{
+			name:     "role of the user: success",
+			token:    ssotest.AccessToken,
+			roleName: "Developer",
+			expect: RoleCredentials{
+				AccessKeyID:     ssotest.AccessKeyID,
+				SecretAccessKey: ssotest.SecretAccessKey,
+				SessionToken:    ssotest.SessionToken,
+				Expiration:      ssotest.Expiration,
+			},
+		},
+		{
+			name:      "rejected token: fails",
+			token:     "expired",
+			roleName:  "Developer",
+			expectErr: ErrUnauthorized,
+		},
+		{
+			name:      "other role: fails",
+			token:     ssotest.AccessToken,
+			roleName:  "Admin",
+			expectErr: ErrRoleCredentials,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newStandInClient(t, &ssotest.Server{AccountID: "123456789012", RoleName: "Developer"})
+
+			cred, err := c.GetRoleCredentials(context.Background(), Token{AccessToken: tt.token}, "123456789012", tt.roleName)
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("GetRoleCredentials() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if cred != tt.expect {
+				t.Errorf("GetRoleCredentials() expected: %+v, got: %+v", tt.expect, cred)
+			}
+		})
+	}
+}
+
+func TestRoleCredentialsExpiresAt(t *testing.T) {
+	expect := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	if e := (RoleCredentials{Expiration: ssotest.Expiration}).ExpiresAt(); !e.Equal(expect) {
+		t.Errorf("ExpiresAt() expected: %v, got: %v", expect, e)
+	}
+}
@@ -0,0 +1,174 @@
+package sso
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/client"
+	"github.com/fox-tech/creds-fetcher/internal/oauth"
+)
+
+// Registration is a public client registered with the SSO OIDC service, the
+// secret expires at ExpiresAt, in seconds since the epoch.
+type Registration struct {
+	ClientID     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret"`
+	ExpiresAt    int64  `json:"clientSecretExpiresAt"`
+}
+
+// Device is the device authorization of a login by the client of
+// Registration, the user must open the verification URI and confirm the user
+// code in it. ExpiresIn and Interval are in seconds, Interval is how long to
+// wait between polls of CreateToken.
+type Device struct {
+	Registration Registration `json:"-"`
+
+	DeviceCode              string `json:"deviceCode"`
+	UserCode                string `json:"userCode"`
+	VerificationURI         string `json:"verificationUri"`
+	VerificationURIComplete string `json:"verificationUriComplete"`
+	ExpiresIn               int64  `json:"expiresIn"`
+	Interval                int64  `json:"interval"`
+}
+
+// tokenResponse is a successful response of CreateToken, ExpiresIn is in
+// seconds.
+type tokenResponse struct {
+	AccessToken string `json:"accessToken"`
+	TokenType   string `json:"tokenType"`
+	ExpiresIn   int64  `json:"expiresIn"`
+}
+
+// timeNow returns the current time, it's a variable so tests can set the
+// expiration of tokens and registrations.
+var timeNow = time.Now
+
+// StartDeviceAuthorization starts a login with the device authorization flow
+// and returns the device authorization to show to the user, which is then
+// passed to PollToken. The client registered by a previous login is reused
+// until its secret expires.
+func (c Client) StartDeviceAuthorization(ctx context.Context) (Device, error) {
+	reg, ok := c.cachedRegistration()
+	if !ok {
+		var err error
+		if reg, err = c.registerClient(ctx); err != nil {
+			return Device{}, err
+		}
+	}
+
+	resp, err := c.postJSON(ctx, "/device_authorization", map[string]string{
+		"clientId":     reg.ClientID,
+		"clientSecret": reg.ClientSecret,
+		"startUrl":     c.startURL,
+	})
+	if err != nil {
+		return Device{}, fmt.Errorf("%w: %v", ErrDeviceAuthorizationRequest, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Device{}, errorFromResponse(ErrDeviceAuthorizationRequest, resp)
+	}
+
+	var device Device
+	if err := json.NewDecoder(resp.Body).Decode(&device); err != nil {
+		return Device{}, fmt.Errorf("%w: %v", ErrJSONDecode, err)
+	}
+	device.Registration = reg
+
+	return device, nil
+}
+
+// PollToken polls CreateToken until the user approves the login of the device
+// and returns its token, which is saved in the cache, increasing the interval
+// when asked to slow down. It returns ErrAccessDenied when the user denied
+// it, ErrExpiredToken or ErrDeviceAuthorizationExpired when the device code
+// expired, and the error of ctx when it's done.
+func (c Client) PollToken(ctx context.Context, device Device) (Token, error) {
+	var token Token
+	err := oauth.Poll(ctx, device.Interval, device.ExpiresIn, func() error {
+		var err error
+		token, err = c.createToken(ctx, device)
+		return err
+	})
+	if err != nil {
+		return Token{}, err
+	}
+
+	c.saveToken(token)
+	return token, nil
+}
+
+// registerClient registers a public client for the logins.
+func (c Client) registerClient(ctx context.Context) (Registration, error) {
+	resp, err := c.postJSON(ctx, "/client/register", map[string]interface{}{
+		"clientName": ClientName,
+		"clientType": "public",
+		"scopes":     []string{Scope},
+	})
+	if err != nil {
+		return Registration{}, fmt.Errorf("%w: %v", ErrRegisterClient, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Registration{}, errorFromResponse(ErrRegisterClient, resp)
+	}
+
+	var reg Registration
+	if err := json.NewDecoder(resp.Body).Decode(&reg); err != nil {
+		return Registration{}, fmt.Errorf("%w: %v", ErrJSONDecode, err)
+	}
+
+	return reg, nil
+}
+
+// createToken requests the token of the device code, returning the device
+// authorization error of the response, if any.
+func (c Client) createToken(ctx context.Context, device Device) (Token, error) {
+	req, err := c.jsonRequest(ctx, "/token", map[string]string{
+		"clientId":     device.Registration.ClientID,
+		"clientSecret": device.Registration.ClientSecret,
+		"deviceCode":   device.DeviceCode,
+		"grantType":    oauth.GrantTypeDeviceCode,
+	})
+	if err != nil {
+		return Token{}, fmt.Errorf("%w: %v", ErrTokenRequest, err)
+	}
+	// the device code is polled until it's used, a poll sent again after a
+	// 5xx error is at worst answered as pending or with the same token
+	client.SetIdempotent(req)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return Token{}, fmt.Errorf("%w: %v", ErrTokenRequest, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Token{}, errorFromResponse(ErrTokenRequest, resp)
+	}
+
+	var res tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return Token{}, fmt.Errorf("%w: %v", ErrJSONDecode, err)
+	}
+
+	token := Token{
+		StartURL:     c.startURL,
+		Region:       c.region,
+		AccessToken:  res.AccessToken,
+		ExpiresAt:    timeNow().Add(time.Duration(res.ExpiresIn) * time.Second).UTC().Truncate(time.Second),
+		ClientID:     device.Registration.ClientID,
+		ClientSecret: device.Registration.ClientSecret,
+	}
+	if device.Registration.ExpiresAt != 0 {
+		expiresAt := time.Unix(device.Registration.ExpiresAt, 0).UTC()
+		token.RegistrationExpiresAt = &expiresAt
+	}
+
+	return token, nil
+}
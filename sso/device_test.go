@@ -0,0 +1,118 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fox-tech/creds-fetcher/internal/ssotest"
+)
+
+// newStandInClient returns a client of the stand-in, with a new home for its
+// cache.
+func newStandInClient(t *testing.T, s *ssotest.Server) Client {
+	t.Setenv("HOME", t.TempDir())
+
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	c, err := New("https://example.awsapps.com/start", "eu-west-1", SetEndpoints(srv.URL, srv.URL))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	return c
+}
+
+func TestClientDeviceFlow(t *testing.T) {
+	tests := []struct {
+		name      string
+		standIn   *ssotest.Server
+		expectErr error
+	}{
+		{
+			name:    "approved after pending: success",
+			standIn: &ssotest.Server{Pending: 1},
+		},
+		{
+			name:      "denied: fails",
+			standIn:   &ssotest.Server{Deny: true},
+			expectErr: ErrAccessDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newStandInClient(t, tt.standIn)
+
+			device, err := c.StartDeviceAuthorization(context.Background())
+			if err != nil {
+				t.Fatalf("StartDeviceAuthorization() unexpected error: %v", err)
+			}
+
+			if device.UserCode != "STAN-DIN1" || device.Registration.ClientID != "standin-client" {
+				t.Fatalf("StartDeviceAuthorization() unexpected device: %+v", device)
+			}
+
+			token, err := c.PollToken(context.Background(), device)
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("PollToken() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			cached, ok := c.CachedToken()
+			if ok != (err == nil) {
+				t.Fatalf("CachedToken() expected cached: %v, got: %v", err == nil, ok)
+			}
+
+			if err == nil && (token.AccessToken != ssotest.AccessToken || !reflect.DeepEqual(cached, token)) {
+				t.Errorf("PollToken() expected cached token with %q, got: %+v, cached: %+v", ssotest.AccessToken, token, cached)
+			}
+		})
+	}
+}
+
+func TestClientStartDeviceAuthorizationReusesClient(t *testing.T) {
+	s := &ssotest.Server{}
+	c := newStandInClient(t, s)
+
+	device, err := c.StartDeviceAuthorization(context.Background())
+	if err != nil {
+		t.Fatalf("StartDeviceAuthorization() unexpected error: %v", err)
+	}
+
+	if _, err := c.PollToken(context.Background(), device); err != nil {
+		t.Fatalf("PollToken() unexpected error: %v", err)
+	}
+
+	// the token expired but the client is still registered
+	prevNow := timeNow
+	timeNow = func() time.Time { return prevNow().Add(24 * time.Hour) }
+	defer func() { timeNow = prevNow }()
+
+	if _, ok := c.CachedToken(); ok {
+		t.Fatalf("CachedToken() expected the token to be expired")
+	}
+
+	if _, err := c.StartDeviceAuthorization(context.Background()); err != nil {
+		t.Fatalf("StartDeviceAuthorization() unexpected error: %v", err)
+	}
+
+	if n := s.Requests("/client/register"); n != 1 {
+		t.Errorf("StartDeviceAuthorization() expected 1 client registration, got: %d", n)
+	}
+}
+
+func TestClientPollTokenContextDone(t *testing.T) {
+	c := newStandInClient(t, &ssotest.Server{Pending: 10})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	device := Device{DeviceCode: "standin-device", ExpiresIn: 5, Interval: 1}
+	if _, err := c.PollToken(ctx, device); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("PollToken() expected error: %v, got: %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,40 @@
+package sso
+
+import (
+	"strings"
+
+	"github.com/fox-tech/creds-fetcher/client"
+)
+
+// Option represents a function that can set a configuration value to the SSO
+// initialization function New. It can return a non-nil error.
+type Option func(*Client) error
+
+// SetEndpoints sets the endpoints of the SSO OIDC service and the SSO portal,
+// e.g. for a local stand-in of them. Empty endpoints are the defaults of the
+// region.
+func SetEndpoints(oidcEndpoint, portalEndpoint string) Option {
+	return func(c *Client) error {
+		c.oidcEndpoint = strings.TrimSuffix(oidcEndpoint, "/")
+		c.portalEndpoint = strings.TrimSuffix(portalEndpoint, "/")
+		return nil
+	}
+}
+
+// SetHTTPClient sets the client used for the requests to IAM Identity Center,
+// e.g. to share the client used for AWS with its proxy and retries.
+func SetHTTPClient(hc client.HTTPClient) Option {
+	return func(c *Client) error {
+		c.httpClient = hc
+		return nil
+	}
+}
+
+// setFileManager returns a function to assign the passed fileSystemManager
+// to the token cache.
+func setFileManager(fm fileSystemManager) Option {
+	return func(c *Client) error {
+		c.fs = fm
+		return nil
+	}
+}
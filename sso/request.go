@@ -0,0 +1,104 @@
+package sso
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fox-tech/creds-fetcher/internal/oauth"
+)
+
+// errorResponse is an error of the SSO OIDC service, with an error code and
+// description, or of the SSO portal, with a message.
+type errorResponse struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+	Message     string `json:"message"`
+}
+
+// postJSON sends body encoded in JSON to the path of the SSO OIDC service,
+// the request is cancelled when ctx is done. It's not retried after 5xx
+// errors, see jsonRequest.
+func (c Client) postJSON(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	req, err := c.jsonRequest(ctx, path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.do(req)
+}
+
+// jsonRequest returns a POST request of body encoded in JSON to the path of
+// the SSO OIDC service, which is cancelled when ctx is done. Requests that can
+// be sent again after 5xx errors are marked with client.SetIdempotent.
+func (c Client) jsonRequest(ctx context.Context, path string, body interface{}) (*http.Request, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.oidcEndpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return req, nil
+}
+
+// do sends the request with the client set with SetHTTPClient or the default
+// one.
+func (c Client) do(req *http.Request) (*http.Response, error) {
+	return oauth.Client{HTTPClient: c.httpClient}.Do(req)
+}
+
+// errorFromResponse returns the error of the unsuccessful response, the
+// device authorization error matching its error code or err otherwise. The
+// code is in the body of the SSO OIDC errors, or the exception in the
+// x-amzn-ErrorType header.
+func errorFromResponse(err error, resp *http.Response) error {
+	var errRes errorResponse
+	json.NewDecoder(resp.Body).Decode(&errRes)
+
+	description := errRes.Description
+	if description == "" {
+		description = errRes.Message
+	}
+
+	code := errRes.Error
+	if code == "" {
+		code = exceptionCodes[errorType(resp)]
+	}
+
+	if err := oauth.DeviceError(code, description); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: statusCode %d/%s: %s", err, resp.StatusCode, errorType(resp), description)
+}
+
+// exceptionCodes are the error codes of the device authorization exceptions
+// of the SSO OIDC service.
+var exceptionCodes = map[string]string{
+	"AuthorizationPendingException": "authorization_pending",
+	"SlowDownException":             "slow_down",
+	"AccessDeniedException":         "access_denied",
+	"ExpiredTokenException":         "expired_token",
+}
+
+// errorType returns the exception of the response without its namespace, e.g.
+// SlowDownException for aws.sso.oidc#SlowDownException:http://....
+func errorType(resp *http.Response) string {
+	t := resp.Header.Get("x-amzn-ErrorType")
+	if i := strings.IndexByte(t, ':'); i >= 0 {
+		t = t[:i]
+	}
+	if i := strings.IndexByte(t, '#'); i >= 0 {
+		t = t[i+1:]
+	}
+
+	return t
+}
@@ -0,0 +1,118 @@
+// Package sso implements getting the credentials of an AWS account and role
+// from AWS IAM Identity Center, formerly AWS SSO. Users log in with the device
+// authorization flow of the SSO OIDC service, RegisterClient,
+// StartDeviceAuthorization and CreateToken, and the token of the login is
+// exchanged for role credentials with GetRoleCredentials of the SSO portal.
+//
+// Tokens are cached in the cache of the AWS CLI, ~/.aws/sso/cache, in its
+// format, so a login is shared with the AWS CLI and SDKs.
+package sso
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fox-tech/creds-fetcher/client"
+	"github.com/fox-tech/creds-fetcher/fsmanager"
+	"github.com/fox-tech/creds-fetcher/internal/oauth"
+)
+
+const (
+	// DefaultOIDCEndpoint and DefaultPortalEndpoint are the endpoints of the
+	// SSO OIDC service and the SSO portal in the region of the client, used
+	// when they're not set.
+	DefaultOIDCEndpoint   = "https://oidc.%s.amazonaws.com"
+	DefaultPortalEndpoint = "https://portal.sso.%s.amazonaws.com"
+
+	// ClientName is the name of the client registered for the logins.
+	ClientName = "creds-fetcher"
+
+	// Scope is the scope of the tokens, which can get role credentials.
+	Scope = "sso:account:access"
+)
+
+var (
+	// ErrMissingClientConfig is returned by New without a start URL or
+	// region.
+	ErrMissingClientConfig = errors.New("missing start URL or region for Client")
+
+	// ErrRegisterClient is returned when the client can't be registered with
+	// the SSO OIDC service.
+	ErrRegisterClient = errors.New("register client request")
+
+	// Errors of the device authorization flow, shared with the other OAuth
+	// 2.0 clients. ErrDeviceAuthorizationExpired is returned when the device
+	// code expired while polling, the login must be started again.
+	ErrDeviceAuthorizationRequest = oauth.ErrDeviceAuthorizationRequest
+	ErrDeviceAuthorizationExpired = oauth.ErrDeviceAuthorizationExpired
+	ErrTokenRequest               = oauth.ErrTokenRequest
+
+	// Device authorization errors returned by CreateToken while polling.
+	// ErrAuthorizationPending and ErrSlowDown are handled by PollToken, which
+	// keeps polling, ErrAccessDenied is returned when the user denied the
+	// authorization and ErrExpiredToken when the device code expired.
+	ErrAuthorizationPending = oauth.ErrAuthorizationPending
+	ErrSlowDown             = oauth.ErrSlowDown
+	ErrAccessDenied         = oauth.ErrAccessDenied
+	ErrExpiredToken         = oauth.ErrExpiredToken
+
+	// ErrRoleCredentials is returned when the role credentials request failed
+	// or the portal responded with an error.
+	ErrRoleCredentials = errors.New("role credentials request")
+
+	// ErrUnauthorized is returned by GetRoleCredentials when the portal
+	// rejects the token, e.g. after the user logged out, a new login is
+	// needed.
+	ErrUnauthorized = errors.New("token rejected by the sso portal")
+
+	// ErrJSONDecode is returned when a response of the service can't be
+	// decoded.
+	ErrJSONDecode = oauth.ErrJSONDecode
+)
+
+// Client logs in to the IAM Identity Center of a start URL.
+type Client struct {
+	startURL string
+	region   string
+
+	// oidcEndpoint and portalEndpoint are DefaultOIDCEndpoint and
+	// DefaultPortalEndpoint in the region unless they're set
+	oidcEndpoint   string
+	portalEndpoint string
+
+	httpClient client.HTTPClient
+
+	// fs reads and writes the token cache
+	fs fileSystemManager
+}
+
+// New returns an initialized and validated Client for the start URL of the
+// AWS access portal, e.g. https://example.awsapps.com/start, in the region of
+// IAM Identity Center. It returns a nil error if both are set.
+func New(startURL, region string, opts ...Option) (Client, error) {
+	c := Client{
+		startURL: startURL,
+		region:   region,
+		fs:       fsmanager.NewDefault(),
+	}
+
+	for _, opt := range opts {
+		if err := opt(&c); err != nil {
+			return c, err
+		}
+	}
+
+	if c.startURL == "" || c.region == "" {
+		return Client{}, ErrMissingClientConfig
+	}
+
+	if c.oidcEndpoint == "" {
+		c.oidcEndpoint = fmt.Sprintf(DefaultOIDCEndpoint, c.region)
+	}
+
+	if c.portalEndpoint == "" {
+		c.portalEndpoint = fmt.Sprintf(DefaultPortalEndpoint, c.region)
+	}
+
+	return c, nil
+}
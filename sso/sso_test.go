@@ -0,0 +1,57 @@
+package sso
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name         string
+		startURL     string
+		region       string
+		opts         []Option
+		expectOIDC   string
+		expectPortal string
+		expectErr    error
+	}{
+		{
+			name:         "start URL and region: default endpoints",
+			startURL:     "https://example.awsapps.com/start",
+			region:       "eu-west-1",
+			expectOIDC:   "https://oidc.eu-west-1.amazonaws.com",
+			expectPortal: "https://portal.sso.eu-west-1.amazonaws.com",
+		},
+		{
+			name:         "endpoints: stand-in endpoints",
+			startURL:     "https://example.awsapps.com/start",
+			region:       "eu-west-1",
+			opts:         []Option{SetEndpoints("http://127.0.0.1:8080/", "http://127.0.0.1:8081")},
+			expectOIDC:   "http://127.0.0.1:8080",
+			expectPortal: "http://127.0.0.1:8081",
+		},
+		{
+			name:      "no start URL: fails",
+			region:    "eu-west-1",
+			expectErr: ErrMissingClientConfig,
+		},
+		{
+			name:      "no region: fails",
+			startURL:  "https://example.awsapps.com/start",
+			expectErr: ErrMissingClientConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(tt.startURL, tt.region, tt.opts...)
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("New() expected error: %v, got: %v", tt.expectErr, err)
+			}
+
+			if c.oidcEndpoint != tt.expectOIDC || c.portalEndpoint != tt.expectPortal {
+				t.Errorf("New() expected endpoints %q and %q, got: %q and %q", tt.expectOIDC, tt.expectPortal, c.oidcEndpoint, c.portalEndpoint)
+			}
+		})
+	}
+}